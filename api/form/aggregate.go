@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/cabin/tailormade/resp"
+	"github.com/quanxiang-cloud/form/internal/service/aggregate"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	config2 "github.com/quanxiang-cloud/form/pkg/misc/config"
+)
+
+// Aggregation group by and metrics of the records.
+type Aggregation struct {
+	aggregation aggregate.Aggregation
+}
+
+// NewAggregation new aggregation.
+func NewAggregation(conf *config2.Config, guidance consensus.Guidance) (*Aggregation, error) {
+	a, err := aggregate.NewAggregation(conf, guidance)
+	if err != nil {
+		return nil, err
+	}
+	return &Aggregation{
+		aggregation: a,
+	}, nil
+}
+
+// Aggregate the tabular result of the groups of the records.
+func (a *Aggregation) Aggregate(c *gin.Context) {
+	profiles := getProfile(c)
+	req := &aggregate.AggregateReq{
+		AppID:    c.Param(_appID),
+		TableID:  c.Param("tableName"),
+		UserID:   profiles.userID,
+		UserName: profiles.userName,
+		DepID:    profiles.depID,
+	}
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("Aggregate").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	permit, err := getFieldPermit(c)
+	if err != nil {
+		logger.Logger.WithName("Aggregate").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	req.Permit = permit
+	resp.Format(a.aggregation.Aggregate(ctx, req)).Context(c)
+}
@@ -178,3 +178,78 @@ func (b *Backup) ImportTableRelation(c *gin.Context) {
 
 	resp.Format(b.backup.ImportTableRelation(ctx, req)).Context(c)
 }
+
+// ExportRecord export record.
+func (b *Backup) ExportRecord(c *gin.Context) {
+	req := &service.ExportRecordReq{}
+
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("ExportRecord").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	req.AppID, req.TableID = c.Param("appID"), c.Param("tableName")
+
+	resp.Format(b.backup.ExportRecord(ctx, req)).Context(c)
+}
+
+// ExportSerial export serial.
+func (b *Backup) ExportSerial(c *gin.Context) {
+	req := &service.ExportSerialReq{}
+
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("ExportSerial").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	req.AppID = c.Param("appID")
+
+	resp.Format(b.backup.ExportSerial(ctx, req)).Context(c)
+}
+
+// ImportRecord import record.
+func (b *Backup) ImportRecord(c *gin.Context) {
+	req := &service.ImportRecordReq{}
+
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("ImportRecord").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	req.AppID, req.TableID = c.Param("appID"), c.Param("tableName")
+
+	resp.Format(b.backup.ImportRecord(ctx, req)).Context(c)
+}
+
+// ImportSerial import serial.
+func (b *Backup) ImportSerial(c *gin.Context) {
+	req := &service.ImportSerialReq{}
+
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("ImportSerial").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	req.AppID = c.Param("appID")
+
+	resp.Format(b.backup.ImportSerial(ctx, req)).Context(c)
+}
+
+// Restore import all the objects of a backup in one transaction, or check them in a dry run.
+func (b *Backup) Restore(c *gin.Context) {
+	req := &service.RestoreReq{}
+
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("Restore").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	req.AppID = c.Param("appID")
+
+	resp.Format(b.backup.Restore(ctx, req)).Context(c)
+}
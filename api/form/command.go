@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/form/internal/component/event"
+	"github.com/quanxiang-cloud/form/internal/service/command"
+)
+
+// the statuses dapr takes from the subscribers.
+const (
+	daprSuccess = "SUCCESS"
+	daprRetry   = "RETRY"
+	daprDrop    = "DROP"
+)
+
+type daprResp struct {
+	Status string `json:"status"`
+}
+
+// consume the record commands subscribed from pub/sub, the commands are trusted,
+// so the route is only served on the inner port, without the permit gateway.
+func consume(cons command.Consumer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := header.MutateContext(c)
+		data := new(event.RecordEvent)
+		if err := c.ShouldBindJSON(data); err != nil {
+			// a malformed event never succeeds.
+			logger.Logger.WithName("consume").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			c.JSON(http.StatusOK, &daprResp{Status: daprDrop})
+			return
+		}
+		if err := cons.Consume(ctx, data.ID, &data.Data); err != nil {
+			logger.Logger.WithName("consume").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			c.JSON(http.StatusOK, &daprResp{Status: daprRetry})
+			return
+		}
+		c.JSON(http.StatusOK, &daprResp{Status: daprSuccess})
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/cabin/tailormade/resp"
+	"github.com/quanxiang-cloud/form/internal/service/feed"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+)
+
+const (
+	mimeEventStream = "text/event-stream"
+	lastEventID     = "Last-Event-ID"
+)
+
+// changes stream the changes of the table as server-sent events, till the client leaves.
+// the gateway passes the row condition as the query and the response permit as the header,
+// a reconnected EventSource resumes by the Last-Event-ID header, or the lastEventID param.
+func changes(f feed.Feed) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := header.MutateContext(c)
+		profiles := getProfile(c)
+		req := &feed.NextReq{
+			AppID:       c.Param(_appID),
+			TableID:     c.Param("tableName"),
+			LastEventID: c.GetHeader(lastEventID),
+			UserID:      profiles.userID,
+			UserName:    profiles.userName,
+			DepID:       profiles.depID,
+		}
+		if req.LastEventID == "" {
+			req.LastEventID = c.Query("lastEventID")
+		}
+		if req.LastEventID != "" && !feed.ValidEventID(req.LastEventID) {
+			resp.Format(nil, error2.New(code.ErrInvalidEventID)).Context(c)
+			return
+		}
+		if query := c.Query("query"); query != "" {
+			if err := json.Unmarshal([]byte(query), &req.Query); err != nil {
+				logger.Logger.WithName("changes").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+				c.AbortWithError(http.StatusBadRequest, err)
+				return
+			}
+		}
+		permit, err := getFieldPermit(c)
+		if err != nil {
+			logger.Logger.WithName("changes").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		req.Permit = permit
+
+		c.Header("Content-Type", mimeEventStream)
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		// no buffering by nginx.
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		done := c.Request.Context().Done()
+		for {
+			next, err := f.Next(ctx, req)
+			if err != nil {
+				logger.Logger.WithName("changes").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+				return
+			}
+			if err = writeEvents(c, req.LastEventID, next); err != nil {
+				return
+			}
+			req.LastEventID = next.LastEventID
+			select {
+			case <-done:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// writeEvents an id without data moves the resume point of the client past the changes out of sight,
+// a comment keeps the connection alive if nothing changed.
+func writeEvents(c *gin.Context, last string, next *feed.NextResp) error {
+	w := c.Writer
+	for _, event := range next.Events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Event, data); err != nil {
+			return err
+		}
+		last = event.ID
+	}
+	var err error
+	switch {
+	case next.LastEventID != last:
+		_, err = fmt.Fprintf(w, "id: %s\n\n", next.LastEventID)
+	case len(next.Events) == 0:
+		_, err = fmt.Fprint(w, ": heartbeat\n\n")
+	}
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
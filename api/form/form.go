@@ -11,6 +11,8 @@ import (
 	"github.com/quanxiang-cloud/cabin/tailormade/header"
 	"github.com/quanxiang-cloud/cabin/tailormade/resp"
 	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/form"
+	"github.com/quanxiang-cloud/form/internal/service/rules"
 	"github.com/quanxiang-cloud/form/internal/service/types"
 )
 
@@ -20,7 +22,7 @@ type profile struct {
 	userName string
 }
 
-func action(ctr consensus.Guidance) gin.HandlerFunc {
+func action(ctr consensus.Guidance, idem form.Idempotency) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := header.MutateContext(c)
 
@@ -35,9 +37,14 @@ func action(ctr consensus.Guidance) gin.HandlerFunc {
 			c.AbortWithError(http.StatusBadRequest, err)
 			return
 		}
-		do, err := ctr.Do(ctx, bus)
-
-		resp.Format(do, err).Context(c)
+		if bus.Method != "create" {
+			format(ctr.Do(ctx, bus)).Context(c)
+			return
+		}
+		do, err := idempotent(c, idem, bus.Method, bus.CreatedOrUpdate.Entity, func() (interface{}, error) {
+			return ctr.Do(ctx, bus)
+		})
+		format(do, err).Context(c)
 	}
 }
 
@@ -46,7 +53,7 @@ type batchCreateResp struct {
 	Total  int                `json:"total"`
 }
 
-func batchCreate(ctr consensus.Guidance) gin.HandlerFunc {
+func batchCreate(ctr consensus.Guidance, idem form.Idempotency) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var err error
 		ctx := header.MutateContext(c)
@@ -56,28 +63,57 @@ func batchCreate(ctr consensus.Guidance) gin.HandlerFunc {
 			c.AbortWithError(http.StatusBadRequest, err)
 			return
 		}
-		total := 0
-		entitys := make([]consensus.Entity, 0)
-		for _, bus := range batch {
-			err = initBus(c, bus, c.Param("action"))
-			if err != nil {
-				continue
+		format(idempotent(c, idem, c.Param("action")+"/batch", batch, func() (interface{}, error) {
+			total := 0
+			entitys := make([]consensus.Entity, 0)
+			for _, bus := range batch {
+				err = initBus(c, bus, c.Param("action"))
+				if err != nil {
+					continue
+				}
+				do, errs := ctr.Do(ctx, bus)
+				if errs != nil {
+					continue
+				}
+				total++
+				entitys = append(entitys, do.Entity)
 			}
-			do, errs := ctr.Do(ctx, bus)
-			if errs != nil {
-				continue
-			}
-			total++
-			entitys = append(entitys, do.Entity)
-		}
-		resp1 := &batchCreateResp{
-			Entity: entitys,
-			Total:  total,
-		}
-		resp.Format(resp1, nil).Context(c)
+			return &batchCreateResp{
+				Entity: entitys,
+				Total:  total,
+			}, nil
+		})).Context(c)
 	}
 }
 
+// format the same as resp.Format, the violated rules are returned as the data,
+// so the caller can tell each of them.
+func format(data interface{}, err error) *resp.Resp {
+	violations, ok := err.(rules.Violations)
+	if !ok {
+		return resp.Format(data, err)
+	}
+	r := resp.Format(nil, violations.Coded())
+	r.Data = violations
+	return r
+}
+
+// idempotent run fn once per Idempotency-Key of the user on the table, a retried request
+// returns the result of the first one, the request tells whether a key is reused by another.
+func idempotent(c *gin.Context, idem form.Idempotency, method string, request interface{},
+	fn func() (interface{}, error)) (interface{}, error) {
+	key := c.GetHeader(consensus.IdempotencyHeader)
+	if key == "" {
+		return fn()
+	}
+	key = strings.Join([]string{c.Param(_appID), c.Param("tableName"), method, c.GetHeader(_userID), key}, ":")
+	result, replayed, err := idem.Do(header.MutateContext(c), key, request, fn)
+	if replayed {
+		c.Header(form.ReplayedHeader, "true")
+	}
+	return result, err
+}
+
 // checkURL CheckURL.
 func checkURL(c *gin.Context) (appID, tableName string, err error) {
 	appID, ok := c.Params.Get("appID")
@@ -111,7 +147,7 @@ func get(ctr consensus.Guidance) gin.HandlerFunc {
 		}
 		do, err := ctr.Do(ctx, bus)
 
-		resp.Format(do, err).Context(c)
+		format(do, err).Context(c)
 	}
 }
 
@@ -132,7 +168,7 @@ func search(ctr consensus.Guidance) gin.HandlerFunc {
 		}
 		if bus.Sub.PID == "" { // is  normal
 			do, err := ctr.Do(header.MutateContext(c), bus)
-			resp.Format(do, err).Context(c)
+			format(do, err).Context(c)
 			return
 		}
 		ids := consensus.GetSimple(consensus.TermKey, "primitiveID", bus.Sub.PID)
@@ -158,7 +194,7 @@ func search(ctr consensus.Guidance) gin.HandlerFunc {
 			subQuery = consensus.GetBool(consensus.Must, subQuery, bus.Get.Query)
 		}
 		bus2 := getBus(bus.AppID, bus.TableID, subQuery, bus.Page, bus.Size)
-		resp.Format(ctr.Do(ctx, bus2)).Context(c)
+		format(ctr.Do(ctx, bus2)).Context(c)
 	}
 }
 
@@ -184,7 +220,7 @@ func delete(ctr consensus.Guidance) gin.HandlerFunc {
 		}
 		do, err := ctr.Do(header.MutateContext(c), bus)
 
-		resp.Format(do, err).Context(c)
+		format(do, err).Context(c)
 	}
 }
 
@@ -210,11 +246,11 @@ func update(ctr consensus.Guidance) gin.HandlerFunc {
 		}
 		do, err := ctr.Do(header.MutateContext(c), bus)
 
-		resp.Format(do, err).Context(c)
+		format(do, err).Context(c)
 	}
 }
 
-func create(ctr consensus.Guidance) gin.HandlerFunc {
+func create(ctr consensus.Guidance, idem form.Idempotency) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := header.MutateContext(c)
 		bus := &consensus.Bus{}
@@ -229,9 +265,9 @@ func create(ctr consensus.Guidance) gin.HandlerFunc {
 			c.AbortWithError(http.StatusBadRequest, err)
 			return
 		}
-		do, err := ctr.Do(header.MutateContext(c), bus)
-
-		resp.Format(do, err).Context(c)
+		format(idempotent(c, idem, bus.Method, bus.CreatedOrUpdate.Entity, func() (interface{}, error) {
+			return ctr.Do(ctx, bus)
+		})).Context(c)
 	}
 }
 
@@ -294,7 +330,7 @@ func relation(ctr consensus.Guidance) gin.HandlerFunc {
 			subQuery = consensus.GetBool(consensus.Must, subQuery, req.Query)
 		}
 		bus1 := getBus(req.AppID, req.SubTableID, subQuery, req.Page, req.Size)
-		resp.Format(ctr.Do(ctx, bus1)).Context(c)
+		format(ctr.Do(ctx, bus1)).Context(c)
 	}
 }
 
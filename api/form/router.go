@@ -1,6 +1,8 @@
 package api
 
 import (
+	"github.com/quanxiang-cloud/form/internal/service/command"
+	"github.com/quanxiang-cloud/form/internal/service/feed"
 	"github.com/quanxiang-cloud/form/internal/service/form"
 	"github.com/quanxiang-cloud/form/pkg/misc/client"
 	config2 "github.com/quanxiang-cloud/form/pkg/misc/config"
@@ -23,6 +25,7 @@ const (
 	internalPath = "internal"
 	v2HomePath   = "v2Home"
 	internalHome = "internalHome"
+	eventPath    = "event"
 )
 
 // Router routing.
@@ -62,6 +65,7 @@ func NewRouter(c *config2.Config) (*Router, error) {
 		v2HomePath:   engine.Group("/api/v2/form/:appID/home"),
 		internalPath: engineInner.Group("/api/v1/form/:appID/internal"),
 		internalHome: engineInner.Group("/api/v1/form/:appID/home"),
+		eventPath:    engineInner.Group("/event"),
 	}
 	for _, f := range routers {
 		err = f(c, r)
@@ -139,27 +143,70 @@ func cometRouter(c *config2.Config, r map[string]*gin.RouterGroup) error {
 	v2Path := r[v2HomePath].Group("/form/:tableName")
 	inner := r[internalPath].Group("/form/:tableName")
 	innerHome := r[internalHome].Group("/form/:tableName")
-	guide, err := form.NewRefs(c)
+	// one chain of the records, shared by all of the handlers.
+	refs, err := form.NewRefs(c)
+	if err != nil {
+		return err
+	}
+	guide, err := form.NewUpsert(c, refs)
+	if err != nil {
+		return err
+	}
+	idem, err := form.NewIdempotency(c)
+	if err != nil {
+		return err
+	}
+	transfers, err := NewTransfer(c, guide)
+	if err != nil {
+		return err
+	}
+	aggregations, err := NewAggregation(c, guide)
+	if err != nil {
+		return err
+	}
+	views, err := NewView(c, guide)
+	if err != nil {
+		return err
+	}
+	feeds, err := feed.NewFeed(c, guide)
+	if err != nil {
+		return err
+	}
+	consumer, err := command.NewConsumer(c, guide, idem)
 	if err != nil {
 		return err
 	}
 	{
-		cometHome.POST("/:action", action(guide))
-
-		cometHome.POST("/:action/batch", batchCreate(guide))
-
-		inner.POST("/:action", action(guide))     // inner use。
-		innerHome.POST("/:action", action(guide)) // poly use
+		cometHome.POST("/:action", action(guide, idem))
+		cometHome.POST("/export", transfers.Export)
+		cometHome.POST("/import", transfers.Import)
+		cometHome.GET("/job/:jobID", transfers.GetJob)
+		cometHome.GET("/job/:jobID/download", transfers.Download)
+		cometHome.POST("/aggregate", aggregations.Aggregate)
+		cometHome.GET("/views", views.ListView)
+		cometHome.POST("/views", views.CreateView)
+		cometHome.GET("/views/:viewID", views.GetView)
+		cometHome.PUT("/views/:viewID", views.UpdateView)
+		cometHome.DELETE("/views/:viewID", views.DeleteView)
+		cometHome.POST("/views/:viewID/search", views.Search)
+		cometHome.GET("/changes", changes(feeds))
+
+		cometHome.POST("/:action/batch", batchCreate(guide, idem))
+
+		inner.POST("/:action", action(guide, idem))     // inner use。
+		innerHome.POST("/:action", action(guide, idem)) // poly use
 
 		v2Path.GET("/:id", get(guide))
 		v2Path.DELETE("/:id", delete(guide))
 		v2Path.PUT("/:id", update(guide))
-		v2Path.POST("", create(guide))
+		v2Path.POST("", create(guide, idem))
 		v2Path.GET("", search(guide))
 
 		cometHome.GET("", search(guide))
 		cometHome.GET("/relation", relation(guide))
 
+		// dapr subscription of the record commands.
+		r[eventPath].POST("/record", consume(consumer))
 	}
 
 	table, err := NewTable(c)
@@ -168,6 +215,8 @@ func cometRouter(c *config2.Config, r map[string]*gin.RouterGroup) error {
 	}
 	// get schema
 	r[homePath].POST("/schema/:tableName", table.GetTable)
+	r[homePath].GET("/schema/:tableName/openapi.json", table.GetOpenAPI)
+	r[homePath].GET("/graphql/schema", table.GetGraphQLSchema)
 	return nil
 }
 
@@ -185,11 +234,48 @@ func tableRouter(c *config2.Config, r map[string]*gin.RouterGroup) error {
 		manager.POST("/search", table.FindTable)
 		manager.POST("/getInfo", table.GetTableInfo)
 		manager.POST("/getXName", table.GetXName)
+		manager.POST("/clone", table.CloneTable)
+		manager.POST("/relationGraph", table.RelationGraph)
+	}
+	managerTemplate := r[managerPath].Group("/template")
+	{
+		managerTemplate.POST("/create", table.SaveTemplate)
+		managerTemplate.POST("/list", table.ListTemplate)
+		managerTemplate.POST("/delete", table.DeleteTemplate)
+		managerTemplate.POST("/instantiate", table.InstantiateTemplate)
 	}
 	managerConfig := r[managerPath].Group("/config")
 	{
 		managerConfig.POST("/create", table.UpdateConfig)
 	}
+	webhooks, err := NewWebhook(c)
+	if err != nil {
+		return err
+	}
+	managerWebhook := r[managerPath].Group("/webhook")
+	{
+		managerWebhook.POST("/create", webhooks.CreateWebhook)
+		managerWebhook.POST("/update", webhooks.UpdateWebhook)
+		managerWebhook.POST("/delete", webhooks.DeleteWebhook)
+		managerWebhook.POST("/get", webhooks.GetWebhook)
+		managerWebhook.POST("/list", webhooks.ListWebhook)
+		managerWebhook.POST("/delivery/list", webhooks.ListDelivery)
+		managerWebhook.POST("/delivery/redeliver", webhooks.Redeliver)
+	}
+	snapshots, err := NewSnapshot(c)
+	if err != nil {
+		return err
+	}
+	managerBackup := r[managerPath].Group("/backup")
+	{
+		managerBackup.POST("/policy/put", snapshots.PutPolicy)
+		managerBackup.POST("/policy/get", snapshots.GetPolicy)
+		managerBackup.POST("/policy/delete", snapshots.DeletePolicy)
+		managerBackup.POST("/snapshot/create", snapshots.CreateSnapshot)
+		managerBackup.POST("/snapshot/get", snapshots.GetSnapshot)
+		managerBackup.POST("/snapshot/list", snapshots.ListSnapshot)
+		managerBackup.POST("/snapshot/restore", snapshots.RestoreSnapshot)
+	}
 	r[internalPath].POST("/schema/:tableName", table.GetTable)
 	return nil
 }
@@ -206,6 +292,8 @@ func innerRouter(c *config2.Config, r map[string]*gin.RouterGroup) error {
 		bg.POST("/export/role", backup.ExportRole)
 		bg.POST("/export/tableSchema", backup.ExportTableSchema)
 		bg.POST("/export/tableRelation", backup.ExportTableRelation)
+		bg.POST("/export/record/:tableName", backup.ExportRecord)
+		bg.POST("/export/serial", backup.ExportSerial)
 	}
 	{
 		bg.POST("/import/table", backup.ImportTable)
@@ -213,6 +301,9 @@ func innerRouter(c *config2.Config, r map[string]*gin.RouterGroup) error {
 		bg.POST("/import/role", backup.ImportRole)
 		bg.POST("/import/tableSchema", backup.ImportTableSchema)
 		bg.POST("/import/tableRelation", backup.ImportTableRelation)
+		bg.POST("/import/record/:tableName", backup.ImportRecord)
+		bg.POST("/import/serial", backup.ImportSerial)
+		bg.POST("/import", backup.Restore)
 	}
 
 	return nil
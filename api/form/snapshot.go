@@ -0,0 +1,114 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/cabin/tailormade/resp"
+	"github.com/quanxiang-cloud/form/internal/service/snapshot"
+	config2 "github.com/quanxiang-cloud/form/pkg/misc/config"
+)
+
+// Snapshot the backup policy and the snapshots of an app, managed by the app admins.
+type Snapshot struct {
+	snapshot snapshot.Snapshot
+}
+
+// NewSnapshot the scheduled backups are taken by the one run from main.
+func NewSnapshot(conf *config2.Config) (*Snapshot, error) {
+	s, err := snapshot.NewSnapshot(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{
+		snapshot: s,
+	}, nil
+}
+
+// bind the app is taken from the path, not the body.
+func bindSnapshot(c *gin.Context, name string, req interface{}, appID *string) bool {
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName(name).Errorw(err.Error(), header.GetRequestIDKV(header.MutateContext(c)).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return false
+	}
+	*appID = c.Param(_appID)
+	return true
+}
+
+// PutPolicy create or update the backup policy of the app.
+func (s *Snapshot) PutPolicy(c *gin.Context) {
+	profiles := getProfile(c)
+	req := &snapshot.PutPolicyReq{
+		UserID:   profiles.userID,
+		UserName: profiles.userName,
+	}
+	if !bindSnapshot(c, "PutPolicy", req, &req.AppID) {
+		return
+	}
+	resp.Format(s.snapshot.PutPolicy(header.MutateContext(c), req)).Context(c)
+}
+
+// GetPolicy get the backup policy of the app.
+func (s *Snapshot) GetPolicy(c *gin.Context) {
+	req := &snapshot.GetPolicyReq{}
+	if !bindSnapshot(c, "GetPolicy", req, &req.AppID) {
+		return
+	}
+	resp.Format(s.snapshot.GetPolicy(header.MutateContext(c), req)).Context(c)
+}
+
+// DeletePolicy stop the scheduled backups of the app.
+func (s *Snapshot) DeletePolicy(c *gin.Context) {
+	req := &snapshot.DeletePolicyReq{}
+	if !bindSnapshot(c, "DeletePolicy", req, &req.AppID) {
+		return
+	}
+	resp.Format(s.snapshot.DeletePolicy(header.MutateContext(c), req)).Context(c)
+}
+
+// CreateSnapshot take a snapshot of the app on demand.
+func (s *Snapshot) CreateSnapshot(c *gin.Context) {
+	profiles := getProfile(c)
+	req := &snapshot.CreateSnapshotReq{
+		UserID:   profiles.userID,
+		UserName: profiles.userName,
+	}
+	if !bindSnapshot(c, "CreateSnapshot", req, &req.AppID) {
+		return
+	}
+	resp.Format(s.snapshot.CreateSnapshot(header.MutateContext(c), req)).Context(c)
+}
+
+// GetSnapshot get a snapshot.
+func (s *Snapshot) GetSnapshot(c *gin.Context) {
+	req := &snapshot.GetSnapshotReq{}
+	if !bindSnapshot(c, "GetSnapshot", req, &req.AppID) {
+		return
+	}
+	resp.Format(s.snapshot.GetSnapshot(header.MutateContext(c), req)).Context(c)
+}
+
+// ListSnapshot list the snapshots of the app.
+func (s *Snapshot) ListSnapshot(c *gin.Context) {
+	req := &snapshot.ListSnapshotReq{}
+	if !bindSnapshot(c, "ListSnapshot", req, &req.AppID) {
+		return
+	}
+	resp.Format(s.snapshot.ListSnapshot(header.MutateContext(c), req)).Context(c)
+}
+
+// RestoreSnapshot restore the app to a snapshot, or check it in a dry run.
+func (s *Snapshot) RestoreSnapshot(c *gin.Context) {
+	profiles := getProfile(c)
+	req := &snapshot.RestoreSnapshotReq{
+		UserID:   profiles.userID,
+		UserName: profiles.userName,
+	}
+	if !bindSnapshot(c, "RestoreSnapshot", req, &req.AppID) {
+		return
+	}
+	resp.Format(s.snapshot.RestoreSnapshot(header.MutateContext(c), req)).Context(c)
+}
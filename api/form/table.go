@@ -11,13 +11,15 @@ import (
 	"github.com/quanxiang-cloud/cabin/tailormade/header"
 	"github.com/quanxiang-cloud/cabin/tailormade/resp"
 	table2 "github.com/quanxiang-cloud/form/internal/service/tables"
+	"github.com/quanxiang-cloud/form/pkg/misc/client"
 	config2 "github.com/quanxiang-cloud/form/pkg/misc/config"
 )
 
 // Table  table.
 type Table struct {
-	table    table2.Table
-	guidance table2.Guidance
+	table     table2.Table
+	guidance  table2.Guidance
+	appCenter client.AppCenterAPI
 }
 
 // NewTable new table.
@@ -31,8 +33,9 @@ func NewTable(conf *config2.Config) (*Table, error) {
 		return nil, err
 	}
 	return &Table{
-		table:    t,
-		guidance: guidance,
+		table:     t,
+		guidance:  guidance,
+		appCenter: client.NewAppCenterAPI(conf),
 	}, nil
 }
 
@@ -150,6 +153,133 @@ func (t *Table) GetXName(c *gin.Context) {
 	}, nil).Context(c)
 }
 
+// GetOpenAPI openapi document of the table, not wrapped so that it can be used by generators.
+func (t *Table) GetOpenAPI(c *gin.Context) {
+	req := &table2.GetOpenAPIReq{
+		AppID:   c.Param(_appID),
+		TableID: c.Param("tableName"),
+	}
+	doc, err := t.table.GetOpenAPI(header.MutateContext(c), req)
+	if err != nil {
+		resp.Format(nil, err).Context(c)
+		return
+	}
+	c.JSON(http.StatusOK, doc)
+}
+
+// GetGraphQLSchema graphql schema of the tables of the app.
+func (t *Table) GetGraphQLSchema(c *gin.Context) {
+	req := &table2.GetGraphQLSchemaReq{
+		AppID: c.Param(_appID),
+	}
+	resp.Format(t.table.GetGraphQLSchema(header.MutateContext(c), req)).Context(c)
+}
+
+// RelationGraph relation graph of the tables.
+func (t *Table) RelationGraph(c *gin.Context) {
+	req := &table2.RelationGraphReq{
+		AppID: c.Param(_appID),
+	}
+	resp.Format(t.table.RelationGraph(header.MutateContext(c), req)).Context(c)
+}
+
+// CloneTable clone table.
+func (t *Table) CloneTable(c *gin.Context) {
+	profiles := getProfile(c)
+	req := &table2.CloneTableReq{
+		AppID:    c.Param(_appID),
+		UserID:   profiles.userID,
+		UserName: profiles.userName,
+	}
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("CloneTable").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if req.DstAppID != "" && req.DstAppID != req.AppID && !t.checkIsAdmin(c, req.DstAppID) {
+		return
+	}
+	resp.Format(t.table.CloneTable(ctx, req)).Context(c)
+}
+
+// SaveTemplate save table as template.
+func (t *Table) SaveTemplate(c *gin.Context) {
+	profiles := getProfile(c)
+	req := &table2.SaveTemplateReq{
+		AppID:    c.Param(_appID),
+		UserID:   profiles.userID,
+		UserName: profiles.userName,
+	}
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("SaveTemplate").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	resp.Format(t.table.SaveTemplate(ctx, req)).Context(c)
+}
+
+// ListTemplate list template.
+func (t *Table) ListTemplate(c *gin.Context) {
+	req := &table2.ListTemplateReq{}
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("ListTemplate").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	resp.Format(t.table.ListTemplate(ctx, req)).Context(c)
+}
+
+// DeleteTemplate delete template.
+func (t *Table) DeleteTemplate(c *gin.Context) {
+	req := &table2.DeleteTemplateReq{
+		AppID: c.Param(_appID),
+	}
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("DeleteTemplate").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	resp.Format(t.table.DeleteTemplate(ctx, req)).Context(c)
+}
+
+// InstantiateTemplate create tables from template.
+func (t *Table) InstantiateTemplate(c *gin.Context) {
+	profiles := getProfile(c)
+	req := &table2.InstantiateTemplateReq{
+		AppID:    c.Param(_appID),
+		UserID:   profiles.userID,
+		UserName: profiles.userName,
+	}
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("InstantiateTemplate").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	resp.Format(t.table.InstantiateTemplate(ctx, req)).Context(c)
+}
+
+// checkIsAdmin the manager group only checks the app in the path,
+// the other app which is written to must be checked as well.
+func (t *Table) checkIsAdmin(c *gin.Context, appID string) bool {
+	ctx := header.MutateContext(c)
+	result, err := t.appCenter.CheckIsAdmin(ctx, appID, c.GetHeader(_userID), client.IsSuper(c))
+	if err != nil {
+		logger.Logger.WithName("checkIsAdmin").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return false
+	}
+	if !result.IsAdmin {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
 func getProfile(c *gin.Context) *profile {
 	depIDS := strings.Split(c.GetHeader(_departmentID), ",")
 	return &profile{
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/cabin/tailormade/resp"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/transfer"
+	config2 "github.com/quanxiang-cloud/form/pkg/misc/config"
+)
+
+const (
+	mimeCSV = "text/csv; charset=utf-8"
+	// maxImportSize the size limit of the file to import.
+	maxImportSize = 32 << 20
+)
+
+// Transfer export and import.
+type Transfer struct {
+	transfer transfer.Transfer
+}
+
+// NewTransfer new transfer.
+func NewTransfer(conf *config2.Config, guidance consensus.Guidance) (*Transfer, error) {
+	t, err := transfer.NewTransfer(conf, guidance)
+	if err != nil {
+		return nil, err
+	}
+	return &Transfer{
+		transfer: t,
+	}, nil
+}
+
+// Export export the records as csv, by a job when there are too many.
+func (t *Transfer) Export(c *gin.Context) {
+	profiles := getProfile(c)
+	req := &transfer.ExportReq{
+		AppID:    c.Param(_appID),
+		TableID:  c.Param("tableName"),
+		UserID:   profiles.userID,
+		UserName: profiles.userName,
+		DepID:    profiles.depID,
+	}
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("Export").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	permit, err := getFieldPermit(c)
+	if err != nil {
+		logger.Logger.WithName("Export").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	req.Permit = permit
+
+	if !req.Async {
+		meta, err := t.transfer.Prepare(ctx, req)
+		if err != nil {
+			resp.Format(nil, err).Context(c)
+			return
+		}
+		if meta.Total <= transfer.SyncLimit {
+			setAttachment(c, meta.FileName)
+			c.Status(http.StatusOK)
+			if err = t.transfer.WriteCSV(ctx, req, c.Writer); err != nil {
+				// the header is written, the error can only be logged.
+				logger.Logger.WithName("Export").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			}
+			return
+		}
+	}
+	resp.Format(t.transfer.Export(ctx, req)).Context(c)
+}
+
+// GetJob get the progress of an export or import job.
+func (t *Transfer) GetJob(c *gin.Context) {
+	req := &transfer.GetJobReq{
+		AppID:   c.Param(_appID),
+		TableID: c.Param("tableName"),
+		ID:      c.Param("jobID"),
+		UserID:  c.GetHeader(_userID),
+	}
+	resp.Format(t.transfer.GetJob(header.MutateContext(c), req)).Context(c)
+}
+
+// Download download the file of an export job.
+func (t *Transfer) Download(c *gin.Context) {
+	req := &transfer.GetJobReq{
+		AppID:   c.Param(_appID),
+		TableID: c.Param("tableName"),
+		ID:      c.Param("jobID"),
+		UserID:  c.GetHeader(_userID),
+	}
+	file, err := t.transfer.Download(header.MutateContext(c), req)
+	if err != nil {
+		resp.Format(nil, err).Context(c)
+		return
+	}
+	defer file.File.Close()
+	setAttachment(c, file.FileName)
+	c.DataFromReader(http.StatusOK, -1, mimeCSV, file.File, nil)
+}
+
+// Import import the records from a csv or xlsx file, by a job when there are too many.
+func (t *Transfer) Import(c *gin.Context) {
+	profiles := getProfile(c)
+	req := &transfer.ImportReq{
+		AppID:    c.Param(_appID),
+		TableID:  c.Param("tableName"),
+		UserID:   profiles.userID,
+		UserName: profiles.userName,
+		DepID:    profiles.depID,
+	}
+	ctx := header.MutateContext(c)
+	if err := bindImport(c, req); err != nil {
+		logger.Logger.WithName("Import").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	permit, err := getFieldPermit(c)
+	if err != nil {
+		logger.Logger.WithName("Import").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	req.Permit = permit
+	resp.Format(t.transfer.Import(ctx, req)).Context(c)
+}
+
+// bindImport the file and the options are sent as multipart form, the mapping as json.
+func bindImport(c *gin.Context, req *transfer.ImportReq) error {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return err
+	}
+	if fh.Size > maxImportSize {
+		return fmt.Errorf("file is larger than %d bytes", maxImportSize)
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if req.File, err = io.ReadAll(file); err != nil {
+		return err
+	}
+	req.FileName = fh.Filename
+
+	if mapping := c.PostForm("mapping"); mapping != "" {
+		if err = json.Unmarshal([]byte(mapping), &req.Mapping); err != nil {
+			return err
+		}
+	}
+	if req.DryRun, err = formBool(c, "dryRun"); err != nil {
+		return err
+	}
+	req.Async, err = formBool(c, "async")
+	return err
+}
+
+func formBool(c *gin.Context, key string) (bool, error) {
+	value := c.PostForm(key)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func setAttachment(c *gin.Context, fileName string) {
+	c.Header("Content-Type", mimeCSV)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(fileName)))
+}
+
+// getFieldPermit nil when the gateway does not restrict the fields.
+func getFieldPermit(c *gin.Context) (models.FiledPermit, error) {
+	value := c.GetHeader(consensus.FieldPermitHeader)
+	if value == "" {
+		return nil, nil
+	}
+	permit := make(models.FiledPermit)
+	if err := json.Unmarshal([]byte(value), &permit); err != nil {
+		return nil, err
+	}
+	return permit, nil
+}
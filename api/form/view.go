@@ -0,0 +1,124 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/cabin/tailormade/resp"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/view"
+	config2 "github.com/quanxiang-cloud/form/pkg/misc/config"
+)
+
+const _viewID = "viewID"
+
+// View the saved views of a table.
+type View struct {
+	view view.View
+}
+
+// NewView new view.
+func NewView(conf *config2.Config, guidance consensus.Guidance) (*View, error) {
+	v, err := view.NewView(conf, guidance)
+	if err != nil {
+		return nil, err
+	}
+	return &View{
+		view: v,
+	}, nil
+}
+
+// CreateView create a view owned by the user.
+func (v *View) CreateView(c *gin.Context) {
+	profiles := getProfile(c)
+	req := &view.CreateViewReq{
+		AppID:    c.Param(_appID),
+		TableID:  c.Param("tableName"),
+		UserID:   profiles.userID,
+		UserName: profiles.userName,
+	}
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("CreateView").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	resp.Format(v.view.CreateView(ctx, req)).Context(c)
+}
+
+// UpdateView update the view of the user.
+func (v *View) UpdateView(c *gin.Context) {
+	req := &view.UpdateViewReq{
+		AppID:   c.Param(_appID),
+		TableID: c.Param("tableName"),
+		ID:      c.Param(_viewID),
+		UserID:  getProfile(c).userID,
+	}
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("UpdateView").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	resp.Format(v.view.UpdateView(ctx, req)).Context(c)
+}
+
+// DeleteView delete the view of the user.
+func (v *View) DeleteView(c *gin.Context) {
+	req := &view.DeleteViewReq{
+		AppID:   c.Param(_appID),
+		TableID: c.Param("tableName"),
+		ID:      c.Param(_viewID),
+		UserID:  getProfile(c).userID,
+	}
+	resp.Format(v.view.DeleteView(header.MutateContext(c), req)).Context(c)
+}
+
+// GetView get a view the user can see.
+func (v *View) GetView(c *gin.Context) {
+	req := &view.GetViewReq{
+		AppID:   c.Param(_appID),
+		TableID: c.Param("tableName"),
+		ID:      c.Param(_viewID),
+		UserID:  getProfile(c).userID,
+	}
+	resp.Format(v.view.GetView(header.MutateContext(c), req)).Context(c)
+}
+
+// ListView list the views the user can see.
+func (v *View) ListView(c *gin.Context) {
+	req := &view.ListViewReq{
+		AppID:   c.Param(_appID),
+		TableID: c.Param("tableName"),
+		UserID:  getProfile(c).userID,
+	}
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBindQuery(req); err != nil {
+		logger.Logger.WithName("ListView").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	resp.Format(v.view.ListView(ctx, req)).Context(c)
+}
+
+// Search search the records by the view.
+func (v *View) Search(c *gin.Context) {
+	profiles := getProfile(c)
+	req := &view.SearchReq{
+		AppID:    c.Param(_appID),
+		TableID:  c.Param("tableName"),
+		ID:       c.Param(_viewID),
+		UserID:   profiles.userID,
+		UserName: profiles.userName,
+		DepID:    profiles.depID,
+	}
+	ctx := header.MutateContext(c)
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName("SearchView").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	resp.Format(v.view.Search(ctx, req)).Context(c)
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/cabin/tailormade/resp"
+	"github.com/quanxiang-cloud/form/internal/service/webhook"
+	config2 "github.com/quanxiang-cloud/form/pkg/misc/config"
+)
+
+// Webhook the webhooks of the tables, managed by the app admins.
+type Webhook struct {
+	webhook webhook.Webhook
+}
+
+// NewWebhook new webhook.
+func NewWebhook(conf *config2.Config) (*Webhook, error) {
+	w, err := webhook.NewWebhook(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Webhook{
+		webhook: w,
+	}, nil
+}
+
+// bind the app is taken from the path, not the body.
+func bindWebhook(c *gin.Context, name string, req interface{}, appID *string) bool {
+	if err := c.ShouldBind(req); err != nil {
+		logger.Logger.WithName(name).Errorw(err.Error(), header.GetRequestIDKV(header.MutateContext(c)).Fuzzy()...)
+		c.AbortWithError(http.StatusBadRequest, err)
+		return false
+	}
+	*appID = c.Param(_appID)
+	return true
+}
+
+// CreateWebhook create a webhook, the secret is only returned here.
+func (w *Webhook) CreateWebhook(c *gin.Context) {
+	profiles := getProfile(c)
+	req := &webhook.CreateWebhookReq{
+		UserID:   profiles.userID,
+		UserName: profiles.userName,
+	}
+	if !bindWebhook(c, "CreateWebhook", req, &req.AppID) {
+		return
+	}
+	resp.Format(w.webhook.CreateWebhook(header.MutateContext(c), req)).Context(c)
+}
+
+// UpdateWebhook update a webhook.
+func (w *Webhook) UpdateWebhook(c *gin.Context) {
+	req := &webhook.UpdateWebhookReq{}
+	if !bindWebhook(c, "UpdateWebhook", req, &req.AppID) {
+		return
+	}
+	resp.Format(w.webhook.UpdateWebhook(header.MutateContext(c), req)).Context(c)
+}
+
+// DeleteWebhook delete a webhook and its deliveries.
+func (w *Webhook) DeleteWebhook(c *gin.Context) {
+	req := &webhook.DeleteWebhookReq{}
+	if !bindWebhook(c, "DeleteWebhook", req, &req.AppID) {
+		return
+	}
+	resp.Format(w.webhook.DeleteWebhook(header.MutateContext(c), req)).Context(c)
+}
+
+// GetWebhook get a webhook.
+func (w *Webhook) GetWebhook(c *gin.Context) {
+	req := &webhook.GetWebhookReq{}
+	if !bindWebhook(c, "GetWebhook", req, &req.AppID) {
+		return
+	}
+	resp.Format(w.webhook.GetWebhook(header.MutateContext(c), req)).Context(c)
+}
+
+// ListWebhook list the webhooks of the app.
+func (w *Webhook) ListWebhook(c *gin.Context) {
+	req := &webhook.ListWebhookReq{}
+	if !bindWebhook(c, "ListWebhook", req, &req.AppID) {
+		return
+	}
+	resp.Format(w.webhook.ListWebhook(header.MutateContext(c), req)).Context(c)
+}
+
+// ListDelivery list the deliveries of a webhook.
+func (w *Webhook) ListDelivery(c *gin.Context) {
+	req := &webhook.ListDeliveryReq{}
+	if !bindWebhook(c, "ListDelivery", req, &req.AppID) {
+		return
+	}
+	resp.Format(w.webhook.ListDelivery(header.MutateContext(c), req)).Context(c)
+}
+
+// Redeliver post a delivery again.
+func (w *Webhook) Redeliver(c *gin.Context) {
+	req := &webhook.RedeliverReq{}
+	if !bindWebhook(c, "Redeliver", req, &req.AppID) {
+		return
+	}
+	resp.Format(w.webhook.Redeliver(header.MutateContext(c), req)).Context(c)
+}
@@ -39,10 +39,40 @@ func Permit(form permit.Permit) echo.HandlerFunc {
 		return nil
 	}
 }
+
+// PermitRaw the body is left untouched, like multipart files, only the path and headers are bound.
+func PermitRaw(form permit.Permit) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := &permit.Request{
+			Echo: c,
+		}
+		ctx := echo2.MutateContext(c)
+		if err := bindPath(c, req); err != nil {
+			logger.Logger.WithName("bind params").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			c.NoContent(http.StatusBadRequest)
+			return nil
+		}
+
+		resp, err := form.Do(ctx, req)
+		if err != nil {
+			return err
+		}
+		if resp == nil {
+			c.NoContent(http.StatusForbidden)
+			return nil
+		}
+		return nil
+	}
+}
+
 func bindParams(c echo.Context, i *permit.Request) error {
 	if err := httputil.GetRequestArgs(c, &i.Data); err != nil {
 		return err
 	}
+	return bindPath(c, i)
+}
+
+func bindPath(c echo.Context, i *permit.Request) error {
 	if err := (&echo.DefaultBinder{}).BindPathParams(c, i); err != nil {
 		return err
 	}
@@ -69,3 +99,24 @@ func V2FormPath(next echo.HandlerFunc) echo.HandlerFunc {
 		return next(c)
 	}
 }
+
+// ActionPath the action is permitted by the permit of another one,
+// like export and aggregate by search, import by create.
+func ActionPath(action string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			paths := c.Request().URL.Path
+			c.Set(path, fmt.Sprintf("%s/%s", paths[0:strings.LastIndex(paths, "/")], action))
+			return next(c)
+		}
+	}
+}
+
+// ViewPath the records of a view are permitted by the permit of search of the table.
+func ViewPath(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		paths := c.Request().URL.Path
+		c.Set(path, fmt.Sprintf("%s/search", paths[0:strings.LastIndex(paths, "/views/")]))
+		return next(c)
+	}
+}
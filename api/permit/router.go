@@ -108,11 +108,43 @@ func formRouter(c *config2.Config, r map[string]*echo.Group) error {
 	if err != nil {
 		return err
 	}
+	exportCor, err := side.NewExportAuth(c, c.Endpoint.Form)
+	if err != nil {
+		logger.Logger.WithName("instantiation form export cor").Error(err)
+		return err
+	}
+	importCor, err := side.NewImportAuth(c, c.Endpoint.Form)
+	if err != nil {
+		logger.Logger.WithName("instantiation form import cor").Error(err)
+		return err
+	}
+	upsertCor, err := side.NewUpsertAuth(c, c.Endpoint.Form)
+	if err != nil {
+		logger.Logger.WithName("instantiation form upsert cor").Error(err)
+		return err
+	}
+	graphQL, err := side.NewGraphQL(c, c.Endpoint.Form)
+	if err != nil {
+		logger.Logger.WithName("instantiation form graphql").Error(err)
+		return err
+	}
 
 	group := r[formPath]
 	{
 		group.Any("/*", Permit(p))
 		group.Any("/:appID/home/form/:tableID/:action", Permit(cor))
+		group.POST("/:appID/home/form/:tableID/export", Permit(exportCor), ActionPath("search"))
+		group.POST("/:appID/home/form/:tableID/import", PermitRaw(importCor), ActionPath("create"))
+		group.POST("/:appID/home/form/:tableID/aggregate", Permit(exportCor), ActionPath("search"))
+		// the stream can not be filtered here, the condition and the readable fields of search are passed on.
+		group.GET("/:appID/home/form/:tableID/changes", Permit(exportCor), ActionPath("search"))
+		group.POST("/:appID/home/form/:tableID/upsert", Permit(upsertCor))
+		// the views expose no record, the records of a view are searched by the permit of search.
+		group.Any("/:appID/home/form/:tableID/views", Permit(p))
+		group.Any("/:appID/home/form/:tableID/views/:viewID", Permit(p))
+		group.POST("/:appID/home/form/:tableID/views/:viewID/search", Permit(cor), ViewPath)
+		// each root field is permitted by the action of its table.
+		group.POST("/:appID/home/graphql", Permit(graphQL))
 	}
 	v2Form := r[v2FormPath]
 	{
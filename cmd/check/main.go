@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/form/internal/service/consistency"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+)
+
+var (
+	configPath = flag.String("config", "../../configs/config.yml", "-config 配置文件地址")
+	appID      = flag.String("app", "", "-app 检查的应用，为空时检查所有应用")
+	repair     = flag.Bool("repair", false, "-repair 修复能够修复的问题")
+	swagger    = flag.Bool("swagger", false, "-swagger 重新注册所有表的 swagger")
+)
+
+// 检查 mysql、redis 与 poly 中的元数据是否一致.
+func main() {
+	flag.Parse()
+	conf, err := config.NewConfig(*configPath)
+	if err != nil {
+		panic(err)
+	}
+	logger.Logger = logger.New(&conf.Log)
+
+	checker, err := consistency.NewChecker(conf)
+	if err != nil {
+		panic(err)
+	}
+	resp, err := checker.Check(context.Background(), &consistency.CheckReq{
+		AppID:   *appID,
+		Repair:  *repair,
+		Swagger: *swagger,
+	})
+	if resp != nil {
+		write(resp)
+	}
+	if err != nil {
+		logger.Logger.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+func write(resp *consistency.CheckResp) {
+	data := make([][]string, 0, len(resp.Issues))
+	for _, issue := range resp.Issues {
+		data = append(data, []string{
+			issue.AppID,
+			issue.Kind,
+			issue.ID,
+			issue.Message,
+			fmt.Sprintf("%t", issue.Repaired),
+		})
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"app", "kind", "id", "message", "repaired"})
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(true)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator("")
+	table.SetRowSeparator("")
+	table.SetHeaderLine(false)
+	table.SetBorder(false)
+	table.SetTablePadding("\t")
+	table.SetNoWhiteSpace(true)
+	table.AppendBulk(data)
+	table.Render()
+	fmt.Printf("%d apps, %d issues\n", resp.Apps, len(resp.Issues))
+}
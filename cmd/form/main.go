@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"os/signal"
@@ -8,6 +9,8 @@ import (
 
 	"github.com/quanxiang-cloud/cabin/logger"
 	api "github.com/quanxiang-cloud/form/api/form"
+	"github.com/quanxiang-cloud/form/internal/service/snapshot"
+	"github.com/quanxiang-cloud/form/internal/service/webhook"
 	"github.com/quanxiang-cloud/form/pkg/misc/config"
 )
 
@@ -28,6 +31,20 @@ func main() {
 		panic(err)
 	}
 
+	// the background jobs stop with the service.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	webhooks, err := webhook.NewWebhook(conf)
+	if err != nil {
+		panic(err)
+	}
+	go webhooks.Run(ctx)
+	snapshots, err := snapshot.NewSnapshot(conf)
+	if err != nil {
+		panic(err)
+	}
+	go snapshots.Run(ctx)
+
 	go router.Run()
 	router.Probe.SetRunning()
 	logger.Logger.Info("running...")
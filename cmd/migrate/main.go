@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/quanxiang-cloud/cabin/logger"
+	mongo2 "github.com/quanxiang-cloud/cabin/tailormade/db/mongo"
+	mysql2 "github.com/quanxiang-cloud/cabin/tailormade/db/mysql"
+	"github.com/quanxiang-cloud/form/pkg/migrate"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	"github.com/quanxiang-cloud/form/schema"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	configPath = flag.String("config", "../../configs/config.yml", "-config 配置文件地址")
+	dryRun     = flag.Bool("dry-run", false, "-dry-run 只列出待执行的步骤及预期数量")
+)
+
+// 按版本顺序执行未完成的迁移步骤，失败后再次执行从失败的步骤继续.
+func main() {
+	flag.Parse()
+	conf, err := config.NewConfig(*configPath)
+	if err != nil {
+		panic(err)
+	}
+	logger.Logger = logger.New(&conf.Log)
+
+	db, err := mysql2.New(conf.Mysql, logger.Logger)
+	if err != nil {
+		panic(err)
+	}
+	var client *mongo.Client
+	if len(conf.Mongo.Hosts) != 0 {
+		client, err = mongo2.New(&conf.Mongo)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	steps, err := migrate.Steps(db, client, schema.FS)
+	if err != nil {
+		panic(err)
+	}
+	migrator, err := migrate.New(migrate.NewMysqlState(db), steps...)
+	if err != nil {
+		panic(err)
+	}
+	records, err := migrator.Run(context.Background(), *dryRun)
+	write(records)
+	if err != nil {
+		logger.Logger.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+func write(records []*migrate.Record) {
+	data := make([][]string, 0, len(records))
+	for _, record := range records {
+		data = append(data, []string{
+			record.ID,
+			string(record.Status),
+			format(record.Expected),
+			format(record.Verified),
+			record.Message,
+		})
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"step", "status", "expected", "verified", "message"})
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(true)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator("")
+	table.SetRowSeparator("")
+	table.SetHeaderLine(false)
+	table.SetBorder(false)
+	table.SetTablePadding("\t")
+	table.SetNoWhiteSpace(true)
+	table.AppendBulk(data)
+	table.Render()
+}
+
+func format(counts migrate.Counts) string {
+	kinds := make([]string, 0, len(counts))
+	for kind, count := range counts {
+		kinds = append(kinds, fmt.Sprintf("%s=%d", kind, count))
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, " ")
+}
@@ -5,7 +5,6 @@ go 1.16
 require (
 	github.com/dapr/go-sdk v1.3.1
 	github.com/gin-gonic/gin v1.7.7
-	github.com/globalsign/mgo v0.0.0-20181015135952-eeefdecb41b8
 	github.com/go-openapi/spec v0.20.4
 	github.com/go-redis/redis/v8 v8.11.4
 	github.com/labstack/echo/v4 v4.7.2
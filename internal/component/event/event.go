@@ -1,6 +1,9 @@
 package event
 
-import "github.com/quanxiang-cloud/form/internal/models"
+import (
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+)
 
 type DaprEvent struct {
 	Topic           string `json:"topic"`
@@ -34,3 +37,51 @@ type PermitSpec struct {
 	Response  models.FiledPermit `json:"response"`
 	Action    string             `json:"action"`
 }
+
+// RecordEvent a command of the other services to write the records, as delivered by dapr.
+type RecordEvent struct {
+	Topic           string        `json:"topic"`
+	Pubsubname      string        `json:"pubsubname"`
+	Traceid         string        `json:"traceid"`
+	ID              string        `json:"id"`
+	Datacontenttype string        `json:"datacontenttype"`
+	Data            RecordCommand `json:"data"`
+	Type            string        `json:"type"`
+	Specversion     string        `json:"specversion"`
+	Source          string        `json:"source"`
+}
+
+// RecordCommand create, update, delete or upsert the records as the user.
+type RecordCommand struct {
+	// ID the idempotency key of the command, the id of the event if empty.
+	ID      string `json:"id"`
+	Method  string `json:"method"`
+	AppID   string `json:"appID"`
+	TableID string `json:"tableID"`
+	// Entity the record to create or upsert, or the fields to update.
+	Entity interface{} `json:"entity,omitempty"`
+	// Query the records to update or delete.
+	Query types.Query `json:"query,omitempty"`
+	Ref   types.Ref   `json:"ref,omitempty"`
+
+	UserID   string `json:"userID"`
+	UserName string `json:"userName"`
+	DepID    string `json:"depID"`
+	// ReplyTopic the topic the reply is published to, dapr.topicReply if empty.
+	ReplyTopic string `json:"replyTopic,omitempty"`
+}
+
+// RecordReply the result of a command.
+type RecordReply struct {
+	ID      string `json:"id"`
+	Method  string `json:"method"`
+	AppID   string `json:"appID"`
+	TableID string `json:"tableID"`
+	// Status succeed or failed.
+	Status  string      `json:"status"`
+	Code    int64       `json:"code,omitempty"`
+	Message string      `json:"msg,omitempty"`
+	Result  interface{} `json:"result,omitempty"`
+	// Details the structured reasons of the failure, like the violated rules.
+	Details interface{} `json:"details,omitempty"`
+}
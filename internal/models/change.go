@@ -0,0 +1,26 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Change a change of the records of a table, in the order of the feed of the table.
+type Change struct {
+	// ID the position in the feed, assigned on append
+	ID      string   `json:"-"`
+	Event   string   `json:"event"`
+	IDs     []string `json:"ids"`
+	Created int64    `json:"created"`
+	// Tombstones the deleted records as they were, the subscribers tell by them which deletes are in sight.
+	Tombstones []map[string]interface{} `json:"tombstones,omitempty"`
+}
+
+type ChangeRepo interface {
+	Append(ctx context.Context, appID, tableID string, change *Change) error
+	// Last the id of the latest change, the changes after it are the new ones.
+	Last(ctx context.Context, appID, tableID string) (string, error)
+	// Read the changes after the id, it waits for the block at most if none,
+	// and returns at once if the block is negative.
+	Read(ctx context.Context, appID, tableID, after string, count int64, block time.Duration) ([]*Change, error)
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"gorm.io/gorm"
+)
+
+// JobType JobType.
+type JobType string
+
+const (
+	ExportJob JobType = "export"
+	ImportJob JobType = "import"
+)
+
+// JobStatus JobStatus.
+type JobStatus string
+
+const (
+	JobRunning JobStatus = "running"
+	JobSucceed JobStatus = "succeed"
+	JobFailed  JobStatus = "failed"
+)
+
+// FormJob an async export or import of the records of a table.
+type FormJob struct {
+	ID      string
+	AppID   string
+	TableID string
+	Type    JobType
+	Status  JobStatus
+	// Total the records or rows to handle
+	Total int64
+	// Processed the records or rows handled
+	Processed int64
+	// FileName the result file name of export, the file is kept in the export directory
+	FileName string
+	// Report the result of import
+	Report  JobReport
+	Message string
+
+	CreatedAt   int64
+	UpdatedAt   int64
+	CreatorID   string
+	CreatorName string
+}
+
+// JobReport JobReport.
+type JobReport map[string]interface{}
+
+// Value 实现方法.
+func (p JobReport) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan 实现方法.
+func (p *JobReport) Scan(data interface{}) error {
+	return json.Unmarshal(data.([]byte), &p)
+}
+
+type FormJobRepo interface {
+	Create(db *gorm.DB, job *FormJob) error
+	Update(db *gorm.DB, id string, job *FormJob) error
+	Get(db *gorm.DB, id string) (*FormJob, error)
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Idempotency the result of a request by its idempotency key.
+type Idempotency struct {
+	// Fingerprint the digest of the request, the key can not be reused by another request.
+	Fingerprint string `json:"fingerprint"`
+	// Done the request is finished, the result is kept until the key expires.
+	Done   bool            `json:"done"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+type IdempotencyRepo interface {
+	// Create create the key if it does not exist.
+	Create(ctx context.Context, key string, value *Idempotency, ttl time.Duration) (bool, error)
+	Get(ctx context.Context, key string) (*Idempotency, error)
+	Update(ctx context.Context, key string, value *Idempotency, ttl time.Duration) error
+	// Expire reset the ttl of the key, if it exists.
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
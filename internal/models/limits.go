@@ -31,15 +31,23 @@ type LimitsRepo interface {
 	GetPermit(ctx context.Context, roleID, path string) (*Limits, error)
 	DeletePermit(ctx context.Context, roleID string) error
 	DeletePermitByPath(ctx context.Context, roleID, path string) error
+	// ListPermit the cached limits of the role by their paths.
+	ListPermit(ctx context.Context, roleID string) (map[string]*Limits, error)
+	// ListPermitRoles the roles whose limits are cached.
+	ListPermitRoles(ctx context.Context) ([]string, error)
 
 	CreatePerMatch(ctx context.Context, match *UserRoles) error
 	GetPerMatch(ctx context.Context, appID, userID string) (*UserRoles, error)
 	DeletePerMatch(ctx context.Context, appID string) error
+	// ListPerMatch the cached roles of the users of the app, by the user ids.
+	ListPerMatch(ctx context.Context, appID string) (map[string]string, error)
 
 	// Lock 设置分布式锁
 	Lock(ctx context.Context, key string, val interface{}, ttl time.Duration) (bool, error)
 	// UnLock 解除分布式锁
 	UnLock(ctx context.Context, key string) error
+	// UnLockOwner release the lock only if it is still held by the owner, the val of Lock.
+	UnLockOwner(ctx context.Context, key string, owner interface{}) error
 	// PerMatchExpire 给某个键设置过期时间
 	PerMatchExpire(ctx context.Context, key string, ttl time.Duration) error
 	// PermitExpire PermitExpire
@@ -0,0 +1,52 @@
+package mysql
+
+import (
+	"github.com/quanxiang-cloud/form/internal/models"
+	"gorm.io/gorm"
+)
+
+type formJobRepo struct{}
+
+func NewFormJobRepo() models.FormJobRepo {
+	return &formJobRepo{}
+}
+
+func (f *formJobRepo) TableName() string {
+	return "form_job"
+}
+
+func (f *formJobRepo) Create(db *gorm.DB, job *models.FormJob) error {
+	return db.Table(f.TableName()).Create(job).Error
+}
+
+func (f *formJobRepo) Update(db *gorm.DB, id string, job *models.FormJob) error {
+	setMap := map[string]interface{}{
+		"processed":  job.Processed,
+		"updated_at": job.UpdatedAt,
+	}
+	if job.Status != "" {
+		setMap["status"] = job.Status
+	}
+	if job.Total != 0 {
+		setMap["total"] = job.Total
+	}
+	if job.FileName != "" {
+		setMap["file_name"] = job.FileName
+	}
+	if job.Report != nil {
+		setMap["report"] = job.Report
+	}
+	if job.Message != "" {
+		setMap["message"] = job.Message
+	}
+	return db.Table(f.TableName()).Where("id = ?", id).Updates(setMap).Error
+}
+
+func (f *formJobRepo) Get(db *gorm.DB, id string) (*models.FormJob, error) {
+	job := new(models.FormJob)
+	err := db.Table(f.TableName()).Where("id = ?", id).Find(job).Error
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
@@ -96,7 +96,7 @@ func (t *permitRepo) List(db *gorm.DB, query *models.PermitQuery, page, size int
 		return nil, 0, err
 	}
 
-	err = db.Offset((page - 1) * size).Limit(size).Find(&permits).Error
+	err = db.Order("id").Offset((page - 1) * size).Limit(size).Find(&permits).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -88,3 +88,12 @@ func (t *roleRepo) TableName() string {
 func NewRoleRepo() models.RoleRepo {
 	return &roleRepo{}
 }
+
+func (t *roleRepo) AppIDs(db *gorm.DB) ([]string, error) {
+	appIDs := make([]string, 0)
+	err := db.Table(t.TableName()).Distinct("app_id").Pluck("app_id", &appIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return appIDs, nil
+}
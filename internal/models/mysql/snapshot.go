@@ -0,0 +1,169 @@
+package mysql
+
+import (
+	"github.com/quanxiang-cloud/form/internal/models"
+	"gorm.io/gorm"
+)
+
+type backupPolicyRepo struct{}
+
+func NewBackupPolicyRepo() models.BackupPolicyRepo {
+	return &backupPolicyRepo{}
+}
+
+func (b *backupPolicyRepo) TableName() string {
+	return "backup_policy"
+}
+
+func (b *backupPolicyRepo) Create(db *gorm.DB, policy *models.BackupPolicy) error {
+	return db.Table(b.TableName()).Create(policy).Error
+}
+
+func (b *backupPolicyRepo) Update(db *gorm.DB, id string, policy *models.BackupPolicy) error {
+	setMap := map[string]interface{}{
+		"interval":   policy.Interval,
+		"full_every": policy.FullEvery,
+		"retention":  policy.Retention,
+		"disabled":   policy.Disabled,
+		"next_at":    policy.NextAt,
+		"updated_at": policy.UpdatedAt,
+	}
+	return db.Table(b.TableName()).Where("id = ?", id).Updates(setMap).Error
+}
+
+func (b *backupPolicyRepo) Get(db *gorm.DB, appID string) (*models.BackupPolicy, error) {
+	policy := new(models.BackupPolicy)
+	err := db.Table(b.TableName()).Where("app_id = ?", appID).Find(policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (b *backupPolicyRepo) Delete(db *gorm.DB, appID string) error {
+	return db.Table(b.TableName()).Where("app_id = ?", appID).Delete(&models.BackupPolicy{}).Error
+}
+
+func (b *backupPolicyRepo) List(db *gorm.DB, query *models.BackupPolicyQuery, page, size int) ([]*models.BackupPolicy, int64, error) {
+	page, size = pages(page, size)
+	db = db.Table(b.TableName())
+	if query.AppID != "" {
+		db = db.Where("app_id = ?", query.AppID)
+	}
+	if query.DueAt != 0 {
+		db = db.Where("disabled = ? and next_at <= ?", false, query.DueAt)
+	}
+
+	var (
+		count    int64
+		policies []*models.BackupPolicy
+	)
+
+	err := db.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = db.Order("next_at").Offset((page - 1) * size).Limit(size).Find(&policies).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return policies, count, nil
+}
+
+func (b *backupPolicyRepo) Claim(db *gorm.DB, id string, nextAt, to int64) (bool, error) {
+	ql := db.Table(b.TableName()).Where("id = ? and next_at = ?", id, nextAt).Update("next_at", to)
+	if ql.Error != nil {
+		return false, ql.Error
+	}
+	return ql.RowsAffected == 1, nil
+}
+
+type snapshotRepo struct{}
+
+func NewSnapshotRepo() models.SnapshotRepo {
+	return &snapshotRepo{}
+}
+
+func (s *snapshotRepo) TableName() string {
+	return "snapshot"
+}
+
+func (s *snapshotRepo) Create(db *gorm.DB, snapshot *models.Snapshot) error {
+	return db.Table(s.TableName()).Create(snapshot).Error
+}
+
+func (s *snapshotRepo) Update(db *gorm.DB, id string, snapshot *models.Snapshot) error {
+	setMap := map[string]interface{}{
+		"size":       snapshot.Size,
+		"updated_at": snapshot.UpdatedAt,
+	}
+	if snapshot.Status != "" {
+		setMap["status"] = snapshot.Status
+	}
+	if snapshot.Message != "" {
+		setMap["message"] = snapshot.Message
+	}
+	if snapshot.Counts != nil {
+		setMap["counts"] = snapshot.Counts
+	}
+	return db.Table(s.TableName()).Where("id = ?", id).Updates(setMap).Error
+}
+
+func (s *snapshotRepo) Get(db *gorm.DB, id string) (*models.Snapshot, error) {
+	snapshot := new(models.Snapshot)
+	err := db.Table(s.TableName()).Where("id = ?", id).Find(snapshot).Error
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (s *snapshotRepo) query(db *gorm.DB, query *models.SnapshotQuery) *gorm.DB {
+	if query.ID != "" {
+		db = db.Where("id = ?", query.ID)
+	}
+	if len(query.IDs) != 0 {
+		db = db.Where("id in ?", query.IDs)
+	}
+	if query.AppID != "" {
+		db = db.Where("app_id = ?", query.AppID)
+	}
+	if query.BaseID != "" {
+		db = db.Where("base_id = ?", query.BaseID)
+	}
+	if query.Type != "" {
+		db = db.Where("type = ?", query.Type)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	return db
+}
+
+func (s *snapshotRepo) Delete(db *gorm.DB, query *models.SnapshotQuery) error {
+	return s.query(db.Table(s.TableName()), query).Delete(&models.Snapshot{}).Error
+}
+
+func (s *snapshotRepo) List(db *gorm.DB, query *models.SnapshotQuery, page, size int) ([]*models.Snapshot, int64, error) {
+	page, size = pages(page, size)
+	db = s.query(db.Table(s.TableName()), query)
+
+	var (
+		count     int64
+		snapshots []*models.Snapshot
+	)
+
+	err := db.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = db.Order("created_at desc").Offset((page - 1) * size).Limit(size).Find(&snapshots).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return snapshots, count, nil
+}
@@ -33,7 +33,17 @@ func (t *tableRepo) Find(db *gorm.DB, query *models.TableQuery) ([]*models.Table
 }
 
 func (t *tableRepo) Delete(db *gorm.DB, query *models.TableQuery) error {
-	return nil
+	ql := db.Table(t.TableName())
+	if query.AppID != "" {
+		ql = ql.Where("app_id = ?", query.AppID)
+	}
+	if query.TableID != "" {
+		ql = ql.Where("table_id = ?", query.TableID)
+	}
+	if len(query.TableIDS) != 0 {
+		ql = ql.Where("table_id in ?", query.TableIDS)
+	}
+	return ql.Delete(&models.Table{}).Error
 }
 
 func (t *tableRepo) Update(db *gorm.DB, appID, tableID string, table *models.Table) error {
@@ -71,3 +81,12 @@ func (t *tableRepo) List(db *gorm.DB, query *models.TableQuery, page, size int)
 
 	return tables, count, nil
 }
+
+func (t *tableRepo) AppIDs(db *gorm.DB) ([]string, error) {
+	appIDs := make([]string, 0)
+	err := db.Table(t.TableName()).Distinct("app_id").Pluck("app_id", &appIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return appIDs, nil
+}
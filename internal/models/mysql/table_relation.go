@@ -46,6 +46,12 @@ func (t *tableRelationRepo) Delete(db *gorm.DB, query *models.TableRelationQuery
 	if query.TableID != "" {
 		ql = ql.Where("table_id = ? ", query.TableID)
 	}
+	if query.SubTableID != "" {
+		ql = ql.Where("sub_table_id = ?", query.SubTableID)
+	}
+	if query.FieldName != "" {
+		ql = ql.Where("field_name = ?", query.FieldName)
+	}
 	if query.AppID != "" {
 		ql = ql.Where("app_id = ?", query.AppID)
 	}
@@ -56,6 +62,9 @@ func (t *tableRelationRepo) List(db *gorm.DB, query *models.TableRelationQuery,
 	if query.AppID != "" {
 		db = db.Where("app_id = ?", query.AppID)
 	}
+	if query.TableID != "" {
+		db = db.Where("table_id = ?", query.TableID)
+	}
 	if query.SubTableID != "" {
 		db = db.Where("sub_table_id = ?", query.SubTableID)
 	}
@@ -95,3 +95,12 @@ func (t *tableSchemaRepo) List(db *gorm.DB, query *models.TableSchemaQuery, page
 
 	return tables, count, nil
 }
+
+func (t *tableSchemaRepo) AppIDs(db *gorm.DB) ([]string, error) {
+	appIDs := make([]string, 0)
+	err := db.Table(t.TableName()).Distinct("app_id").Pluck("app_id", &appIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return appIDs, nil
+}
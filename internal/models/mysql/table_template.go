@@ -0,0 +1,69 @@
+package mysql
+
+import (
+	"github.com/quanxiang-cloud/form/internal/models"
+	"gorm.io/gorm"
+)
+
+type tableTemplateRepo struct{}
+
+func NewTableTemplateRepo() models.TableTemplateRepo {
+	return &tableTemplateRepo{}
+}
+
+func (t *tableTemplateRepo) TableName() string {
+	return "table_template"
+}
+
+func (t *tableTemplateRepo) BatchCreate(db *gorm.DB, templates ...*models.TableTemplate) error {
+	return db.Table(t.TableName()).CreateInBatches(templates, len(templates)).Error
+}
+
+func (t *tableTemplateRepo) Get(db *gorm.DB, id string) (*models.TableTemplate, error) {
+	template := new(models.TableTemplate)
+	err := db.Table(t.TableName()).Where("id = ? ", id).Find(template).Error
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (t *tableTemplateRepo) Delete(db *gorm.DB, query *models.TableTemplateQuery) error {
+	resp := make([]models.TableTemplate, 0)
+	ql := db.Table(t.TableName())
+	if query.ID != "" {
+		ql = ql.Where("id = ?", query.ID)
+	}
+	if query.AppID != "" {
+		ql = ql.Where("app_id = ?", query.AppID)
+	}
+	return ql.Delete(resp).Error
+}
+
+func (t *tableTemplateRepo) List(db *gorm.DB, query *models.TableTemplateQuery, page, size int) ([]*models.TableTemplate, int64, error) {
+	page, size = pages(page, size)
+	db = db.Table(t.TableName())
+	if query.AppID != "" {
+		db = db.Where("app_id = ?", query.AppID)
+	}
+	if query.Name != "" {
+		db = db.Where("name like ?", "%"+query.Name+"%")
+	}
+
+	var (
+		count     int64
+		templates []*models.TableTemplate
+	)
+
+	err := db.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = db.Order("created_at desc").Offset((page - 1) * size).Limit(size).Find(&templates).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return templates, count, nil
+}
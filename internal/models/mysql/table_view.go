@@ -0,0 +1,91 @@
+package mysql
+
+import (
+	"github.com/quanxiang-cloud/form/internal/models"
+	"gorm.io/gorm"
+)
+
+type tableViewRepo struct{}
+
+func NewTableViewRepo() models.TableViewRepo {
+	return &tableViewRepo{}
+}
+
+func (t *tableViewRepo) TableName() string {
+	return "table_view"
+}
+
+func (t *tableViewRepo) BatchCreate(db *gorm.DB, views ...*models.TableView) error {
+	return db.Table(t.TableName()).CreateInBatches(views, len(views)).Error
+}
+
+func (t *tableViewRepo) Update(db *gorm.DB, id string, view *models.TableView) error {
+	setMap := map[string]interface{}{
+		"name":       view.Name,
+		"query":      view.Query,
+		"sort":       view.Sort,
+		"columns":    view.Columns,
+		"page_size":  view.PageSize,
+		"role_id":    view.RoleID,
+		"updated_at": view.UpdatedAt,
+	}
+	return db.Table(t.TableName()).Where("id = ?", id).Updates(setMap).Error
+}
+
+func (t *tableViewRepo) Get(db *gorm.DB, id string) (*models.TableView, error) {
+	view := new(models.TableView)
+	err := db.Table(t.TableName()).Where("id = ? ", id).Find(view).Error
+	if err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+func (t *tableViewRepo) Delete(db *gorm.DB, query *models.TableViewQuery) error {
+	resp := make([]models.TableView, 0)
+	ql := db.Table(t.TableName())
+	if query.ID != "" {
+		ql = ql.Where("id = ?", query.ID)
+	}
+	if query.AppID != "" {
+		ql = ql.Where("app_id = ?", query.AppID)
+	}
+	if query.TableID != "" {
+		ql = ql.Where("table_id = ?", query.TableID)
+	}
+	return ql.Delete(resp).Error
+}
+
+func (t *tableViewRepo) List(db *gorm.DB, query *models.TableViewQuery, page, size int) ([]*models.TableView, int64, error) {
+	page, size = pages(page, size)
+	db = db.Table(t.TableName())
+	if query.AppID != "" {
+		db = db.Where("app_id = ?", query.AppID)
+	}
+	if query.TableID != "" {
+		db = db.Where("table_id = ?", query.TableID)
+	}
+	switch {
+	case query.OwnerID != "" && query.RoleID != "":
+		db = db.Where("(creator_id = ? or role_id = ?)", query.OwnerID, query.RoleID)
+	case query.OwnerID != "":
+		db = db.Where("creator_id = ?", query.OwnerID)
+	}
+
+	var (
+		count int64
+		views []*models.TableView
+	)
+
+	err := db.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = db.Order("created_at desc").Offset((page - 1) * size).Limit(size).Find(&views).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return views, count, nil
+}
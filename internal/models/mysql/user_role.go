@@ -35,7 +35,33 @@ func (r *userRoleRepo) Delete(db *gorm.DB, query *models.UserRoleQuery) error {
 }
 
 func (r *userRoleRepo) List(db *gorm.DB, query *models.UserRoleQuery, page, size int) ([]*models.UserRole, int64, error) {
-	return nil, 0, nil
+	page, size = pages(page, size)
+	ql := db.Table(r.TableName())
+	if query.AppID != "" {
+		ql = ql.Where("app_id = ?", query.AppID)
+	}
+	if query.UserID != "" {
+		ql = ql.Where("user_id = ?", query.UserID)
+	}
+	if query.RoleID != "" {
+		ql = ql.Where("role_id = ?", query.RoleID)
+	}
+	if len(query.UserIDS) != 0 {
+		ql = ql.Where("user_id in ?", query.UserIDS)
+	}
+	var (
+		count     int64
+		userRoles = make([]*models.UserRole, 0)
+	)
+	err := ql.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	err = ql.Order("id").Offset((page - 1) * size).Limit(size).Find(&userRoles).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return userRoles, count, nil
 }
 
 func (r *userRoleRepo) TableName() string {
@@ -0,0 +1,175 @@
+package mysql
+
+import (
+	"github.com/quanxiang-cloud/form/internal/models"
+	"gorm.io/gorm"
+)
+
+type webhookRepo struct{}
+
+func NewWebhookRepo() models.WebhookRepo {
+	return &webhookRepo{}
+}
+
+func (w *webhookRepo) TableName() string {
+	return "webhook"
+}
+
+func (w *webhookRepo) Create(db *gorm.DB, webhook *models.Webhook) error {
+	return db.Table(w.TableName()).Create(webhook).Error
+}
+
+func (w *webhookRepo) Update(db *gorm.DB, id string, webhook *models.Webhook) error {
+	setMap := map[string]interface{}{
+		"name":       webhook.Name,
+		"url":        webhook.URL,
+		"events":     webhook.Events,
+		"filter":     webhook.Filter,
+		"disabled":   webhook.Disabled,
+		"updated_at": webhook.UpdatedAt,
+	}
+	return db.Table(w.TableName()).Where("id = ?", id).Updates(setMap).Error
+}
+
+func (w *webhookRepo) Get(db *gorm.DB, id string) (*models.Webhook, error) {
+	webhook := new(models.Webhook)
+	err := db.Table(w.TableName()).Where("id = ? ", id).Find(webhook).Error
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (w *webhookRepo) Delete(db *gorm.DB, query *models.WebhookQuery) error {
+	resp := make([]models.Webhook, 0)
+	ql := db.Table(w.TableName())
+	if query.ID != "" {
+		ql = ql.Where("id = ?", query.ID)
+	}
+	if query.AppID != "" {
+		ql = ql.Where("app_id = ?", query.AppID)
+	}
+	if query.TableID != "" {
+		ql = ql.Where("table_id = ?", query.TableID)
+	}
+	return ql.Delete(resp).Error
+}
+
+func (w *webhookRepo) List(db *gorm.DB, query *models.WebhookQuery, page, size int) ([]*models.Webhook, int64, error) {
+	page, size = pages(page, size)
+	db = db.Table(w.TableName())
+	if query.AppID != "" {
+		db = db.Where("app_id = ?", query.AppID)
+	}
+	if query.TableID != "" {
+		db = db.Where("table_id = ?", query.TableID)
+	}
+	if query.Enabled {
+		db = db.Where("disabled = ?", false)
+	}
+
+	var (
+		count    int64
+		webhooks []*models.Webhook
+	)
+
+	err := db.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = db.Order("created_at desc").Offset((page - 1) * size).Limit(size).Find(&webhooks).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return webhooks, count, nil
+}
+
+type webhookDeliveryRepo struct{}
+
+func NewWebhookDeliveryRepo() models.WebhookDeliveryRepo {
+	return &webhookDeliveryRepo{}
+}
+
+func (w *webhookDeliveryRepo) TableName() string {
+	return "webhook_delivery"
+}
+
+func (w *webhookDeliveryRepo) Create(db *gorm.DB, delivery *models.WebhookDelivery) error {
+	return db.Table(w.TableName()).Create(delivery).Error
+}
+
+func (w *webhookDeliveryRepo) Update(db *gorm.DB, id string, delivery *models.WebhookDelivery) error {
+	setMap := map[string]interface{}{
+		"status":      delivery.Status,
+		"attempts":    delivery.Attempts,
+		"status_code": delivery.StatusCode,
+		"response":    delivery.Response,
+		"next_at":     delivery.NextAt,
+		"updated_at":  delivery.UpdatedAt,
+	}
+	return db.Table(w.TableName()).Where("id = ?", id).Updates(setMap).Error
+}
+
+func (w *webhookDeliveryRepo) Get(db *gorm.DB, id string) (*models.WebhookDelivery, error) {
+	delivery := new(models.WebhookDelivery)
+	err := db.Table(w.TableName()).Where("id = ? ", id).Find(delivery).Error
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (w *webhookDeliveryRepo) Delete(db *gorm.DB, query *models.WebhookDeliveryQuery) error {
+	resp := make([]models.WebhookDelivery, 0)
+	ql := db.Table(w.TableName())
+	if query.WebhookID != "" {
+		ql = ql.Where("webhook_id = ?", query.WebhookID)
+	}
+	if query.Status != "" {
+		ql = ql.Where("status = ?", query.Status)
+	}
+	return ql.Delete(resp).Error
+}
+
+func (w *webhookDeliveryRepo) List(db *gorm.DB, query *models.WebhookDeliveryQuery, page, size int) ([]*models.WebhookDelivery, int64, error) {
+	page, size = pages(page, size)
+	db = db.Table(w.TableName())
+	if query.WebhookID != "" {
+		db = db.Where("webhook_id = ?", query.WebhookID)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.DueAt != 0 {
+		db = db.Where("next_at <= ?", query.DueAt)
+	}
+
+	var (
+		count      int64
+		deliveries []*models.WebhookDelivery
+	)
+
+	err := db.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = db.Order("created_at desc").Offset((page - 1) * size).Limit(size).Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, count, nil
+}
+
+func (w *webhookDeliveryRepo) Claim(db *gorm.DB, id string, nextAt, until int64) (bool, error) {
+	result := db.Table(w.TableName()).
+		Where("id = ? AND status = ? AND next_at = ?", id, models.DeliveryPending, nextAt).
+		Update("next_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -23,6 +23,15 @@ type Permit struct {
 	CreatorName string
 }
 
+// the keys and the types of the permits of the bodies.
+const (
+	dataKey     = "data"
+	entityKey   = "entity"
+	entitiesKey = "entities"
+	objectType  = "object"
+	arrayType   = "array"
+)
+
 type Condition map[string]interface{}
 
 // Value 实现方法
@@ -52,6 +61,53 @@ func (p *FiledPermit) Scan(data interface{}) error {
 	return json.Unmarshal(data.([]byte), &p)
 }
 
+// Sub descend to the permit of the keys, the same way the response is filtered:
+// nil for all fields, the empty one for none.
+func (p FiledPermit) Sub(keys ...string) FiledPermit {
+	for _, key := range keys {
+		if p == nil {
+			return nil
+		}
+		k, ok := p[key]
+		if !ok {
+			return FiledPermit{}
+		}
+		if k.Type != objectType && k.Type != arrayType {
+			return nil
+		}
+		p = k.Properties
+	}
+	return p
+}
+
+// RecordPermit the permit of the records, the response permit is rooted at the body,
+// like {"data":{"entities":{...}}} of search and {"data":{"entity":{...}}} of get.
+func (p FiledPermit) RecordPermit() FiledPermit {
+	body := p.Sub(dataKey)
+	if _, ok := body[entitiesKey]; ok {
+		return body.Sub(entitiesKey)
+	}
+	return body.Sub(entityKey)
+}
+
+// Readable the field of the path can be seen, only objects and arrays are checked in depth.
+func (p FiledPermit) Readable(path []string) bool {
+	for _, key := range path {
+		if p == nil {
+			return true
+		}
+		k, ok := p[key]
+		if !ok {
+			return false
+		}
+		if k.Type != objectType && k.Type != arrayType {
+			return true
+		}
+		p = k.Properties
+	}
+	return true
+}
+
 type PermitQuery struct {
 	ID      string
 	RoleID  string
@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/quanxiang-cloud/form/internal/models"
+)
+
+const (
+	// changeMaxLen the changes kept of a table, the older ones can not be resumed from.
+	changeMaxLen = 10000
+	changeField  = "change"
+	// firstChange before all the changes of a stream.
+	firstChange = "0-0"
+)
+
+type changeRepo struct {
+	c *redis.ClusterClient
+}
+
+// NewChangeRepo the changes of a table are kept in a redis stream.
+func NewChangeRepo(c *redis.ClusterClient) models.ChangeRepo {
+	return &changeRepo{
+		c: c,
+	}
+}
+
+func (r *changeRepo) Key(appID, tableID string) string {
+	return redisChangeKey + appID + ":" + tableID
+}
+
+func (r *changeRepo) Append(ctx context.Context, appID, tableID string, change *models.Change) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	id, err := r.c.XAdd(ctx, &redis.XAddArgs{
+		Stream:       r.Key(appID, tableID),
+		MaxLenApprox: changeMaxLen,
+		Values:       map[string]interface{}{changeField: data},
+	}).Result()
+	if err != nil {
+		return err
+	}
+	change.ID = id
+	return nil
+}
+
+func (r *changeRepo) Last(ctx context.Context, appID, tableID string) (string, error) {
+	messages, err := r.c.XRevRangeN(ctx, r.Key(appID, tableID), "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+	if len(messages) == 0 {
+		return firstChange, nil
+	}
+	return messages[0].ID, nil
+}
+
+func (r *changeRepo) Read(ctx context.Context, appID, tableID, after string, count int64, block time.Duration) ([]*models.Change, error) {
+	streams, err := r.c.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{r.Key(appID, tableID), after},
+		Count:   count,
+		Block:   block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]*models.Change, 0)
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			change := new(models.Change)
+			if data, ok := message.Values[changeField].(string); ok {
+				if err := json.Unmarshal([]byte(data), change); err != nil {
+					return nil, err
+				}
+			}
+			change.ID = message.ID
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/quanxiang-cloud/form/internal/models"
+)
+
+type idempotencyRepo struct {
+	c *redis.ClusterClient
+}
+
+// NewIdempotencyRepo NewIdempotencyRepo
+func NewIdempotencyRepo(c *redis.ClusterClient) models.IdempotencyRepo {
+	return &idempotencyRepo{
+		c: c,
+	}
+}
+
+func (i *idempotencyRepo) Key() string {
+	return redisIdempotencyKey
+}
+
+func (i *idempotencyRepo) Create(ctx context.Context, key string, value *models.Idempotency, ttl time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	return i.c.SetNX(ctx, i.Key()+key, data, ttl).Result()
+}
+
+func (i *idempotencyRepo) Get(ctx context.Context, key string) (*models.Idempotency, error) {
+	data, err := i.c.Get(ctx, i.Key()+key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	value := new(models.Idempotency)
+	if err = json.Unmarshal(data, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (i *idempotencyRepo) Update(ctx context.Context, key string, value *models.Idempotency, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return i.c.Set(ctx, i.Key()+key, data, ttl).Err()
+}
+
+func (i *idempotencyRepo) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return i.c.Expire(ctx, i.Key()+key, ttl).Err()
+}
+
+func (i *idempotencyRepo) Delete(ctx context.Context, key string) error {
+	return i.c.Del(ctx, i.Key()+key).Err()
+}
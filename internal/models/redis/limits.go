@@ -3,6 +3,8 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -53,6 +55,42 @@ func (p *limitRepo) GetPermit(ctx context.Context, roleID, path string) (*models
 	return limits, nil
 }
 
+func (p *limitRepo) ListPermit(ctx context.Context, roleID string) (map[string]*models.Limits, error) {
+	values, err := p.c.HGetAll(ctx, p.PerKey()+roleID).Result()
+	if err != nil {
+		return nil, err
+	}
+	limits := make(map[string]*models.Limits, len(values))
+	for path, value := range values {
+		limit := new(models.Limits)
+		if err = json.Unmarshal([]byte(value), limit); err != nil {
+			return nil, err
+		}
+		limits[path] = limit
+	}
+	return limits, nil
+}
+
+func (p *limitRepo) ListPermitRoles(ctx context.Context) ([]string, error) {
+	var (
+		mu    sync.Mutex
+		roles = make([]string, 0)
+	)
+	err := p.c.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		iter := client.Scan(ctx, 0, p.PerKey()+"*", 100).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			roles = append(roles, strings.TrimPrefix(iter.Val(), p.PerKey()))
+			mu.Unlock()
+		}
+		return iter.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
 func (p *limitRepo) DeletePermit(ctx context.Context, roleID string) error {
 	return p.c.Del(ctx, p.PerKey()+roleID).Err()
 }
@@ -78,6 +116,10 @@ func (p *limitRepo) GetPerMatch(ctx context.Context, appID, userID string) (*mod
 	return resp, nil
 }
 
+func (p *limitRepo) ListPerMatch(ctx context.Context, appID string) (map[string]string, error) {
+	return p.c.HGetAll(ctx, p.PerMatchKey()+appID).Result()
+}
+
 func (p *limitRepo) DeletePerMatch(ctx context.Context, appID string) error {
 
 	return p.c.Del(ctx, p.PerMatchKey()+appID).Err()
@@ -91,6 +133,16 @@ func (p *limitRepo) UnLock(ctx context.Context, key string) error {
 	return p.c.Del(ctx, p.LockKey()+key).Err()
 }
 
+// unlockOwner delete the key only if its value is the owner.
+var unlockOwner = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+func (p *limitRepo) UnLockOwner(ctx context.Context, key string, owner interface{}) error {
+	return unlockOwner.Run(ctx, p.c, []string{p.LockKey() + key}, owner).Err()
+}
+
 func (p *limitRepo) PerMatchExpire(ctx context.Context, key string, ttl time.Duration) error {
 	return p.c.Expire(ctx, p.PerMatchKey()+key, ttl).Err()
 }
@@ -9,4 +9,10 @@ const (
 	Template = "template"
 
 	redisSerialKey = "structor:serial:"
+
+	redisIdempotencyKey = "form:idempotency:"
+
+	redisChangeKey = "form:changes:"
+
+	redisSequenceKey = "form:sequence:"
 )
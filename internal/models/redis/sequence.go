@@ -0,0 +1,27 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/quanxiang-cloud/form/internal/models"
+)
+
+type sequenceRepo struct {
+	c *redis.ClusterClient
+}
+
+// NewSequenceRepo NewSequenceRepo
+func NewSequenceRepo(c *redis.ClusterClient) models.SequenceRepo {
+	return &sequenceRepo{
+		c: c,
+	}
+}
+
+func (s *sequenceRepo) Key() string {
+	return redisSequenceKey
+}
+
+func (s *sequenceRepo) Next(ctx context.Context, appID, tableID string) (int64, error) {
+	return s.c.Incr(ctx, s.Key()+appID+":"+tableID).Result()
+}
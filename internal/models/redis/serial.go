@@ -2,6 +2,8 @@ package redis
 
 import (
 	"context"
+	"strings"
+	"sync"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/quanxiang-cloud/form/internal/models"
@@ -36,3 +38,51 @@ func (s *serialRepo) GetAll(ctx context.Context, appID, tableID, fieldID string)
 	key := s.Key() + appID + ":" + tableID + ":" + fieldID
 	return s.c.HGetAll(ctx, key).Val()
 }
+
+// Delete remove the serials of all fields of the table.
+func (s *serialRepo) Delete(ctx context.Context, appID, tableID string) error {
+	pattern := s.Key() + appID + ":" + tableID + ":*"
+	return s.c.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		iter := client.Scan(ctx, 0, pattern, 100).Iterator()
+		for iter.Next(ctx) {
+			if err := client.Del(ctx, iter.Val()).Err(); err != nil {
+				return err
+			}
+		}
+		return iter.Err()
+	})
+}
+
+func (s *serialRepo) List(ctx context.Context, appID string) ([]*models.Serial, error) {
+	prefix := s.Key() + appID + ":"
+	var (
+		mu      sync.Mutex
+		serials = make([]*models.Serial, 0)
+	)
+	err := s.c.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		iter := client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+		for iter.Next(ctx) {
+			// the key ends with tableID:fieldID.
+			ids := strings.SplitN(strings.TrimPrefix(iter.Val(), prefix), ":", 2)
+			if len(ids) != 2 {
+				continue
+			}
+			values, err := client.HGetAll(ctx, iter.Val()).Result()
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			serials = append(serials, &models.Serial{
+				TableID: ids[0],
+				FieldID: ids[1],
+				Values:  values,
+			})
+			mu.Unlock()
+		}
+		return iter.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return serials, nil
+}
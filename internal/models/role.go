@@ -38,4 +38,6 @@ type RoleRepo interface {
 	Update(db *gorm.DB, id string, role *Role) error
 	Delete(db *gorm.DB, query *RoleQuery) error
 	List(db *gorm.DB, query *RoleQuery, page, size int) ([]*Role, int64, error)
+	// AppIDs the apps which have roles.
+	AppIDs(db *gorm.DB) ([]string, error)
 }
@@ -0,0 +1,9 @@
+package models
+
+import "context"
+
+// SequenceRepo the sequences of the events of the tables.
+type SequenceRepo interface {
+	// Next the next of the sequence of the table, starting at 1.
+	Next(ctx context.Context, appID, tableID string) (int64, error)
+}
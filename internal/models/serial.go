@@ -16,9 +16,19 @@ type SerialExcute struct {
 	Incr string
 }
 
+// Serial the state of the serial of a field, for the backups.
+type Serial struct {
+	TableID string            `json:"tableID"`
+	FieldID string            `json:"fieldID"`
+	Values  map[string]string `json:"values"`
+}
+
 // SerialRepo SerialRepo
 type SerialRepo interface {
 	Create(ctx context.Context, appID, tableID, fieldID string, values map[string]interface{}) error
 	Get(ctx context.Context, appID, tableID, fieldID, field string) string
 	GetAll(ctx context.Context, appID, tableID, fieldID string) map[string]string
+	Delete(ctx context.Context, appID, tableID string) error
+	// List the serials of all fields of the app.
+	List(ctx context.Context, appID string) ([]*Serial, error)
 }
@@ -0,0 +1,89 @@
+package models
+
+import "gorm.io/gorm"
+
+// BackupPolicy the scheduled backups of an app, an app has one policy at most.
+type BackupPolicy struct {
+	ID    string
+	AppID string
+	// Interval the seconds between two backups
+	Interval int64
+	// FullEvery a full backup every so many backups, the others are incremental, all are full if it is 1
+	FullEvery int
+	// Retention the full backups kept, each with the incremental ones after it
+	Retention int
+	Disabled  bool
+	// NextAt the unix milliseconds the next backup is due at
+	NextAt int64
+
+	CreatedAt   int64
+	UpdatedAt   int64
+	CreatorID   string
+	CreatorName string
+}
+
+type BackupPolicyQuery struct {
+	AppID string
+	// DueAt only the enabled policies whose next backups are due at the unix milliseconds
+	DueAt int64
+}
+
+type BackupPolicyRepo interface {
+	Create(db *gorm.DB, policy *BackupPolicy) error
+	Update(db *gorm.DB, id string, policy *BackupPolicy) error
+	Get(db *gorm.DB, appID string) (*BackupPolicy, error)
+	Delete(db *gorm.DB, appID string) error
+	List(db *gorm.DB, query *BackupPolicyQuery, page, size int) ([]*BackupPolicy, int64, error)
+	// Claim move the next backup of the policy from nextAt to to, false if it is moved already,
+	// so a backup is taken by one of the instances only.
+	Claim(db *gorm.DB, id string, nextAt, to int64) (bool, error)
+}
+
+// SnapshotType SnapshotType.
+type SnapshotType string
+
+const (
+	FullSnapshot        SnapshotType = "full"
+	IncrementalSnapshot SnapshotType = "incremental"
+)
+
+// Snapshot a backup of an app kept in the store, the status is the one of a job.
+type Snapshot struct {
+	ID    string
+	AppID string
+	Type  SnapshotType
+	// BaseID the full snapshot the incremental one is taken after, the id of itself if full
+	BaseID string
+	// Since the records created or updated since are held, in unix milliseconds, all if zero
+	Since int64
+	// Key the file in the store
+	Key     string
+	Size    int64
+	Status  JobStatus
+	Message string
+	// Counts the objects of each kind, and the records
+	Counts JobReport
+
+	CreatedAt   int64
+	UpdatedAt   int64
+	CreatorID   string
+	CreatorName string
+}
+
+type SnapshotQuery struct {
+	ID     string
+	IDs    []string
+	AppID  string
+	BaseID string
+	Type   SnapshotType
+	Status JobStatus
+}
+
+type SnapshotRepo interface {
+	Create(db *gorm.DB, snapshot *Snapshot) error
+	Update(db *gorm.DB, id string, snapshot *Snapshot) error
+	Get(db *gorm.DB, id string) (*Snapshot, error)
+	Delete(db *gorm.DB, query *SnapshotQuery) error
+	// List the latest come first.
+	List(db *gorm.DB, query *SnapshotQuery, page, size int) ([]*Snapshot, int64, error)
+}
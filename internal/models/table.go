@@ -62,4 +62,6 @@ type TableRepo interface {
 	Delete(db *gorm.DB, query *TableQuery) error
 	Update(db *gorm.DB, appID, tableID string, table *Table) error
 	List(db *gorm.DB, query *TableQuery, page, size int) ([]*Table, int64, error)
+	// AppIDs the apps which have tables.
+	AppIDs(db *gorm.DB) ([]string, error)
 }
@@ -41,6 +41,38 @@ type SchemaProps struct {
 	ReadOnly   bool             `json:"read_only,omitempty"`
 	Items      *SchemaProps     `json:"items,omitempty"`
 	Properties SchemaProperties `json:"properties,omitempty"`
+	Default    *DefaultValue    `json:"default,omitempty"`
+	// Enum the options of select components.
+	Enum []interface{} `json:"enum,omitempty"`
+}
+
+// DefaultValueType DefaultValueType.
+type DefaultValueType string
+
+const (
+	// StaticDefault fill the field with a fixed value.
+	StaticDefault DefaultValueType = "static"
+	// CurrentUserDefault fill the field with the operator.
+	CurrentUserDefault DefaultValueType = "currentUser"
+	// CurrentDepDefault fill the field with the operator's department.
+	CurrentDepDefault DefaultValueType = "currentDep"
+	// CurrentDateDefault fill the field with the time of creation.
+	CurrentDateDefault DefaultValueType = "currentDate"
+	// RelationDefault fill the field with a value of a related record.
+	RelationDefault DefaultValueType = "relation"
+)
+
+// DefaultValue the value used when the field is absent on create.
+type DefaultValue struct {
+	Type  DefaultValueType `json:"type"`
+	Value interface{}      `json:"value,omitempty"`
+	// AppID、TableID the table of the related record, only for relation.
+	AppID   string `json:"appID,omitempty"`
+	TableID string `json:"tableID,omitempty"`
+	// RefField the field of the entity which holds the related record id.
+	RefField string `json:"refField,omitempty"`
+	// FieldName the field of the related record to copy.
+	FieldName string `json:"fieldName,omitempty"`
 }
 
 // Value 实现方法.
@@ -66,4 +98,6 @@ type TableSchemeRepo interface {
 	Delete(db *gorm.DB, query *TableSchemaQuery) error
 	Update(db *gorm.DB, appID, tableID string, baseSchema *TableSchema) error
 	List(db *gorm.DB, query *TableSchemaQuery, page, size int) ([]*TableSchema, int64, error)
+	// AppIDs the apps which have schemas.
+	AppIDs(db *gorm.DB) ([]string, error)
 }
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"gorm.io/gorm"
+)
+
+// TableTemplate a reusable table definition.
+type TableTemplate struct {
+	ID string
+	// app id the template is saved from
+	AppID       string
+	Name        string
+	Description string
+	// table definitions, the first one is the main table
+	Content TemplateContent
+
+	CreatedAt   int64
+	CreatorID   string
+	CreatorName string
+}
+
+// TemplateContent TemplateContent.
+type TemplateContent []*TemplateTable
+
+// TemplateTable the definition of one table in the template.
+type TemplateTable struct {
+	TableID string     `json:"tableID"`
+	Title   string     `json:"title"`
+	Source  SourceType `json:"source"`
+	Schema  WebSchema  `json:"schema"`
+	Config  Config     `json:"config"`
+}
+
+// Value 实现方法.
+func (p TemplateContent) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan 实现方法.
+func (p *TemplateContent) Scan(data interface{}) error {
+	return json.Unmarshal(data.([]byte), &p)
+}
+
+type TableTemplateQuery struct {
+	ID    string
+	AppID string
+	Name  string
+}
+
+type TableTemplateRepo interface {
+	BatchCreate(db *gorm.DB, templates ...*TableTemplate) error
+	Get(db *gorm.DB, id string) (*TableTemplate, error)
+	Delete(db *gorm.DB, query *TableTemplateQuery) error
+	List(db *gorm.DB, query *TableTemplateQuery, page, size int) ([]*TableTemplate, int64, error)
+}
@@ -0,0 +1,40 @@
+package models
+
+import "gorm.io/gorm"
+
+// TableView a saved search of a table, owned by the creator and shared to a role if any.
+type TableView struct {
+	ID      string
+	AppID   string
+	TableID string
+	Name    string
+	Query   Condition
+	Sort    Filters
+	// Columns the visible fields, all fields if empty
+	Columns  Filters
+	PageSize int64
+	// RoleID the role the view is shared to, only the creator can see it if empty
+	RoleID string
+
+	CreatedAt   int64
+	UpdatedAt   int64
+	CreatorID   string
+	CreatorName string
+}
+
+type TableViewQuery struct {
+	ID      string
+	AppID   string
+	TableID string
+	// OwnerID、RoleID the views created by the owner or shared to the role
+	OwnerID string
+	RoleID  string
+}
+
+type TableViewRepo interface {
+	BatchCreate(db *gorm.DB, views ...*TableView) error
+	Update(db *gorm.DB, id string, view *TableView) error
+	Get(db *gorm.DB, id string) (*TableView, error)
+	Delete(db *gorm.DB, query *TableViewQuery) error
+	List(db *gorm.DB, query *TableViewQuery, page, size int) ([]*TableView, int64, error)
+}
@@ -0,0 +1,87 @@
+package models
+
+import "gorm.io/gorm"
+
+// Webhook a subscription of the changes of a table, the events are posted to the url.
+type Webhook struct {
+	ID      string
+	AppID   string
+	TableID string
+	Name    string
+	URL     string
+	// Secret the key of the signature of the payloads
+	Secret string
+	// Events create, update or delete
+	Events Filters
+	// Filter only the records matching the query are sent, all if empty
+	Filter   Condition
+	Disabled bool
+
+	CreatedAt   int64
+	UpdatedAt   int64
+	CreatorID   string
+	CreatorName string
+}
+
+type WebhookQuery struct {
+	ID      string
+	AppID   string
+	TableID string
+	// Enabled only the enabled webhooks
+	Enabled bool
+}
+
+type WebhookRepo interface {
+	Create(db *gorm.DB, webhook *Webhook) error
+	Update(db *gorm.DB, id string, webhook *Webhook) error
+	Get(db *gorm.DB, id string) (*Webhook, error)
+	Delete(db *gorm.DB, query *WebhookQuery) error
+	List(db *gorm.DB, query *WebhookQuery, page, size int) ([]*Webhook, int64, error)
+}
+
+// DeliveryStatus DeliveryStatus.
+type DeliveryStatus string
+
+const (
+	DeliveryPending DeliveryStatus = "pending"
+	DeliverySucceed DeliveryStatus = "succeed"
+	DeliveryFailed  DeliveryStatus = "failed"
+)
+
+// WebhookDelivery a payload posted to a webhook, with the result of the last attempt.
+type WebhookDelivery struct {
+	ID        string
+	WebhookID string
+	AppID     string
+	TableID   string
+	Event     string
+	Payload   string
+	Status    DeliveryStatus
+	Attempts  int
+	// StatusCode、Response the http status and body of the last attempt, Response is the error if no response
+	StatusCode int
+	Response   string
+	// NextAt a pending delivery is taken over by the retry loop after, if its sender is gone
+	NextAt int64
+
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+type WebhookDeliveryQuery struct {
+	WebhookID string
+	Status    DeliveryStatus
+	// DueAt only the deliveries whose NextAt is not after
+	DueAt int64
+}
+
+type WebhookDeliveryRepo interface {
+	Create(db *gorm.DB, delivery *WebhookDelivery) error
+	Update(db *gorm.DB, id string, delivery *WebhookDelivery) error
+	Get(db *gorm.DB, id string) (*WebhookDelivery, error)
+	Delete(db *gorm.DB, query *WebhookDeliveryQuery) error
+	List(db *gorm.DB, query *WebhookDeliveryQuery, page, size int) ([]*WebhookDelivery, int64, error)
+	// Claim move the NextAt of the pending delivery from nextAt to until,
+	// false if it is claimed by another or not pending any more.
+	Claim(db *gorm.DB, id string, nextAt, until int64) (bool, error)
+}
@@ -26,4 +26,7 @@ type Universal struct {
 }
 
 type Object map[string]interface{}
-type Response struct{}
+type Response struct {
+	// Data the data of the form service, for the callers which shape the response themselves.
+	Data interface{}
+}
@@ -2,13 +2,22 @@ package side
 
 import (
 	"context"
+	"path"
+
 	"github.com/quanxiang-cloud/form/internal/models"
 	"github.com/quanxiang-cloud/form/internal/permit"
 	"github.com/quanxiang-cloud/form/internal/permit/treasure"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
 	httputil2 "github.com/quanxiang-cloud/form/pkg/httputil"
 	"github.com/quanxiang-cloud/form/pkg/misc/config"
 )
 
+// listKeys the controls of listing expose no field, they are kept out of the params permit.
+var listKeys = []string{"cursor", fieldsKey, "expand", qKey, searchFieldsKey}
+
+// aggregateKeys the fields of an aggregation are checked against the response permit by the form service.
+var aggregateKeys = []string{"groupBy", "metrics", "having", "limit", "timeZone"}
+
 // Auth is a guard for permit.
 type Auth struct {
 	auth *treasure.Auth
@@ -31,6 +40,91 @@ func NewAuth(conf *config.Config, rawurl string) (*Auth, error) {
 		next: next,
 	}, nil
 }
+
+// NewExportAuth returns a new guard for export, which passes the readable fields
+// to the form service since the csv response can not be filtered here.
+func NewExportAuth(conf *config.Config, rawurl string) (*Auth, error) {
+	auth, err := treasure.NewAuth(conf)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := NewFieldPermitProxy(conf, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return &Auth{
+		auth: auth,
+		next: &Condition{
+			cond: treasure.NewCondition(conf),
+			next: proxy,
+		},
+	}, nil
+}
+
+// NewImportAuth returns a new guard for import, the multipart body is forwarded
+// as it is with the writable fields, without the conditions of query.
+func NewImportAuth(conf *config.Config, rawurl string) (*Auth, error) {
+	auth, err := treasure.NewAuth(conf)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := NewRawProxy(conf, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return &Auth{
+		auth: auth,
+		next: proxy,
+	}, nil
+}
+
+// Upsert is a guard for upsert, which creates or updates a record, so it is permitted by both,
+// the condition of update limits the records which can be updated.
+type Upsert struct {
+	auth *treasure.Auth
+	next permit.Permit
+}
+
+// NewUpsertAuth returns a new guard for upsert.
+func NewUpsertAuth(conf *config.Config, rawurl string) (*Upsert, error) {
+	auth, err := treasure.NewAuth(conf)
+	if err != nil {
+		return nil, err
+	}
+	next, err := NewCondition(conf, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return &Upsert{
+		auth: auth,
+		next: next,
+	}, nil
+}
+
+func (u *Upsert) Do(ctx context.Context, req *permit.Request) (*permit.Response, error) {
+	base := path.Dir(req.Echo.Request().URL.Path)
+	permits := make([]*consensus.Permit, 0, 2)
+	for _, action := range []string{"create", "update"} {
+		req.Path = base + "/" + action
+		p, err := u.auth.Auth(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return nil, nil
+		}
+		permits = append(permits, p)
+	}
+	// the response and the condition are of update.
+	req.Permit = permits[1]
+	for _, p := range permits {
+		if p.Types != models.InitType && !p.ParamsAll {
+			filterParams(req.Data, p.Params)
+		}
+	}
+	return u.next.Do(ctx, req)
+}
+
 func (a *Auth) Do(ctx context.Context, req *permit.Request) (*permit.Response, error) {
 	p, err := a.auth.Auth(ctx, req)
 	if err != nil {
@@ -44,10 +138,28 @@ func (a *Auth) Do(ctx context.Context, req *permit.Request) (*permit.Response, e
 		return a.next.Do(ctx, req)
 	}
 	if !p.ParamsAll {
-		treasure.Filter(req.Data, p.Params)
+		filterParams(req.Data, p.Params)
+	}
+	if !p.ResponseAll {
+		selectFields(req.Data, p.Response)
+		limitSort(req.Data, p.Response)
+		limitSearchFields(req.Data, p.Response, httputil2.IsQueryMethod(req.Echo.Request().Method))
 	}
 	if httputil2.IsQueryMethod(req.Echo.Request().Method) {
 		req.Echo.Request().URL.RawQuery = httputil2.ObjectBodyToQuery(req.Data)
 	}
 	return a.next.Do(ctx, req)
 }
+
+func filterParams(data map[string]interface{}, params models.FiledPermit) {
+	kept := make(map[string]interface{}, len(listKeys)+len(aggregateKeys))
+	for _, key := range append(listKeys, aggregateKeys...) {
+		if value, ok := data[key]; ok {
+			kept[key] = value
+		}
+	}
+	treasure.Filter(data, params)
+	for key, value := range kept {
+		data[key] = value
+	}
+}
@@ -53,7 +53,12 @@ func (c *Condition) Do(ctx context.Context, req *permit.Request) (*permit.Respon
 	var query permit.Object
 	switch req.Echo.Request().Method {
 	case http.MethodGet:
-		//query = req.Query
+		// the query param is the json of the query.
+		if s, ok := oldQuery.(string); ok && s != "" {
+			if err := json.Unmarshal([]byte(s), &query); err != nil {
+				return nil, err
+			}
+		}
 	case http.MethodPost:
 		bytes, err := json.Marshal(oldQuery)
 		if err != nil {
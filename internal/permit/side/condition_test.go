@@ -0,0 +1,64 @@
+package side
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/permit"
+	"github.com/quanxiang-cloud/form/internal/permit/treasure"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/pkg/httputil"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+)
+
+// fakeNext keep the request passed on.
+type fakeNext struct {
+	req *permit.Request
+}
+
+func (f *fakeNext) Do(ctx context.Context, req *permit.Request) (*permit.Response, error) {
+	f.req = req
+	return &permit.Response{}, nil
+}
+
+func TestConditionGet(t *testing.T) {
+	next := &fakeNext{}
+	c := &Condition{
+		cond: treasure.NewCondition(&config.Config{}),
+		next: next,
+	}
+	subscriber := `{"term":{"status":"open"}}`
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/form/app/home/form/t/changes?query="+url.QueryEscape(subscriber), nil)
+	e := echo.New().NewContext(r, httptest.NewRecorder())
+	req := &permit.Request{
+		Echo: e,
+		Permit: &consensus.Permit{Condition: models.Condition{
+			_query: map[string]interface{}{"term": map[string]interface{}{"owner": "alice"}},
+		}},
+	}
+	if err := httputil.GetRequestArgs(e, &req.Data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	var query map[string]interface{}
+	if err := json.Unmarshal([]byte(next.req.Echo.Request().URL.Query().Get(_query)), &query); err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]interface{}{_bool: map[string]interface{}{_must: []interface{}{
+		map[string]interface{}{"term": map[string]interface{}{"status": "open"}},
+		map[string]interface{}{"term": map[string]interface{}{"owner": "alice"}},
+	}}}
+	if !reflect.DeepEqual(query, expect) {
+		t.Fatalf("the query of the subscriber should be ANDed with the condition, got %v", query)
+	}
+}
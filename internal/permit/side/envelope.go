@@ -0,0 +1,49 @@
+package side
+
+import (
+	"strings"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+)
+
+// envelopeKeys the keys of the response data besides the records.
+var envelopeKeys = []string{"nextCursor", highlightsKey}
+
+// keepEnvelope restore the keys of the response data besides the records after filtering,
+// the highlights are left with the readable fields.
+func keepEnvelope(result map[string]interface{}, response models.FiledPermit, filter func()) {
+	data, ok := result[dataKey].(map[string]interface{})
+	if !ok {
+		filter()
+		return
+	}
+	kept := make(map[string]interface{}, len(envelopeKeys))
+	for _, key := range envelopeKeys {
+		if value, ok := data[key]; ok {
+			kept[key] = value
+		}
+	}
+	filter()
+	if data, ok = result[dataKey].(map[string]interface{}); !ok {
+		return
+	}
+	for key, value := range kept {
+		data[key] = value
+	}
+	highlights, ok := data[highlightsKey].(map[string]interface{})
+	if !ok {
+		return
+	}
+	permit := response.RecordPermit()
+	for _, value := range highlights {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for field := range fields {
+			if !permit.Readable(strings.Split(field, ".")) {
+				delete(fields, field)
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package side
+
+import (
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/permit/treasure"
+)
+
+func TestKeepEnvelope(t *testing.T) {
+	result := map[string]interface{}{
+		"data": map[string]interface{}{
+			"entities":   []interface{}{map[string]interface{}{"_id": "1", "a": "x", "b": "y"}},
+			"total":      1,
+			"nextCursor": "c1",
+		},
+	}
+	// the permit of a restricted user lists the fields of the records only.
+	response := models.FiledPermit{
+		"data": {Type: "object", Properties: models.FiledPermit{
+			"entities": {Type: "array", Properties: models.FiledPermit{"_id": {}, "a": {}}},
+			"total":    {},
+		}},
+	}
+	keepEnvelope(result, response, func() {
+		treasure.Filter(result, response)
+	})
+
+	data := result["data"].(map[string]interface{})
+	if data["nextCursor"] != "c1" || data["total"] != 1 {
+		t.Fatalf("data %v", data)
+	}
+	entity := data["entities"].([]interface{})[0].(map[string]interface{})
+	if _, ok := entity["b"]; ok || entity["a"] != "x" {
+		t.Fatalf("entity %v", entity)
+	}
+}
@@ -0,0 +1,141 @@
+package side
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/permit/treasure"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+)
+
+const (
+	fieldsKey       = "fields"
+	sortKey         = "sort"
+	descPrefix      = "-"
+	qKey            = "q"
+	searchFieldsKey = "searchFields"
+	highlightsKey   = "highlights"
+	dataKey         = "data"
+	entityKey       = "entity"
+	entitiesKey     = "entities"
+	totalKey        = "total"
+)
+
+// selectFields intersect the selected fields with the response permit, so the form service
+// only returns the readable ones, the response is still filtered after.
+func selectFields(data map[string]interface{}, response models.FiledPermit) {
+	value, ok := data[fieldsKey]
+	if !ok || !treasure.Intercept() {
+		return
+	}
+	fields, ok := parseFields(value)
+	if !ok {
+		delete(data, fieldsKey)
+		return
+	}
+	if len(fields) == 0 {
+		return
+	}
+	setFields(data, fieldsKey, readableFields(response.RecordPermit(), fields), isString(value))
+}
+
+// limitSort drop the sort keys out of the response permit, the cursor holds the values
+// of the sort keys of the last record, which would expose the fields which can not be seen.
+func limitSort(data map[string]interface{}, response models.FiledPermit) {
+	value, ok := data[sortKey]
+	if !ok || !treasure.Intercept() {
+		return
+	}
+	keys, ok := parseFields(value)
+	if !ok {
+		delete(data, sortKey)
+		return
+	}
+	permit := response.RecordPermit()
+	kept := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if permit.Readable(strings.Split(strings.TrimPrefix(key, descPrefix), ".")) {
+			kept = append(kept, key)
+		}
+	}
+	if len(kept) == len(keys) {
+		return
+	}
+	if len(kept) == 0 {
+		delete(data, sortKey)
+		return
+	}
+	setFields(data, sortKey, kept, isString(value))
+}
+
+// limitSearchFields the fields q matches are limited to the readable ones,
+// the records must not be found by the fields which can not be seen.
+func limitSearchFields(data map[string]interface{}, response models.FiledPermit, query bool) {
+	if q, ok := data[qKey]; !ok || q == "" || !treasure.Intercept() {
+		return
+	}
+	permit := response.RecordPermit()
+	if permit == nil {
+		return
+	}
+	fields, ok := parseFields(data[searchFieldsKey])
+	if !ok || len(fields) == 0 {
+		fields = make([]string, 0, len(permit))
+		for key := range permit {
+			fields = append(fields, key)
+		}
+		sort.Strings(fields)
+	}
+	setFields(data, searchFieldsKey, readableFields(permit, fields), query)
+}
+
+func parseFields(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, true
+	case string:
+		// fields=a,b.c in the url.
+		return consensus.SplitFields([]string{v}), true
+	case []interface{}:
+		fields := make([]string, 0, len(v))
+		for _, elem := range v {
+			if field, ok := elem.(string); ok {
+				fields = append(fields, field)
+			}
+		}
+		return consensus.SplitFields(fields), true
+	}
+	return nil, false
+}
+
+func readableFields(permit models.FiledPermit, fields []string) []string {
+	selected := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if permit.Readable(strings.Split(field, ".")) {
+			selected = append(selected, field)
+		}
+	}
+	if len(selected) == 0 {
+		// none is readable, the empty fields would mean all.
+		selected = append(selected, consensus.IDKey)
+	}
+	return selected
+}
+
+func setFields(data map[string]interface{}, key string, fields []string, asString bool) {
+	if asString {
+		data[key] = strings.Join(fields, ",")
+		return
+	}
+	elems := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		elems = append(elems, field)
+	}
+	data[key] = elems
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
@@ -0,0 +1,32 @@
+package side
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+)
+
+func TestLimitSort(t *testing.T) {
+	response := models.FiledPermit{
+		"data": {Type: "object", Properties: models.FiledPermit{
+			"entities": {Type: "array", Properties: models.FiledPermit{"_id": {}, "a": {}}},
+		}},
+	}
+	cases := []struct {
+		sort   interface{}
+		expect interface{}
+	}{
+		{"-a,salary", "-a"},
+		{[]interface{}{"salary", "a"}, []interface{}{"a"}},
+		{"-salary", nil},
+		{"a,_id", "a,_id"},
+	}
+	for _, c := range cases {
+		data := map[string]interface{}{"sort": c.sort}
+		limitSort(data, response)
+		if !reflect.DeepEqual(data["sort"], c.expect) {
+			t.Fatalf("%v: expect %v, got %v", c.sort, c.expect, data["sort"])
+		}
+	}
+}
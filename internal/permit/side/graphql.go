@@ -0,0 +1,578 @@
+package side
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/permit"
+	"github.com/quanxiang-cloud/form/internal/permit/treasure"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/pkg/graphql"
+	httputil2 "github.com/quanxiang-cloud/form/pkg/httputil"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+)
+
+const (
+	// maxRelationDepth the same as the levels of expand of the form service.
+	maxRelationDepth = 3
+	graphQLSchema    = "graphql/schema"
+	expandKey        = "expand"
+)
+
+// arguments of the root fields by action, true for the required ones.
+var arguments = map[string]map[string]bool{
+	graphql.GetAction: {"id": true},
+	graphql.SearchAction: {
+		_query: false, "sort": false, "page": false, "size": false, "cursor": false, qKey: false, searchFieldsKey: false,
+	},
+	graphql.CreateAction: {entityKey: true},
+	graphql.UpdateAction: {"id": true, entityKey: true},
+	graphql.DeleteAction: {"id": true},
+}
+
+// GraphQL executes the queries and the mutations of the tables of an app, each root field
+// is permitted, conditioned and filtered the same as the action of its table.
+type GraphQL struct {
+	form *formCall
+	auth permitter
+	next permit.Permit
+}
+
+// permitter the permit of the user on the path of the request, as treasure.Auth.
+type permitter interface {
+	Auth(ctx context.Context, req *permit.Request) (*consensus.Permit, error)
+}
+
+// NewGraphQL returns a new graphql executor.
+func NewGraphQL(conf *config.Config, rawurl string) (*GraphQL, error) {
+	url, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := treasure.NewAuth(conf)
+	if err != nil {
+		return nil, err
+	}
+	form := &formCall{
+		url: url,
+		client: &http.Client{
+			Transport: httputil2.Transport(conf),
+		},
+	}
+	return &GraphQL{
+		form: form,
+		auth: auth,
+		next: &Auth{
+			auth: auth,
+			next: &Condition{
+				cond: treasure.NewCondition(conf),
+				next: form,
+			},
+		},
+	}, nil
+}
+
+// Do executes the request of {query, variables, operationName}, the errors are returned
+// in the response of graphql.
+func (g *GraphQL) Do(ctx context.Context, req *permit.Request) (*permit.Response, error) {
+	query, _ := req.Data[_query].(string)
+	variables, _ := req.Data["variables"].(map[string]interface{})
+	operationName, _ := req.Data["operationName"].(string)
+
+	resp := &graphql.Response{}
+	data, err := g.execute(ctx, req, query, variables, operationName, resp)
+	if err != nil {
+		logger.Logger.WithName("graphql").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		resp.Errors = append(resp.Errors, toGraphQLError(err))
+	}
+	resp.Data = data
+	return &permit.Response{}, req.Echo.JSON(http.StatusOK, resp)
+}
+
+func (g *GraphQL) execute(ctx context.Context, req *permit.Request, query string, values map[string]interface{},
+	operationName string, resp *graphql.Response) (*graphql.Object, error) {
+	doc, err := graphql.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	op, err := doc.Operation(operationName)
+	if err != nil {
+		return nil, err
+	}
+	variables, err := op.Coerce(values)
+	if err != nil {
+		return nil, err
+	}
+	root := graphql.QueryRoot
+	if op.Type == graphql.Mutation {
+		root = graphql.MutationRoot
+	}
+	fields, err := doc.Collect(op.Selections, root, variables)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := g.schema(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &execution{
+		g:         g,
+		req:       req,
+		doc:       doc,
+		schema:    schema,
+		variables: variables,
+		mutation:  op.Type == graphql.Mutation,
+		permits:   make(map[string]*consensus.Permit),
+	}
+	data := graphql.NewObject()
+	for _, field := range fields {
+		if field.Name == graphql.TypeNameField {
+			data.Set(field.Key(), root)
+			continue
+		}
+		value, err := e.resolve(ctx, field)
+		if err != nil {
+			ge := toGraphQLError(err)
+			ge.Path = []interface{}{field.Key()}
+			resp.Errors = append(resp.Errors, ge)
+		}
+		data.Set(field.Key(), value)
+	}
+	return data, nil
+}
+
+// schema the types of the tables of the app from the form service.
+func (g *GraphQL) schema(ctx context.Context, req *permit.Request) (*graphql.Schema, error) {
+	data, err := g.form.call(ctx, req, http.MethodGet, path.Join(homePrefix(req), graphQLSchema), nil)
+	if err != nil {
+		return nil, err
+	}
+	schema := &graphql.Schema{}
+	if err = remarshal(data, schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// homePrefix the path like /api/v1/form/<appID>/home of the graphql path.
+func homePrefix(req *permit.Request) string {
+	return path.Dir(req.Echo.Request().URL.Path)
+}
+
+type execution struct {
+	g         *GraphQL
+	req       *permit.Request
+	doc       *graphql.Document
+	schema    *graphql.Schema
+	variables map[string]interface{}
+	mutation  bool
+	// permits the permits of search of the related types, nil if forbidden.
+	permits map[string]*consensus.Permit
+}
+
+func (e *execution) resolve(ctx context.Context, field *graphql.Selection) (interface{}, error) {
+	if !e.mutation && (field.Name == graphql.SchemaField || field.Name == graphql.TypeField) {
+		return e.doc.Introspect(e.schema, field, e.variables)
+	}
+	action, typeName, ok := graphql.RootField(field.Name)
+	isQuery := action == graphql.GetAction || action == graphql.SearchAction
+	typ := e.schema.Type(typeName)
+	if !ok || isQuery == e.mutation || typ == nil {
+		return nil, graphql.Errorf("unknown field %q", field.Name)
+	}
+	args, err := e.arguments(field, arguments[action])
+	if err != nil {
+		return nil, err
+	}
+
+	body := make(map[string]interface{})
+	var selections []*graphql.Selection
+	switch action {
+	case graphql.GetAction:
+		body[_query] = idQuery(args["id"])
+		selections = field.Selections
+	case graphql.SearchAction:
+		for name, value := range args {
+			body[name] = value
+		}
+		if selections, err = e.entitiesSelections(typ, field.Selections); err != nil {
+			return nil, err
+		}
+	case graphql.CreateAction:
+		body[entityKey] = args[entityKey]
+		selections = field.Selections
+	case graphql.UpdateAction:
+		body[_query] = idQuery(args["id"])
+		body[entityKey] = args[entityKey]
+	case graphql.DeleteAction:
+		body[_query] = idQuery(args["id"])
+	}
+
+	isCount := action == graphql.UpdateAction || action == graphql.DeleteAction
+	if isCount && len(field.Selections) != 0 {
+		return nil, graphql.Errorf("field %q of type Int must not have a selection", field.Name)
+	}
+	if !isCount && len(field.Selections) == 0 {
+		return nil, graphql.Errorf("field %q must have a selection of subfields", field.Name)
+	}
+	if !isCount {
+		p := &projection{fields: []string{consensus.IDKey}}
+		if err = e.plan(typ, selections, "", 1, p); err != nil {
+			return nil, err
+		}
+		if isQuery {
+			body[fieldsKey] = toInterfaces(p.fields)
+			if len(p.expand) != 0 {
+				body[expandKey] = toInterfaces(p.expand)
+			}
+		}
+	}
+
+	sub := &permit.Request{
+		Echo:      e.req.Echo,
+		Data:      body,
+		Universal: e.req.Universal,
+		Path:      path.Join(homePrefix(e.req), "form", typ.TableID, action),
+	}
+	resp, err := e.g.next.Do(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, graphql.Errorf("%s of %s is forbidden", action, typ.Name)
+	}
+	data, _ := resp.Data.(map[string]interface{})
+
+	switch action {
+	case graphql.GetAction, graphql.CreateAction:
+		return e.shape(ctx, typ, field.Selections, data[entityKey])
+	case graphql.SearchAction:
+		return e.shapePage(ctx, typ, field.Selections, data)
+	}
+	return data[totalKey], nil
+}
+
+// arguments the values of the arguments of the field, the unknown and the missing ones are errors.
+func (e *execution) arguments(field *graphql.Selection, allowed map[string]bool) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(field.Arguments))
+	for _, arg := range field.Arguments {
+		if _, ok := allowed[arg.Name]; !ok {
+			return nil, graphql.Errorf("unknown argument %q of field %q", arg.Name, field.Name)
+		}
+		value, err := arg.Value.Resolve(e.variables)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			args[arg.Name] = value
+		}
+	}
+	for name, required := range allowed {
+		if required && args[name] == nil {
+			return nil, graphql.Errorf("argument %q of field %q is required", name, field.Name)
+		}
+	}
+	if id, ok := args["id"]; ok {
+		if s, ok := id.(string); !ok || s == "" {
+			return nil, graphql.Errorf("argument \"id\" of field %q must be a string", field.Name)
+		}
+	}
+	if entity, ok := args[entityKey]; ok {
+		if _, ok := entity.(map[string]interface{}); !ok {
+			return nil, graphql.Errorf("argument \"entity\" of field %q must be an object", field.Name)
+		}
+	}
+	return args, nil
+}
+
+// entitiesSelections the selections of the records in the page of search.
+func (e *execution) entitiesSelections(typ *graphql.Type, selections []*graphql.Selection) ([]*graphql.Selection, error) {
+	fields, err := e.doc.Collect(selections, typ.Name+graphql.PageSuffix, e.variables)
+	if err != nil {
+		return nil, err
+	}
+	var entities []*graphql.Selection
+	for _, field := range fields {
+		switch field.Name {
+		case graphql.EntitiesField:
+			entities = append(entities, field.Selections...)
+		case graphql.TotalField, graphql.NextCursorField, graphql.TypeNameField:
+		default:
+			return nil, graphql.Errorf("unknown field %q on %s%s", field.Name, typ.Name, graphql.PageSuffix)
+		}
+	}
+	return entities, nil
+}
+
+// projection the fields and the relations to expand of the records.
+type projection struct {
+	fields []string
+	expand []string
+}
+
+// plan check the selections on the type, and collect the projection of them.
+func (e *execution) plan(typ *graphql.Type, selections []*graphql.Selection, prefix string, depth int, p *projection) error {
+	fields, err := e.doc.Collect(selections, typ.Name, e.variables)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if field.Name == graphql.TypeNameField {
+			continue
+		}
+		f := typ.Field(field.Name)
+		if f == nil {
+			return graphql.Errorf("unknown field %q on %s", field.Name, typ.Name)
+		}
+		if len(field.Arguments) != 0 {
+			return graphql.Errorf("field %q on %s takes no argument", field.Name, typ.Name)
+		}
+		if !f.Relation {
+			if len(field.Selections) != 0 {
+				return graphql.Errorf("field %q on %s must not have a selection", field.Name, typ.Name)
+			}
+			if prefix == "" && f.Key != consensus.IDKey {
+				p.fields = append(p.fields, f.Key)
+			}
+			continue
+		}
+		if len(field.Selections) == 0 {
+			return graphql.Errorf("field %q on %s must have a selection of subfields", field.Name, typ.Name)
+		}
+		if depth > maxRelationDepth {
+			return graphql.Errorf("the relations are nested deeper than %d", maxRelationDepth)
+		}
+		related := e.schema.Type(f.Type)
+		if related == nil {
+			return graphql.Errorf("unknown type %q", f.Type)
+		}
+		expand := f.Key
+		if prefix != "" {
+			expand = prefix + "." + f.Key
+		}
+		p.expand = append(p.expand, expand)
+		if err = e.plan(related, field.Selections, expand, depth+1, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shape the record by the selections, which are checked by plan.
+func (e *execution) shape(ctx context.Context, typ *graphql.Type, selections []*graphql.Selection, value interface{}) (interface{}, error) {
+	entity, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	fields, err := e.doc.Collect(selections, typ.Name, e.variables)
+	if err != nil {
+		return nil, err
+	}
+	object := graphql.NewObject()
+	for _, field := range fields {
+		if field.Name == graphql.TypeNameField {
+			object.Set(field.Key(), typ.Name)
+			continue
+		}
+		f := typ.Field(field.Name)
+		if f == nil {
+			continue
+		}
+		if !f.Relation {
+			object.Set(field.Key(), entity[f.Key])
+			continue
+		}
+		related := e.schema.Type(f.Type)
+		p, err := e.relatedPermit(ctx, related)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			// the related records are expanded by the form service, but the user can not search them.
+			object.Set(field.Key(), nil)
+			continue
+		}
+		var records []interface{}
+		switch v := entity[f.Key].(type) {
+		case []interface{}:
+			records = v
+		case map[string]interface{}:
+			records = []interface{}{v}
+		default:
+			object.Set(field.Key(), nil)
+			continue
+		}
+		list := make([]interface{}, 0, len(records))
+		for _, record := range records {
+			if p.Types != models.InitType && !p.ResponseAll {
+				treasure.Filter(record, p.Response.RecordPermit())
+			}
+			shaped, err := e.shape(ctx, related, field.Selections, record)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, shaped)
+		}
+		object.Set(field.Key(), list)
+	}
+	return object, nil
+}
+
+// relatedPermit the permit of search of the related type, the expanded records are filtered by it
+// the same as searching the related table.
+func (e *execution) relatedPermit(ctx context.Context, typ *graphql.Type) (*consensus.Permit, error) {
+	if p, ok := e.permits[typ.Name]; ok {
+		return p, nil
+	}
+	p, err := e.g.auth.Auth(ctx, &permit.Request{
+		Echo:      e.req.Echo,
+		Universal: e.req.Universal,
+		Path:      path.Join(homePrefix(e.req), "form", typ.TableID, graphql.SearchAction),
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.permits[typ.Name] = p
+	return p, nil
+}
+
+func (e *execution) shapePage(ctx context.Context, typ *graphql.Type, selections []*graphql.Selection, data map[string]interface{}) (interface{}, error) {
+	pageType := typ.Name + graphql.PageSuffix
+	fields, err := e.doc.Collect(selections, pageType, e.variables)
+	if err != nil {
+		return nil, err
+	}
+	page := graphql.NewObject()
+	for _, field := range fields {
+		switch field.Name {
+		case graphql.TypeNameField:
+			page.Set(field.Key(), pageType)
+		case graphql.TotalField:
+			page.Set(field.Key(), data[totalKey])
+		case graphql.NextCursorField:
+			page.Set(field.Key(), data[graphql.NextCursorField])
+		case graphql.EntitiesField:
+			records, _ := data[entitiesKey].([]interface{})
+			list := make([]interface{}, 0, len(records))
+			for _, record := range records {
+				shaped, err := e.shape(ctx, typ, field.Selections, record)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, shaped)
+			}
+			page.Set(field.Key(), list)
+		}
+	}
+	return page, nil
+}
+
+// formCall calls the form service and filters the response by the permit, the data is returned
+// to the caller instead of being written to the client.
+type formCall struct {
+	url    *url.URL
+	client *http.Client
+}
+
+func (f *formCall) Do(ctx context.Context, req *permit.Request) (*permit.Response, error) {
+	result, err := f.call(ctx, req, http.MethodPost, req.Path, req.Data)
+	if err != nil {
+		return nil, err
+	}
+	if p := req.Permit; p != nil && p.Types != models.InitType && !p.ResponseAll {
+		envelope := map[string]interface{}{dataKey: result}
+		keepEnvelope(envelope, p.Response, func() {
+			treasure.Filter(envelope, p.Response)
+		})
+		result = envelope[dataKey]
+	}
+	return &permit.Response{
+		Data: result,
+	}, nil
+}
+
+// call the data of the response of the form service, the headers of the user are passed on.
+func (f *formCall) call(ctx context.Context, req *permit.Request, method, path string, body interface{}) (interface{}, error) {
+	u := *f.url
+	u.Path = path
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	r, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	// the key of the request is not of the sub requests, the creates of it would be replayed as one.
+	for key, values := range req.Echo.Request().Header {
+		switch http.CanonicalHeaderKey(key) {
+		case "Content-Length", "Content-Type", "Accept-Encoding", consensus.FieldPermitHeader, consensus.IdempotencyHeader:
+			continue
+		}
+		r.Header[key] = values
+	}
+	r.Header.Set("Content-Type", mimeApplicationJSON)
+
+	resp, err := f.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("form service: %s", resp.Status)
+	}
+	var result struct {
+		Code int64       `json:"code"`
+		Msg  string      `json:"msg"`
+		Data interface{} `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if result.Code != 0 {
+		return nil, graphql.Errorf("%s", result.Msg)
+	}
+	return result.Data, nil
+}
+
+func idQuery(id interface{}) map[string]interface{} {
+	return consensus.GetSimple(consensus.TermKey, consensus.IDKey, id)
+}
+
+func toInterfaces(s []string) []interface{} {
+	elems := make([]interface{}, 0, len(s))
+	for _, elem := range s {
+		elems = append(elems, elem)
+	}
+	return elems
+}
+
+func remarshal(from, to interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
+
+func toGraphQLError(err error) *graphql.Error {
+	if ge, ok := err.(*graphql.Error); ok {
+		return ge
+	}
+	return graphql.Errorf("%s", err.Error())
+}
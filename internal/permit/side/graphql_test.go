@@ -0,0 +1,187 @@
+package side
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/permit"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/pkg/graphql"
+)
+
+// fakePermitter the permits by the path, nil for the others.
+type fakePermitter map[string]*consensus.Permit
+
+func (f fakePermitter) Auth(ctx context.Context, req *permit.Request) (*consensus.Permit, error) {
+	return f[req.Path], nil
+}
+
+func TestGraphQL(t *testing.T) {
+	schema := &graphql.Schema{Types: []*graphql.Type{
+		{Name: "order", TableID: "order", Fields: []*graphql.Field{
+			{Name: "_id", Key: "_id", Type: graphql.ID},
+			{Name: "amount", Key: "amount", Type: graphql.Float},
+			{Name: "items", Key: "items", Type: "item", List: true, Relation: true},
+		}},
+		{Name: "item", TableID: "item", Fields: []*graphql.Field{
+			{Name: "_id", Key: "_id", Type: graphql.ID},
+			{Name: "name", Key: "name", Type: graphql.String},
+			{Name: "cost", Key: "cost", Type: graphql.Float},
+		}},
+	}}
+	bodies := make(map[string]map[string]interface{})
+	forwarded := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = forwarded || r.Header.Get(consensus.IdempotencyHeader) != ""
+		var data interface{}
+		switch r.URL.Path {
+		case "/api/v1/form/app/home/graphql/schema":
+			data = schema
+		case "/api/v1/form/app/home/form/order/search":
+			data = map[string]interface{}{
+				"total": 1,
+				"entities": []interface{}{map[string]interface{}{
+					"_id": "o1", "amount": 2, "items": []interface{}{map[string]interface{}{"_id": "i1", "name": "pen", "cost": 1}},
+				}},
+			}
+		case "/api/v1/form/app/home/form/order/update":
+			data = map[string]interface{}{"total": 1}
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": 1, "msg": "not found"})
+			return
+		}
+		body := make(map[string]interface{})
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies[r.URL.Path] = body
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "data": data})
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	form := &formCall{url: u, client: srv.Client()}
+	// the cost of the items is hidden from the user.
+	items := &consensus.Permit{Response: models.FiledPermit{
+		"data": {Type: "object", Properties: models.FiledPermit{
+			"entities": {Type: "array", Properties: models.FiledPermit{
+				"_id": {}, "name": {},
+			}},
+		}},
+	}}
+	permits := fakePermitter{"/api/v1/form/app/home/form/item/search": items}
+	g := &GraphQL{form: form, auth: permits, next: form}
+
+	do := func(data map[string]interface{}) map[string]interface{} {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/form/app/home/graphql", nil)
+		r.Header.Set(consensus.IdempotencyHeader, "k")
+		rec := httptest.NewRecorder()
+		req := &permit.Request{Echo: echo.New().NewContext(r, rec), Data: data}
+		if _, err := g.Do(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+		result := make(map[string]interface{})
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	result := do(map[string]interface{}{
+		"query": `query ($size: Int) {
+			__typename
+			orders: search_order(size: $size, q: "pen") {
+				total
+				entities { id: _id, ...items }
+			}
+			get_item(id: "i1") { name }
+		}
+		fragment items on order { items { name, cost, __typename } }`,
+		"variables": map[string]interface{}{"size": 5},
+	})
+	expect := map[string]interface{}{
+		"__typename": "Query",
+		"orders": map[string]interface{}{
+			"total": 1.0,
+			"entities": []interface{}{map[string]interface{}{
+				"id": "o1", "items": []interface{}{map[string]interface{}{"name": "pen", "cost": nil, "__typename": "item"}},
+			}},
+		},
+		"get_item": nil,
+	}
+	if !reflect.DeepEqual(result["data"], expect) {
+		t.Fatalf("data %v", result["data"])
+	}
+	errs, _ := result["errors"].([]interface{})
+	if len(errs) != 1 || !reflect.DeepEqual(errs[0].(map[string]interface{})["path"], []interface{}{"get_item"}) {
+		t.Fatalf("errors %v", result["errors"])
+	}
+	search := bodies["/api/v1/form/app/home/form/order/search"]
+	if !reflect.DeepEqual(search["fields"], []interface{}{"_id"}) || !reflect.DeepEqual(search["expand"], []interface{}{"items"}) ||
+		search["size"] != 5.0 || search["q"] != "pen" {
+		t.Fatalf("search body %v", search)
+	}
+
+	delete(permits, "/api/v1/form/app/home/form/item/search")
+	result = do(map[string]interface{}{
+		"query": `{ search_order { entities { items { name } } } }`,
+	})
+	entities := result["data"].(map[string]interface{})["search_order"].(map[string]interface{})["entities"]
+	if !reflect.DeepEqual(entities, []interface{}{map[string]interface{}{"items": nil}}) {
+		t.Fatalf("the items can not be searched, got %v", entities)
+	}
+
+	result = do(map[string]interface{}{
+		"query": `{
+			__schema { queryType { name } types { name } }
+			__type(name: "order") { kind fields { name type { kind name ofType { name } } } }
+		}`,
+	})
+	if errs := result["errors"]; errs != nil {
+		t.Fatalf("introspection %v", errs)
+	}
+	data := result["data"].(map[string]interface{})
+	if data["__schema"].(map[string]interface{})["queryType"].(map[string]interface{})["name"] != "Query" {
+		t.Fatalf("schema %v", data["__schema"])
+	}
+	fields := data["__type"].(map[string]interface{})["fields"].([]interface{})
+	expectItems := map[string]interface{}{"name": "items", "type": map[string]interface{}{
+		"kind": "LIST", "name": nil, "ofType": map[string]interface{}{"name": "item"},
+	}}
+	if len(fields) != 3 || !reflect.DeepEqual(fields[2], expectItems) {
+		t.Fatalf("fields of order %v", fields)
+	}
+
+	result = do(map[string]interface{}{
+		"query": `mutation { update_order(id: "o1", entity: {amount: 3}) }`,
+	})
+	if !reflect.DeepEqual(result["data"], map[string]interface{}{"update_order": 1.0}) {
+		t.Fatalf("update %v", result)
+	}
+	if forwarded {
+		t.Fatal("the idempotency key should not be passed to the sub requests")
+	}
+
+	for _, query := range []string{
+		`{ search_order { entities { unknown } } }`,
+		`{ search_order { entities { items } } }`,
+		`{ get_order(id: "o1") { amount { x } } }`,
+		`{ create_order(entity: {}) { _id } }`,
+		`{ get_order { _id } }`,
+		`{ __schema { types { unknown } } }`,
+		`mutation { __schema { types { name } } }`,
+	} {
+		result = do(map[string]interface{}{"query": query})
+		if errs, _ := result["errors"].([]interface{}); len(errs) != 1 {
+			t.Fatalf("%s should fail, got %v", query, result)
+		}
+	}
+	if result = do(map[string]interface{}{"query": `{`}); result["data"] != nil {
+		t.Fatalf("syntax error should have no data, got %v", result)
+	}
+}
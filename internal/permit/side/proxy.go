@@ -28,6 +28,12 @@ type Proxy struct {
 	next permit.Permit
 
 	isPermit bool
+
+	// passPermit pick the fields passed to the form service instead of filtering, nil for not passing.
+	passPermit func(p *consensus.Permit) (models.FiledPermit, bool)
+
+	// keepBody forward the body as it is.
+	keepBody bool
 }
 
 func NewProxy(conf *config.Config, rawurl string) (*Proxy, error) {
@@ -54,14 +60,48 @@ func NewNilModifyProxy(conf *config.Config, rawurl string) (*Proxy, error) {
 	}, nil
 }
 
+// NewFieldPermitProxy the response is not json, like csv, so the form service filters the fields itself.
+func NewFieldPermitProxy(conf *config.Config, rawurl string) (*Proxy, error) {
+	url, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return &Proxy{
+		url:        url,
+		transport:  httputil2.Transport(conf),
+		passPermit: responsePermit,
+	}, nil
+}
+
+// NewRawProxy the body is forwarded as it is, like the files to import,
+// so the form service checks the writable fields itself.
+func NewRawProxy(conf *config.Config, rawurl string) (*Proxy, error) {
+	url, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return &Proxy{
+		url:        url,
+		transport:  httputil2.Transport(conf),
+		passPermit: paramsPermit,
+		keepBody:   true,
+	}, nil
+}
+
 func (p *Proxy) Do(ctx context.Context, req *permit.Request) (*permit.Response, error) {
 	var filters httputil2.ModifyResponse
 	if p.isPermit {
 		filters = Filter(req.Permit)
 	}
+	if p.passPermit != nil {
+		if err := setFieldPermit(req, p.passPermit); err != nil {
+			return nil, err
+		}
+	}
 	err := httputil2.DoPoxy(ctx, req, &httputil2.Proxys{
 		Url:       p.url,
 		Transport: p.transport,
+		KeepBody:  p.keepBody,
 	}, filters)
 	if err != nil {
 		return nil, err
@@ -73,6 +113,36 @@ const (
 	mimeApplicationJSON = "application/json"
 )
 
+func responsePermit(p *consensus.Permit) (models.FiledPermit, bool) {
+	return p.Response, p.ResponseAll
+}
+
+func paramsPermit(p *consensus.Permit) (models.FiledPermit, bool) {
+	return p.Params, p.ParamsAll
+}
+
+func setFieldPermit(req *permit.Request, pick func(p *consensus.Permit) (models.FiledPermit, bool)) error {
+	h := req.Echo.Request().Header
+	// never trust the header from the client.
+	h.Del(consensus.FieldPermitHeader)
+	if req.Permit == nil || req.Permit.Types == models.InitType {
+		return nil
+	}
+	fields, all := pick(req.Permit)
+	if all {
+		return nil
+	}
+	if fields == nil {
+		fields = models.FiledPermit{}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	h.Set(consensus.FieldPermitHeader, string(data))
+	return nil
+}
+
 func Filter(permit *consensus.Permit) httputil2.ModifyResponse {
 	return func(resp *http.Response) error {
 		return filter(resp, permit)
@@ -122,7 +192,9 @@ func doFilterJSON(resp *httputil.Response, permit *consensus.Permit) (err error)
 		return err
 	}
 	if !permit.ResponseAll {
-		treasure.Filter(result, permit.Response)
+		keepEnvelope(result, permit.Response, func() {
+			treasure.Filter(result, permit.Response)
+		})
 	}
 	data, err := json.Marshal(result)
 	if err != nil {
@@ -18,6 +18,11 @@ func init() {
 	}
 }
 
+// Intercept the fields are filtered by the permits, unless FORM_INTERCEPT is not true.
+func Intercept() bool {
+	return intercept == "true"
+}
+
 const (
 	object = "object"
 	array  = "array"
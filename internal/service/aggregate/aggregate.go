@@ -0,0 +1,399 @@
+package aggregate
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/models/mysql"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxScan the records of a table scanned by an aggregation at most.
+	MaxScan = 100000
+	// MaxRows the rows of a result at most.
+	MaxRows  = 10000
+	pageSize = 1000
+
+	objectType = "object"
+	numberType = "number"
+	stringType = "string"
+)
+
+// Aggregation group the records of a table and compute the metrics of each group.
+type Aggregation interface {
+	Aggregate(ctx context.Context, req *AggregateReq) (*AggregateResp, error)
+}
+
+type aggregation struct {
+	db              *gorm.DB
+	guidance        consensus.Guidance
+	tableSchemaRepo models.TableSchemeRepo
+}
+
+func NewAggregation(conf *config.Config, guidance consensus.Guidance) (Aggregation, error) {
+	db, err := service.CreateMysqlConn(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &aggregation{
+		db:              db,
+		guidance:        guidance,
+		tableSchemaRepo: mysql.NewTableSchema(),
+	}, nil
+}
+
+type AggregateReq struct {
+	AppID   string      `json:"appID"`
+	TableID string      `json:"tableID"`
+	Query   types.Query `json:"query"`
+	// GroupBy the dimensions of the rows in order, no group by for one row of the whole table.
+	GroupBy []*GroupBy `json:"groupBy"`
+	Metrics []*Metric  `json:"metrics"`
+	// Having filter the rows by the metrics, all of them must be met.
+	Having []*Having `json:"having"`
+	// Sort the rows by the aliases, like -count for descending, by the dimensions if empty.
+	Sort  []string `json:"sort"`
+	Limit int      `json:"limit"`
+	// TimeZone the IANA name of the zone the dates are bucketed in, UTC if empty.
+	TimeZone string `json:"timeZone"`
+	// Permit the response permit of search passed by the permit gateway, nil for all fields.
+	Permit   models.FiledPermit `json:"-"`
+	UserID   string             `json:"-"`
+	UserName string             `json:"-"`
+	DepID    string             `json:"-"`
+}
+
+// Interval the buckets of a date histogram.
+type Interval string
+
+const (
+	Day   Interval = "day"
+	Week  Interval = "week"
+	Month Interval = "month"
+)
+
+type GroupBy struct {
+	Field string `json:"field"`
+	// Interval bucket the dates of the field, the weeks start on monday.
+	Interval Interval `json:"interval,omitempty"`
+	Alias    string   `json:"alias,omitempty"`
+}
+
+// Op the operation of a metric.
+type Op string
+
+const (
+	Count    Op = "count"
+	Sum      Op = "sum"
+	Avg      Op = "avg"
+	Min      Op = "min"
+	Max      Op = "max"
+	Distinct Op = "distinct"
+)
+
+type Metric struct {
+	Op Op `json:"op"`
+	// Field count counts the records without it, and the records with the field otherwise.
+	Field string `json:"field,omitempty"`
+	Alias string `json:"alias,omitempty"`
+}
+
+type Having struct {
+	Alias string `json:"alias"`
+	// Op eq, ne, gt, gte, lt or lte.
+	Op    string  `json:"op"`
+	Value float64 `json:"value"`
+}
+
+// ColumnType dimension or metric.
+type ColumnType string
+
+const (
+	DimensionColumn ColumnType = "dimension"
+	MetricColumn    ColumnType = "metric"
+)
+
+type Column struct {
+	Name     string     `json:"name"`
+	Type     ColumnType `json:"type"`
+	Field    string     `json:"field,omitempty"`
+	Interval Interval   `json:"interval,omitempty"`
+	Op       Op         `json:"op,omitempty"`
+}
+
+type AggregateResp struct {
+	Columns []*Column       `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+	// Scanned the records of the query.
+	Scanned int64 `json:"scanned"`
+}
+
+// Aggregate check the fields against the schema and the permit, then group the records of the query.
+func (a *aggregation) Aggregate(ctx context.Context, req *AggregateReq) (*AggregateResp, error) {
+	schema, err := a.tableSchemaRepo.Get(a.db, req.AppID, req.TableID)
+	if err != nil {
+		return nil, err
+	}
+	if schema.ID == "" {
+		return nil, error2.New(code.ErrNotExistTable)
+	}
+	plan, err := newPlan(req, schema.Schema)
+	if err != nil {
+		return nil, err
+	}
+	if aggs, ok := plan.backendAggs(); ok {
+		return a.pushdown(ctx, req, plan, aggs)
+	}
+
+	var (
+		scanned int64
+		cursor  string
+	)
+	for {
+		resp, err := a.guidance.Do(ctx, a.searchBus(req, plan.fields(), cursor))
+		if err != nil {
+			return nil, err
+		}
+		if cursor == "" && resp.Total > MaxScan {
+			return nil, error2.New(code.ErrAggregateLimit, MaxScan)
+		}
+		for _, entity := range resp.Entities {
+			plan.add(entity)
+		}
+		scanned += int64(len(resp.Entities))
+		if scanned > MaxScan {
+			// the records are added while scanning.
+			return nil, error2.New(code.ErrAggregateLimit, MaxScan)
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+
+	return &AggregateResp{
+		Columns: plan.columns(),
+		Rows:    plan.rows(),
+		Scanned: scanned,
+	}, nil
+}
+
+// pushdown the metrics are computed by the backend, the count is the total of the query.
+func (a *aggregation) pushdown(ctx context.Context, req *AggregateReq, plan *plan, aggs types.Any) (*AggregateResp, error) {
+	bus := a.searchBus(req, []string{consensus.IDKey}, "")
+	bus.List = consensus.List{Page: 1, Size: 1}
+	resp, err := a.guidance.Do(ctx, bus)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	if aggs != nil {
+		bus = a.searchBus(req, nil, "")
+		bus.List = consensus.List{Page: 1, Size: 1}
+		bus.Aggs = aggs
+		result, err := a.guidance.Do(ctx, bus)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Entities) != 0 {
+			values = result.Entities[0]
+		}
+	}
+	plan.setWhole(resp.Total, values)
+	return &AggregateResp{
+		Columns: plan.columns(),
+		Rows:    plan.rows(),
+		Scanned: resp.Total,
+	}, nil
+}
+
+func (a *aggregation) searchBus(req *AggregateReq, fields []string, cursor string) *consensus.Bus {
+	bus := new(consensus.Bus)
+	bus.Universal = consensus.Universal{
+		UserID:   req.UserID,
+		UserName: req.UserName,
+		DepID:    req.DepID,
+	}
+	bus.Foundation = consensus.Foundation{
+		AppID:   req.AppID,
+		TableID: req.TableID,
+		Method:  "search",
+	}
+	bus.Get.Query = req.Query
+	bus.Get.Fields = fields
+	bus.List = consensus.List{
+		Page:   1,
+		Size:   pageSize,
+		Sort:   []string{consensus.IDKey},
+		Cursor: cursor,
+	}
+	return bus
+}
+
+// newPlan check the request, the fields must be in the schema and readable.
+func newPlan(req *AggregateReq, properties models.SchemaProperties) (*plan, error) {
+	if len(req.Metrics) == 0 {
+		return nil, error2.New(code.ErrInvalidAggregate, "metrics")
+	}
+	location := time.UTC
+	if req.TimeZone != "" {
+		loc, err := time.LoadLocation(req.TimeZone)
+		if err != nil {
+			return nil, error2.New(code.ErrInvalidAggregate, req.TimeZone)
+		}
+		location = loc
+	}
+	permit := req.Permit.RecordPermit()
+	p := &plan{
+		location: location,
+		groups:   make(map[string]*group),
+		aliases:  make(map[string]int),
+	}
+
+	for _, g := range req.GroupBy {
+		props, err := checkField(properties, permit, g.Field)
+		if err != nil {
+			return nil, err
+		}
+		switch g.Interval {
+		case "":
+		case Day, Week, Month:
+			if props.Type != stringType && props.Type != numberType {
+				return nil, error2.New(code.ErrInvalidAggregate, g.Field)
+			}
+		default:
+			return nil, error2.New(code.ErrInvalidAggregate, string(g.Interval))
+		}
+		alias := g.Alias
+		if alias == "" {
+			alias = g.Field
+			if g.Interval != "" {
+				alias = g.Field + "_" + string(g.Interval)
+			}
+		}
+		if err = p.addAlias(alias); err != nil {
+			return nil, err
+		}
+		p.dimensions = append(p.dimensions, &dimension{
+			alias:    alias,
+			field:    g.Field,
+			path:     strings.Split(g.Field, "."),
+			interval: g.Interval,
+		})
+	}
+
+	for _, m := range req.Metrics {
+		switch m.Op {
+		case Count:
+		case Sum, Avg, Min, Max, Distinct:
+			if m.Field == "" {
+				return nil, error2.New(code.ErrInvalidAggregate, string(m.Op))
+			}
+		default:
+			return nil, error2.New(code.ErrInvalidAggregate, string(m.Op))
+		}
+		if m.Field != "" {
+			props, err := checkField(properties, permit, m.Field)
+			if err != nil {
+				return nil, err
+			}
+			numeric := m.Op == Sum || m.Op == Avg
+			if numeric && props.Type != numberType {
+				return nil, error2.New(code.ErrInvalidAggregate, m.Field)
+			}
+			// the dates are compared as strings.
+			if (m.Op == Min || m.Op == Max) && props.Type != numberType && props.Type != stringType {
+				return nil, error2.New(code.ErrInvalidAggregate, m.Field)
+			}
+		}
+		alias := m.Alias
+		if alias == "" {
+			alias = string(m.Op)
+			if m.Field != "" {
+				alias = string(m.Op) + "_" + m.Field
+			}
+		}
+		if err := p.addAlias(alias); err != nil {
+			return nil, err
+		}
+		p.metrics = append(p.metrics, &metric{
+			alias: alias,
+			op:    m.Op,
+			field: m.Field,
+			path:  splitPath(m.Field),
+		})
+	}
+
+	for _, h := range req.Having {
+		index, ok := p.aliases[h.Alias]
+		if !ok || index < len(p.dimensions) || !validCompare(h.Op) {
+			return nil, error2.New(code.ErrInvalidAggregate, h.Alias)
+		}
+		p.having = append(p.having, &having{
+			index: index,
+			op:    h.Op,
+			value: h.Value,
+		})
+	}
+
+	for _, key := range req.Sort {
+		desc := strings.HasPrefix(key, "-")
+		index, ok := p.aliases[strings.TrimPrefix(key, "-")]
+		if !ok {
+			return nil, error2.New(code.ErrInvalidAggregate, key)
+		}
+		p.sorts = append(p.sorts, &sortKey{
+			index: index,
+			desc:  desc,
+		})
+	}
+	if len(p.sorts) == 0 {
+		for index := range p.dimensions {
+			p.sorts = append(p.sorts, &sortKey{
+				index: index,
+			})
+		}
+	}
+
+	p.limit = req.Limit
+	if p.limit <= 0 || p.limit > MaxRows {
+		p.limit = MaxRows
+	}
+	return p, nil
+}
+
+// checkField the field must be a column of the schema, the nested ones are joined by dots.
+func checkField(properties models.SchemaProperties, permit models.FiledPermit, field string) (*models.SchemaProps, error) {
+	path := splitPath(field)
+	if len(path) == 0 {
+		return nil, error2.New(code.ErrInvalidAggregate, field)
+	}
+	var props models.SchemaProps
+	for index, key := range path {
+		p, ok := properties[key]
+		if !ok || index != len(path)-1 && p.Type != objectType {
+			return nil, error2.New(code.ErrInvalidAggregate, field)
+		}
+		props, properties = p, p.Properties
+	}
+	if !permit.Readable(path) {
+		return nil, error2.New(code.ErrInvalidAggregate, field)
+	}
+	return &props, nil
+}
+
+func splitPath(field string) []string {
+	if field == "" {
+		return nil
+	}
+	return strings.Split(field, ".")
+}
@@ -0,0 +1,148 @@
+package aggregate
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"gorm.io/gorm"
+)
+
+var properties = models.SchemaProperties{
+	"_id":        {Type: "string"},
+	"created_at": {Type: "string"},
+	"city":       {Type: "string"},
+	"tags":       {Type: "array"},
+	"amount":     {Type: "number"},
+	"secret":     {Type: "number"},
+}
+
+func TestAggregate(t *testing.T) {
+	p, err := newPlan(&AggregateReq{
+		GroupBy: []*GroupBy{{Field: "city"}, {Field: "created_at", Interval: Month}},
+		Metrics: []*Metric{
+			{Op: Count},
+			{Op: Sum, Field: "amount"},
+			{Op: Max, Field: "amount"},
+			{Op: Distinct, Field: "tags", Alias: "tags"},
+		},
+		Having:   []*Having{{Alias: "count", Op: "gte", Value: 1}},
+		Sort:     []string{"-sum_amount"},
+		TimeZone: "Asia/Shanghai",
+	}, properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []map[string]interface{}{
+		{"_id": "1", "city": "sh", "created_at": "2022-01-31T17:00:00.000Z", "amount": float64(1), "tags": []interface{}{"a"}},
+		{"_id": "2", "city": "sh", "created_at": "2022-02-01T00:00:00.000Z", "amount": float64(2), "tags": []interface{}{"b"}},
+		{"_id": "3", "city": "bj", "created_at": "2022-01-01T00:00:00.000Z", "tags": []interface{}{"a"}},
+	}
+	for _, record := range records {
+		p.add(record)
+	}
+	if fields := p.fields(); !reflect.DeepEqual(fields, []string{"_id", "city", "created_at", "amount", "tags"}) {
+		t.Fatalf("fields %v", fields)
+	}
+	expect := [][]interface{}{
+		// 17:00 utc is the next day in shanghai.
+		{"sh", "2022-02", int64(2), float64(3), float64(2), int64(2)},
+		{"bj", "2022-01", int64(1), nil, nil, int64(1)},
+	}
+	if rows := p.rows(); !reflect.DeepEqual(rows, expect) {
+		t.Fatalf("expect %v, got %v", expect, rows)
+	}
+}
+
+func TestAggregateWhole(t *testing.T) {
+	p, err := newPlan(&AggregateReq{
+		Metrics: []*Metric{{Op: Count}, {Op: Avg, Field: "amount"}},
+	}, properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows := p.rows(); !reflect.DeepEqual(rows, [][]interface{}{{int64(0), nil}}) {
+		t.Fatalf("the empty table is one row, got %v", rows)
+	}
+}
+
+func TestAggregateInvalid(t *testing.T) {
+	permit := models.FiledPermit{"data": {Type: "object", Properties: models.FiledPermit{
+		"entities": {Type: "array", Properties: models.FiledPermit{"amount": {Type: "number"}}},
+	}}}
+	cases := []*AggregateReq{
+		{},
+		{Metrics: []*Metric{{Op: "median", Field: "amount"}}},
+		{Metrics: []*Metric{{Op: Sum, Field: "city"}}},
+		{Metrics: []*Metric{{Op: Sum, Field: "missing"}}},
+		{Metrics: []*Metric{{Op: Sum}}},
+		{Metrics: []*Metric{{Op: Count}, {Op: Count}}},
+		{GroupBy: []*GroupBy{{Field: "city", Interval: "year"}}, Metrics: []*Metric{{Op: Count}}},
+		{GroupBy: []*GroupBy{{Field: "city"}}, Metrics: []*Metric{{Op: Count}},
+			Having: []*Having{{Alias: "city", Op: "eq"}}},
+		{Metrics: []*Metric{{Op: Count}}, Sort: []string{"-total"}},
+		{Metrics: []*Metric{{Op: Sum, Field: "secret"}}, Permit: permit},
+		{Metrics: []*Metric{{Op: Count}}, TimeZone: "Mars/Base"},
+	}
+	for index, req := range cases {
+		if _, err := newPlan(req, properties); err == nil {
+			t.Fatalf("case %d should be refused", index)
+		}
+	}
+}
+
+type fakeSchemaRepo struct {
+	models.TableSchemeRepo
+}
+
+func (fakeSchemaRepo) Get(db *gorm.DB, appID, tableID string) (*models.TableSchema, error) {
+	return &models.TableSchema{ID: "1", Schema: properties}, nil
+}
+
+// fakeBackend the total of the query, and the metrics of the aggs.
+type fakeBackend struct {
+	aggs []types.Any
+}
+
+func (f *fakeBackend) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	if bus.Aggs == nil {
+		return &consensus.Response{Total: 5, Entities: types.Entities{{"_id": "1"}}}, nil
+	}
+	f.aggs = append(f.aggs, bus.Aggs)
+	return &consensus.Response{Total: 1, Entities: types.Entities{{"sum_amount": "10.5", "max_amount": float64(4)}}}, nil
+}
+
+func TestAggregatePushdown(t *testing.T) {
+	backend := &fakeBackend{}
+	a := &aggregation{guidance: backend, tableSchemaRepo: fakeSchemaRepo{}}
+	resp, err := a.Aggregate(context.Background(), &AggregateReq{
+		Metrics: []*Metric{{Op: Count}, {Op: Sum, Field: "amount"}, {Op: Max, Field: "amount"}, {Op: Min, Field: "amount"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.aggs) != 1 {
+		t.Fatalf("the metrics should be pushed down once, got %v", backend.aggs)
+	}
+	expect := [][]interface{}{{int64(5), float64(10.5), float64(4), nil}}
+	if !reflect.DeepEqual(resp.Rows, expect) || resp.Scanned != 5 {
+		t.Fatalf("expect %v, got %v of %d", expect, resp.Rows, resp.Scanned)
+	}
+
+	for _, req := range []*AggregateReq{
+		{GroupBy: []*GroupBy{{Field: "city"}}, Metrics: []*Metric{{Op: Sum, Field: "amount"}}},
+		{Metrics: []*Metric{{Op: Distinct, Field: "tags"}}},
+		{Metrics: []*Metric{{Op: Count, Field: "amount"}}},
+	} {
+		p, err := newPlan(req, properties)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := p.backendAggs(); ok {
+			t.Fatalf("%+v is computed in memory", req.Metrics[0])
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+)
+
+const labelKey = "label"
+
+// dateLayouts the dates are kept in ISO8601, the others are written by hand.
+var dateLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+type dimension struct {
+	alias    string
+	field    string
+	path     []string
+	interval Interval
+}
+
+type metric struct {
+	alias string
+	op    Op
+	field string
+	path  []string
+}
+
+type having struct {
+	index int
+	op    string
+	value float64
+}
+
+type sortKey struct {
+	index int
+	desc  bool
+}
+
+// plan the checked request and the groups computed so far.
+type plan struct {
+	dimensions []*dimension
+	metrics    []*metric
+	having     []*having
+	sorts      []*sortKey
+	limit      int
+	location   *time.Location
+
+	// aliases the index of each column.
+	aliases map[string]int
+	groups  map[string]*group
+	order   []string
+}
+
+type group struct {
+	values []interface{}
+	states []*state
+}
+
+// state the accumulation of a metric in a group.
+type state struct {
+	count    int64
+	sum      float64
+	min      interface{}
+	max      interface{}
+	distinct map[string]struct{}
+}
+
+// backendAggs the aggs of the backend, which only computes sum, avg, min and max of the whole query,
+// nil aggs if there is only the count. the groups, the date histograms, the distinct values and
+// the counts of a field are computed in memory.
+func (p *plan) backendAggs() (types.Any, bool) {
+	if len(p.dimensions) != 0 {
+		return nil, false
+	}
+	aggs := make(map[string]interface{})
+	for _, m := range p.metrics {
+		switch {
+		case m.op == Count && m.field == "":
+		case m.op == Sum || m.op == Avg || m.op == Min || m.op == Max:
+			if len(m.path) != 1 {
+				return nil, false
+			}
+			aggs[m.alias] = consensus.KeyValue{
+				string(m.op): consensus.KeyValue{"field": m.field},
+			}
+		default:
+			return nil, false
+		}
+	}
+	if len(aggs) == 0 {
+		return nil, true
+	}
+	return aggs, true
+}
+
+// setWhole the one row of the whole query, by the total and the metrics of the backend by alias.
+func (p *plan) setWhole(total int64, values map[string]interface{}) {
+	g := p.group([]interface{}{})
+	for index, m := range p.metrics {
+		s := g.states[index]
+		if m.op == Count {
+			s.count = total
+			continue
+		}
+		value := values[m.alias]
+		if value == nil {
+			continue
+		}
+		s.count = 1
+		switch m.op {
+		case Sum, Avg:
+			// the decimals of sql may come as strings.
+			if str, ok := value.(string); ok {
+				value, _ = strconv.ParseFloat(str, 64)
+			}
+			s.sum, _ = toFloat(value)
+		case Min, Max:
+			s.min, s.max = value, value
+		}
+	}
+}
+
+func (p *plan) addAlias(alias string) error {
+	if _, ok := p.aliases[alias]; ok {
+		return error2.New(code.ErrInvalidAggregate, alias)
+	}
+	p.aliases[alias] = len(p.aliases)
+	return nil
+}
+
+// fields the fields to search, the records are projected to them.
+func (p *plan) fields() []string {
+	fields := []string{consensus.IDKey}
+	for _, d := range p.dimensions {
+		fields = append(fields, d.field)
+	}
+	for _, m := range p.metrics {
+		if m.field != "" {
+			fields = append(fields, m.field)
+		}
+	}
+	return consensus.SplitFields(fields)
+}
+
+// add the record to the groups of its dimensions, a record with many options
+// of a multiple select is added to the group of each option.
+func (p *plan) add(entity map[string]interface{}) {
+	tuples := [][]interface{}{{}}
+	for _, d := range p.dimensions {
+		values := p.dimensionValues(d, consensus.GetValue(entity, d.path))
+		next := make([][]interface{}, 0, len(tuples)*len(values))
+		for _, tuple := range tuples {
+			for _, value := range values {
+				next = append(next, append(append(make([]interface{}, 0, len(tuple)+1), tuple...), value))
+			}
+		}
+		tuples = next
+	}
+	for _, tuple := range tuples {
+		g := p.group(tuple)
+		for index, m := range p.metrics {
+			g.states[index].add(m, entity)
+		}
+	}
+}
+
+func (p *plan) group(values []interface{}) *group {
+	data, _ := json.Marshal(values)
+	key := string(data)
+	g, ok := p.groups[key]
+	if !ok {
+		g = &group{
+			values: values,
+			states: make([]*state, len(p.metrics)),
+		}
+		for index := range g.states {
+			g.states[index] = &state{
+				distinct: make(map[string]struct{}),
+			}
+		}
+		p.groups[key] = g
+		p.order = append(p.order, key)
+	}
+	return g
+}
+
+func (p *plan) dimensionValues(d *dimension, value interface{}) []interface{} {
+	elems, ok := value.([]interface{})
+	if !ok {
+		elems = []interface{}{value}
+	} else if len(elems) == 0 {
+		elems = []interface{}{nil}
+	}
+	values := make([]interface{}, 0, len(elems))
+	for _, elem := range elems {
+		// label-value fields are grouped by the labels.
+		if m, ok := elem.(map[string]interface{}); ok {
+			elem = m[labelKey]
+		}
+		if d.interval != "" {
+			elem = bucket(elem, d.interval, p.location)
+		}
+		values = append(values, elem)
+	}
+	return values
+}
+
+func (s *state) add(m *metric, entity map[string]interface{}) {
+	if m.field == "" {
+		s.count++
+		return
+	}
+	value := consensus.GetValue(entity, m.path)
+	if value == nil {
+		return
+	}
+	switch m.op {
+	case Count:
+		s.count++
+	case Sum, Avg:
+		if v, ok := value.(float64); ok {
+			s.count++
+			s.sum += v
+		}
+	case Min, Max:
+		if !ordered(value) {
+			return
+		}
+		s.count++
+		if s.min == nil || compare(value, s.min) < 0 {
+			s.min = value
+		}
+		if s.max == nil || compare(value, s.max) > 0 {
+			s.max = value
+		}
+	case Distinct:
+		data, _ := json.Marshal(value)
+		s.distinct[string(data)] = struct{}{}
+	}
+}
+
+// result nil for the metrics of no value, the same as sql.
+func (s *state) result(op Op) interface{} {
+	switch op {
+	case Count:
+		return s.count
+	case Distinct:
+		return int64(len(s.distinct))
+	}
+	if s.count == 0 {
+		return nil
+	}
+	switch op {
+	case Sum:
+		return s.sum
+	case Avg:
+		return s.sum / float64(s.count)
+	case Min:
+		return s.min
+	case Max:
+		return s.max
+	}
+	return nil
+}
+
+func (p *plan) columns() []*Column {
+	columns := make([]*Column, 0, len(p.dimensions)+len(p.metrics))
+	for _, d := range p.dimensions {
+		columns = append(columns, &Column{
+			Name:     d.alias,
+			Type:     DimensionColumn,
+			Field:    d.field,
+			Interval: d.interval,
+		})
+	}
+	for _, m := range p.metrics {
+		columns = append(columns, &Column{
+			Name:  m.alias,
+			Type:  MetricColumn,
+			Field: m.field,
+			Op:    m.op,
+		})
+	}
+	return columns
+}
+
+// rows filter the groups by having, sort and limit them.
+func (p *plan) rows() [][]interface{} {
+	if len(p.dimensions) == 0 && len(p.groups) == 0 {
+		// the whole table is one row even if it is empty.
+		p.group([]interface{}{})
+	}
+	rows := make([][]interface{}, 0, len(p.groups))
+	for _, key := range p.order {
+		g := p.groups[key]
+		row := make([]interface{}, 0, len(g.values)+len(g.states))
+		row = append(row, g.values...)
+		for index, m := range p.metrics {
+			row = append(row, g.states[index].result(m.op))
+		}
+		if p.match(row) {
+			rows = append(rows, row)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, s := range p.sorts {
+			c := compare(rows[i][s.index], rows[j][s.index])
+			if c == 0 {
+				continue
+			}
+			if s.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	if len(rows) > p.limit {
+		rows = rows[:p.limit]
+	}
+	return rows
+}
+
+func (p *plan) match(row []interface{}) bool {
+	for _, h := range p.having {
+		value, ok := toFloat(row[h.index])
+		if !ok || !compareOp(h.op, value, h.value) {
+			return false
+		}
+	}
+	return true
+}
+
+func validCompare(op string) bool {
+	switch op {
+	case "eq", "ne", "gt", "gte", "lt", "lte":
+		return true
+	}
+	return false
+}
+
+func compareOp(op string, value, target float64) bool {
+	switch op {
+	case "eq":
+		return value == target
+	case "ne":
+		return value != target
+	case "gt":
+		return value > target
+	case "gte":
+		return value >= target
+	case "lt":
+		return value < target
+	case "lte":
+		return value <= target
+	}
+	return false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func ordered(value interface{}) bool {
+	switch value.(type) {
+	case float64, string:
+		return true
+	}
+	return false
+}
+
+// compare the nulls come first, then the numbers, the strings and the others.
+func compare(a, b interface{}) int {
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	switch ra {
+	case 1:
+		fa, _ := toFloat(a)
+		fb, _ := toFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+	case 2:
+		sa, sb := a.(string), b.(string)
+		switch {
+		case sa < sb:
+			return -1
+		case sa > sb:
+			return 1
+		}
+	case 3:
+		ba, bb := a.(bool), b.(bool)
+		switch {
+		case !ba && bb:
+			return -1
+		case ba && !bb:
+			return 1
+		}
+	}
+	return 0
+}
+
+func rank(value interface{}) int {
+	switch value.(type) {
+	case nil:
+		return 0
+	case float64, int64:
+		return 1
+	case string:
+		return 2
+	case bool:
+		return 3
+	}
+	return 4
+}
+
+// bucket the start of the day, week or month of the date, the numbers are unix milliseconds.
+func bucket(value interface{}, interval Interval, location *time.Location) interface{} {
+	var t time.Time
+	switch v := value.(type) {
+	case float64:
+		t = time.Unix(0, int64(v)*int64(time.Millisecond))
+	case string:
+		parsed := false
+		for _, layout := range dateLayouts {
+			if d, err := time.ParseInLocation(layout, v, location); err == nil {
+				t, parsed = d, true
+				break
+			}
+		}
+		if !parsed {
+			return nil
+		}
+	default:
+		return nil
+	}
+	t = t.In(location)
+	switch interval {
+	case Week:
+		return t.AddDate(0, 0, -(int(t.Weekday())+6)%7).Format("2006-01-02")
+	case Month:
+		return t.Format("2006-01")
+	}
+	return t.Format("2006-01-02")
+}
@@ -5,13 +5,25 @@ import (
 
 	"github.com/quanxiang-cloud/form/internal/models"
 	"github.com/quanxiang-cloud/form/internal/models/mysql"
+	redis2 "github.com/quanxiang-cloud/form/internal/models/redis"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/pkg/misc/client"
 	config2 "github.com/quanxiang-cloud/form/pkg/misc/config"
 
 	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/db/redis"
 	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
 	"gorm.io/gorm"
 )
 
+const (
+	createdAtKey = "created_at"
+	updatedAtKey = "updated_at"
+	// dslQueryKey the query of a dsl of structor.
+	dslQueryKey = "query"
+)
+
 // Backup import and export data interface.
 type Backup interface {
 	ExportTable(context.Context, *ExportTableReq) (*ExportTableResp, error)
@@ -19,12 +31,18 @@ type Backup interface {
 	ExportTableRelation(context.Context, *ExportTableRelationReq) (*ExportTableRelationResp, error)
 	ExportTableScheme(context.Context, *ExportTableSchemeReq) (*ExportTableSchemeResp, error)
 	ExportRole(context.Context, *ExportRoleReq) (*ExportRoleResp, error)
+	ExportRecord(context.Context, *ExportRecordReq) (*ExportRecordResp, error)
+	ExportSerial(context.Context, *ExportSerialReq) (*ExportSerialResp, error)
 
 	ImportTable(context.Context, *ImportTableReq) (*ImportTableResp, error)
 	ImportPermit(context.Context, *ImportPermitReq) (*ImportPermitResp, error)
 	ImportTableRelation(context.Context, *ImportTableRelationReq) (*ImportTableRelationResp, error)
 	ImportTableScheme(context.Context, *ImportTableSchemeReq) (*ImportTableSchemeResp, error)
 	ImportRole(context.Context, *ImportRoleReq) (*ImportRoleResp, error)
+	ImportRecord(context.Context, *ImportRecordReq) (*ImportRecordResp, error)
+	ImportSerial(context.Context, *ImportSerialReq) (*ImportSerialResp, error)
+
+	Restore(context.Context, *RestoreReq) (*RestoreResp, error)
 }
 
 type backup struct {
@@ -34,6 +52,8 @@ type backup struct {
 	tableRepo         models.TableRepo
 	tableRelationRepo models.TableRelationRepo
 	tableSchemeRepo   models.TableSchemeRepo
+	serialRepo        models.SerialRepo
+	formAPI           *client.FormAPI
 }
 
 // NewBackup create a new backup service.
@@ -42,6 +62,14 @@ func NewBackup(conf *config2.Config) (Backup, error) {
 	if err != nil {
 		return nil, err
 	}
+	redisClient, err := redis.NewClient(conf.Redis)
+	if err != nil {
+		return nil, err
+	}
+	formAPI, err := client.NewFormAPI(conf)
+	if err != nil {
+		return nil, err
+	}
 
 	return &backup{
 		db:                db,
@@ -50,6 +78,8 @@ func NewBackup(conf *config2.Config) (Backup, error) {
 		tableRepo:         mysql.NewTableRepo(),
 		tableRelationRepo: mysql.NewTableRelationRepo(),
 		tableSchemeRepo:   mysql.NewTableSchema(),
+		serialRepo:        redis2.NewSerialRepo(redisClient),
+		formAPI:           formAPI,
 	}, nil
 }
 
@@ -298,3 +328,153 @@ func (b *backup) ImportRole(ctx context.Context, req *ImportRoleReq) (*ImportRol
 
 	return &ImportRoleResp{}, nil
 }
+
+type ExportRecordReq struct {
+	AppID string `uri:"appID"`
+	// TableID the table, or the relation of a table to its sub table.
+	TableID string `uri:"tableName"`
+	Page    int    `json:"page"`
+	Size    int    `json:"size"`
+	// Since only the records created or updated since, in unix milliseconds.
+	Since int64 `json:"since"`
+}
+
+type ExportRecordResp struct {
+	Data  []map[string]interface{} `json:"data"`
+	Count int64                    `json:"count"`
+}
+
+// ExportRecord export the records as they are stored, in the order of _id, so the pages are stable.
+func (b *backup) ExportRecord(ctx context.Context, req *ExportRecordReq) (*ExportRecordResp, error) {
+	formReq := &client.FormReq{
+		TableID: consensus.GetTableID(req.AppID, req.TableID),
+	}
+	formReq.Page = int64(req.Page)
+	formReq.Size = int64(req.Size)
+	formReq.Sort = []string{consensus.IDKey}
+	if req.Since != 0 {
+		since := time2.Format(req.Since)
+		formReq.DslQuery = consensus.GetBool(consensus.Should,
+			consensus.GetSimple(consensus.RangeKey, createdAtKey, map[string]interface{}{"gte": since}),
+			consensus.GetSimple(consensus.RangeKey, updatedAtKey, map[string]interface{}{"gte": since}),
+		)
+	}
+	records, err := b.formAPI.Search(ctx, formReq)
+	if err != nil {
+		logger.Logger.WithName("ExportRecord").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return nil, err
+	}
+
+	return &ExportRecordResp{
+		Data:  records.Entities,
+		Count: records.Total,
+	}, nil
+}
+
+type ExportSerialReq struct {
+	AppID string `uri:"appID"`
+	Page  int    `json:"page"`
+	Size  int    `json:"size"`
+}
+
+type ExportSerialResp struct {
+	Data  []*models.Serial `json:"data"`
+	Count int64            `json:"count"`
+}
+
+// ExportSerial export the serials of the fields.
+func (b *backup) ExportSerial(ctx context.Context, req *ExportSerialReq) (*ExportSerialResp, error) {
+	serials, err := b.serialRepo.List(ctx, req.AppID)
+	if err != nil {
+		logger.Logger.WithName("ExportSerial").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return nil, err
+	}
+
+	// the serials are few, they are paged in memory.
+	count := len(serials)
+	if req.Page > 0 && req.Size > 0 {
+		start, end := (req.Page-1)*req.Size, req.Page*req.Size
+		if start > count {
+			start = count
+		}
+		if end > count {
+			end = count
+		}
+		serials = serials[start:end]
+	}
+
+	return &ExportSerialResp{
+		Data:  serials,
+		Count: int64(count),
+	}, nil
+}
+
+type ImportRecordReq struct {
+	AppID   string                   `uri:"appID"`
+	TableID string                   `uri:"tableName"`
+	Data    []map[string]interface{} `json:"data"`
+}
+
+type ImportRecordResp struct{}
+
+// ImportRecord import the records as they are, without the guidance, so no events are sent.
+// the records of the same ids are replaced, so the import can be retried, and the incremental
+// backups are imported over the full one.
+func (b *backup) ImportRecord(ctx context.Context, req *ImportRecordReq) (*ImportRecordResp, error) {
+	if len(req.Data) == 0 {
+		return &ImportRecordResp{}, nil
+	}
+	tableID := consensus.GetTableID(req.AppID, req.TableID)
+	entities := make([]interface{}, 0, len(req.Data))
+	ids := make([]interface{}, 0, len(req.Data))
+	for _, record := range req.Data {
+		entities = append(entities, record)
+		if id, ok := record[consensus.IDKey]; ok {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) != 0 {
+		_, err := b.formAPI.Delete(ctx, &client.FormReq{
+			TableID:  tableID,
+			DslQuery: map[string]interface{}{dslQueryKey: consensus.GetSimple(consensus.TermsKey, consensus.IDKey, ids)},
+		})
+		if err != nil {
+			logger.Logger.WithName("ImportRecord").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			return nil, err
+		}
+	}
+	_, err := b.formAPI.BatchInsert(ctx, tableID, entities)
+	if err != nil {
+		logger.Logger.WithName("ImportRecord").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return nil, err
+	}
+
+	return &ImportRecordResp{}, nil
+}
+
+type ImportSerialReq struct {
+	AppID string           `uri:"appID"`
+	Data  []*models.Serial `json:"data"`
+}
+
+type ImportSerialResp struct{}
+
+// ImportSerial import the serials, the next numbers go on from the exported ones.
+func (b *backup) ImportSerial(ctx context.Context, req *ImportSerialReq) (*ImportSerialResp, error) {
+	for _, serial := range req.Data {
+		if len(serial.Values) == 0 {
+			continue
+		}
+		values := make(map[string]interface{}, len(serial.Values))
+		for key, value := range serial.Values {
+			values[key] = value
+		}
+		err := b.serialRepo.Create(ctx, req.AppID, serial.TableID, serial.FieldID, values)
+		if err != nil {
+			logger.Logger.WithName("ImportSerial").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			return nil, err
+		}
+	}
+
+	return &ImportSerialResp{}, nil
+}
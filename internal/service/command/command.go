@@ -0,0 +1,170 @@
+package command
+
+import (
+	"context"
+	"strings"
+
+	daprd "github.com/dapr/go-sdk/client"
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/form/internal/component/event"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/form"
+	"github.com/quanxiang-cloud/form/internal/service/rules"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	daprd2 "github.com/quanxiang-cloud/form/pkg/misc/dapr"
+)
+
+const (
+	StatusSucceed = "succeed"
+	StatusFailed  = "failed"
+
+	MethodCreate = "create"
+	MethodUpdate = "update"
+	MethodDelete = "delete"
+	MethodUpsert = "upsert"
+
+	keyPrefix = "command"
+)
+
+// publisher the dapr client, it publishes the replies.
+type publisher interface {
+	PublishEvent(ctx context.Context, pubsubName, topicName string, data interface{}, opts ...daprd.PublishEventOption) error
+}
+
+// Consumer run the record commands of the other services through the guidance, as the users of the commands.
+type Consumer interface {
+	// Consume the failures of the command are replied, an error is returned only if the command should be redelivered.
+	Consume(ctx context.Context, eventID string, cmd *event.RecordCommand) error
+}
+
+type consumer struct {
+	guidance   consensus.Guidance
+	idem       form.Idempotency
+	publisher  publisher
+	pubSubName string
+	replyTopic string
+}
+
+// NewConsumer the commands of the same id are run once, a redelivered one is replied the first result.
+func NewConsumer(conf *config.Config, guidance consensus.Guidance, idem form.Idempotency) (Consumer, error) {
+	client, err := daprd2.InitDaprClientIfNil()
+	if err != nil {
+		return nil, err
+	}
+	return &consumer{
+		guidance:   guidance,
+		idem:       idem,
+		publisher:  client,
+		pubSubName: conf.Dapr.PubSubName,
+		replyTopic: conf.Dapr.TopicReply,
+	}, nil
+}
+
+func (c *consumer) Consume(ctx context.Context, eventID string, cmd *event.RecordCommand) error {
+	if cmd.ID == "" {
+		cmd.ID = eventID
+	}
+	reply := &event.RecordReply{
+		ID:      cmd.ID,
+		Method:  cmd.Method,
+		AppID:   cmd.AppID,
+		TableID: cmd.TableID,
+		Status:  StatusSucceed,
+	}
+	result, err := c.run(ctx, cmd)
+	if violations, ok := err.(rules.Violations); ok {
+		reply.Details, err = violations, violations.Coded()
+	}
+	if err != nil {
+		e, ok := err.(error2.Error)
+		if !ok || retryable(e.Code) {
+			return err
+		}
+		reply.Status, reply.Code, reply.Message = StatusFailed, e.Code, e.Message
+	} else {
+		reply.Result = result
+	}
+	return c.reply(ctx, cmd, reply)
+}
+
+func (c *consumer) run(ctx context.Context, cmd *event.RecordCommand) (interface{}, error) {
+	if err := validate(cmd); err != nil {
+		return nil, err
+	}
+	key := strings.Join([]string{keyPrefix, cmd.AppID, cmd.TableID, cmd.ID}, ":")
+	result, _, err := c.idem.Do(ctx, key, cmd, func() (interface{}, error) {
+		return c.guidance.Do(ctx, toBus(cmd))
+	})
+	return result, err
+}
+
+// reply a failed publish has the command redelivered, the result is replayed then.
+func (c *consumer) reply(ctx context.Context, cmd *event.RecordCommand, reply *event.RecordReply) error {
+	topic := cmd.ReplyTopic
+	if topic == "" {
+		topic = c.replyTopic
+	}
+	if topic == "" {
+		return nil
+	}
+	if err := c.publisher.PublishEvent(ctx, c.pubSubName, topic, reply); err != nil {
+		logger.Logger.WithName("command").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return err
+	}
+	return nil
+}
+
+// retryable the command may succeed later, so it is not replied as failed.
+func retryable(c int64) bool {
+	return c == code.ErrIdempotencyInProgress || c == code.ErrUpsertBusy
+}
+
+func validate(cmd *event.RecordCommand) error {
+	switch {
+	case cmd.ID == "":
+		return error2.New(code.ErrInvalidCommand, "id is required")
+	case cmd.AppID == "" || cmd.TableID == "":
+		return error2.New(code.ErrInvalidCommand, "appID and tableID are required")
+	case cmd.UserID == "":
+		return error2.New(code.ErrInvalidCommand, "userID is required")
+	}
+	switch cmd.Method {
+	case MethodCreate, MethodUpsert:
+		if cmd.Entity == nil {
+			return error2.New(code.ErrInvalidCommand, "entity is required")
+		}
+	case MethodUpdate:
+		if cmd.Entity == nil || len(cmd.Query) == 0 {
+			return error2.New(code.ErrInvalidCommand, "entity and query are required")
+		}
+	case MethodDelete:
+		// the records of the table are not deleted all by a command without a query.
+		if len(cmd.Query) == 0 {
+			return error2.New(code.ErrInvalidCommand, "query is required")
+		}
+	default:
+		return error2.New(code.ErrInvalidCommand, "unknown method "+cmd.Method)
+	}
+	return nil
+}
+
+func toBus(cmd *event.RecordCommand) *consensus.Bus {
+	bus := new(consensus.Bus)
+	bus.Universal = consensus.Universal{
+		UserID:   cmd.UserID,
+		UserName: cmd.UserName,
+		DepID:    cmd.DepID,
+	}
+	bus.Foundation = consensus.Foundation{
+		AppID:   cmd.AppID,
+		TableID: cmd.TableID,
+		Method:  cmd.Method,
+	}
+	bus.Ref.Ref = cmd.Ref
+	bus.Get.Query = cmd.Query
+	bus.CreatedOrUpdate.Entity = cmd.Entity
+	return bus
+}
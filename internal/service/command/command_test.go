@@ -0,0 +1,133 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	daprd "github.com/dapr/go-sdk/client"
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/form/internal/component/event"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/rules"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+)
+
+type fakeGuidance struct {
+	calls int
+	err   error
+	bus   *consensus.Bus
+}
+
+func (f *fakeGuidance) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	f.calls++
+	f.bus = bus
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &consensus.Response{Total: 1}, nil
+}
+
+type fakeIdempotency struct {
+	results map[string]interface{}
+}
+
+func (f *fakeIdempotency) Do(ctx context.Context, key string, request interface{}, fn func() (interface{}, error)) (interface{}, bool, error) {
+	if result, ok := f.results[key]; ok {
+		return result, true, nil
+	}
+	result, err := fn()
+	if err != nil {
+		return nil, false, err
+	}
+	f.results[key] = result
+	return result, false, nil
+}
+
+type fakePublisher struct {
+	topic   string
+	replies []*event.RecordReply
+	err     error
+}
+
+func (f *fakePublisher) PublishEvent(ctx context.Context, pubsubName, topicName string, data interface{}, opts ...daprd.PublishEventOption) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.topic = topicName
+	f.replies = append(f.replies, data.(*event.RecordReply))
+	return nil
+}
+
+func TestConsume(t *testing.T) {
+	guidance := &fakeGuidance{}
+	pub := &fakePublisher{}
+	c := &consumer{
+		guidance:   guidance,
+		idem:       &fakeIdempotency{results: make(map[string]interface{})},
+		publisher:  pub,
+		replyTopic: "form.Reply",
+	}
+	ctx := context.Background()
+	cmd := func() *event.RecordCommand {
+		return &event.RecordCommand{
+			Method:  MethodUpdate,
+			AppID:   "app",
+			TableID: "t",
+			Entity:  map[string]interface{}{"name": "a"},
+			Query:   types.Query(consensus.GetSimple(consensus.TermKey, consensus.IDKey, "r1")),
+			UserID:  "u1",
+		}
+	}
+
+	if err := c.Consume(ctx, "e1", cmd()); err != nil {
+		t.Fatal(err)
+	}
+	reply := pub.replies[0]
+	if pub.topic != "form.Reply" || reply.ID != "e1" || reply.Status != StatusSucceed || reply.Result == nil {
+		t.Fatalf("topic %s, reply %+v", pub.topic, reply)
+	}
+	if bus := guidance.bus; bus.Method != MethodUpdate || bus.UserID != "u1" || len(bus.Get.Query) == 0 {
+		t.Fatalf("bus %+v", bus)
+	}
+
+	// redelivered.
+	if err := c.Consume(ctx, "e1", cmd()); err != nil || guidance.calls != 1 || len(pub.replies) != 2 {
+		t.Fatalf("err %v, calls %d, replies %d", err, guidance.calls, len(pub.replies))
+	}
+
+	invalid := cmd()
+	invalid.Query = nil
+	invalid.ReplyTopic = "other"
+	if err := c.Consume(ctx, "e2", invalid); err != nil {
+		t.Fatal(err)
+	}
+	if reply := pub.replies[2]; pub.topic != "other" || reply.Status != StatusFailed || reply.Code != code.ErrInvalidCommand {
+		t.Fatalf("topic %s, reply %+v", pub.topic, reply)
+	}
+
+	violations := rules.Violations{{ID: "salary", Message: "salary must be positive"}}
+	guidance.err = violations
+	if err := c.Consume(ctx, "e3", cmd()); err != nil {
+		t.Fatal(err)
+	}
+	if reply := pub.replies[3]; reply.Status != StatusFailed || reply.Code != code.ErrRuleViolation ||
+		!reflect.DeepEqual(reply.Details, violations) {
+		t.Fatalf("reply %+v", reply)
+	}
+
+	for _, err := range []error{errors.New("connection refused"), error2.New(code.ErrUpsertBusy)} {
+		guidance.err = err
+		if c.Consume(ctx, "e4", cmd()) == nil || len(pub.replies) != 4 {
+			t.Fatalf("%v should be redelivered", err)
+		}
+	}
+
+	guidance.err = nil
+	pub.err = errors.New("publish")
+	if c.Consume(ctx, "e5", cmd()) == nil {
+		t.Fatal("the reply should be redelivered")
+	}
+}
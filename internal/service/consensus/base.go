@@ -11,6 +11,7 @@ const (
 	TermKey  = "term"
 	IDKey    = "_id"
 	Must     = "must"
+	MatchKey = "match"
 )
 
 // KeyValue KeyValue.
@@ -31,12 +31,22 @@ type Get struct {
 	Query    types.Query `json:"query,omitempty" form:"query"`
 	OldQuery types.Query `json:"OldQuery"`
 	Aggs     types.Any   `json:"aggs"`
+	// Fields the fields to return, the nested ones are joined by dots, all fields if empty.
+	Fields []string `json:"fields,omitempty" form:"fields"`
+	// Expand the relation fields filled with the related records, like items or items.supplier.
+	Expand []string `json:"expand,omitempty" form:"expand"`
+	// Q the text matched by any string field of search, the matches are highlighted.
+	Q string `json:"q,omitempty" form:"q"`
+	// SearchFields the fields q matches, all the string fields if empty.
+	SearchFields []string `json:"searchFields,omitempty" form:"searchFields"`
 }
 
 type List struct {
 	Page int64    `json:"page,omitempty" form:"page"`
 	Size int64    `json:"size,omitempty" form:"size"`
 	Sort []string `json:"sort,omitempty" form:"sort"`
+	// Cursor the nextCursor of the last page, the page is ignored when it is set.
+	Cursor string `json:"cursor,omitempty" form:"cursor"`
 }
 
 type CreatedOrUpdate struct {
@@ -45,6 +55,13 @@ type CreatedOrUpdate struct {
 
 type Delete struct{}
 
+// FieldPermitHeader the field permit passed by the permit gateway,
+// for the bodies which can not be filtered by the gateway, like csv.
+const FieldPermitHeader = "Field-Permit"
+
+// IdempotencyHeader the key of a request, a retried one with the same key returns the first result.
+const IdempotencyHeader = "Idempotency-Key"
+
 type Incidental struct {
 	Permit *Permit `json:"-,omitempty"`
 }
@@ -80,6 +97,12 @@ type Response struct {
 	Entity   Entity         `json:"entity,omitempty"`
 	Total    int64          `json:"total"`
 	Entities types.Entities `json:"entities,omitempty"`
+	// NextCursor the position after the last record, empty when there are no more.
+	NextCursor string `json:"nextCursor,omitempty"`
+	// Highlights the fragments of the fields matching q by the _id of the records.
+	Highlights map[string]map[string]string `json:"highlights,omitempty"`
+	// Action the action taken by upsert, create or update.
+	Action string `json:"action,omitempty"`
 }
 type Guidance interface {
 	Do(ctx context.Context, bus *Bus) (*Response, error)
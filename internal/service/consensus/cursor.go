@@ -0,0 +1,132 @@
+package consensus
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+)
+
+const (
+	Should   = "should"
+	MustNot  = "must_not"
+	RangeKey = "range"
+
+	descPrefix = "-"
+	gtKey      = "gt"
+	ltKey      = "lt"
+)
+
+// cursor the values of the sort keys of the last record of a page.
+type cursor struct {
+	Sort   []string      `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// CursorSort the sort keys with _id as the tiebreaker, which makes the order total.
+func CursorSort(sort []string) []string {
+	sorts := make([]string, 0, len(sort)+1)
+	for _, key := range sort {
+		sorts = append(sorts, key)
+		if strings.TrimPrefix(key, descPrefix) == IDKey {
+			return sorts
+		}
+	}
+	return append(sorts, IDKey)
+}
+
+// EncodeCursor the opaque position after the entity in the order of sort.
+func EncodeCursor(sort []string, entity map[string]interface{}) string {
+	c := &cursor{
+		Sort:   sort,
+		Values: make([]interface{}, 0, len(sort)),
+	}
+	for _, key := range sort {
+		c.Values = append(c.Values, GetValue(entity, strings.Split(strings.TrimPrefix(key, descPrefix), ".")))
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor the values of the cursor, which must come from a search of the same sort.
+func DecodeCursor(value string, sort []string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, error2.New(code.ErrInvalidCursor)
+	}
+	c := &cursor{}
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, error2.New(code.ErrInvalidCursor)
+	}
+	if !reflect.DeepEqual(c.Sort, sort) || len(c.Values) != len(sort) {
+		return nil, error2.New(code.ErrInvalidCursor)
+	}
+	return c.Values, nil
+}
+
+// AfterQuery restrict the query to the records after the values in the order of sort, like
+// (k1 > v1) or (k1 = v1 and k2 > v2) ..., the nulls come first in ascending order.
+func AfterQuery(query types.Query, sort []string, values []interface{}) types.Query {
+	branches := make([]interface{}, 0, len(sort))
+	for index, key := range sort {
+		field := strings.TrimPrefix(key, descPrefix)
+		after := afterValue(field, values[index], strings.HasPrefix(key, descPrefix))
+		if after == nil {
+			continue
+		}
+		conditions := make([]interface{}, 0, index+1)
+		for i := 0; i < index; i++ {
+			conditions = append(conditions, GetSimple(TermKey, strings.TrimPrefix(sort[i], descPrefix), values[i]))
+		}
+		conditions = append(conditions, after)
+		if len(conditions) == 1 {
+			branches = append(branches, after)
+			continue
+		}
+		branches = append(branches, GetBool(Must, conditions...))
+	}
+	after := GetBool(Should, branches...)
+	if len(query) == 0 {
+		return after
+	}
+	return GetBool(Must, map[string]interface{}(query), after)
+}
+
+func afterValue(field string, value interface{}, desc bool) map[string]interface{} {
+	if value == nil {
+		if desc {
+			// nothing is less than null.
+			return nil
+		}
+		return GetBool(MustNot, GetSimple(TermKey, field, nil))
+	}
+	if !desc {
+		return GetSimple(RangeKey, field, KeyValue{
+			gtKey: value,
+		})
+	}
+	// the nulls come last in descending order.
+	return GetBool(Should, GetSimple(RangeKey, field, KeyValue{
+		ltKey: value,
+	}), GetSimple(TermKey, field, nil))
+}
+
+// GetValue the value of the nested field of the path, like [a b] of a.b.
+func GetValue(entity map[string]interface{}, path []string) interface{} {
+	var current interface{} = entity
+	for _, key := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
@@ -0,0 +1,44 @@
+package consensus
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	sort := CursorSort([]string{"-created_at"})
+	if !reflect.DeepEqual(sort, []string{"-created_at", "_id"}) {
+		t.Fatalf("sort is %v", sort)
+	}
+	if s := CursorSort([]string{"-_id", "name"}); !reflect.DeepEqual(s, []string{"-_id"}) {
+		t.Fatalf("sort after _id should be dropped, got %v", s)
+	}
+
+	cursor := EncodeCursor(sort, map[string]interface{}{
+		"_id":        "b",
+		"created_at": float64(100),
+	})
+	values, err := DecodeCursor(cursor, sort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{float64(100), "b"}) {
+		t.Fatalf("values are %v", values)
+	}
+	if _, err = DecodeCursor(cursor, []string{"_id"}); err == nil {
+		t.Fatal("cursor of another sort should be refused")
+	}
+	if _, err = DecodeCursor("!", sort); err == nil {
+		t.Fatal("broken cursor should be refused")
+	}
+
+	query := AfterQuery(nil, sort, values)
+	data, _ := json.Marshal(query)
+	expect := `{"bool":{"should":[` +
+		`{"bool":{"should":[{"range":{"created_at":{"lt":100}}},{"term":{"created_at":null}}]}},` +
+		`{"bool":{"must":[{"term":{"created_at":100}},{"range":{"_id":{"gt":"b"}}}]}}]}}`
+	if string(data) != expect {
+		t.Fatalf("query is %s", data)
+	}
+}
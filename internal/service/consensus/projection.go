@@ -0,0 +1,81 @@
+package consensus
+
+import (
+	"strings"
+)
+
+const fieldSeparator = ","
+
+// SplitFields the fields may be joined by commas in the url, like fields=a,b.c.
+func SplitFields(fields []string) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, value := range fields {
+		for _, field := range strings.Split(value, fieldSeparator) {
+			field = strings.TrimSpace(field)
+			if field == "" || seen[field] {
+				continue
+			}
+			seen[field] = true
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// Project keep the fields of the entity, _id is always kept to address the record.
+func Project(entity map[string]interface{}, fields []string) map[string]interface{} {
+	if entity == nil || len(fields) == 0 {
+		return entity
+	}
+	result := make(map[string]interface{}, len(fields)+1)
+	if id, ok := entity[IDKey]; ok {
+		result[IDKey] = id
+	}
+	for _, field := range fields {
+		project(entity, result, strings.Split(field, "."))
+	}
+	return result
+}
+
+func project(src, dst map[string]interface{}, path []string) {
+	value, ok := src[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		dst[path[0]] = value
+		return
+	}
+	switch sub := value.(type) {
+	case map[string]interface{}:
+		next, ok := dst[path[0]].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			dst[path[0]] = next
+		}
+		project(sub, next, path[1:])
+	case []interface{}:
+		// the records of an array, like items.name, merged with the other paths of the items.
+		items, ok := dst[path[0]].([]interface{})
+		if !ok || len(items) != len(sub) {
+			items = make([]interface{}, len(sub))
+			dst[path[0]] = items
+		}
+		for index, elem := range sub {
+			m, ok := elem.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			next, ok := items[index].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				items[index] = next
+			}
+			project(m, next, path[1:])
+		}
+	}
+}
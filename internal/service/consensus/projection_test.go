@@ -0,0 +1,38 @@
+package consensus
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	fields := SplitFields([]string{"name, addr.city", "items.sku,name"})
+	if !reflect.DeepEqual(fields, []string{"name", "addr.city", "items.sku"}) {
+		t.Fatalf("fields are %v", fields)
+	}
+	entity := map[string]interface{}{
+		"_id":  "1",
+		"name": "a",
+		"age":  float64(3),
+		"addr": map[string]interface{}{"city": "c", "zip": "z"},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "s1", "price": float64(1)},
+			map[string]interface{}{"sku": "s2", "price": float64(2)},
+		},
+	}
+	expect := map[string]interface{}{
+		"_id":  "1",
+		"name": "a",
+		"addr": map[string]interface{}{"city": "c"},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "s1"},
+			map[string]interface{}{"sku": "s2"},
+		},
+	}
+	if got := Project(entity, fields); !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect %v, got %v", expect, got)
+	}
+	if got := Project(entity, nil); !reflect.DeepEqual(got, entity) {
+		t.Fatal("all fields should be returned without fields")
+	}
+}
@@ -0,0 +1,408 @@
+package consistency
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/tables/swagger"
+	"github.com/quanxiang-cloud/form/internal/service/tables/util"
+)
+
+// app the check of an app, the objects are loaded once and checked against each other.
+type app struct {
+	*checker
+	req   *CheckReq
+	resp  *CheckResp
+	appID string
+
+	tables  map[string]*models.Table
+	schemas map[string]*models.TableSchema
+	roles   map[string]*models.Role
+	// grants the owners of each role.
+	grants map[string][]string
+}
+
+func (a *app) check(ctx context.Context) error {
+	if err := a.load(); err != nil {
+		return err
+	}
+	for _, check := range []func(ctx context.Context) error{
+		a.checkSchemas,
+		a.checkRelations,
+		a.checkGrants,
+		a.checkUserRoles,
+		a.checkPermitCache,
+		a.checkSerials,
+		a.checkSwagger,
+	} {
+		if err := check(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *app) load() error {
+	tables, _, err := a.tableRepo.List(a.db, &models.TableQuery{AppID: a.appID}, 1, all)
+	if err != nil {
+		return err
+	}
+	a.tables = make(map[string]*models.Table, len(tables))
+	for _, table := range tables {
+		a.tables[table.TableID] = table
+	}
+
+	schemas, _, err := a.tableSchemaRepo.List(a.db, &models.TableSchemaQuery{AppID: a.appID}, 1, all)
+	if err != nil {
+		return err
+	}
+	a.schemas = make(map[string]*models.TableSchema, len(schemas))
+	for _, schema := range schemas {
+		a.schemas[schema.TableID] = schema
+	}
+
+	roles, _, err := a.roleRepo.List(a.db, &models.RoleQuery{AppID: a.appID}, 1, all)
+	if err != nil {
+		return err
+	}
+	a.roles = make(map[string]*models.Role, len(roles))
+	for _, role := range roles {
+		a.roles[role.ID] = role
+	}
+	a.grants = make(map[string][]string)
+	return nil
+}
+
+// checkSchemas the schema of a deleted table is deleted as the table would be, with its swagger;
+// its serials are found by checkSerials.
+func (a *app) checkSchemas(ctx context.Context) error {
+	for tableID := range a.schemas {
+		if _, ok := a.tables[tableID]; ok {
+			continue
+		}
+		issue := a.report(KindSchemaWithoutTable, tableID, "the table does not exist")
+		if !a.req.Repair {
+			continue
+		}
+		err := a.tableSchemaRepo.Delete(a.db, &models.TableSchemaQuery{AppID: a.appID, TableID: tableID})
+		if err != nil {
+			return err
+		}
+		if _, err = a.polyAPI.DeleteNamespace(ctx, a.appID, tableID); err != nil {
+			return err
+		}
+		delete(a.schemas, tableID)
+		issue.Repaired = true
+	}
+	for tableID := range a.tables {
+		if _, ok := a.schemas[tableID]; !ok {
+			a.report(KindTableWithoutSchema, tableID, "the schema is not converted")
+		}
+	}
+	return nil
+}
+
+// checkRelations a sub table may be of another app, it is looked for in all the apps.
+func (a *app) checkRelations(ctx context.Context) error {
+	relations, _, err := a.tableRelationRepo.List(a.db, &models.TableRelationQuery{AppID: a.appID}, 1, all)
+	if err != nil {
+		return err
+	}
+	for _, relation := range relations {
+		missing := ""
+		if _, ok := a.tables[relation.TableID]; !ok {
+			missing = relation.TableID
+		} else if _, ok := a.tables[relation.SubTableID]; !ok {
+			_, total, err := a.tableRepo.List(a.db, &models.TableQuery{TableID: relation.SubTableID}, 1, 1)
+			if err != nil {
+				return err
+			}
+			if total == 0 {
+				missing = relation.SubTableID
+			}
+		}
+		if missing == "" {
+			continue
+		}
+
+		issue := a.report(KindRelationMissingTable, relation.TableID+"."+relation.FieldName, "table "+missing+" does not exist")
+		if !a.req.Repair {
+			continue
+		}
+		err = a.tableRelationRepo.Delete(a.db, &models.TableRelationQuery{
+			AppID:     a.appID,
+			TableID:   relation.TableID,
+			FieldName: relation.FieldName,
+		})
+		if err != nil {
+			return err
+		}
+		issue.Repaired = true
+	}
+	return nil
+}
+
+func (a *app) checkGrants(ctx context.Context) error {
+	grants, _, err := a.roleGrantRepo.List(a.db, &models.RoleGrantQuery{AppID: a.appID}, 1, all)
+	if err != nil {
+		return err
+	}
+	deleted := make(map[string]*Issue)
+	for _, grant := range grants {
+		if _, ok := a.roles[grant.RoleID]; ok {
+			a.grants[grant.RoleID] = append(a.grants[grant.RoleID], grant.Owner)
+			continue
+		}
+		if _, ok := deleted[grant.RoleID]; !ok {
+			deleted[grant.RoleID] = a.report(KindGrantMissingRole, grant.RoleID, "the role does not exist")
+		}
+	}
+	if !a.req.Repair {
+		return nil
+	}
+	for roleID, issue := range deleted {
+		if err = a.roleGrantRepo.Delete(a.db, &models.RoleGrantQuery{RoleID: roleID}); err != nil {
+			return err
+		}
+		issue.Repaired = true
+	}
+	return nil
+}
+
+// checkUserRoles a user_role is stale if its role is deleted, or granted neither to the user nor to
+// a department of the user, the departments are taken from org.
+// the cache of the app is dropped if a cached role differs, it is loaded again on use.
+func (a *app) checkUserRoles(ctx context.Context) error {
+	userRoles, _, err := a.userRoleRepo.List(a.db, &models.UserRoleQuery{AppID: a.appID}, 1, all)
+	if err != nil {
+		return err
+	}
+	owners, err := a.owners(ctx, userRoles)
+	if err != nil {
+		return err
+	}
+	roles := make(map[string]string, len(userRoles))
+	for _, userRole := range userRoles {
+		message := ""
+		if _, ok := a.roles[userRole.RoleID]; !ok {
+			message = "role " + userRole.RoleID + " does not exist"
+		} else if !granted(a.grants[userRole.RoleID], owners[userRole.UserID]) {
+			message = "role " + userRole.RoleID + " is granted neither to the user nor to the departments"
+		}
+		if message == "" {
+			roles[userRole.UserID] = userRole.RoleID
+			continue
+		}
+
+		issue := a.report(KindStaleUserRole, userRole.UserID, message)
+		if !a.req.Repair {
+			continue
+		}
+		err = a.userRoleRepo.Delete(a.db, &models.UserRoleQuery{
+			AppID:  a.appID,
+			UserID: userRole.UserID,
+			RoleID: userRole.RoleID,
+		})
+		if err != nil {
+			return err
+		}
+		issue.Repaired = true
+	}
+
+	cached, err := a.limitRepo.ListPerMatch(ctx, a.appID)
+	if err != nil {
+		return err
+	}
+	issues := make([]*Issue, 0)
+	for userID, roleID := range cached {
+		if roleID != roles[userID] {
+			issues = append(issues, a.report(KindUserRoleCache, userID, "role "+roleID+" is cached, not "+roles[userID]))
+		}
+	}
+	if len(issues) == 0 || !a.req.Repair {
+		return nil
+	}
+	if err = a.limitRepo.DeletePerMatch(ctx, a.appID); err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		issue.Repaired = true
+	}
+	return nil
+}
+
+// owners the user and the departments of each user, the grants are owned by either.
+func (a *app) owners(ctx context.Context, userRoles []*models.UserRole) (map[string][]string, error) {
+	owners := make(map[string][]string, len(userRoles))
+	userIDs := make([]string, 0, len(userRoles))
+	for _, userRole := range userRoles {
+		if _, ok := owners[userRole.UserID]; !ok {
+			owners[userRole.UserID] = []string{userRole.UserID}
+			userIDs = append(userIDs, userRole.UserID)
+		}
+	}
+	for i := 0; i < len(userIDs); i += orgBatch {
+		end := i + orgBatch
+		if end > len(userIDs) {
+			end = len(userIDs)
+		}
+		users, err := a.orgAPI.GetUserByIDs(ctx, userIDs[i:end]...)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			if _, ok := owners[user.ID]; !ok {
+				continue
+			}
+			for _, dep := range user.Deps {
+				owners[user.ID] = append(owners[user.ID], dep.ID)
+			}
+		}
+	}
+	return owners, nil
+}
+
+func granted(grantees, owners []string) bool {
+	for _, grantee := range grantees {
+		for _, owner := range owners {
+			if grantee == owner {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkPermitCache the cache of a role is dropped if a cached limit differs from its permits.
+func (a *app) checkPermitCache(ctx context.Context) error {
+	for roleID := range a.roles {
+		cached, err := a.limitRepo.ListPermit(ctx, roleID)
+		if err != nil {
+			return err
+		}
+		if len(cached) == 0 {
+			continue
+		}
+		permits, _, err := a.permitRepo.List(a.db, &models.PermitQuery{RoleID: roleID}, 1, all)
+		if err != nil {
+			return err
+		}
+		limits := make(map[string][][]byte, len(permits))
+		for _, permit := range permits {
+			limit, err := limitOf(permit.Path, permit.Condition, permit.Params, permit.Response)
+			if err != nil {
+				return err
+			}
+			limits[permit.Path] = append(limits[permit.Path], limit)
+		}
+
+		issues := make([]*Issue, 0)
+		for path, value := range cached {
+			limit, err := limitOf(path, value.Condition, value.Params, value.Response)
+			if err != nil {
+				return err
+			}
+			if !contains(limits[path], limit) {
+				issues = append(issues, a.report(KindPermitCache, roleID+" "+path, "the cached limit differs from the permit"))
+			}
+		}
+		if len(issues) == 0 || !a.req.Repair {
+			continue
+		}
+		if err = a.limitRepo.DeletePermit(ctx, roleID); err != nil {
+			return err
+		}
+		for _, issue := range issues {
+			issue.Repaired = true
+		}
+	}
+	return nil
+}
+
+// checkSerials the serials of a field not in the schema are reported only, they are deleted by table.
+func (a *app) checkSerials(ctx context.Context) error {
+	serials, err := a.serialRepo.List(ctx, a.appID)
+	if err != nil {
+		return err
+	}
+	deleted := make(map[string]*Issue)
+	for _, serial := range serials {
+		if _, ok := a.tables[serial.TableID]; ok {
+			if schema, ok := a.schemas[serial.TableID]; ok {
+				if _, ok := schema.Schema[serial.FieldID]; !ok {
+					a.report(KindSerial, serial.TableID+"."+serial.FieldID, "the field is not in the schema")
+				}
+			}
+			continue
+		}
+		if _, ok := deleted[serial.TableID]; !ok {
+			deleted[serial.TableID] = a.report(KindSerial, serial.TableID, "the table does not exist")
+		}
+	}
+	if !a.req.Repair {
+		return nil
+	}
+	for tableID, issue := range deleted {
+		if err = a.serialRepo.Delete(ctx, a.appID, tableID); err != nil {
+			return err
+		}
+		issue.Repaired = true
+	}
+	return nil
+}
+
+// checkSwagger register the swagger of each table again, as the table is saved.
+func (a *app) checkSwagger(ctx context.Context) error {
+	if !a.req.Swagger {
+		return nil
+	}
+	for tableID, schema := range a.schemas {
+		if _, ok := a.tables[tableID]; !ok {
+			continue
+		}
+		properties, require := util.GetSpecSchema(schema.Schema)
+		swag, err := swagger.DoSchemas(a.appID, tableID, schema.Title, properties, require)
+		if err == nil {
+			_, err = a.polyAPI.RegSwagger(ctx, "form", base64.StdEncoding.EncodeToString([]byte(swag)), a.appID, tableID, schema.Title)
+		}
+		if err != nil {
+			a.report(KindSwagger, tableID, err.Error())
+		}
+	}
+	return nil
+}
+
+func (a *app) report(kind, id, message string) *Issue {
+	return report(a.resp, kind, a.appID, id, message)
+}
+
+// limitOf the limit as it is cached, to compare. an empty value is the same as none.
+func limitOf(path string, condition models.Condition, params, response models.FiledPermit) ([]byte, error) {
+	if len(condition) == 0 {
+		condition = nil
+	}
+	if len(params) == 0 {
+		params = nil
+	}
+	if len(response) == 0 {
+		response = nil
+	}
+	return json.Marshal(&models.Limits{
+		Path:      path,
+		Condition: condition,
+		Params:    params,
+		Response:  response,
+	})
+}
+
+func contains(list [][]byte, b []byte) bool {
+	for _, value := range list {
+		if bytes.Equal(value, b) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+package consistency
+
+import (
+	"context"
+	"sort"
+
+	redis2 "github.com/quanxiang-cloud/cabin/tailormade/db/redis"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/models/mysql"
+	"github.com/quanxiang-cloud/form/internal/models/redis"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/pkg/misc/client"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	"gorm.io/gorm"
+)
+
+// all the rows of a query are read at once, pages may skip rows whose order is not stable.
+const all = 1 << 20
+
+// orgBatch the users asked of org at once.
+const orgBatch = 100
+
+// the kinds of the issues.
+const (
+	// KindSchemaWithoutTable a table_schema whose table is deleted, with its swagger.
+	KindSchemaWithoutTable = "schemaWithoutTable"
+	// KindTableWithoutSchema a table whose schema is not converted, it is reported only.
+	KindTableWithoutSchema = "tableWithoutSchema"
+	// KindRelationMissingTable a relation of a table, or to a table, which does not exist.
+	KindRelationMissingTable = "relationMissingTable"
+	// KindGrantMissingRole a role_grant of a deleted role.
+	KindGrantMissingRole = "grantMissingRole"
+	// KindPermitMissingRole a permit of a deleted role, only looked for in all the apps.
+	KindPermitMissingRole = "permitMissingRole"
+	// KindStaleUserRole a user_role whose role is deleted or granted to no one.
+	KindStaleUserRole = "staleUserRole"
+	// KindUserRoleCache a cached role of a user which differs from user_role.
+	KindUserRoleCache = "userRoleCache"
+	// KindPermitCache a cached limit of a role which differs from permit.
+	KindPermitCache = "permitCache"
+	// KindSerial a serial of a deleted table, or of a field not in the schema which is reported only.
+	KindSerial = "serial"
+	// KindSwagger a swagger which fails to register.
+	KindSwagger = "swagger"
+)
+
+// Checker find the metadata of mysql, redis and poly which do not agree, and repair them.
+type Checker interface {
+	Check(ctx context.Context, req *CheckReq) (*CheckResp, error)
+}
+
+type checker struct {
+	db                *gorm.DB
+	tableRepo         models.TableRepo
+	tableSchemaRepo   models.TableSchemeRepo
+	tableRelationRepo models.TableRelationRepo
+	roleRepo          models.RoleRepo
+	roleGrantRepo     models.RoleRantRepo
+	permitRepo        models.PermitRepo
+	userRoleRepo      models.UserRoleRepo
+	serialRepo        models.SerialRepo
+	limitRepo         models.LimitsRepo
+	polyAPI           client.PolyAPI
+	orgAPI            client.OrgAPI
+}
+
+// NewChecker NewChecker.
+func NewChecker(conf *config.Config) (Checker, error) {
+	db, err := service.CreateMysqlConn(conf)
+	if err != nil {
+		return nil, err
+	}
+	redisClient, err := redis2.NewClient(conf.Redis)
+	if err != nil {
+		return nil, err
+	}
+	return &checker{
+		db:                db,
+		tableRepo:         mysql.NewTableRepo(),
+		tableSchemaRepo:   mysql.NewTableSchema(),
+		tableRelationRepo: mysql.NewTableRelationRepo(),
+		roleRepo:          mysql.NewRoleRepo(),
+		roleGrantRepo:     mysql.NewRoleGrantRepo(),
+		permitRepo:        mysql.NewPermitRepo(),
+		userRoleRepo:      mysql.NewUserRoleRepo(),
+		serialRepo:        redis.NewSerialRepo(redisClient),
+		limitRepo:         redis.NewLimitRepo(redisClient),
+		polyAPI:           client.NewPolyAPI(conf),
+		orgAPI:            client.NewOrgAPI(conf),
+	}, nil
+}
+
+type CheckReq struct {
+	// AppID the app to check, all the apps if empty.
+	AppID string `json:"appID"`
+	// Repair the issues which can be, the others are reported only.
+	Repair bool `json:"repair"`
+	// Swagger register the swagger of the tables again, poly can not be listed to compare.
+	Swagger bool `json:"swagger"`
+}
+
+type CheckResp struct {
+	Apps   int      `json:"apps"`
+	Issues []*Issue `json:"issues"`
+}
+
+// Issue an object which does not agree with the others.
+type Issue struct {
+	Kind    string `json:"kind"`
+	AppID   string `json:"appID"`
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	// Repaired the object is deleted, or the cache is dropped to be loaded again.
+	Repaired bool `json:"repaired"`
+}
+
+// Check the app or all the apps, the orphans which refer to no app are looked for in all the apps only.
+// a repair which fails stops the check, the issues found are returned with the error.
+func (c *checker) Check(ctx context.Context, req *CheckReq) (*CheckResp, error) {
+	resp := &CheckResp{
+		Issues: make([]*Issue, 0),
+	}
+	appIDs := []string{req.AppID}
+	if req.AppID == "" {
+		var err error
+		appIDs, err = c.apps()
+		if err != nil {
+			return nil, err
+		}
+	}
+	resp.Apps = len(appIDs)
+
+	for _, appID := range appIDs {
+		a := &app{checker: c, req: req, resp: resp, appID: appID}
+		if err := a.check(ctx); err != nil {
+			sortIssues(resp.Issues)
+			return resp, err
+		}
+	}
+	if req.AppID == "" {
+		if err := c.orphans(ctx, req, resp); err != nil {
+			return resp, err
+		}
+	}
+	sortIssues(resp.Issues)
+	return resp, nil
+}
+
+// apps the apps which have tables, schemas or roles.
+func (c *checker) apps() ([]string, error) {
+	seen := make(map[string]bool)
+	for _, list := range []func(db *gorm.DB) ([]string, error){
+		c.tableRepo.AppIDs,
+		c.tableSchemaRepo.AppIDs,
+		c.roleRepo.AppIDs,
+	} {
+		appIDs, err := list(c.db)
+		if err != nil {
+			return nil, err
+		}
+		for _, appID := range appIDs {
+			seen[appID] = true
+		}
+	}
+
+	appIDs := make([]string, 0, len(seen))
+	for appID := range seen {
+		if appID != "" {
+			appIDs = append(appIDs, appID)
+		}
+	}
+	sort.Strings(appIDs)
+	return appIDs, nil
+}
+
+// orphans the permits of the deleted roles, and their cached limits.
+func (c *checker) orphans(ctx context.Context, req *CheckReq, resp *CheckResp) error {
+	roles, _, err := c.roleRepo.List(c.db, &models.RoleQuery{}, 1, all)
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		exists[role.ID] = true
+	}
+
+	permits, _, err := c.permitRepo.List(c.db, &models.PermitQuery{}, 1, all)
+	if err != nil {
+		return err
+	}
+	for _, permit := range permits {
+		if exists[permit.RoleID] {
+			continue
+		}
+		issue := report(resp, KindPermitMissingRole, "", permit.ID, "role "+permit.RoleID+" does not exist")
+		if req.Repair {
+			if err = c.permitRepo.Delete(c.db, &models.PermitQuery{ID: permit.ID}); err != nil {
+				return err
+			}
+			issue.Repaired = true
+		}
+	}
+
+	cached, err := c.limitRepo.ListPermitRoles(ctx)
+	if err != nil {
+		return err
+	}
+	for _, roleID := range cached {
+		if exists[roleID] {
+			continue
+		}
+		issue := report(resp, KindPermitCache, "", roleID, "the limits of a deleted role are cached")
+		if req.Repair {
+			if err = c.limitRepo.DeletePermit(ctx, roleID); err != nil {
+				return err
+			}
+			issue.Repaired = true
+		}
+	}
+	return nil
+}
+
+func report(resp *CheckResp, kind, appID, id, message string) *Issue {
+	issue := &Issue{
+		Kind:    kind,
+		AppID:   appID,
+		ID:      id,
+		Message: message,
+	}
+	resp.Issues = append(resp.Issues, issue)
+	return issue
+}
+
+// sortIssues by the apps, the kinds and the ids, the maps are checked in no order.
+func sortIssues(issues []*Issue) {
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].AppID != issues[j].AppID {
+			return issues[i].AppID < issues[j].AppID
+		}
+		if issues[i].Kind != issues[j].Kind {
+			return issues[i].Kind < issues[j].Kind
+		}
+		return issues[i].ID < issues[j].ID
+	})
+}
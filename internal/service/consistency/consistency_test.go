@@ -0,0 +1,232 @@
+package consistency
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/pkg/misc/client"
+	"gorm.io/gorm"
+)
+
+// deleted the objects deleted by the repair, by their kinds.
+type deleted map[string][]string
+
+type fakeTables struct {
+	models.TableRepo
+}
+
+func (f *fakeTables) List(db *gorm.DB, query *models.TableQuery, page, size int) ([]*models.Table, int64, error) {
+	if query.AppID != "a" {
+		return nil, 0, nil
+	}
+	return []*models.Table{{AppID: "a", TableID: "t1"}, {AppID: "a", TableID: "t2"}}, 2, nil
+}
+
+type fakeSchemas struct {
+	models.TableSchemeRepo
+	deleted deleted
+}
+
+func (f *fakeSchemas) List(db *gorm.DB, query *models.TableSchemaQuery, page, size int) ([]*models.TableSchema, int64, error) {
+	return []*models.TableSchema{
+		{AppID: "a", TableID: "t1", Schema: models.SchemaProperties{"no": {}}},
+		{AppID: "a", TableID: "t3"},
+	}, 2, nil
+}
+
+func (f *fakeSchemas) Delete(db *gorm.DB, query *models.TableSchemaQuery) error {
+	f.deleted["schema"] = append(f.deleted["schema"], query.TableID)
+	return nil
+}
+
+type fakeRelations struct {
+	models.TableRelationRepo
+	deleted deleted
+}
+
+func (f *fakeRelations) List(db *gorm.DB, query *models.TableRelationQuery, page, size int) ([]*models.TableRelation, int64, error) {
+	return []*models.TableRelation{
+		{AppID: "a", TableID: "t1", FieldName: "items", SubTableID: "t2"},
+		{AppID: "a", TableID: "t1", FieldName: "owner", SubTableID: "t9"},
+	}, 2, nil
+}
+
+func (f *fakeRelations) Delete(db *gorm.DB, query *models.TableRelationQuery) error {
+	f.deleted["relation"] = append(f.deleted["relation"], query.TableID+"."+query.FieldName)
+	return nil
+}
+
+type fakeRoles struct {
+	models.RoleRepo
+}
+
+func (f *fakeRoles) List(db *gorm.DB, query *models.RoleQuery, page, size int) ([]*models.Role, int64, error) {
+	return []*models.Role{{ID: "r1", AppID: "a"}}, 1, nil
+}
+
+type fakeGrants struct {
+	models.RoleRantRepo
+	deleted deleted
+}
+
+func (f *fakeGrants) List(db *gorm.DB, query *models.RoleGrantQuery, page, size int) ([]*models.RoleGrant, int64, error) {
+	return []*models.RoleGrant{{RoleID: "r1", Owner: "u1"}, {RoleID: "r1", Owner: "d1"}, {RoleID: "r2", Owner: "u2"}}, 3, nil
+}
+
+func (f *fakeGrants) Delete(db *gorm.DB, query *models.RoleGrantQuery) error {
+	f.deleted["grant"] = append(f.deleted["grant"], query.RoleID)
+	return nil
+}
+
+type fakeUserRoles struct {
+	models.UserRoleRepo
+	deleted deleted
+}
+
+func (f *fakeUserRoles) List(db *gorm.DB, query *models.UserRoleQuery, page, size int) ([]*models.UserRole, int64, error) {
+	// u4 is granted r1 by its department, u5 is not granted r1.
+	return []*models.UserRole{
+		{AppID: "a", UserID: "u1", RoleID: "r1"},
+		{AppID: "a", UserID: "u3", RoleID: "r2"},
+		{AppID: "a", UserID: "u4", RoleID: "r1"},
+		{AppID: "a", UserID: "u5", RoleID: "r1"},
+	}, 4, nil
+}
+
+func (f *fakeUserRoles) Delete(db *gorm.DB, query *models.UserRoleQuery) error {
+	f.deleted["userRole"] = append(f.deleted["userRole"], query.UserID)
+	return nil
+}
+
+type fakePermits struct {
+	models.PermitRepo
+}
+
+func (f *fakePermits) List(db *gorm.DB, query *models.PermitQuery, page, size int) ([]*models.Permit, int64, error) {
+	return []*models.Permit{{RoleID: "r1", Path: "/p", Condition: models.Condition{}}}, 1, nil
+}
+
+type fakeSerials struct {
+	models.SerialRepo
+	deleted deleted
+}
+
+func (f *fakeSerials) List(ctx context.Context, appID string) ([]*models.Serial, error) {
+	return []*models.Serial{{TableID: "t1", FieldID: "no"}, {TableID: "t3", FieldID: "no"}}, nil
+}
+
+func (f *fakeSerials) Delete(ctx context.Context, appID, tableID string) error {
+	f.deleted["serial"] = append(f.deleted["serial"], tableID)
+	return nil
+}
+
+type fakeLimits struct {
+	models.LimitsRepo
+	deleted deleted
+}
+
+func (f *fakeLimits) ListPermit(ctx context.Context, roleID string) (map[string]*models.Limits, error) {
+	return map[string]*models.Limits{"/p": {Path: "/p"}, "/q": {Path: "/q"}}, nil
+}
+
+func (f *fakeLimits) ListPerMatch(ctx context.Context, appID string) (map[string]string, error) {
+	return map[string]string{"u1": "r1", "u3": "r2"}, nil
+}
+
+func (f *fakeLimits) DeletePermit(ctx context.Context, roleID string) error {
+	f.deleted["permitCache"] = append(f.deleted["permitCache"], roleID)
+	return nil
+}
+
+func (f *fakeLimits) DeletePerMatch(ctx context.Context, appID string) error {
+	f.deleted["userRoleCache"] = append(f.deleted["userRoleCache"], appID)
+	return nil
+}
+
+type fakeOrg struct {
+	client.OrgAPI
+}
+
+func (f *fakeOrg) GetUserByIDs(ctx context.Context, ids ...string) ([]*client.User, error) {
+	return []*client.User{{ID: "u4", Deps: []client.Dep{{ID: "d1"}}}, {ID: "u5", Deps: []client.Dep{{ID: "d2"}}}}, nil
+}
+
+type fakePoly struct {
+	client.PolyAPI
+	deleted deleted
+}
+
+func (f *fakePoly) DeleteNamespace(ctx context.Context, appID, tableID string) (*client.DeleteNamespaceResp, error) {
+	f.deleted["namespace"] = append(f.deleted["namespace"], tableID)
+	return &client.DeleteNamespaceResp{}, nil
+}
+
+func newFakeChecker(d deleted) *checker {
+	return &checker{
+		tableRepo:         &fakeTables{},
+		tableSchemaRepo:   &fakeSchemas{deleted: d},
+		tableRelationRepo: &fakeRelations{deleted: d},
+		roleRepo:          &fakeRoles{},
+		roleGrantRepo:     &fakeGrants{deleted: d},
+		permitRepo:        &fakePermits{},
+		userRoleRepo:      &fakeUserRoles{deleted: d},
+		serialRepo:        &fakeSerials{deleted: d},
+		limitRepo:         &fakeLimits{deleted: d},
+		polyAPI:           &fakePoly{deleted: d},
+		orgAPI:            &fakeOrg{},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	want := []string{
+		KindGrantMissingRole + " r2",
+		KindPermitCache + " r1 /q",
+		KindRelationMissingTable + " t1.owner",
+		KindSchemaWithoutTable + " t3",
+		KindSerial + " t3",
+		KindStaleUserRole + " u3",
+		KindStaleUserRole + " u5",
+		KindTableWithoutSchema + " t2",
+		KindUserRoleCache + " u3",
+	}
+
+	d := make(deleted)
+	resp, err := newFakeChecker(d).Check(context.Background(), &CheckReq{AppID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Issues) != len(want) || len(d) != 0 {
+		t.Fatalf("issues %d, deleted %v", len(resp.Issues), d)
+	}
+	for i, issue := range resp.Issues {
+		if issue.Kind+" "+issue.ID != want[i] || issue.AppID != "a" || issue.Repaired {
+			t.Fatalf("issue %d %+v", i, issue)
+		}
+	}
+
+	resp, err = newFakeChecker(d).Check(context.Background(), &CheckReq{AppID: "a", Repair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range resp.Issues {
+		if issue.Repaired == (issue.Kind == KindTableWithoutSchema) {
+			t.Fatalf("issue %+v", issue)
+		}
+	}
+	for kind, ids := range map[string]string{
+		"schema":        "t3",
+		"namespace":     "t3",
+		"serial":        "t3",
+		"relation":      "t1.owner",
+		"grant":         "r2",
+		"userRole":      "u3,u5",
+		"userRoleCache": "a",
+		"permitCache":   "r1",
+	} {
+		if strings.Join(d[kind], ",") != ids {
+			t.Fatalf("deleted %s %v", kind, d[kind])
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package feed
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/db/redis"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/form/internal/models"
+	redis2 "github.com/quanxiang-cloud/form/internal/models/redis"
+	"github.com/quanxiang-cloud/form/internal/permit/treasure"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/form/inform"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+)
+
+const (
+	// Block how long a read waits for the changes, the subscribers are sent a heartbeat after.
+	Block = 15 * time.Second
+	// maxChanges the changes read at most once.
+	maxChanges = 100
+	// tombstonePage the records to be deleted read at most once.
+	tombstonePage = 500
+	// noBlock a read returns at once.
+	noBlock = -1
+	// retryWatch the wait of a watcher after a failed read.
+	retryWatch = time.Second
+
+	eventDelete = "delete"
+	dataKey     = "data"
+	entitiesKey = "entities"
+)
+
+var eventIDPattern = regexp.MustCompile(`^\d+-\d+$`)
+
+// ValidEventID the id of an event sent before, to resume after.
+func ValidEventID(id string) bool {
+	return eventIDPattern.MatchString(id)
+}
+
+// Publisher append the changes passing through the flow to the feeds of the tables.
+type Publisher interface {
+	Publish(ctx context.Context, appID string, data *inform.FormData)
+	// Tombstones the records to be deleted by the query, read before the delete and kept on the change of it.
+	Tombstones(ctx context.Context, appID, tableID string, query types.Query) []map[string]interface{}
+}
+
+type publisher struct {
+	changeRepo models.ChangeRepo
+	guidance   consensus.Guidance
+}
+
+// NewPublisher the records to be deleted are read by the guidance.
+func NewPublisher(conf *config.Config, guidance consensus.Guidance) (Publisher, error) {
+	redisClient, err := redis.NewClient(conf.Redis)
+	if err != nil {
+		return nil, err
+	}
+	return &publisher{
+		changeRepo: redis2.NewChangeRepo(redisClient),
+		guidance:   guidance,
+	}, nil
+}
+
+// Tombstones the failure is only logged, the deletes are left out of the feed then.
+// the records are read page by page, a delete by a query may match any number of them.
+func (p *publisher) Tombstones(ctx context.Context, appID, tableID string, query types.Query) []map[string]interface{} {
+	if len(query) == 0 {
+		return nil
+	}
+	bus := new(consensus.Bus)
+	bus.Foundation = consensus.Foundation{
+		AppID:   appID,
+		TableID: tableID,
+		Method:  "search",
+	}
+	bus.Get.Query = query
+	tombstones := make([]map[string]interface{}, 0)
+	for page := int64(1); ; page++ {
+		bus.List = consensus.List{
+			Page: page,
+			Size: tombstonePage,
+			Sort: []string{consensus.IDKey},
+		}
+		resp, err := p.guidance.Do(ctx, bus)
+		if err != nil {
+			logger.Logger.WithName("feed").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			return nil
+		}
+		tombstones = append(tombstones, resp.Entities...)
+		if len(resp.Entities) < tombstonePage {
+			return tombstones
+		}
+	}
+}
+
+// Publish the failure is only logged, the change is made already.
+func (p *publisher) Publish(ctx context.Context, appID string, data *inform.FormData) {
+	change := toChange(data)
+	if change == nil {
+		return
+	}
+	if err := p.changeRepo.Append(ctx, appID, data.TableID, change); err != nil {
+		logger.Logger.WithName("feed").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+	}
+}
+
+// toChange only the ids are kept, the records are read by each subscriber with its permit,
+// but the deleted ones, which are kept as tombstones.
+func toChange(data *inform.FormData) *models.Change {
+	event := data.Event()
+	if event == "" {
+		return nil
+	}
+	ids := data.IDs()
+	if len(ids) == 0 {
+		return nil
+	}
+	change := &models.Change{
+		Event:   event,
+		IDs:     ids,
+		Created: time2.NowUnix(),
+	}
+	if event == eventDelete {
+		change.Tombstones = data.Deleted
+	}
+	return change
+}
+
+// Feed the changes of a table, as a subscriber sees them.
+type Feed interface {
+	Next(ctx context.Context, req *NextReq) (*NextResp, error)
+}
+
+type feed struct {
+	changeRepo models.ChangeRepo
+	guidance   consensus.Guidance
+	hub        *hub
+}
+
+// NewFeed the records of the changes are read by the guidance.
+func NewFeed(conf *config.Config, guidance consensus.Guidance) (Feed, error) {
+	redisClient, err := redis.NewClient(conf.Redis)
+	if err != nil {
+		return nil, err
+	}
+	changeRepo := redis2.NewChangeRepo(redisClient)
+	return &feed{
+		changeRepo: changeRepo,
+		guidance:   guidance,
+		hub:        newHub(changeRepo),
+	}, nil
+}
+
+type NextReq struct {
+	AppID   string
+	TableID string
+	// LastEventID resume after the event, the changes from now on if empty.
+	LastEventID string
+	// Query the row condition of the subscriber, the gateway ANDs the condition of the permit in.
+	Query types.Query
+	// Permit the response permit of the subscriber, nil for all the fields.
+	Permit   models.FiledPermit
+	UserID   string
+	UserName string
+	DepID    string
+}
+
+// Event a change the subscriber can see.
+type Event struct {
+	ID    string   `json:"id"`
+	Event string   `json:"event"`
+	IDs   []string `json:"ids"`
+	// Entities the created or updated records, read when sent, so the latest of them.
+	Entities []map[string]interface{} `json:"entities,omitempty"`
+	Created  int64                    `json:"created"`
+}
+
+type NextResp struct {
+	Events []*Event
+	// LastEventID where to resume from, it moves on even if the changes are all out of sight.
+	LastEventID string
+}
+
+// Next the changes after the last event, it waits for Block at most.
+// the records out of the query are left out, the deleted ones are gone,
+// so their tombstones are matched to the query instead.
+func (f *feed) Next(ctx context.Context, req *NextReq) (*NextResp, error) {
+	after := req.LastEventID
+	if after == "" {
+		last, err := f.changeRepo.Last(ctx, req.AppID, req.TableID)
+		if err != nil {
+			return nil, err
+		}
+		after = last
+	} else if !ValidEventID(after) {
+		return nil, error2.New(code.ErrInvalidEventID)
+	}
+	changes, err := f.read(ctx, req, after)
+	if err != nil {
+		return nil, err
+	}
+	resp := &NextResp{
+		Events:      make([]*Event, 0, len(changes)),
+		LastEventID: after,
+	}
+	if len(changes) == 0 {
+		return resp, nil
+	}
+	resp.LastEventID = changes[len(changes)-1].ID
+
+	records, err := f.visible(ctx, req, changes)
+	if err != nil {
+		return nil, err
+	}
+	query, err := normalize(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		event := &Event{
+			ID:      change.ID,
+			Event:   change.Event,
+			IDs:     change.IDs,
+			Created: change.Created,
+		}
+		if change.Event == eventDelete {
+			event.IDs = deleted(query, change)
+		} else {
+			event.IDs = make([]string, 0, len(change.IDs))
+			for _, id := range change.IDs {
+				if record, ok := records[id]; ok {
+					event.IDs = append(event.IDs, id)
+					event.Entities = append(event.Entities, record)
+				}
+			}
+		}
+		if len(event.IDs) == 0 {
+			continue
+		}
+		resp.Events = append(resp.Events, event)
+	}
+	return resp, nil
+}
+
+// read the changes after the id, the subscriber waits on the watcher of the table if none,
+// so the connections of redis are not held by the subscribers.
+func (f *feed) read(ctx context.Context, req *NextReq, after string) ([]*models.Change, error) {
+	timer := time.NewTimer(Block)
+	defer timer.Stop()
+	for {
+		// it is subscribed before the read, the changes in between are not missed.
+		notify, release := f.hub.subscribe(req.AppID, req.TableID, after)
+		changes, err := f.changeRepo.Read(ctx, req.AppID, req.TableID, after, maxChanges, noBlock)
+		if err != nil || len(changes) != 0 {
+			release()
+			return changes, err
+		}
+		select {
+		case <-notify:
+			// the watcher may wake up for the changes read before, read again.
+			release()
+			continue
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		release()
+		return nil, nil
+	}
+}
+
+// deleted the ids of the deleted records the query matches, those without a tombstone are left out.
+func deleted(query map[string]interface{}, change *models.Change) []string {
+	ids := make([]string, 0, len(change.Tombstones))
+	for _, tombstone := range change.Tombstones {
+		if id, ok := tombstone[consensus.IDKey].(string); ok && matches(query, tombstone) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// visible the created or updated records matching the query, after the field permit.
+func (f *feed) visible(ctx context.Context, req *NextReq, changes []*models.Change) (map[string]map[string]interface{}, error) {
+	ids := make([]interface{}, 0)
+	seen := make(map[string]bool)
+	for _, change := range changes {
+		if change.Event == eventDelete {
+			continue
+		}
+		for _, id := range change.IDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	records := make(map[string]map[string]interface{}, len(ids))
+	if len(ids) == 0 {
+		return records, nil
+	}
+	query := consensus.GetSimple(consensus.TermsKey, consensus.IDKey, ids)
+	if len(req.Query) != 0 {
+		query = consensus.GetBool(consensus.Must, query, map[string]interface{}(req.Query))
+	}
+	bus := new(consensus.Bus)
+	bus.Universal = consensus.Universal{
+		UserID:   req.UserID,
+		UserName: req.UserName,
+		DepID:    req.DepID,
+	}
+	bus.Foundation = consensus.Foundation{
+		AppID:   req.AppID,
+		TableID: req.TableID,
+		Method:  "search",
+	}
+	bus.Get.Query = types.Query(query)
+	bus.List = consensus.List{
+		Page: 1,
+		Size: int64(len(ids)),
+	}
+	resp, err := f.guidance.Do(ctx, bus)
+	if err != nil {
+		return nil, err
+	}
+	entities := make([]interface{}, 0, len(resp.Entities))
+	for _, entity := range resp.Entities {
+		// the _id may be filtered out below.
+		if id, ok := entity[consensus.IDKey].(string); ok {
+			records[id] = entity
+		}
+		entities = append(entities, entity)
+	}
+	if req.Permit != nil {
+		// the permit is rooted at the body of search, filter the same way the gateway does.
+		treasure.Filter(map[string]interface{}{
+			dataKey: map[string]interface{}{entitiesKey: entities},
+		}, req.Permit)
+	}
+	return records, nil
+}
+
+// hub one watcher per table waits for the changes, and wakes up the subscribers of the table.
+type hub struct {
+	changeRepo models.ChangeRepo
+
+	mu       sync.Mutex
+	watchers map[string]*watcher
+}
+
+type watcher struct {
+	// last the latest change seen, notify is closed and replaced when it moves on.
+	last    string
+	notify  chan struct{}
+	waiters int
+}
+
+func newHub(changeRepo models.ChangeRepo) *hub {
+	return &hub{
+		changeRepo: changeRepo,
+		watchers:   make(map[string]*watcher),
+	}
+}
+
+// subscribe the channel is closed on the next change of the table,
+// the watcher is started by the first subscriber, and stops after the last one is released.
+func (h *hub) subscribe(appID, tableID, after string) (<-chan struct{}, func()) {
+	key := appID + ":" + tableID
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	w, ok := h.watchers[key]
+	if !ok {
+		w = &watcher{
+			last:   after,
+			notify: make(chan struct{}),
+		}
+		h.watchers[key] = w
+		go h.watch(appID, tableID, key, w)
+	}
+	w.waiters++
+	return w.notify, func() {
+		h.mu.Lock()
+		w.waiters--
+		h.mu.Unlock()
+	}
+}
+
+func (h *hub) watch(appID, tableID, key string, w *watcher) {
+	ctx := context.Background()
+	for {
+		h.mu.Lock()
+		if w.waiters == 0 {
+			delete(h.watchers, key)
+			h.mu.Unlock()
+			return
+		}
+		last := w.last
+		h.mu.Unlock()
+
+		changes, err := h.changeRepo.Read(ctx, appID, tableID, last, maxChanges, Block)
+		if err != nil {
+			logger.Logger.WithName("feed").Errorw(err.Error(), "key", key)
+			time.Sleep(retryWatch)
+			continue
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		h.mu.Lock()
+		w.last = changes[len(changes)-1].ID
+		close(w.notify)
+		w.notify = make(chan struct{})
+		h.mu.Unlock()
+	}
+}
@@ -0,0 +1,227 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/form/inform"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+)
+
+// fakeChangeRepo the blocking reads wait for the next append.
+type fakeChangeRepo struct {
+	mu       sync.Mutex
+	changes  []*models.Change
+	after    string
+	appended chan struct{}
+}
+
+func (f *fakeChangeRepo) Append(ctx context.Context, appID, tableID string, change *models.Change) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	change.ID = fmt.Sprintf("%d-0", len(f.changes)+1)
+	f.changes = append(f.changes, change)
+	close(f.appended)
+	f.appended = make(chan struct{})
+	return nil
+}
+
+func (f *fakeChangeRepo) Last(ctx context.Context, appID, tableID string) (string, error) {
+	return "0-0", nil
+}
+
+func (f *fakeChangeRepo) Read(ctx context.Context, appID, tableID, after string, count int64, block time.Duration) ([]*models.Change, error) {
+	f.mu.Lock()
+	if block < 0 {
+		f.after = after
+	}
+	changes := f.since(after)
+	appended := f.appended
+	f.mu.Unlock()
+	if len(changes) != 0 || block < 0 {
+		return changes, nil
+	}
+	select {
+	case <-appended:
+	case <-time.After(block):
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.since(after), nil
+}
+
+func (f *fakeChangeRepo) since(after string) []*models.Change {
+	changes := make([]*models.Change, 0)
+	for _, change := range f.changes {
+		if change.ID > after {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// fakeRecords the records of bob are out of the query of the subscriber.
+type fakeRecords struct {
+	records map[string]map[string]interface{}
+}
+
+func (f *fakeRecords) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	_, scoped := bus.Get.Query["bool"]
+	entities := make([]map[string]interface{}, 0)
+	for _, id := range []string{"r1", "r2"} {
+		record := f.records[id]
+		if scoped && record["owner"] != "alice" {
+			continue
+		}
+		copied := make(map[string]interface{}, len(record))
+		for key, value := range record {
+			copied[key] = value
+		}
+		entities = append(entities, copied)
+	}
+	return &consensus.Response{Entities: entities}, nil
+}
+
+func TestToChange(t *testing.T) {
+	cases := []struct {
+		data   *inform.FormData
+		expect *models.Change
+	}{
+		{&inform.FormData{Method: "post", Entity: map[string]interface{}{"_id": "r1"}}, &models.Change{Event: "create", IDs: []string{"r1"}}},
+		{&inform.FormData{Method: "put", Entity: map[string]interface{}{"_id": "r1", "name": "a"}}, &models.Change{Event: "update", IDs: []string{"r1"}}},
+		{&inform.FormData{Method: "delete", Entity: map[string]interface{}{"data": []string{"r1", "r2"}}, Deleted: []map[string]interface{}{{"_id": "r1"}}},
+			&models.Change{Event: "delete", IDs: []string{"r1", "r2"}, Tombstones: []map[string]interface{}{{"_id": "r1"}}}},
+		{&inform.FormData{Method: "get", Entity: map[string]interface{}{"_id": "r1"}}, nil},
+		{&inform.FormData{Method: "post", Entity: map[string]interface{}{}}, nil},
+	}
+	for _, c := range cases {
+		change := toChange(c.data)
+		if change != nil {
+			change.Created = 0
+		}
+		if !reflect.DeepEqual(change, c.expect) {
+			t.Fatalf("%+v: expect %+v, got %+v", c.data, c.expect, change)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	repo := &fakeChangeRepo{appended: make(chan struct{})}
+	ctx := context.Background()
+	for _, change := range []*models.Change{
+		{Event: "create", IDs: []string{"r1"}},
+		{Event: "update", IDs: []string{"r2"}},
+		// r4 is out of the query, and r5 has no tombstone.
+		{Event: "delete", IDs: []string{"r3", "r4", "r5"}, Tombstones: []map[string]interface{}{
+			{"_id": "r3", "owner": "alice"},
+			{"_id": "r4", "owner": "bob"},
+		}},
+		{Event: "delete", IDs: []string{"r6"}, Tombstones: []map[string]interface{}{
+			{"_id": "r6", "owner": "bob"},
+		}},
+	} {
+		_ = repo.Append(ctx, "app", "t", change)
+	}
+	f := &feed{
+		changeRepo: repo,
+		guidance: &fakeRecords{records: map[string]map[string]interface{}{
+			"r1": {"_id": "r1", "owner": "alice", "salary": 1.0},
+			"r2": {"_id": "r2", "owner": "bob", "salary": 2.0},
+		}},
+		hub: newHub(repo),
+	}
+	req := &NextReq{
+		AppID:   "app",
+		TableID: "t",
+		Query:   types.Query(consensus.GetSimple(consensus.TermKey, "owner", "alice")),
+		Permit: models.FiledPermit{
+			"data": {Type: "object", Properties: models.FiledPermit{
+				"entities": {Type: "array", Properties: models.FiledPermit{
+					"_id":   {Type: "string"},
+					"owner": {Type: "string"},
+				}},
+			}},
+		},
+	}
+	resp, err := f.Next(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.after != "0-0" || resp.LastEventID != "4-0" || len(resp.Events) != 2 {
+		t.Fatalf("after %s, resp %+v", repo.after, resp)
+	}
+	created, deleted := resp.Events[0], resp.Events[1]
+	if created.ID != "1-0" || !reflect.DeepEqual(created.Entities, []map[string]interface{}{{"_id": "r1", "owner": "alice"}}) {
+		t.Fatalf("created %+v", created)
+	}
+	if deleted.ID != "3-0" || !reflect.DeepEqual(deleted.IDs, []string{"r3"}) || deleted.Entities != nil {
+		t.Fatalf("deleted %+v", deleted)
+	}
+
+	// the subscriber waits on the watcher of the table for the next change.
+	req.LastEventID = "4-0"
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = repo.Append(ctx, "app", "t", &models.Change{Event: "update", IDs: []string{"r1"}})
+	}()
+	resp, err = f.Next(ctx, req)
+	if err != nil || resp.LastEventID != "5-0" || len(resp.Events) != 1 || resp.Events[0].ID != "5-0" {
+		t.Fatalf("resume after 4-0, resp %+v, err %v", resp, err)
+	}
+	req.LastEventID = "$"
+	if _, err = f.Next(ctx, req); err == nil {
+		t.Fatal("the event id should be checked")
+	}
+}
+
+// fakePages the records of bob, page by page.
+type fakePages struct {
+	records []map[string]interface{}
+	query   types.Query
+	pages   int
+}
+
+func (f *fakePages) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	f.query = bus.Get.Query
+	f.pages++
+	matched := make([]map[string]interface{}, 0)
+	for _, record := range f.records {
+		if record["owner"] == "bob" {
+			matched = append(matched, record)
+		}
+	}
+	start := int((bus.List.Page - 1) * bus.List.Size)
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := start + int(bus.List.Size)
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return &consensus.Response{Entities: matched[start:end]}, nil
+}
+
+func TestTombstones(t *testing.T) {
+	records := &fakePages{}
+	for i := 0; i <= tombstonePage; i++ {
+		records.records = append(records.records, map[string]interface{}{"_id": fmt.Sprint(i), "owner": "bob"})
+	}
+	records.records = append(records.records, map[string]interface{}{"_id": "alice", "owner": "alice"})
+	p := &publisher{guidance: records}
+
+	// a delete by a query without ids.
+	query := types.Query(consensus.GetSimple(consensus.TermKey, "owner", "bob"))
+	if tombstones := p.Tombstones(context.Background(), "app", "t", query); len(tombstones) != tombstonePage+1 ||
+		records.pages != 2 || !reflect.DeepEqual(records.query, query) {
+		t.Fatalf("tombstones %d in %d pages", len(tombstones), records.pages)
+	}
+	if tombstones := p.Tombstones(context.Background(), "app", "t", nil); tombstones != nil {
+		t.Fatalf("no query, got %v", tombstones)
+	}
+}
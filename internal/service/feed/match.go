@@ -0,0 +1,201 @@
+package feed
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+)
+
+const (
+	filterKey = "filter"
+	existsKey = "exists"
+	valueKey  = "value"
+	fieldKey  = "field"
+	gtKey     = "gt"
+	gteKey    = "gte"
+	ltKey     = "lt"
+	lteKey    = "lte"
+)
+
+// matches tell whether the record matches the query the way the backend does, for the deleted
+// records which can not be searched any more. the clauses it does not know match nothing,
+// so a deletion out of sight is never sent.
+func matches(query map[string]interface{}, record map[string]interface{}) bool {
+	for op, value := range query {
+		clause, ok := value.(map[string]interface{})
+		if !ok || !matchClause(op, clause, record) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchClause(op string, clause map[string]interface{}, record map[string]interface{}) bool {
+	switch op {
+	case "bool":
+		return matchBool(clause, record)
+	case consensus.TermKey, consensus.MatchKey:
+		for field, want := range clause {
+			if m, ok := want.(map[string]interface{}); ok {
+				want = m[valueKey]
+			}
+			if !anyValue(fieldValue(record, field), func(v interface{}) bool { return equal(v, want) }) {
+				return false
+			}
+		}
+		return true
+	case consensus.TermsKey:
+		for field, want := range clause {
+			wants, ok := want.([]interface{})
+			if !ok {
+				return false
+			}
+			found := anyValue(fieldValue(record, field), func(v interface{}) bool {
+				for _, w := range wants {
+					if equal(v, w) {
+						return true
+					}
+				}
+				return false
+			})
+			if !found {
+				return false
+			}
+		}
+		return true
+	case consensus.RangeKey:
+		for field, value := range clause {
+			bounds, ok := value.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			if !anyValue(fieldValue(record, field), func(v interface{}) bool { return inRange(v, bounds) }) {
+				return false
+			}
+		}
+		return true
+	case existsKey:
+		field, _ := clause[fieldKey].(string)
+		return field != "" && fieldValue(record, field) != nil
+	}
+	return false
+}
+
+func matchBool(clause map[string]interface{}, record map[string]interface{}) bool {
+	for occur, value := range clause {
+		var subs []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			subs = v
+		case map[string]interface{}:
+			subs = []interface{}{v}
+		default:
+			return false
+		}
+		matched := 0
+		for _, sub := range subs {
+			if m, ok := sub.(map[string]interface{}); ok && matches(m, record) {
+				matched++
+			}
+		}
+		switch occur {
+		case consensus.Must, filterKey:
+			if matched != len(subs) {
+				return false
+			}
+		case consensus.Should:
+			if len(subs) != 0 && matched == 0 {
+				return false
+			}
+		case consensus.MustNot:
+			if matched != 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func fieldValue(record map[string]interface{}, field string) interface{} {
+	return consensus.GetValue(record, strings.Split(field, "."))
+}
+
+// anyValue a field of many values matches if any of them does.
+func anyValue(value interface{}, fn func(interface{}) bool) bool {
+	if values, ok := value.([]interface{}); ok {
+		for _, v := range values {
+			if fn(v) {
+				return true
+			}
+		}
+		return false
+	}
+	return value != nil && fn(value)
+}
+
+func equal(a, b interface{}) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func inRange(value interface{}, bounds map[string]interface{}) bool {
+	for op, bound := range bounds {
+		c, ok := compare(value, bound)
+		if !ok {
+			return false
+		}
+		switch op {
+		case gtKey:
+			ok = c > 0
+		case gteKey:
+			ok = c >= 0
+		case ltKey:
+			ok = c < 0
+		case lteKey:
+			ok = c <= 0
+		default:
+			ok = false
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// compare the numbers by value, the others as strings.
+func compare(a, b interface{}) (int, bool) {
+	x, xok := a.(float64)
+	y, yok := b.(float64)
+	if xok && yok {
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	s, sok := a.(string)
+	t, tok := b.(string)
+	if sok && tok {
+		return strings.Compare(s, t), true
+	}
+	return 0, false
+}
+
+// normalize the query to plain maps and slices, as it is read from json.
+func normalize(query map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	normalized := make(map[string]interface{})
+	if err = json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
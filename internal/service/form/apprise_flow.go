@@ -7,12 +7,17 @@ import (
 
 	"github.com/quanxiang-cloud/cabin/logger"
 	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/feed"
 	"github.com/quanxiang-cloud/form/internal/service/form/inform"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/internal/service/webhook"
 )
 
 type appriseFlow struct {
-	next   consensus.Guidance
-	inform *inform.HookManger
+	next     consensus.Guidance
+	inform   *inform.HookManger
+	webhooks webhook.Dispatcher
+	feeds    feed.Publisher
 }
 
 func NewAppriseFlow(conf *config.Config) (consensus.Guidance, error) {
@@ -26,14 +31,29 @@ func NewAppriseFlow(conf *config.Config) (consensus.Guidance, error) {
 		return nil, err
 	}
 	go manger.Start(ctx)
+	webhooks, err := webhook.NewDispatcher(conf, form)
+	if err != nil {
+		return nil, err
+	}
+	feeds, err := feed.NewPublisher(conf, form)
+	if err != nil {
+		return nil, err
+	}
 	return &appriseFlow{
-		next:   form,
-		inform: manger,
+		next:     form,
+		inform:   manger,
+		webhooks: webhooks,
+		feeds:    feeds,
 	}, nil
 }
 
 // Do 可以用策略模式改，可以先用switch.
 func (a *appriseFlow) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	// the deleted records are gone after, keep them for the change feed.
+	var deleted []map[string]interface{}
+	if bus.Method == "delete" {
+		deleted = a.feeds.Tombstones(ctx, bus.AppID, bus.TableID, deleteQuery(bus))
+	}
 	//	先去创建数据
 	do, err := a.next.Do(ctx, bus)
 	if err != nil {
@@ -49,7 +69,7 @@ func (a *appriseFlow) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Re
 	case "update":
 		a.updateApprise(ctx, bus)
 	case "delete":
-		a.deleteApprise(ctx, bus)
+		a.deleteApprise(ctx, bus, deleted)
 	}
 	return do, nil
 }
@@ -60,27 +80,30 @@ func (a *appriseFlow) createApprise(ctx context.Context, bus *consensus.Bus) {
 	data.Entity = bus.CreatedOrUpdate.Entity
 	inform.DefaultFormFiled(ctx, data, "post")
 	logger.Logger.Infow("create", "data is ", data)
-	a.inform.Send <- data
+	a.send(ctx, bus, data)
 }
 
-func (a *appriseFlow) deleteApprise(ctx context.Context, bus *consensus.Bus) {
-	ids := make([]string, 0)
-	if len(bus.Get.OldQuery) == 0 {
-		ids = consensus.GetIDByQuery(bus.Get.Query)
-	} else {
-		ids = consensus.GetIDByQuery(bus.Get.OldQuery)
+func (a *appriseFlow) deleteApprise(ctx context.Context, bus *consensus.Bus, deleted []map[string]interface{}) {
+	ids := deleteIDs(bus)
+	if len(ids) == 0 {
+		// deleted by a query without ids, they are the ones read before the delete.
+		for _, record := range deleted {
+			if id, ok := record[consensus.IDKey].(string); ok {
+				ids = append(ids, id)
+			}
+		}
 	}
-
 	data := &inform.FormData{
 		TableID: bus.TableID,
 		Entity: map[string]interface{}{
 			"data":      ids,
 			"delete_id": bus.UserID,
 		},
+		Deleted: deleted,
 	}
 	inform.DefaultFormFiled(ctx, data, "delete")
 	logger.Logger.Infow("delete", "data is ", data)
-	a.inform.Send <- data
+	a.send(ctx, bus, data)
 }
 
 func (a *appriseFlow) updateApprise(ctx context.Context, bus *consensus.Bus) {
@@ -92,7 +115,8 @@ func (a *appriseFlow) updateApprise(ctx context.Context, bus *consensus.Bus) {
 	}
 
 	for _, id := range ids {
-		entity := consensus.DefaultField(bus.CreatedOrUpdate.Entity,
+		// each event has its own entity, they are published after the loop.
+		entity := consensus.DefaultField(copyEntity(bus.CreatedOrUpdate.Entity),
 			consensus.WithUpdateID(id),
 		)
 		data := &inform.FormData{
@@ -101,6 +125,37 @@ func (a *appriseFlow) updateApprise(ctx context.Context, bus *consensus.Bus) {
 		}
 		inform.DefaultFormFiled(ctx, data, "put")
 		logger.Logger.Infow("update", "data is ", data)
-		a.inform.Send <- data
+		a.send(ctx, bus, data)
+	}
+}
+
+// send to the flow, and to the webhooks and the change feed of the table.
+func (a *appriseFlow) send(ctx context.Context, bus *consensus.Bus, data *inform.FormData) {
+	data.AppID, data.UserID = bus.AppID, bus.UserID
+	a.feeds.Publish(ctx, bus.AppID, data)
+	a.webhooks.Dispatch(ctx, bus.AppID, data)
+	a.inform.Send <- data
+}
+
+func deleteIDs(bus *consensus.Bus) []string {
+	return consensus.GetIDByQuery(deleteQuery(bus))
+}
+
+func deleteQuery(bus *consensus.Bus) types.Query {
+	if len(bus.Get.OldQuery) == 0 {
+		return bus.Get.Query
+	}
+	return bus.Get.OldQuery
+}
+
+func copyEntity(entity consensus.Entity) consensus.Entity {
+	src, ok := entity.(map[string]interface{})
+	if !ok {
+		return entity
+	}
+	dst := make(map[string]interface{}, len(src)+1)
+	for key, value := range src {
+		dst[key] = value
 	}
+	return dst
 }
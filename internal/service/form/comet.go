@@ -42,7 +42,14 @@ func (c *comet) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response
 		req.Base = base
 		req.Query = bus.Query
 		req.Aggs = bus.Aggs
-		return c.callGet(ctx, req)
+		resp, err := c.callGet(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if entity, ok := resp.Entity.(map[string]interface{}); ok {
+			resp.Entity = consensus.Project(entity, consensus.SplitFields(bus.Fields))
+		}
+		return resp, nil
 
 	case "find", "search":
 		req := &SearchReq{
@@ -53,7 +60,17 @@ func (c *comet) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response
 			Base:  base,
 			Aggs:  bus.Aggs,
 		}
-		return c.callSearch(ctx, req)
+		resp, err := c.cursorSearch(ctx, req, bus.List.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		// the cursor is taken before, the sort keys may be left out.
+		if fields := consensus.SplitFields(bus.Fields); len(fields) != 0 {
+			for index, entity := range resp.Entities {
+				resp.Entities[index] = consensus.Project(entity, fields)
+			}
+		}
+		return resp, nil
 	case "create":
 		req := &CreateReq{
 			Entity: bus.CreatedOrUpdate.Entity,
@@ -77,6 +94,31 @@ func (c *comet) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response
 	return nil, nil
 }
 
+// cursorSearch the sorted searches return the cursor of the next page, the cursor
+// takes the place of the page, which keeps stable while the records change.
+func (c *comet) cursorSearch(ctx context.Context, req *SearchReq, cursor string) (*consensus.Response, error) {
+	if len(req.Sort) == 0 && cursor == "" {
+		return c.callSearch(ctx, req)
+	}
+	req.Sort = consensus.CursorSort(req.Sort)
+	if cursor != "" {
+		values, err := consensus.DecodeCursor(cursor, req.Sort)
+		if err != nil {
+			return nil, err
+		}
+		req.Query = consensus.AfterQuery(req.Query, req.Sort, values)
+		req.Page = 1
+	}
+	resp, err := c.callSearch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Size > 0 && len(resp.Entities) == int(req.Size) {
+		resp.NextCursor = consensus.EncodeCursor(req.Sort, resp.Entities[len(resp.Entities)-1])
+	}
+	return resp, nil
+}
+
 func (c *comet) callSearch(ctx context.Context, req *SearchReq) (*consensus.Response, error) {
 	dsl := make(map[string]interface{})
 	if req.Query != nil {
@@ -0,0 +1,272 @@
+package form
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/models/mysql"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/client"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	"gorm.io/gorm"
+)
+
+const (
+	labelKey = "label"
+	valueKey = "value"
+	// getPath the path of the get of a table, which the permits of it are kept by.
+	getPath = "/api/v1/form/%s/home/form/%s/get"
+)
+
+// defaultValue fill the fields which are absent on create with the default declared in the table schema.
+type defaultValue struct {
+	next            consensus.Guidance
+	db              *gorm.DB
+	tableSchemaRepo models.TableSchemeRepo
+	orgAPI          client.OrgAPI
+	appCenterAPI    client.AppCenterAPI
+	permit          service.Permit
+}
+
+func newDefaultValue(conf *config.Config, next consensus.Guidance) (consensus.Guidance, error) {
+	db, err := service.CreateMysqlConn(conf)
+	if err != nil {
+		return nil, err
+	}
+	permit, err := service.NewPermit(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &defaultValue{
+		next:            next,
+		db:              db,
+		tableSchemaRepo: mysql.NewTableSchema(),
+		orgAPI:          client.NewOrgAPI(conf),
+		appCenterAPI:    client.NewAppCenterAPI(conf),
+		permit:          permit,
+	}, nil
+}
+
+func (d *defaultValue) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	if bus.Method != create {
+		return d.next.Do(ctx, bus)
+	}
+	entity, ok := bus.CreatedOrUpdate.Entity.(map[string]interface{})
+	if !ok {
+		return d.next.Do(ctx, bus)
+	}
+	schema, err := d.tableSchemaRepo.Get(d.db, bus.AppID, bus.TableID)
+	if err != nil {
+		return nil, err
+	}
+	for fieldKey, props := range schema.Schema {
+		if props.Default == nil {
+			continue
+		}
+		if value, ok := entity[fieldKey]; ok && value != nil {
+			continue
+		}
+		value, err := d.getValue(ctx, bus, entity, props)
+		if err != nil {
+			logger.Logger.WithName("default value").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			continue
+		}
+		if value != nil {
+			entity[fieldKey] = value
+		}
+	}
+	return d.next.Do(ctx, bus)
+}
+
+func (d *defaultValue) getValue(ctx context.Context, bus *consensus.Bus, entity map[string]interface{}, props models.SchemaProps) (interface{}, error) {
+	switch props.Default.Type {
+	case models.StaticDefault:
+		return props.Default.Value, nil
+	case models.CurrentUserDefault:
+		if bus.UserID == "" {
+			return nil, nil
+		}
+		return withLabel(props.Type, bus.UserName, bus.UserID), nil
+	case models.CurrentDepDefault:
+		if bus.DepID == "" {
+			return nil, nil
+		}
+		return withLabel(props.Type, d.depName(ctx, bus.DepID), bus.DepID), nil
+	case models.CurrentDateDefault:
+		if props.Type == "number" {
+			return time2.NowUnix(), nil
+		}
+		return time2.Now(), nil
+	case models.RelationDefault:
+		return d.relationValue(ctx, bus, entity, props.Default)
+	}
+	return nil, nil
+}
+
+// depName the name of the department from the org service, the id if it is not found.
+func (d *defaultValue) depName(ctx context.Context, depID string) string {
+	deps, err := d.orgAPI.GetDepByIDs(ctx, depID)
+	if err != nil {
+		logger.Logger.WithName("default value").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return depID
+	}
+	for _, dep := range deps {
+		if dep.ID == depID && dep.Name != "" {
+			return dep.Name
+		}
+	}
+	return depID
+}
+
+// relationValue copy the field of the record which the ref field points to,
+// the get is below the permit gateway, so the caller must be able to read the field of the table.
+func (d *defaultValue) relationValue(ctx context.Context, bus *consensus.Bus, entity map[string]interface{}, dv *models.DefaultValue) (interface{}, error) {
+	id := getRelatedID(entity[dv.RefField])
+	if id == "" || dv.TableID == "" || dv.FieldName == "" {
+		return nil, nil
+	}
+	appID := dv.AppID
+	if appID == "" {
+		appID = bus.AppID
+	}
+	readable, err := d.readable(ctx, bus, appID, dv.TableID, dv.FieldName)
+	if err != nil {
+		return nil, err
+	}
+	if !readable {
+		return nil, error2.New(code.ErrNotPermit)
+	}
+	getBus := new(consensus.Bus)
+	getBus.Universal = bus.Universal
+	getBus.Foundation = consensus.Foundation{
+		AppID:   appID,
+		TableID: dv.TableID,
+		Method:  "get",
+	}
+	getBus.Get.Query = types.Query{
+		consensus.TermKey: types.M{
+			consensus.IDKey: id,
+		},
+	}
+	resp, err := d.next.Do(ctx, getBus)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, nil
+	}
+	record, ok := resp.Entity.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	return record[dv.FieldName], nil
+}
+
+// readable the caller can get the records of the table and see the field of them,
+// the permit is found the same way as the permit gateway does for the get of the table.
+func (d *defaultValue) readable(ctx context.Context, bus *consensus.Bus, appID, tableID, field string) (bool, error) {
+	if bus.UserID == "" {
+		return false, nil
+	}
+	path := fmt.Sprintf(getPath, appID, tableID)
+	app, err := d.appCenterAPI.GetOne(ctx, appID)
+	if err != nil {
+		return false, err
+	}
+	var (
+		response    models.FiledPermit
+		responseAll bool
+	)
+	if app.PerPoly {
+		poly, err := d.permit.PerPoly(ctx, &service.PerPolyReq{
+			UserID: bus.UserID,
+			DepID:  bus.DepID,
+			AppID:  appID,
+			Path:   path,
+		})
+		if err != nil {
+			return false, err
+		}
+		if poly == nil {
+			return false, nil
+		}
+		if poly.Types == models.InitType {
+			return true, nil
+		}
+		if poly.ID == "" {
+			return false, nil
+		}
+		response, responseAll = poly.Response, poly.ResponseAll
+	} else {
+		role, err := d.permit.GetUserRole(ctx, &service.GetUserRoleReq{
+			UserID: bus.UserID,
+			DepID:  bus.DepID,
+			AppID:  appID,
+		})
+		if err != nil {
+			return false, err
+		}
+		if role.RoleID == "" {
+			return false, nil
+		}
+		if role.Types == models.InitType {
+			return true, nil
+		}
+		permit, err := d.permit.GetPermit(ctx, &service.GetPermitReq{
+			RoleID: role.RoleID,
+			Path:   path,
+			Method: http.MethodPost,
+		})
+		if err != nil {
+			return false, err
+		}
+		if permit.ID == "" {
+			return false, nil
+		}
+		response, responseAll = permit.Response, permit.ResponseAll
+	}
+	return responseAll || response.RecordPermit().Readable([]string{field}), nil
+}
+
+// withLabel the label-value components save an object, or an array of them when multiple.
+func withLabel(fieldType, label, value string) interface{} {
+	lv := map[string]interface{}{
+		labelKey: label,
+		valueKey: value,
+	}
+	switch fieldType {
+	case "array":
+		return []interface{}{lv}
+	case "object":
+		return lv
+	}
+	return value
+}
+
+func getRelatedID(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if id, ok := v[consensus.IDKey].(string); ok {
+			return id
+		}
+		if id, ok := v[valueKey].(string); ok {
+			return id
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			return getRelatedID(v[0])
+		}
+	}
+	return ""
+}
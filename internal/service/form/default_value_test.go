@@ -0,0 +1,179 @@
+package form
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/client"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+)
+
+type fakeOrg struct {
+	client.OrgAPI
+}
+
+func (f *fakeOrg) GetDepByIDs(ctx context.Context, ids ...string) ([]*client.Dep, error) {
+	if ids[0] == "broken" {
+		return nil, errors.New("broken")
+	}
+	return []*client.Dep{{ID: "d1", Name: "sales"}}, nil
+}
+
+func TestCurrentDepDefault(t *testing.T) {
+	d := &defaultValue{orgAPI: &fakeOrg{}}
+	props := models.SchemaProps{Type: "object", Default: &models.DefaultValue{Type: models.CurrentDepDefault}}
+	for depID, label := range map[string]string{"d1": "sales", "broken": "broken"} {
+		bus := &consensus.Bus{}
+		bus.DepID = depID
+		value, err := d.getValue(context.Background(), bus, nil, props)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lv := value.(map[string]interface{})
+		if lv[labelKey] != label || lv[valueKey] != depID {
+			t.Fatalf("%s: %v", depID, lv)
+		}
+	}
+}
+
+func TestStaticAndUserDefault(t *testing.T) {
+	d := &defaultValue{}
+	bus := &consensus.Bus{}
+	bus.UserID, bus.UserName = "u1", "alice"
+
+	value, err := d.getValue(context.Background(), bus, nil, models.SchemaProps{
+		Type: "string", Default: &models.DefaultValue{Type: models.StaticDefault, Value: "open"},
+	})
+	if err != nil || value != "open" {
+		t.Fatalf("static: %v %v", value, err)
+	}
+	value, _ = d.getValue(context.Background(), bus, nil, models.SchemaProps{
+		Type: "array", Default: &models.DefaultValue{Type: models.CurrentUserDefault},
+	})
+	users, ok := value.([]interface{})
+	if !ok || len(users) != 1 {
+		t.Fatalf("user: %v", value)
+	}
+	if lv := users[0].(map[string]interface{}); lv[labelKey] != "alice" || lv[valueKey] != "u1" {
+		t.Fatalf("user: %v", lv)
+	}
+	value, _ = d.getValue(context.Background(), bus, nil, models.SchemaProps{
+		Type: "string", Default: &models.DefaultValue{Type: models.CurrentUserDefault},
+	})
+	if value != "u1" {
+		t.Fatalf("user of string: %v", value)
+	}
+	value, _ = d.getValue(context.Background(), &consensus.Bus{}, nil, models.SchemaProps{
+		Type: "string", Default: &models.DefaultValue{Type: models.CurrentUserDefault},
+	})
+	if value != nil {
+		t.Fatalf("user without the operator: %v", value)
+	}
+}
+
+func TestDateDefault(t *testing.T) {
+	d := &defaultValue{}
+	value, _ := d.getValue(context.Background(), &consensus.Bus{}, nil, models.SchemaProps{
+		Type: "number", Default: &models.DefaultValue{Type: models.CurrentDateDefault},
+	})
+	if ms, ok := value.(int64); !ok || ms <= 0 {
+		t.Fatalf("number date: %v", value)
+	}
+	value, _ = d.getValue(context.Background(), &consensus.Bus{}, nil, models.SchemaProps{
+		Type: "datetime", Default: &models.DefaultValue{Type: models.CurrentDateDefault},
+	})
+	if s, ok := value.(string); !ok || s == "" {
+		t.Fatalf("string date: %v", value)
+	}
+}
+
+type fakeAppCenter struct {
+	client.AppCenterAPI
+	perPoly bool
+}
+
+func (f *fakeAppCenter) GetOne(ctx context.Context, appID string) (*client.AppResp, error) {
+	return &client.AppResp{Id: appID, PerPoly: f.perPoly}, nil
+}
+
+// fakePermit the role of u1 may read the name of the customers, u2 has no role.
+type fakePermit struct {
+	service.Permit
+	path string
+}
+
+func (f *fakePermit) GetUserRole(ctx context.Context, req *service.GetUserRoleReq) (*service.GetUserRoleResp, error) {
+	if req.UserID != "u1" {
+		return &service.GetUserRoleResp{}, nil
+	}
+	return &service.GetUserRoleResp{RoleID: "r1"}, nil
+}
+
+func (f *fakePermit) GetPermit(ctx context.Context, req *service.GetPermitReq) (*service.GetPermitResp, error) {
+	f.path = req.Path
+	return &service.GetPermitResp{
+		ID: "p1",
+		Response: models.FiledPermit{
+			"data": {Type: "object", Properties: models.FiledPermit{
+				"entity": {Type: "object", Properties: models.FiledPermit{
+					"name": {Type: "string"},
+				}},
+			}},
+		},
+	}, nil
+}
+
+// fakeGet the records of the get by id.
+type fakeGet struct {
+	records map[string]map[string]interface{}
+	gets    int
+}
+
+func (f *fakeGet) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	f.gets++
+	id, _ := bus.Get.Query[consensus.TermKey].(types.M)[consensus.IDKey].(string)
+	return &consensus.Response{Entity: f.records[id]}, nil
+}
+
+func TestRelationDefault(t *testing.T) {
+	next := &fakeGet{records: map[string]map[string]interface{}{
+		"c1": {consensus.IDKey: "c1", "name": "acme", "phone": "123"},
+	}}
+	permit := &fakePermit{}
+	d := &defaultValue{next: next, appCenterAPI: &fakeAppCenter{}, permit: permit}
+	entity := map[string]interface{}{
+		"customer": []interface{}{map[string]interface{}{labelKey: "acme", valueKey: "c1"}},
+	}
+	props := func(field string) models.SchemaProps {
+		return models.SchemaProps{Type: "string", Default: &models.DefaultValue{
+			Type: models.RelationDefault, TableID: "customers", RefField: "customer", FieldName: field,
+		}}
+	}
+	bus := &consensus.Bus{}
+	bus.AppID, bus.UserID = "app", "u1"
+
+	value, err := d.getValue(context.Background(), bus, entity, props("name"))
+	if err != nil || value != "acme" {
+		t.Fatalf("name: %v %v", value, err)
+	}
+	if permit.path != "/api/v1/form/app/home/form/customers/get" {
+		t.Fatalf("permit of %s", permit.path)
+	}
+	// the phone is out of the permit of the customers.
+	if _, err = d.getValue(context.Background(), bus, entity, props("phone")); errCode(err) != code.ErrNotPermit {
+		t.Fatalf("phone: %v", err)
+	}
+	// u2 can not read the customers at all.
+	bus.UserID = "u2"
+	if _, err = d.getValue(context.Background(), bus, entity, props("name")); errCode(err) != code.ErrNotPermit {
+		t.Fatalf("u2: %v", err)
+	}
+	if next.gets != 1 {
+		t.Fatalf("the records are got %d times", next.gets)
+	}
+}
@@ -0,0 +1,222 @@
+package form
+
+import (
+	"context"
+	"strings"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+)
+
+const (
+	// maxExpandDepth the levels of the expanded relations, like items.supplier.contact.
+	maxExpandDepth = 3
+	expandPageSize = 1000
+	aggregationTag = "aggregation"
+
+	propertiesKey      = "properties"
+	xComponentPropsKey = "x-component-props"
+)
+
+// expand fill the relation fields of the whole page with the related records,
+// by one search of the relation table and one of the related table per field.
+func (c *refs) expand(ctx context.Context, bus *consensus.Bus, entities []map[string]interface{}) error {
+	paths := consensus.SplitFields(bus.Expand)
+	if len(paths) == 0 || len(entities) == 0 {
+		return nil
+	}
+	for _, path := range paths {
+		if len(strings.Split(path, ".")) > maxExpandDepth {
+			return error2.New(code.ErrInvalidExpand, path)
+		}
+	}
+	return c.expandFields(ctx, bus.Universal, bus.AppID, bus.TableID, entities, paths)
+}
+
+func (c *refs) expandFields(ctx context.Context, universal consensus.Universal, appID, tableID string,
+	entities []map[string]interface{}, paths []string) error {
+	fields, subPaths := groupPaths(paths)
+	ids := make([]interface{}, 0, len(entities))
+	for _, entity := range entities {
+		if id, ok := entity[consensus.IDKey]; ok {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	for _, field := range fields {
+		relation, err := c.getRelation(appID, tableID, field)
+		if err != nil {
+			return err
+		}
+		// the relation table is kept in the app of the related table, like the subGet of the refs.
+		subAppID, err := c.relatedApp(appID, tableID, field)
+		if err != nil {
+			return err
+		}
+		links, err := c.searchAll(ctx, universal, subAppID, getRelationName(tableID, relation.SubTableID),
+			consensus.GetBool(consensus.Must,
+				consensus.GetSimple(consensus.TermsKey, primitiveID, ids),
+				consensus.GetSimple(consensus.TermKey, fieldName, field),
+			))
+		if err != nil {
+			return err
+		}
+		linked := make(map[interface{}][]interface{}, len(ids))
+		uniqueIDs := make([]interface{}, 0, len(links))
+		seen := make(map[interface{}]bool, len(links))
+		for _, link := range links {
+			pid, sid := link[primitiveID], link[subIDs]
+			if pid == nil || sid == nil {
+				continue
+			}
+			linked[pid] = append(linked[pid], sid)
+			if !seen[sid] {
+				seen[sid] = true
+				uniqueIDs = append(uniqueIDs, sid)
+			}
+		}
+
+		records := make([]map[string]interface{}, 0)
+		if len(uniqueIDs) != 0 {
+			records, err = c.searchAll(ctx, universal, subAppID, relation.SubTableID,
+				consensus.GetSimple(consensus.TermsKey, consensus.IDKey, uniqueIDs))
+			if err != nil {
+				return err
+			}
+		}
+		filter := relation.Filter
+		if len(subPaths[field]) != 0 {
+			if err = c.expandFields(ctx, universal, subAppID, relation.SubTableID, records, subPaths[field]); err != nil {
+				return err
+			}
+			if filter != nil {
+				// the expanded fields are kept out of the columns of the relation.
+				nested, _ := groupPaths(subPaths[field])
+				filter = append(append([]string{}, filter...), nested...)
+			}
+		}
+		byID := make(map[interface{}]map[string]interface{}, len(records))
+		for _, record := range records {
+			byID[record[consensus.IDKey]] = record
+			propertiesFilter(record, filter)
+		}
+		for _, entity := range entities {
+			related := make([]interface{}, 0)
+			for _, sid := range linked[entity[consensus.IDKey]] {
+				if record, ok := byID[sid]; ok {
+					related = append(related, record)
+				}
+			}
+			entity[field] = related
+		}
+	}
+	return nil
+}
+
+// groupPaths the first level fields in order, and the paths under each of them.
+func groupPaths(paths []string) ([]string, map[string][]string) {
+	fields := make([]string, 0, len(paths))
+	subPaths := make(map[string][]string, len(paths))
+	for _, path := range paths {
+		field, sub := path, ""
+		if index := strings.Index(path, "."); index > 0 {
+			field, sub = path[:index], path[index+1:]
+		}
+		if _, ok := subPaths[field]; !ok {
+			fields = append(fields, field)
+			subPaths[field] = nil
+		}
+		if sub != "" {
+			subPaths[field] = append(subPaths[field], sub)
+		}
+	}
+	return fields, subPaths
+}
+
+// getRelation the relations kept in the relation tables, the aggregations are computed values.
+func (c *refs) getRelation(appID, tableID, field string) (*models.TableRelation, error) {
+	relations, _, err := c.relationRepo.List(c.db, &models.TableRelationQuery{
+		AppID:     appID,
+		TableID:   tableID,
+		FieldName: field,
+	}, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(relations) == 0 || relations[0].SubTableType == aggregationTag {
+		return nil, error2.New(code.ErrInvalidExpand, field)
+	}
+	return relations[0], nil
+}
+
+// relatedApp the app of the table the field refers to, in the component props of the schema,
+// the associated records may refer to a table of another app.
+func (c *refs) relatedApp(appID, tableID, field string) (string, error) {
+	table, err := c.tableRepo.Get(c.db, appID, tableID)
+	if err != nil {
+		return "", err
+	}
+	properties, _ := table.Schema[propertiesKey].(map[string]interface{})
+	if props := componentProps(properties, field); props != nil {
+		if subAppID, ok := props[appIDKey].(string); ok && subAppID != "" {
+			return subAppID, nil
+		}
+	}
+	return appID, nil
+}
+
+// componentProps the x-component-props of the field, the fields within layout components included.
+func componentProps(properties map[string]interface{}, field string) map[string]interface{} {
+	for key, value := range properties {
+		schema, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if key == field {
+			props, _ := schema[xComponentPropsKey].(map[string]interface{})
+			return props
+		}
+		if nested, ok := schema[propertiesKey].(map[string]interface{}); ok {
+			if props := componentProps(nested, field); props != nil {
+				return props
+			}
+		}
+	}
+	return nil
+}
+
+// searchAll page through the records of the query by cursor.
+func (c *refs) searchAll(ctx context.Context, universal consensus.Universal, appID, tableID string,
+	query map[string]interface{}) ([]map[string]interface{}, error) {
+	records := make([]map[string]interface{}, 0)
+	cursor := ""
+	for {
+		bus := new(consensus.Bus)
+		bus.Universal = universal
+		bus.Foundation = consensus.Foundation{
+			AppID:   appID,
+			TableID: tableID,
+			Method:  "search",
+		}
+		bus.Get.Query = query
+		bus.List = consensus.List{
+			Page:   1,
+			Size:   expandPageSize,
+			Sort:   []string{"created_at"},
+			Cursor: cursor,
+		}
+		resp, err := c.next.Do(ctx, bus)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, resp.Entities...)
+		if resp.NextCursor == "" {
+			return records, nil
+		}
+		cursor = resp.NextCursor
+	}
+}
@@ -0,0 +1,159 @@
+package form
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"gorm.io/gorm"
+)
+
+type fakeRelationRepo struct {
+	models.TableRelationRepo
+	relations []*models.TableRelation
+}
+
+func (f *fakeRelationRepo) List(db *gorm.DB, query *models.TableRelationQuery, page, size int) ([]*models.TableRelation, int64, error) {
+	for _, relation := range f.relations {
+		if relation.TableID == query.TableID && relation.FieldName == query.FieldName {
+			return []*models.TableRelation{relation}, 1, nil
+		}
+	}
+	return nil, 0, nil
+}
+
+// fakeTables answer the terms and term queries of the expansion, in one page.
+type fakeTables struct {
+	tables   map[string][]map[string]interface{}
+	searches int
+	// apps the app each table is searched in.
+	apps map[string]string
+}
+
+func (f *fakeTables) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	f.searches++
+	if f.apps != nil {
+		f.apps[bus.TableID] = bus.AppID
+	}
+	resp := &consensus.Response{}
+	for _, record := range f.tables[bus.TableID] {
+		if match(bus.Get.Query, record) {
+			copied := make(map[string]interface{}, len(record))
+			for key, value := range record {
+				copied[key] = value
+			}
+			resp.Entities = append(resp.Entities, copied)
+		}
+	}
+	return resp, nil
+}
+
+func match(query map[string]interface{}, record map[string]interface{}) bool {
+	for op, value := range query {
+		switch op {
+		case "bool":
+			for _, sub := range value.(consensus.KeyValue)[consensus.Must].([]interface{}) {
+				if !match(sub.(map[string]interface{}), record) {
+					return false
+				}
+			}
+		case consensus.TermKey:
+			for key, v := range value.(consensus.KeyValue) {
+				if record[key] != v {
+					return false
+				}
+			}
+		case consensus.TermsKey:
+			for key, v := range value.(consensus.KeyValue) {
+				found := false
+				for _, elem := range v.([]interface{}) {
+					found = found || record[key] == elem
+				}
+				if !found {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func TestExpand(t *testing.T) {
+	tables := &fakeTables{tables: map[string][]map[string]interface{}{
+		"order_item": {
+			{primitiveID: "o1", subIDs: "i1", fieldName: "items"},
+			{primitiveID: "o1", subIDs: "i2", fieldName: "items"},
+			{primitiveID: "o2", subIDs: "i3", fieldName: "items"},
+		},
+		"item": {
+			{"_id": "i1", "sku": "a", "price": float64(1)},
+			{"_id": "i2", "sku": "b", "price": float64(2)},
+			{"_id": "i3", "sku": "c", "price": float64(3)},
+		},
+		"item_vendor": {
+			{primitiveID: "i3", subIDs: "v1", fieldName: "vendor"},
+		},
+		"vendor": {
+			{"_id": "v1", "name": "x"},
+		},
+	}}
+	tables.apps = make(map[string]string)
+	c := &refs{
+		next: tables,
+		// the vendors are kept in another app.
+		tableRepo: &fakeTableRepo{schemas: map[string]models.WebSchema{
+			"item": {"properties": map[string]interface{}{
+				"layout": map[string]interface{}{"properties": map[string]interface{}{
+					"vendor": map[string]interface{}{
+						"x-component-props": map[string]interface{}{"appID": "app2", "tableID": "vendor"},
+					},
+				}},
+			}},
+		}},
+		relationRepo: &fakeRelationRepo{relations: []*models.TableRelation{
+			{TableID: "order", FieldName: "items", SubTableID: "item", Filter: models.Filters{"sku"}},
+			{TableID: "item", FieldName: "vendor", SubTableID: "vendor"},
+			{TableID: "order", FieldName: "total", SubTableID: "item", SubTableType: aggregationTag},
+		}},
+	}
+	entities := []map[string]interface{}{{"_id": "o1"}, {"_id": "o2"}, {"_id": "o3"}}
+	bus := &consensus.Bus{}
+	bus.AppID, bus.TableID = "app", "order"
+	bus.Expand = []string{"items,items.vendor"}
+	if err := c.expand(context.Background(), bus, entities); err != nil {
+		t.Fatal(err)
+	}
+	expect := []map[string]interface{}{
+		{"_id": "o1", "items": []interface{}{
+			map[string]interface{}{"sku": "a", "vendor": []interface{}{}},
+			map[string]interface{}{"sku": "b", "vendor": []interface{}{}},
+		}},
+		{"_id": "o2", "items": []interface{}{
+			map[string]interface{}{"sku": "c", "vendor": []interface{}{
+				map[string]interface{}{"_id": "v1", "name": "x"},
+			}},
+		}},
+		{"_id": "o3", "items": []interface{}{}},
+	}
+	if !reflect.DeepEqual(entities, expect) {
+		t.Fatalf("expect %v, got %v", expect, entities)
+	}
+	// one search of the relation table and one of the related table per level.
+	if tables.searches != 4 {
+		t.Fatalf("searched %d times", tables.searches)
+	}
+	if tables.apps["item"] != "app" || tables.apps["item_vendor"] != "app2" || tables.apps["vendor"] != "app2" {
+		t.Fatalf("the tables are searched in the wrong apps %v", tables.apps)
+	}
+
+	bus.Expand = []string{"total"}
+	if err := c.expand(context.Background(), bus, entities); err == nil {
+		t.Fatal("aggregation should not be expanded")
+	}
+	bus.Expand = []string{"a.b.c.d"}
+	if err := c.expand(context.Background(), bus, entities); err == nil {
+		t.Fatal("too deep to expand")
+	}
+}
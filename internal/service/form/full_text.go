@@ -0,0 +1,223 @@
+package form
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/models/mysql"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/tables/util"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	"gorm.io/gorm"
+)
+
+const (
+	stringType = "string"
+	objectType = "object"
+	// fragmentSize the bytes of the text around the first match in a highlight.
+	fragmentSize  = 120
+	highlightPre  = "<em>"
+	highlightPost = "</em>"
+	ellipsis      = "..."
+)
+
+// likeEscaper the wildcards of like, % for any characters and _ for any one.
+var likeEscaper = strings.NewReplacer("%", `\%`, "_", `\_`)
+
+// fullText expand q of search to the matches of the string fields, and highlight the matches.
+type fullText struct {
+	next            consensus.Guidance
+	db              *gorm.DB
+	tableSchemaRepo models.TableSchemeRepo
+}
+
+func newFullText(conf *config.Config, next consensus.Guidance) (consensus.Guidance, error) {
+	db, err := service.CreateMysqlConn(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &fullText{
+		next:            next,
+		db:              db,
+		tableSchemaRepo: mysql.NewTableSchema(),
+	}, nil
+}
+
+func (f *fullText) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	q := strings.TrimSpace(bus.Q)
+	if bus.Method != "search" || q == "" {
+		return f.next.Do(ctx, bus)
+	}
+	schema, err := f.tableSchemaRepo.Get(f.db, bus.AppID, bus.TableID)
+	if err != nil {
+		return nil, err
+	}
+	fields := textFields(schema.Schema, consensus.SplitFields(bus.SearchFields))
+	if len(fields) == 0 {
+		// no field to match, nothing is found.
+		return &consensus.Response{
+			Entities: types.Entities{},
+		}, nil
+	}
+
+	// match is a regex of mongo and a like of mysql, both escape by backslashes,
+	// the wildcards of like are escaped as well, a regex takes them as they are.
+	pattern := likeEscaper.Replace(regexp.QuoteMeta(q))
+	matches := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		matches = append(matches, consensus.GetSimple(consensus.MatchKey, field, pattern))
+	}
+	matched := consensus.GetBool(consensus.Should, matches...)
+	if len(bus.Get.Query) == 0 {
+		bus.Get.Query = matched
+	} else {
+		bus.Get.Query = consensus.GetBool(consensus.Must, map[string]interface{}(bus.Get.Query), matched)
+	}
+
+	resp, err := f.next.Do(ctx, bus)
+	if err != nil {
+		return nil, err
+	}
+	resp.Highlights = highlights(resp.Entities, fields, q)
+	return resp, nil
+}
+
+// textFields the string fields of the schema except the system ones, nested ones are joined by dots.
+// the fields are limited to the selected ones and the fields under them if any is selected.
+func textFields(properties models.SchemaProperties, selected []string) []string {
+	fields := make([]string, 0)
+	walkTextFields(properties, "", &fields)
+	if len(selected) == 0 {
+		return fields
+	}
+	picked := make([]string, 0, len(fields))
+	for _, field := range fields {
+		for _, s := range selected {
+			if field == s || strings.HasPrefix(field, s+".") {
+				picked = append(picked, field)
+				break
+			}
+		}
+	}
+	return picked
+}
+
+func walkTextFields(properties models.SchemaProperties, parent string, fields *[]string) {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		props := properties[key]
+		if parent == "" && util.IsSystemField(key) {
+			continue
+		}
+		path := key
+		if parent != "" {
+			path = parent + "." + key
+		}
+		switch props.Type {
+		case stringType:
+			*fields = append(*fields, path)
+		case objectType:
+			walkTextFields(props.Properties, path, fields)
+		}
+	}
+}
+
+// highlights the fragments of the matched fields by the _id of the records.
+// a regex of mongo matches the case, while a like of mysql ignores it under the default collation,
+// so the case is ignored only for the records which have no match of the case, as mysql found them.
+func highlights(entities types.Entities, fields []string, q string) map[string]map[string]string {
+	exact := regexp.MustCompile(regexp.QuoteMeta(q))
+	folded := regexp.MustCompile("(?i)" + regexp.QuoteMeta(q))
+	result := make(map[string]map[string]string, len(entities))
+	for _, entity := range entities {
+		id, ok := entity[consensus.IDKey].(string)
+		if !ok {
+			continue
+		}
+		fragments := highlightFields(entity, fields, exact)
+		if len(fragments) == 0 {
+			fragments = highlightFields(entity, fields, folded)
+		}
+		if len(fragments) != 0 {
+			result[id] = fragments
+		}
+	}
+	return result
+}
+
+func highlightFields(entity map[string]interface{}, fields []string, re *regexp.Regexp) map[string]string {
+	fragments := make(map[string]string)
+	for _, field := range fields {
+		text, ok := consensus.GetValue(entity, strings.Split(field, ".")).(string)
+		if !ok {
+			continue
+		}
+		if fragment, ok := highlight(text, re); ok {
+			fragments[field] = fragment
+		}
+	}
+	return fragments
+}
+
+// highlight the text around the first match, the matches are wrapped by em and the rest is escaped.
+func highlight(text string, re *regexp.Regexp) (string, bool) {
+	locs := re.FindAllStringIndex(text, -1)
+	if len(locs) == 0 {
+		return "", false
+	}
+	start, end := 0, len(text)
+	if end > fragmentSize {
+		start = locs[0][0] - fragmentSize/2
+		if start < 0 {
+			start = 0
+		}
+		end = start + fragmentSize
+		if end > len(text) {
+			end, start = len(text), len(text)-fragmentSize
+		}
+		if end < locs[0][1] {
+			end = locs[0][1]
+		}
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(ellipsis)
+	}
+	last := start
+	for _, loc := range locs {
+		if loc[0] < last {
+			continue
+		}
+		if loc[1] > end {
+			break
+		}
+		b.WriteString(html.EscapeString(text[last:loc[0]]))
+		b.WriteString(highlightPre)
+		b.WriteString(html.EscapeString(text[loc[0]:loc[1]]))
+		b.WriteString(highlightPost)
+		last = loc[1]
+	}
+	b.WriteString(html.EscapeString(text[last:end]))
+	if end < len(text) {
+		b.WriteString(ellipsis)
+	}
+	return b.String(), true
+}
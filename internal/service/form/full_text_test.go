@@ -0,0 +1,69 @@
+package form
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+)
+
+func TestTextFields(t *testing.T) {
+	properties := models.SchemaProperties{
+		"_id":          {Type: "string"},
+		"creator_name": {Type: "string"},
+		"name":         {Type: "string"},
+		"count":        {Type: "number"},
+		"addr": {Type: "object", Properties: models.SchemaProperties{
+			"city": {Type: "string"},
+			"zip":  {Type: "number"},
+		}},
+	}
+	if fields := textFields(properties, nil); !reflect.DeepEqual(fields, []string{"addr.city", "name"}) {
+		t.Fatalf("fields %v", fields)
+	}
+	if fields := textFields(properties, []string{"addr", "count"}); !reflect.DeepEqual(fields, []string{"addr.city"}) {
+		t.Fatalf("selected fields %v", fields)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	re := regexp.MustCompile("(?i)" + regexp.QuoteMeta("a.b"))
+	fragment, ok := highlight("<x> A.B and a.b, not axb", re)
+	if !ok || fragment != "&lt;x&gt; <em>A.B</em> and <em>a.b</em>, not axb" {
+		t.Fatalf("fragment %q", fragment)
+	}
+	if _, ok = highlight("nothing", re); ok {
+		t.Fatal("nothing should be matched")
+	}
+
+	long := strings.Repeat("中", 100) + "a.b" + strings.Repeat("文", 100)
+	fragment, _ = highlight(long, re)
+	if !strings.HasPrefix(fragment, ellipsis+"中") || !strings.HasSuffix(fragment, "文"+ellipsis) ||
+		!strings.Contains(fragment, "<em>a.b</em>") || len(fragment) > fragmentSize+30 {
+		t.Fatalf("long fragment %q", fragment)
+	}
+}
+
+func TestHighlights(t *testing.T) {
+	if pattern := likeEscaper.Replace(regexp.QuoteMeta("50%_a.b")); pattern != `50\%\_a\.b` {
+		t.Fatalf("pattern %s", pattern)
+	}
+	if !regexp.MustCompile(likeEscaper.Replace(regexp.QuoteMeta("50%_"))).MatchString("50%_") {
+		t.Fatal("the escaped wildcards should match themselves as a regex")
+	}
+
+	entities := types.Entities{
+		{"_id": "1", "name": "Abc", "remark": "abc"},
+		{"_id": "2", "name": "ABC"},
+	}
+	result := highlights(entities, []string{"name", "remark"}, "abc")
+	if len(result["1"]) != 1 || result["1"]["remark"] != "<em>abc</em>" {
+		t.Fatalf("the case is matched when any field matches it, got %v", result["1"])
+	}
+	if result["2"]["name"] != "<em>ABC</em>" {
+		t.Fatalf("the case is ignored when none matches it, got %v", result["2"])
+	}
+}
@@ -0,0 +1,137 @@
+package form
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/db/redis"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/form/internal/models"
+	redis2 "github.com/quanxiang-cloud/form/internal/models/redis"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+)
+
+const (
+	// ReplayedHeader set on the responses of the retried requests.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	defaultIdempotencyTTL = 24 * time.Hour
+	// pendingTTL the key of an unfinished request is released after, if the service stops in the middle.
+	pendingTTL = time.Minute
+)
+
+// Idempotency run the requests of the same key once, the result is kept for the ttl.
+// a failed request is not kept, so it can be retried.
+type Idempotency interface {
+	Do(ctx context.Context, key string, request interface{}, fn func() (interface{}, error)) (result interface{}, replayed bool, err error)
+}
+
+type idempotency struct {
+	repo models.IdempotencyRepo
+	ttl  time.Duration
+}
+
+// NewIdempotency returns the results of the requests by the idempotency keys.
+func NewIdempotency(conf *config.Config) (Idempotency, error) {
+	redisClient, err := redis.NewClient(conf.Redis)
+	if err != nil {
+		return nil, err
+	}
+	ttl := conf.Idempotency.TTL
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	return &idempotency{
+		repo: redis2.NewIdempotencyRepo(redisClient),
+		ttl:  ttl,
+	}, nil
+}
+
+func (i *idempotency) Do(ctx context.Context, key string, request interface{}, fn func() (interface{}, error)) (interface{}, bool, error) {
+	// the request is taken before fn, which may change it.
+	fingerprint, err := digest(request)
+	if err != nil {
+		return nil, false, err
+	}
+	created, err := i.repo.Create(ctx, key, &models.Idempotency{Fingerprint: fingerprint}, pendingTTL)
+	if err != nil {
+		return nil, false, err
+	}
+	if !created {
+		record, err := i.repo.Get(ctx, key)
+		if err != nil {
+			return nil, false, err
+		}
+		switch {
+		case record == nil || !record.Done:
+			return nil, false, error2.New(code.ErrIdempotencyInProgress)
+		case record.Fingerprint != fingerprint:
+			return nil, false, error2.New(code.ErrIdempotencyMismatch)
+		}
+		return record.Result, true, nil
+	}
+
+	stop := i.keepPending(ctx, key)
+	result, err := fn()
+	stop()
+	if err != nil {
+		if err := i.repo.Delete(ctx, key); err != nil {
+			logger.Logger.WithName("idempotency").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		}
+		return nil, false, err
+	}
+	data, err := json.Marshal(result)
+	if err == nil {
+		err = i.repo.Update(ctx, key, &models.Idempotency{
+			Fingerprint: fingerprint,
+			Done:        true,
+			Result:      data,
+		}, i.ttl)
+	}
+	if err != nil {
+		// the request is done, only the retries are not protected.
+		logger.Logger.WithName("idempotency").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		i.repo.Delete(ctx, key)
+	}
+	return result, false, nil
+}
+
+// keepPending refresh the pending key until stop, so a slow request is not taken as released.
+func (i *idempotency) keepPending(ctx context.Context, key string) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(pendingTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := i.repo.Expire(ctx, key, pendingTTL); err != nil {
+					logger.Logger.WithName("idempotency").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+func digest(request interface{}) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
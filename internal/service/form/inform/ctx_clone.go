@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
 )
 
 type cs string
@@ -69,6 +70,15 @@ func WithSeq(formData *FormData) HeaderOpt {
 	}
 }
 
+// WithRequestID WithRequestID.
+func WithRequestID(formData *FormData) HeaderOpt {
+	return func(ctx context.Context) {
+		if requestID, ok := ctx.Value(header.RequestID).(string); ok {
+			formData.RequestID = requestID
+		}
+	}
+}
+
 // CloneHeader CloneHeader.
 func CloneHeader(ctx context.Context, opts ...HeaderOpt) {
 	for _, opt := range opts {
@@ -87,7 +97,7 @@ func CTXHeader(c context.Context, ctx *gin.Context) context.Context {
 
 // DefaultFormFiled DefaultFormFiled.
 func DefaultFormFiled(ctx context.Context, data *FormData, method string) {
-	CloneHeader(ctx, WithMethod(data, method), WithVersion(data), WithMagic(data), WithSeq(data))
+	CloneHeader(ctx, WithMethod(data, method), WithVersion(data), WithMagic(data), WithSeq(data), WithRequestID(data))
 }
 
 func md5Value(str string) string {
@@ -0,0 +1,92 @@
+package inform
+
+import (
+	"fmt"
+	"time"
+
+	id2 "github.com/quanxiang-cloud/cabin/id"
+)
+
+// the CloudEvents of the changes of the records, see docs/events.md.
+const (
+	SpecVersion = "1.0"
+	// ContentType the events are published in the structured mode.
+	ContentType     = "application/cloudevents+json"
+	dataContentType = "application/json"
+
+	// RecordSchema the version of RecordData, bumped on the breaking changes.
+	RecordSchema = "urn:quanxiang:form:record:v1"
+
+	typePrefix = "com.quanxiang.form.record."
+)
+
+// eventTypes the types of the events.
+var eventTypes = map[string]string{
+	"create": typePrefix + "created",
+	"update": typePrefix + "updated",
+	"delete": typePrefix + "deleted",
+}
+
+// CloudEvent a change of the records, as a CloudEvent 1.0 with the extensions of form.
+type CloudEvent struct {
+	SpecVersion     string `json:"specversion"`
+	ID              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Subject         string `json:"subject,omitempty"`
+	Time            string `json:"time"`
+	DataContentType string `json:"datacontenttype"`
+	DataSchema      string `json:"dataschema"`
+
+	AppID   string `json:"appid"`
+	TableID string `json:"tableid"`
+	// Sequence increases by one for each event of the table, the event is skipped if it can not be taken.
+	Sequence  int64  `json:"sequence,omitempty"`
+	UserID    string `json:"userid,omitempty"`
+	RequestID string `json:"requestid,omitempty"`
+
+	Data *RecordData `json:"data"`
+}
+
+// RecordData the data of the events, version v1.
+type RecordData struct {
+	Event string   `json:"event"`
+	IDs   []string `json:"ids"`
+	// Entity the created record, or the updated fields, none for delete.
+	Entity interface{} `json:"entity,omitempty"`
+}
+
+// NewCloudEvent nil if the data is not a change.
+func NewCloudEvent(data *FormData, sequence int64, now time.Time) *CloudEvent {
+	event := data.Event()
+	if event == "" {
+		return nil
+	}
+	ids := data.IDs()
+	record := &RecordData{
+		Event: event,
+		IDs:   ids,
+	}
+	if event != "delete" {
+		record.Entity = data.Entity
+	}
+	ce := &CloudEvent{
+		SpecVersion:     SpecVersion,
+		ID:              id2.StringUUID(),
+		Source:          fmt.Sprintf("/api/v1/form/%s/home/form/%s", data.AppID, data.TableID),
+		Type:            eventTypes[event],
+		Time:            now.UTC().Format(time.RFC3339Nano),
+		DataContentType: dataContentType,
+		DataSchema:      RecordSchema,
+		AppID:           data.AppID,
+		TableID:         data.TableID,
+		Sequence:        sequence,
+		UserID:          data.UserID,
+		RequestID:       data.RequestID,
+		Data:            record,
+	}
+	if len(ids) == 1 {
+		ce.Subject = ids[0]
+	}
+	return ce
+}
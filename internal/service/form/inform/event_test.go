@@ -0,0 +1,70 @@
+package inform
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+)
+
+func TestNewCloudEvent(t *testing.T) {
+	now := time.Date(2022, 5, 1, 8, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	data := &FormData{
+		TableID:   "t",
+		Method:    "put",
+		Entity:    map[string]interface{}{"_id": "r1", "name": "a"},
+		AppID:     "app",
+		UserID:    "u",
+		RequestID: "req",
+	}
+	event := NewCloudEvent(data, 7, now)
+	if event.Type != "com.quanxiang.form.record.updated" || event.Source != "/api/v1/form/app/home/form/t" ||
+		event.Subject != "r1" || event.Time != "2022-05-01T00:00:00Z" || event.Sequence != 7 ||
+		event.AppID != "app" || event.UserID != "u" || event.RequestID != "req" || event.ID == "" {
+		t.Fatalf("event %+v", event)
+	}
+	if !reflect.DeepEqual(event.Data, &RecordData{Event: "update", IDs: []string{"r1"}, Entity: data.Entity}) {
+		t.Fatalf("data %+v", event.Data)
+	}
+
+	deleted := NewCloudEvent(&FormData{
+		Method: "delete",
+		Entity: map[string]interface{}{"data": []string{"r1", "r2"}, "delete_id": "u"},
+	}, 8, now)
+	if deleted.Subject != "" || deleted.Data.Entity != nil || !reflect.DeepEqual(deleted.Data.IDs, []string{"r1", "r2"}) {
+		t.Fatalf("deleted %+v", deleted)
+	}
+	if NewCloudEvent(&FormData{Method: "get"}, 9, now) != nil {
+		t.Fatal("get is not a change")
+	}
+}
+
+type fakeSequenceRepo struct {
+	models.SequenceRepo
+	fails int
+	calls int
+}
+
+func (f *fakeSequenceRepo) Next(ctx context.Context, appID, tableID string) (int64, error) {
+	f.calls++
+	if f.calls <= f.fails {
+		return 0, errors.New("connection refused")
+	}
+	return int64(f.calls), nil
+}
+
+func TestNextSequence(t *testing.T) {
+	repo := &fakeSequenceRepo{fails: 1}
+	manager := &HookManger{sequenceRepo: repo}
+	if sequence, err := manager.nextSequence(context.Background(), &FormData{}); err != nil || sequence != 2 {
+		t.Fatalf("sequence %d, err %v", sequence, err)
+	}
+	repo = &fakeSequenceRepo{fails: sequenceRetries}
+	manager.sequenceRepo = repo
+	if _, err := manager.nextSequence(context.Background(), &FormData{}); err == nil || repo.calls != sequenceRetries {
+		t.Fatalf("calls %d, err %v", repo.calls, err)
+	}
+}
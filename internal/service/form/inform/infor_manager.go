@@ -2,7 +2,13 @@ package inform
 
 import (
 	"context"
+	"encoding/json"
+	"time"
+
 	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/db/redis"
+	"github.com/quanxiang-cloud/form/internal/models"
+	redis2 "github.com/quanxiang-cloud/form/internal/models/redis"
 
 	daprd "github.com/dapr/go-sdk/client"
 	"github.com/quanxiang-cloud/form/pkg/misc/config"
@@ -17,13 +23,66 @@ type FormData struct {
 	Seq     string      `json:"seq"`
 	Version string      `json:"version"`
 	Method  string      `json:"method"`
+
+	// AppID、UserID、RequestID for the CloudEvents, the legacy payload is kept as it was.
+	AppID     string `json:"-"`
+	UserID    string `json:"-"`
+	RequestID string `json:"-"`
+	// Deleted the records as they were before the delete, for the change feed.
+	Deleted []map[string]interface{} `json:"-"`
 }
 
+// events the events of the methods.
+var events = map[string]string{
+	"post":   "create",
+	"put":    "update",
+	"delete": "delete",
+}
+
+// Event create, update or delete by the method, empty for the others.
+func (f *FormData) Event() string {
+	return events[f.Method]
+}
+
+// IDs the records changed, the created or updated one, or the deleted ones.
+func (f *FormData) IDs() []string {
+	entity, ok := f.Entity.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	ids := make([]string, 0)
+	if id, ok := entity["_id"].(string); ok {
+		ids = append(ids, id)
+	}
+	if f.Event() == "delete" {
+		switch value := entity["data"].(type) {
+		case []string:
+			ids = append(ids, value...)
+		case []interface{}:
+			for _, elem := range value {
+				if id, ok := elem.(string); ok {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	return ids
+}
+
+const (
+	// sendBuffer the changes waiting to be published, the requests are not held by the publishing.
+	sendBuffer = 1024
+	// sequenceRetries how many times the sequence is taken before the event is skipped.
+	sequenceRetries  = 3
+	sequenceInterval = 100 * time.Millisecond
+)
+
 // HookManger 管理发送kafka.
 type HookManger struct {
-	Send       chan *FormData // 增删改数据后，放到这个信道
-	conf       *config.Config
-	daprClient daprd.Client
+	Send         chan *FormData // 增删改数据后，放到这个信道
+	conf         *config.Config
+	daprClient   daprd.Client
+	sequenceRepo models.SequenceRepo
 }
 
 // NewHookManger NewHookManger.
@@ -32,10 +91,15 @@ func NewHookManger(ctx context.Context, conf *config.Config) (*HookManger, error
 	if err != nil {
 		return nil, err
 	}
+	redisClient, err := redis.NewClient(conf.Redis)
+	if err != nil {
+		return nil, err
+	}
 	m := &HookManger{
-		daprClient: client,
-		Send:       make(chan *FormData),
-		conf:       conf,
+		daprClient:   client,
+		Send:         make(chan *FormData, sendBuffer),
+		conf:         conf,
+		sequenceRepo: redis2.NewSequenceRepo(redisClient),
 	}
 	return m, nil
 }
@@ -46,6 +110,19 @@ func (manager *HookManger) Start(ctx context.Context) {
 		select {
 		case sendData := <-manager.Send:
 			logger.Logger.Infow("listen channel start", "data is ", sendData)
+			if manager.conf.Dapr.TopicEvents != "" {
+				// the sequence is only carried by the events, the legacy seq is kept as it was.
+				sequence, err := manager.nextSequence(ctx, sendData)
+				if err != nil {
+					// an event without the sequence would be taken as a duplicate, or break the order.
+					logger.Logger.Error(err, "appID", sendData.AppID, "tableID", sendData.TableID, "skipped", sendData.Event())
+				} else {
+					manager.publishEvent(ctx, NewCloudEvent(sendData, sequence, time.Now()))
+				}
+			}
+			if manager.conf.Dapr.DisableLegacy {
+				continue
+			}
 			if err := manager.publish(ctx, manager.conf.Dapr.TopicFlow, sendData); err != nil {
 				continue
 			}
@@ -55,6 +132,21 @@ func (manager *HookManger) Start(ctx context.Context) {
 	}
 }
 
+// nextSequence retry the sequence of the table a few times, the redis may be unavailable for a moment.
+func (manager *HookManger) nextSequence(ctx context.Context, data *FormData) (int64, error) {
+	var err error
+	for i := 0; i < sequenceRetries; i++ {
+		if i != 0 {
+			time.Sleep(sequenceInterval)
+		}
+		var sequence int64
+		if sequence, err = manager.sequenceRepo.Next(ctx, data.AppID, data.TableID); err == nil {
+			return sequence, nil
+		}
+	}
+	return 0, err
+}
+
 func (manager *HookManger) publish(ctx context.Context, topic string, data interface{}) error {
 	if err := manager.daprClient.PublishEvent(ctx, manager.conf.Dapr.PubSubName, topic, data); err != nil {
 		logger.Logger.Error(err, "topic", topic, "pubsubName", manager.conf.Dapr.PubSubName)
@@ -62,3 +154,20 @@ func (manager *HookManger) publish(ctx context.Context, topic string, data inter
 	}
 	return nil
 }
+
+// publishEvent the event is published as it is, dapr does not wrap it again.
+func (manager *HookManger) publishEvent(ctx context.Context, event *CloudEvent) {
+	if event == nil {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.Logger.Error(err, "event", event.ID)
+		return
+	}
+	topic := manager.conf.Dapr.TopicEvents
+	err = manager.daprClient.PublishEvent(ctx, manager.conf.Dapr.PubSubName, topic, data, daprd.PublishEventWithContentType(ContentType))
+	if err != nil {
+		logger.Logger.Error(err, "topic", topic, "pubsubName", manager.conf.Dapr.PubSubName)
+	}
+}
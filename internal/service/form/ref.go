@@ -27,30 +27,42 @@ type refs struct {
 	component    *component
 	serialRepo   models.SerialRepo
 	relationRepo models.TableRelationRepo
+	tableRepo    models.TableRepo
 	db           *gorm.DB
 }
 
+// NewRefs the chain of the records: the defaults are filled and the rules are checked
+// before the components of the refs write anything.
 func NewRefs(conf *config.Config) (consensus.Guidance, error) {
 	db, err := service.CreateMysqlConn(conf)
 	if err != nil {
 		return nil, err
 	}
-
 	appriseFlows, err := NewAppriseFlow(conf)
 	if err != nil {
 		return nil, err
 	}
+	fullTexts, err := newFullText(conf, appriseFlows)
+	if err != nil {
+		return nil, err
+	}
 	redisClient, err := redis2.NewClient(conf.Redis)
 	if err != nil {
 		return nil, err
 	}
-	return &refs{
+	refs := &refs{
 		db:           db,
 		relationRepo: mysql.NewTableRelationRepo(),
-		next:         appriseFlows,
+		tableRepo:    mysql.NewTableRepo(),
+		next:         fullTexts,
 		component:    newFormComponent(),
 		serialRepo:   redis.NewSerialRepo(redisClient),
-	}, nil
+	}
+	validations, err := newValidation(conf, refs)
+	if err != nil {
+		return nil, err
+	}
+	return newDefaultValue(conf, validations)
 }
 
 // Do create update.
@@ -111,7 +123,17 @@ func (c *refs) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response,
 			}
 		}
 	}
-	return c.next.Do(ctx, bus)
+	if bus.Method != "search" || len(bus.Expand) == 0 {
+		return c.next.Do(ctx, bus)
+	}
+	resp, err := c.next.Do(ctx, bus)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.expand(ctx, bus, resp.Entities); err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
 func (c *refs) get(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
@@ -167,6 +189,11 @@ func (c *refs) get(ctx context.Context, bus *consensus.Bus) (*consensus.Response
 			}
 		}
 	}
+	if entity, ok := resp.Entity.(map[string]interface{}); ok && len(bus.Expand) != 0 {
+		if err = c.expand(ctx, bus, []map[string]interface{}{entity}); err != nil {
+			return nil, err
+		}
+	}
 	return resp, nil
 }
 
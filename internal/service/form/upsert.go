@@ -0,0 +1,188 @@
+package form
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	redis2 "github.com/quanxiang-cloud/cabin/tailormade/db/redis"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/models/mysql"
+	"github.com/quanxiang-cloud/form/internal/models/redis"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/tables/util"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	"gorm.io/gorm"
+)
+
+const (
+	upsert = "upsert"
+	// upsertLockTTL the lock of the keys is released after, if the service stops in the middle.
+	upsertLockTTL = 10 * time.Second
+	// upsertWait how long an upsert waits for another one of the same keys.
+	upsertWait     = 5 * time.Second
+	upsertInterval = 50 * time.Millisecond
+)
+
+// upserts update the record matching the upsert keys of the table, or create one if none matches.
+// the keys are looked up over the whole table, the query of upsert limits the records which can be updated,
+// like the condition of the permit, a match out of it is forbidden rather than created again.
+type upserts struct {
+	next      consensus.Guidance
+	db        *gorm.DB
+	tableRepo models.TableRepo
+	limitRepo models.LimitsRepo
+}
+
+// NewUpsert returns the guidance of the actions of records, with upsert before next, like the refs.
+func NewUpsert(conf *config.Config, next consensus.Guidance) (consensus.Guidance, error) {
+	db, err := service.CreateMysqlConn(conf)
+	if err != nil {
+		return nil, err
+	}
+	redisClient, err := redis2.NewClient(conf.Redis)
+	if err != nil {
+		return nil, err
+	}
+	return &upserts{
+		next:      next,
+		db:        db,
+		tableRepo: mysql.NewTableRepo(),
+		limitRepo: redis.NewLimitRepo(redisClient),
+	}, nil
+}
+
+func (u *upserts) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	if bus.Method != upsert {
+		return u.next.Do(ctx, bus)
+	}
+	entity, ok := bus.CreatedOrUpdate.Entity.(map[string]interface{})
+	if !ok {
+		return nil, error2.New(code.ErrInvalidUpsert, "entity must be an object")
+	}
+	table, err := u.tableRepo.Get(u.db, bus.AppID, bus.TableID)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := util.UpsertKeys(table.Config, nil)
+	if err != nil {
+		return nil, error2.New(code.ErrInvalidUpsert, err.Error())
+	}
+	if len(keys) == 0 {
+		return nil, error2.New(code.ErrInvalidUpsert, "no upsert key is declared")
+	}
+	matches := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		value, ok := entity[key]
+		if !ok || value == nil || value == "" {
+			return nil, error2.New(code.ErrInvalidUpsert, fmt.Sprintf("%s is required", key))
+		}
+		matches = append(matches, consensus.GetSimple(consensus.TermKey, key, value))
+	}
+	matched := consensus.GetBool(consensus.Must, matches...)
+
+	// the concurrent upserts of the same keys would create the record twice.
+	unlock, err := u.lock(ctx, bus, matched)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// two are enough to tell the key is not unique.
+	records, err := u.search(ctx, bus, matched, 2)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 1 {
+		return nil, error2.New(code.ErrUpsertConflict, strings.Join(keys, ","))
+	}
+	byID := map[string]interface{}(nil)
+	if len(records) == 1 {
+		byID = consensus.GetSimple(consensus.TermKey, consensus.IDKey, records[0][consensus.IDKey])
+		// creating another record of the keys would break them, so the match must be in the scope.
+		if scope := bus.Get.Query; len(scope) != 0 {
+			scoped, err := u.search(ctx, bus, consensus.GetBool(consensus.Must, byID, map[string]interface{}(scope)), 1)
+			if err != nil {
+				return nil, err
+			}
+			if len(scoped) == 0 {
+				return nil, error2.New(code.ErrUpsertForbidden)
+			}
+		}
+	}
+	delete(entity, consensus.IDKey)
+	if byID == nil {
+		bus.Method = create
+		bus.Get.Query, bus.Get.OldQuery = nil, nil
+	} else {
+		bus.Method = update
+		bus.Get.Query, bus.Get.OldQuery = byID, byID
+	}
+
+	resp, err := u.next.Do(ctx, bus)
+	if err != nil {
+		return nil, err
+	}
+	resp.Action = bus.Method
+	if updated, ok := resp.Entity.(map[string]interface{}); ok && bus.Method == update {
+		// the entity of update has no _id, the matched one is returned.
+		updated[consensus.IDKey] = records[0][consensus.IDKey]
+	}
+	return resp, nil
+}
+
+func (u *upserts) lock(ctx context.Context, bus *consensus.Bus, matched map[string]interface{}) (func(), error) {
+	fingerprint, err := digest(matched)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.Join([]string{upsert, bus.AppID, bus.TableID, fingerprint}, ":")
+	// the lock may expire and be taken by another upsert, only the owner releases it.
+	owner := id2.StringUUID()
+	for deadline := time.Now().Add(upsertWait); ; {
+		locked, err := u.limitRepo.Lock(ctx, key, owner, upsertLockTTL)
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			return func() {
+				if err := u.limitRepo.UnLockOwner(ctx, key, owner); err != nil {
+					logger.Logger.WithName("upsert").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+				}
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, error2.New(code.ErrUpsertBusy)
+		}
+		time.Sleep(upsertInterval)
+	}
+}
+
+func (u *upserts) search(ctx context.Context, bus *consensus.Bus, query map[string]interface{}, size int64) ([]map[string]interface{}, error) {
+	searchBus := new(consensus.Bus)
+	searchBus.Universal = bus.Universal
+	searchBus.Foundation = consensus.Foundation{
+		AppID:   bus.AppID,
+		TableID: bus.TableID,
+		Method:  "search",
+	}
+	searchBus.Get.Query = types.Query(query)
+	searchBus.Get.Fields = []string{consensus.IDKey}
+	searchBus.List = consensus.List{
+		Page: 1,
+		Size: size,
+	}
+	resp, err := u.next.Do(ctx, searchBus)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Entities, nil
+}
@@ -0,0 +1,195 @@
+package form
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"gorm.io/gorm"
+)
+
+type fakeTableRepo struct {
+	models.TableRepo
+	config  models.Config
+	schemas map[string]models.WebSchema
+}
+
+func (f *fakeTableRepo) Get(db *gorm.DB, appID, tableID string) (*models.Table, error) {
+	return &models.Table{AppID: appID, TableID: tableID, Config: f.config, Schema: f.schemas[tableID]}, nil
+}
+
+type fakeLimitRepo struct {
+	models.LimitsRepo
+	locks map[string]interface{}
+}
+
+func (f *fakeLimitRepo) Lock(ctx context.Context, key string, val interface{}, ttl time.Duration) (bool, error) {
+	if _, ok := f.locks[key]; ok {
+		return false, nil
+	}
+	f.locks[key] = val
+	return true, nil
+}
+
+func (f *fakeLimitRepo) UnLockOwner(ctx context.Context, key string, owner interface{}) error {
+	if f.locks[key] == owner {
+		delete(f.locks, key)
+	}
+	return nil
+}
+
+// fakeRecords search by fakeTables, and keep the bus of the other actions.
+type fakeRecords struct {
+	fakeTables
+	bus *consensus.Bus
+}
+
+func (f *fakeRecords) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	if bus.Method == "search" {
+		return f.fakeTables.Do(ctx, bus)
+	}
+	f.bus = bus
+	return &consensus.Response{Total: 1, Entity: bus.CreatedOrUpdate.Entity}, nil
+}
+
+func errCode(err error) int64 {
+	if e, ok := err.(error2.Error); ok {
+		return e.Code
+	}
+	return 0
+}
+
+func TestUpsert(t *testing.T) {
+	records := &fakeRecords{fakeTables: fakeTables{tables: map[string][]map[string]interface{}{
+		"customer": {
+			{"_id": "c1", "code": "a", "owner": "alice"},
+			{"_id": "c2", "code": "b", "owner": "bob"},
+			{"_id": "c3", "code": "b", "owner": "bob"},
+		},
+	}}}
+	limits := &fakeLimitRepo{locks: map[string]interface{}{}}
+	u := &upserts{
+		next:      records,
+		tableRepo: &fakeTableRepo{config: models.Config{"upsertKeys": []interface{}{"code"}}},
+		limitRepo: limits,
+	}
+	do := func(entity map[string]interface{}, scope map[string]interface{}) (*consensus.Response, error) {
+		bus := &consensus.Bus{}
+		bus.AppID, bus.TableID, bus.Method = "app", "customer", upsert
+		bus.CreatedOrUpdate.Entity = entity
+		bus.Get.Query = scope
+		return u.Do(context.Background(), bus)
+	}
+
+	resp, err := do(map[string]interface{}{"_id": "x", "code": "new"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Action != create || records.bus.Method != create || records.bus.Get.Query != nil {
+		t.Fatalf("should create, got %s %+v", resp.Action, records.bus.Get)
+	}
+	if _, ok := records.bus.CreatedOrUpdate.Entity.(map[string]interface{})["_id"]; ok {
+		t.Fatal("the _id of the entity should be dropped")
+	}
+
+	resp, err = do(map[string]interface{}{"code": "a", "name": "A"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Action != update || records.bus.Get.Query["term"].(consensus.KeyValue)["_id"] != "c1" ||
+		resp.Entity.(map[string]interface{})["_id"] != "c1" {
+		t.Fatalf("should update c1, got %s %+v", resp.Action, records.bus.Get)
+	}
+	if len(limits.locks) != 0 {
+		t.Fatal("the lock should be released")
+	}
+
+	// c1 is out of the scope, it is neither updated nor created again.
+	bobs := consensus.GetSimple(consensus.TermKey, "owner", "bob")
+	if _, err = do(map[string]interface{}{"code": "a"}, bobs); errCode(err) != code.ErrUpsertForbidden {
+		t.Fatalf("c1 is out of the scope, got %v", err)
+	}
+	alices := consensus.GetSimple(consensus.TermKey, "owner", "alice")
+	if resp, err = do(map[string]interface{}{"code": "a"}, alices); err != nil || resp.Action != update {
+		t.Fatalf("c1 is in the scope, got %v %v", resp, err)
+	}
+	if _, err = do(map[string]interface{}{"code": "b"}, nil); errCode(err) != code.ErrUpsertConflict {
+		t.Fatalf("b matches two, got %v", err)
+	}
+	if _, err = do(map[string]interface{}{"name": "A"}, nil); errCode(err) != code.ErrInvalidUpsert {
+		t.Fatalf("code is required, got %v", err)
+	}
+}
+
+type fakeIdempotencyRepo struct {
+	values map[string]*models.Idempotency
+}
+
+func (f *fakeIdempotencyRepo) Create(ctx context.Context, key string, value *models.Idempotency, ttl time.Duration) (bool, error) {
+	if _, ok := f.values[key]; ok {
+		return false, nil
+	}
+	f.values[key] = value
+	return true, nil
+}
+
+func (f *fakeIdempotencyRepo) Get(ctx context.Context, key string) (*models.Idempotency, error) {
+	return f.values[key], nil
+}
+
+func (f *fakeIdempotencyRepo) Update(ctx context.Context, key string, value *models.Idempotency, ttl time.Duration) error {
+	f.values[key] = value
+	return nil
+}
+
+func (f *fakeIdempotencyRepo) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return nil
+}
+
+func (f *fakeIdempotencyRepo) Delete(ctx context.Context, key string) error {
+	delete(f.values, key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	repo := &fakeIdempotencyRepo{values: map[string]*models.Idempotency{}}
+	idem := &idempotency{repo: repo, ttl: time.Hour}
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return &consensus.Response{Total: 1, Entity: map[string]interface{}{"_id": "1"}}, nil
+	}
+	ctx := context.Background()
+	request := map[string]interface{}{"name": "a"}
+
+	if _, replayed, err := idem.Do(ctx, "k", request, fn); err != nil || replayed {
+		t.Fatalf("first request, replayed %v, err %v", replayed, err)
+	}
+	result, replayed, err := idem.Do(ctx, "k", request, fn)
+	if err != nil || !replayed || calls != 1 {
+		t.Fatalf("retried request, replayed %v, calls %d, err %v", replayed, calls, err)
+	}
+	if data, _ := json.Marshal(result); string(data) != `{"entity":{"_id":"1"},"total":1}` {
+		t.Fatalf("replayed result %s", data)
+	}
+	if _, _, err = idem.Do(ctx, "k", map[string]interface{}{"name": "b"}, fn); errCode(err) != code.ErrIdempotencyMismatch {
+		t.Fatalf("the key is reused, got %v", err)
+	}
+
+	failed := func() (interface{}, error) {
+		calls++
+		return nil, errors.New("failed")
+	}
+	if _, _, err = idem.Do(ctx, "f", request, failed); err == nil {
+		t.Fatal("the error should be returned")
+	}
+	if _, replayed, err = idem.Do(ctx, "f", request, fn); err != nil || replayed || calls != 3 {
+		t.Fatalf("a failed request can be retried, replayed %v, calls %d, err %v", replayed, calls, err)
+	}
+}
@@ -0,0 +1,120 @@
+package form
+
+import (
+	"context"
+
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/models/mysql"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/rules"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	"gorm.io/gorm"
+)
+
+// validation check the rules declared in the table config on create and update,
+// before the refs, so a violation leaves no sub record nor serial behind.
+type validation struct {
+	next      consensus.Guidance
+	db        *gorm.DB
+	tableRepo models.TableRepo
+}
+
+func newValidation(conf *config.Config, next consensus.Guidance) (consensus.Guidance, error) {
+	db, err := service.CreateMysqlConn(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &validation{
+		next:      next,
+		db:        db,
+		tableRepo: mysql.NewTableRepo(),
+	}, nil
+}
+
+func (v *validation) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	if bus.Method != create && bus.Method != update {
+		return v.next.Do(ctx, bus)
+	}
+	entity, ok := bus.CreatedOrUpdate.Entity.(map[string]interface{})
+	if !ok {
+		return v.next.Do(ctx, bus)
+	}
+	table, err := v.tableRepo.Get(v.db, bus.AppID, bus.TableID)
+	if err != nil {
+		return nil, err
+	}
+	tableRules, err := rules.Parse(table.Config)
+	if err != nil {
+		// the rules are checked when saved, a broken one should not block the data.
+		logger.Logger.WithName("validation").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return v.next.Do(ctx, bus)
+	}
+	if len(tableRules) == 0 {
+		return v.next.Do(ctx, bus)
+	}
+
+	evaluate := rules.Evaluate
+	targets := []map[string]interface{}{entity}
+	if bus.Method == update {
+		var merged bool
+		targets, merged, err = v.merge(ctx, bus, entity)
+		if err != nil {
+			return nil, err
+		}
+		if !merged {
+			evaluate = rules.EvaluatePartial
+		}
+	}
+	for _, target := range targets {
+		if violations := evaluate(tableRules, target); len(violations) != 0 {
+			return nil, violations
+		}
+	}
+	return v.next.Do(ctx, bus)
+}
+
+// merge the updated fields into the stored records, the rules may refer to fields which are not updated.
+// the records updated by a query without ids are not loaded, merged is false then.
+func (v *validation) merge(ctx context.Context, bus *consensus.Bus, entity map[string]interface{}) (targets []map[string]interface{}, merged bool, err error) {
+	ids := consensus.GetIDByQuery(bus.Get.Query)
+	if len(bus.Get.OldQuery) != 0 {
+		ids = consensus.GetIDByQuery(bus.Get.OldQuery)
+	}
+	if len(ids) == 0 {
+		return []map[string]interface{}{entity}, false, nil
+	}
+	getBus := new(consensus.Bus)
+	getBus.Universal = bus.Universal
+	getBus.Foundation = consensus.Foundation{
+		AppID:   bus.AppID,
+		TableID: bus.TableID,
+		Method:  "search",
+	}
+	getBus.Get.Query = consensus.GetSimple(consensus.TermsKey, consensus.IDKey, ids)
+	getBus.List = consensus.List{
+		Page: 1,
+		Size: int64(len(ids)),
+	}
+	resp, err := v.next.Do(ctx, getBus)
+	if err != nil {
+		return nil, false, err
+	}
+	targets = make([]map[string]interface{}, 0, len(ids))
+	for _, record := range resp.Entities {
+		target := make(map[string]interface{}, len(record)+len(entity))
+		for key, value := range record {
+			target[key] = value
+		}
+		for key, value := range entity {
+			target[key] = value
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		return []map[string]interface{}{entity}, false, nil
+	}
+	return targets, true, nil
+}
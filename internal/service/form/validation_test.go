@@ -0,0 +1,59 @@
+package form
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/rules"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+)
+
+func TestValidation(t *testing.T) {
+	records := &fakeRecords{}
+	v := &validation{
+		next: records,
+		tableRepo: &fakeTableRepo{config: models.Config{rules.ConfigKey: []interface{}{
+			map[string]interface{}{"id": "name", "message": "name is required",
+				"assert": map[string]interface{}{"field": "name", "op": "required"}},
+			map[string]interface{}{"id": "age", "message": "age must be positive",
+				"assert": map[string]interface{}{"field": "age", "op": "gt", "value": 0}},
+		}}},
+	}
+
+	// the violation stops the chain before the refs.
+	bus := &consensus.Bus{}
+	bus.Method = create
+	bus.CreatedOrUpdate.Entity = map[string]interface{}{}
+	bus.CreatedOrUpdate.Entity = map[string]interface{}{"age": float64(-1)}
+	_, err := v.Do(context.Background(), bus)
+	violations, ok := err.(rules.Violations)
+	if !ok || len(violations) != 2 || violations[0].ID != "name" || violations[1].ID != "age" || records.bus != nil {
+		t.Fatalf("err %v, bus %v", err, records.bus)
+	}
+	if errCode(violations.Coded()) != code.ErrRuleViolation {
+		t.Fatalf("code of %v", violations.Coded())
+	}
+
+	// the records updated by a query are not loaded, the name absent from the update is kept.
+	bus.Method = update
+	bus.Get.Query = consensus.GetSimple(consensus.TermKey, "age", float64(1))
+	bus.CreatedOrUpdate.Entity = map[string]interface{}{"age": float64(2)}
+	if _, err = v.Do(context.Background(), bus); err != nil || records.bus != bus {
+		t.Fatalf("err %v", err)
+	}
+	records.bus = nil
+	bus.CreatedOrUpdate.Entity = map[string]interface{}{"name": "", "age": float64(2)}
+	if _, err = v.Do(context.Background(), bus); err == nil || records.bus != nil {
+		t.Fatal("a blank name should be refused")
+	}
+
+	bus.Method = create
+	bus.Get.Query = nil
+
+	bus.CreatedOrUpdate.Entity = map[string]interface{}{"name": "a"}
+	if _, err := v.Do(context.Background(), bus); err != nil || records.bus != bus {
+		t.Fatalf("err %v", err)
+	}
+}
@@ -0,0 +1,618 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/pkg/misc/client"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"gorm.io/gorm"
+)
+
+// the kinds of the objects which may exist in the app already.
+const (
+	KindTables         = "tables"
+	KindTableRelations = "tableRelations"
+	KindRoles          = "roles"
+)
+
+// Strategy what to do with an object which exists in the app already.
+type Strategy string
+
+const (
+	// StrategySkip keep the existing one, the imported one is dropped.
+	StrategySkip Strategy = "skip"
+	// StrategyOverwrite replace the existing one, an overwritten role keeps its id, so its users.
+	StrategyOverwrite Strategy = "overwrite"
+	// StrategyRename import with a new table id or role name.
+	StrategyRename Strategy = "rename"
+)
+
+var strategies = map[string][]Strategy{
+	KindTables:         {StrategySkip, StrategyOverwrite, StrategyRename},
+	KindTableRelations: {StrategySkip, StrategyOverwrite},
+	KindRoles:          {StrategySkip, StrategyOverwrite, StrategyRename},
+}
+
+const (
+	tableIDKey     = "tableID"
+	restoreMaxSize = 999
+	existsKey      = "exists"
+	fieldKey       = "field"
+)
+
+type RestoreReq struct {
+	AppID    string `json:"-"`
+	UserID   string `json:"userID"`
+	UserName string `json:"userName"`
+	// DryRun check the objects against the app, nothing is written.
+	DryRun bool `json:"dryRun"`
+	// Strategies by the kinds, a conflict of a kind without a strategy fails the restore.
+	Strategies map[string]Strategy `json:"strategies"`
+
+	Tables         []*models.Table         `json:"tables"`
+	TableSchemas   []*models.TableSchema   `json:"tableSchemas"`
+	TableRelations []*models.TableRelation `json:"tableRelations"`
+	Roles          []*models.Role          `json:"roles"`
+	Permits        []*models.Permit        `json:"permits"`
+	Serials        []*models.Serial        `json:"serials"`
+}
+
+// Conflict an object which exists in the app already.
+type Conflict struct {
+	Kind string `json:"kind"`
+	// ID the table id, the role name, or the table id and field name of a relation.
+	ID string `json:"id"`
+	// Strategy empty if the kind has no strategy.
+	Strategy Strategy `json:"strategy,omitempty"`
+	// To the new table id or role name, when renamed.
+	To string `json:"to,omitempty"`
+}
+
+type RestoreResp struct {
+	DryRun bool `json:"dryRun"`
+	// Valid false if a conflict has no strategy or a problem is found, nothing is written then.
+	Valid     bool        `json:"valid"`
+	Conflicts []*Conflict `json:"conflicts"`
+	// Problems which no strategy solves, like a relation to a missing table.
+	Problems []string `json:"problems"`
+	// TableIDs where the records of the tables go, a new id if renamed, empty if skipped.
+	TableIDs map[string]string `json:"tableIDs"`
+	// Counts the objects written, or to be written in a dry run.
+	Counts map[string]int `json:"counts"`
+	// Cleared the tables whose records are deleted, those of the overwritten tables and of their relations,
+	// the records of the backup replace them.
+	Cleared []string `json:"cleared"`
+}
+
+// Restore import the objects of a backup in one transaction, the conflicts with the app are solved
+// by the strategies, the records of the overwritten tables are cleared and the serials are stored after the commit.
+// the tables are overwritten again by a retry, so a restore which fails after the commit can be retried.
+func (b *backup) Restore(ctx context.Context, req *RestoreReq) (*RestoreResp, error) {
+	for kind, strategy := range req.Strategies {
+		if !validStrategy(kind, strategy) {
+			return nil, error2.New(code.ErrInvalidRestore, fmt.Sprintf("%s can not be %s", kind, strategy))
+		}
+	}
+
+	var r *restorer
+	err := b.db.Transaction(func(tx *gorm.DB) error {
+		r = &restorer{backup: b, tx: tx, req: req}
+		if err := r.plan(); err != nil {
+			return err
+		}
+		if !r.resp.Valid || req.DryRun {
+			return nil
+		}
+		return r.apply()
+	})
+	if err != nil {
+		logger.Logger.WithName("Restore").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return nil, err
+	}
+	if !r.resp.Valid || req.DryRun {
+		return r.resp, nil
+	}
+
+	// the records are out of the transaction.
+	if err = b.clearRecords(ctx, req.AppID, r.resp.Cleared); err != nil {
+		logger.Logger.WithName("Restore").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return nil, error2.New(code.ErrRestoreIncomplete, err.Error())
+	}
+	_, err = b.ImportSerial(ctx, &ImportSerialReq{
+		AppID: req.AppID,
+		Data:  req.Serials,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.resp, nil
+}
+
+func validStrategy(kind string, strategy Strategy) bool {
+	for _, elem := range strategies[kind] {
+		if elem == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// restorer plan finds the conflicts and rewrites the request by the strategies, apply writes it.
+type restorer struct {
+	*backup
+	tx   *gorm.DB
+	req  *RestoreReq
+	resp *RestoreResp
+
+	// the existing objects to be replaced.
+	overwriteTables    []string
+	overwriteRelations []*models.TableRelation
+	overwriteRoles     []*models.Role
+}
+
+func (r *restorer) plan() error {
+	r.resp = &RestoreResp{
+		DryRun:    r.req.DryRun,
+		Conflicts: make([]*Conflict, 0),
+		Problems:  make([]string, 0),
+		TableIDs:  make(map[string]string),
+		Cleared:   make([]string, 0),
+	}
+
+	renames, err := r.planTables()
+	if err != nil {
+		return err
+	}
+	if err = r.planRelations(); err != nil {
+		return err
+	}
+	if err = r.planCleared(); err != nil {
+		return err
+	}
+	roleIDs, err := r.planRoles()
+	if err != nil {
+		return err
+	}
+	r.planPermits(roleIDs, renames)
+	r.planSerials()
+
+	r.resp.Valid = len(r.resp.Problems) == 0
+	for _, conflict := range r.resp.Conflicts {
+		if conflict.Strategy == "" {
+			r.resp.Valid = false
+		}
+	}
+	r.resp.Counts = map[string]int{
+		KindTables:         len(r.req.Tables),
+		"tableSchemas":     len(r.req.TableSchemas),
+		KindTableRelations: len(r.req.TableRelations),
+		KindRoles:          len(r.req.Roles),
+		"permits":          len(r.req.Permits),
+		"serials":          len(r.req.Serials),
+	}
+	return nil
+}
+
+func (r *restorer) conflict(kind, id string) *Conflict {
+	conflict := &Conflict{
+		Kind:     kind,
+		ID:       id,
+		Strategy: r.req.Strategies[kind],
+	}
+	r.resp.Conflicts = append(r.resp.Conflicts, conflict)
+	return conflict
+}
+
+// planTables a table conflicts if the app has the table or its schema.
+func (r *restorer) planTables() (map[string]string, error) {
+	ids := make([]string, 0, len(r.req.Tables))
+	for _, table := range r.req.Tables {
+		ids = append(ids, table.TableID)
+	}
+	for _, schema := range r.req.TableSchemas {
+		ids = append(ids, schema.TableID)
+	}
+
+	renames := make(map[string]string)
+	for _, id := range ids {
+		if _, ok := r.resp.TableIDs[id]; ok {
+			continue
+		}
+		exists, err := r.tableExists(id)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			r.resp.TableIDs[id] = id
+			continue
+		}
+		conflict := r.conflict(KindTables, id)
+		switch conflict.Strategy {
+		case StrategyOverwrite:
+			r.resp.TableIDs[id] = id
+			r.overwriteTables = append(r.overwriteTables, id)
+		case StrategyRename:
+			newID, err := r.newTableID(ids)
+			if err != nil {
+				return nil, err
+			}
+			conflict.To = newID
+			r.resp.TableIDs[id] = newID
+			renames[id] = newID
+		default:
+			r.resp.TableIDs[id] = ""
+		}
+	}
+
+	tables := make([]*models.Table, 0, len(r.req.Tables))
+	for _, table := range r.req.Tables {
+		if table.TableID = r.resp.TableIDs[table.TableID]; table.TableID == "" {
+			continue
+		}
+		if err := renameTables(&table.Schema, renames); err != nil {
+			return nil, err
+		}
+		if err := renameTables(&table.Config, renames); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	r.req.Tables = tables
+
+	schemas := make([]*models.TableSchema, 0, len(r.req.TableSchemas))
+	for _, schema := range r.req.TableSchemas {
+		if schema.TableID = r.resp.TableIDs[schema.TableID]; schema.TableID == "" {
+			continue
+		}
+		if err := renameTables(&schema.Schema, renames); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	r.req.TableSchemas = schemas
+	return renames, nil
+}
+
+func (r *restorer) tableExists(tableID string) (bool, error) {
+	table, err := r.tableRepo.Get(r.tx, r.req.AppID, tableID)
+	if err != nil {
+		return false, err
+	}
+	if table.ID != "" {
+		return true, nil
+	}
+	schema, err := r.tableSchemeRepo.Get(r.tx, r.req.AppID, tableID)
+	if err != nil {
+		return false, err
+	}
+	return schema.ID != "", nil
+}
+
+// newTableID unique in the app and the backup.
+func (r *restorer) newTableID(imported []string) (string, error) {
+	for {
+		id := id2.String(5)
+		used := false
+		for _, elem := range imported {
+			used = used || elem == id
+		}
+		for _, elem := range r.resp.TableIDs {
+			used = used || elem == id
+		}
+		if used {
+			continue
+		}
+		exists, err := r.tableExists(id)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return id, nil
+		}
+	}
+}
+
+// planRelations the sub table must be in the backup or the app,
+// the relations of an overwritten table are replaced with it, so they do not conflict.
+func (r *restorer) planRelations() error {
+	overwritten := make(map[string]bool, len(r.overwriteTables))
+	for _, id := range r.overwriteTables {
+		overwritten[id] = true
+	}
+
+	relations := make([]*models.TableRelation, 0, len(r.req.TableRelations))
+	for _, relation := range r.req.TableRelations {
+		if id, ok := r.resp.TableIDs[relation.TableID]; ok {
+			if id == "" {
+				continue
+			}
+			relation.TableID = id
+		}
+		if id, ok := r.resp.TableIDs[relation.SubTableID]; ok && id != "" {
+			relation.SubTableID = id
+		} else if !ok {
+			exists, err := r.tableExists(relation.SubTableID)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				r.resp.Problems = append(r.resp.Problems, fmt.Sprintf("the relation %s.%s refers to the missing table %s",
+					relation.TableID, relation.FieldName, relation.SubTableID))
+				continue
+			}
+		}
+		if !overwritten[relation.TableID] {
+			existing, _, err := r.tableRelationRepo.List(r.tx, &models.TableRelationQuery{
+				AppID:     r.req.AppID,
+				TableID:   relation.TableID,
+				FieldName: relation.FieldName,
+			}, 1, 1)
+			if err != nil {
+				return err
+			}
+			if len(existing) != 0 {
+				conflict := r.conflict(KindTableRelations, relation.TableID+"."+relation.FieldName)
+				if conflict.Strategy != StrategyOverwrite {
+					continue
+				}
+				r.overwriteRelations = append(r.overwriteRelations, existing[0])
+			}
+		}
+		relations = append(relations, relation)
+	}
+	r.req.TableRelations = relations
+	return nil
+}
+
+// planCleared the records of an overwritten table, and of the overwritten relations, are replaced
+// with the ones of the backup, like the records of its relations to the sub tables.
+func (r *restorer) planCleared() error {
+	seen := make(map[string]bool)
+	clear := func(tableID string) {
+		if !seen[tableID] {
+			seen[tableID] = true
+			r.resp.Cleared = append(r.resp.Cleared, tableID)
+		}
+	}
+	for _, tableID := range r.overwriteTables {
+		clear(tableID)
+		for page := 1; ; page++ {
+			relations, _, err := r.tableRelationRepo.List(r.tx, &models.TableRelationQuery{
+				AppID:   r.req.AppID,
+				TableID: tableID,
+			}, page, restoreMaxSize)
+			if err != nil {
+				return err
+			}
+			for _, relation := range relations {
+				clear(relation.TableID + "_" + relation.SubTableID)
+			}
+			if len(relations) < restoreMaxSize {
+				break
+			}
+		}
+	}
+	for _, relation := range r.overwriteRelations {
+		clear(relation.TableID + "_" + relation.SubTableID)
+	}
+	return nil
+}
+
+// clearRecords delete all of the records of the tables, or of the relations as <tableID>_<subTableID>.
+func (b *backup) clearRecords(ctx context.Context, appID string, tableIDs []string) error {
+	for _, tableID := range tableIDs {
+		_, err := b.formAPI.Delete(ctx, &client.FormReq{
+			TableID:  consensus.GetTableID(appID, tableID),
+			DslQuery: map[string]interface{}{dslQueryKey: consensus.GetSimple(existsKey, fieldKey, consensus.IDKey)},
+		})
+		if err != nil {
+			return fmt.Errorf("the records of %s are not cleared: %w", tableID, err)
+		}
+	}
+	return nil
+}
+
+// planRoles a role conflicts by the name, the imported role ids to the ones written, empty if skipped.
+func (r *restorer) planRoles() (map[string]string, error) {
+	existing, _, err := r.roleRepo.List(r.tx, &models.RoleQuery{
+		AppID: r.req.AppID,
+	}, 1, restoreMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]*models.Role, len(existing)+len(r.req.Roles))
+	for _, role := range existing {
+		names[role.Name] = role
+	}
+
+	roleIDs := make(map[string]string, len(r.req.Roles))
+	roles := make([]*models.Role, 0, len(r.req.Roles))
+	for _, role := range r.req.Roles {
+		roleIDs[role.ID] = role.ID
+		same, ok := names[role.Name]
+		if !ok {
+			names[role.Name] = role
+			roles = append(roles, role)
+			continue
+		}
+		conflict := r.conflict(KindRoles, role.Name)
+		switch conflict.Strategy {
+		case StrategyOverwrite:
+			roleIDs[role.ID] = same.ID
+			role.ID = same.ID
+			r.overwriteRoles = append(r.overwriteRoles, role)
+		case StrategyRename:
+			name := role.Name
+			for i := 2; names[name] != nil; i++ {
+				name = fmt.Sprintf("%s(%d)", role.Name, i)
+			}
+			conflict.To, role.Name = name, name
+			names[name] = role
+			roles = append(roles, role)
+		default:
+			roleIDs[role.ID] = ""
+		}
+	}
+	r.req.Roles = roles
+	return roleIDs, nil
+}
+
+// planPermits the permits follow their roles, the paths follow the renamed tables.
+func (r *restorer) planPermits(roleIDs, renames map[string]string) {
+	permits := make([]*models.Permit, 0, len(r.req.Permits))
+	for _, permit := range r.req.Permits {
+		roleID, ok := roleIDs[permit.RoleID]
+		if !ok {
+			r.resp.Problems = append(r.resp.Problems, fmt.Sprintf("the permit of %s refers to a missing role", permit.Path))
+			continue
+		}
+		if roleID == "" {
+			continue
+		}
+		permit.RoleID = roleID
+		if len(renames) != 0 {
+			segments := strings.Split(permit.Path, "/")
+			for i, segment := range segments {
+				if id, ok := renames[segment]; ok {
+					segments[i] = id
+				}
+			}
+			permit.Path = strings.Join(segments, "/")
+		}
+		permits = append(permits, permit)
+	}
+	r.req.Permits = permits
+}
+
+// planSerials a skipped table keeps its serials.
+func (r *restorer) planSerials() {
+	serials := make([]*models.Serial, 0, len(r.req.Serials))
+	for _, serial := range r.req.Serials {
+		if id, ok := r.resp.TableIDs[serial.TableID]; ok {
+			if id == "" {
+				continue
+			}
+			serial.TableID = id
+		}
+		serials = append(serials, serial)
+	}
+	r.req.Serials = serials
+}
+
+func (r *restorer) apply() error {
+	for _, tableID := range r.overwriteTables {
+		err := r.tableRepo.Delete(r.tx, &models.TableQuery{
+			AppID:   r.req.AppID,
+			TableID: tableID,
+		})
+		if err != nil {
+			return err
+		}
+		err = r.tableSchemeRepo.Delete(r.tx, &models.TableSchemaQuery{
+			AppID:   r.req.AppID,
+			TableID: tableID,
+		})
+		if err != nil {
+			return err
+		}
+		err = r.tableRelationRepo.Delete(r.tx, &models.TableRelationQuery{
+			AppID:   r.req.AppID,
+			TableID: tableID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, relation := range r.overwriteRelations {
+		err := r.tableRelationRepo.Delete(r.tx, &models.TableRelationQuery{
+			AppID:     r.req.AppID,
+			TableID:   relation.TableID,
+			FieldName: relation.FieldName,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, role := range r.overwriteRoles {
+		if err := r.roleRepo.Update(r.tx, role.ID, role); err != nil {
+			return err
+		}
+		if err := r.permitRepo.Delete(r.tx, &models.PermitQuery{RoleID: role.ID}); err != nil {
+			return err
+		}
+	}
+
+	if len(r.req.Tables) != 0 {
+		if err := r.tableRepo.BatchCreate(r.tx, r.req.Tables...); err != nil {
+			return err
+		}
+	}
+	if len(r.req.TableSchemas) != 0 {
+		if err := r.tableSchemeRepo.BatchCreate(r.tx, r.req.TableSchemas...); err != nil {
+			return err
+		}
+	}
+	if len(r.req.TableRelations) != 0 {
+		if err := r.tableRelationRepo.BatchCreate(r.tx, r.req.TableRelations...); err != nil {
+			return err
+		}
+	}
+	if len(r.req.Roles) != 0 {
+		if err := r.roleRepo.BatchCreate(r.tx, r.req.Roles...); err != nil {
+			return err
+		}
+	}
+	if len(r.req.Permits) != 0 {
+		if err := r.permitRepo.BatchCreate(r.tx, r.req.Permits...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameTables replace the renamed table ids the object refers to, like the sub tables of a schema.
+func renameTables(object interface{}, renames map[string]string) error {
+	if len(renames) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	if err = json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if data, err = json.Marshal(renameValue(value, renames)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, object)
+}
+
+func renameValue(value interface{}, renames map[string]string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = renameValue(elem, renames)
+		}
+		if tableID, ok := v[tableIDKey].(string); ok {
+			if id, ok := renames[tableID]; ok {
+				v[tableIDKey] = id
+			}
+		}
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = renameValue(elem, renames)
+		}
+	}
+	return value
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"gorm.io/gorm"
+)
+
+// the app has the table t1 and the role admin.
+type fakeRestoreTables struct {
+	models.TableRepo
+}
+
+func (f *fakeRestoreTables) Get(db *gorm.DB, appID, tableID string) (*models.Table, error) {
+	if tableID == "t1" {
+		return &models.Table{ID: "1", AppID: appID, TableID: tableID}, nil
+	}
+	return &models.Table{}, nil
+}
+
+type fakeRestoreSchemas struct {
+	models.TableSchemeRepo
+}
+
+func (f *fakeRestoreSchemas) Get(db *gorm.DB, appID, tableID string) (*models.TableSchema, error) {
+	return &models.TableSchema{}, nil
+}
+
+// fakeRestoreRelations the app has the relation of t1 to t3.
+type fakeRestoreRelations struct {
+	models.TableRelationRepo
+}
+
+func (f *fakeRestoreRelations) List(db *gorm.DB, query *models.TableRelationQuery, page, size int) ([]*models.TableRelation, int64, error) {
+	if query.TableID != "t1" || page != 1 {
+		return nil, 0, nil
+	}
+	relation := &models.TableRelation{AppID: query.AppID, TableID: "t1", FieldName: "items", SubTableID: "t3"}
+	if query.FieldName != "" && query.FieldName != relation.FieldName {
+		return nil, 0, nil
+	}
+	return []*models.TableRelation{relation}, 1, nil
+}
+
+type fakeRestoreRoles struct {
+	models.RoleRepo
+}
+
+func (f *fakeRestoreRoles) List(db *gorm.DB, query *models.RoleQuery, page, size int) ([]*models.Role, int64, error) {
+	return []*models.Role{{ID: "r0", AppID: query.AppID, Name: "admin"}}, 1, nil
+}
+
+func newRestoreReq() *RestoreReq {
+	return &RestoreReq{
+		AppID: "dst",
+		Tables: []*models.Table{
+			{TableID: "t1", Schema: models.WebSchema{"items": map[string]interface{}{"tableID": "t1"}}},
+			{TableID: "t2"},
+		},
+		TableSchemas: []*models.TableSchema{{TableID: "t1"}, {TableID: "t2"}},
+		TableRelations: []*models.TableRelation{
+			{TableID: "t1", FieldName: "items", SubTableID: "t2"},
+			{TableID: "t2", FieldName: "owner", SubTableID: "t9"},
+		},
+		Roles:   []*models.Role{{ID: "r1", Name: "admin"}},
+		Permits: []*models.Permit{{RoleID: "r1", Path: "/api/v1/form/dst/home/form/t1/search"}},
+		Serials: []*models.Serial{{TableID: "t1", FieldID: "no"}},
+	}
+}
+
+func TestRestorePlan(t *testing.T) {
+	b := &backup{
+		tableRepo:         &fakeRestoreTables{},
+		tableSchemeRepo:   &fakeRestoreSchemas{},
+		tableRelationRepo: &fakeRestoreRelations{},
+		roleRepo:          &fakeRestoreRoles{},
+	}
+
+	// without the strategies, the conflicts and the missing table are reported.
+	r := &restorer{backup: b, req: newRestoreReq()}
+	if err := r.plan(); err != nil {
+		t.Fatal(err)
+	}
+	if r.resp.Valid || len(r.resp.Conflicts) != 2 || len(r.resp.Problems) != 1 || r.resp.TableIDs["t1"] != "" {
+		t.Fatalf("resp %+v", r.resp)
+	}
+
+	req := newRestoreReq()
+	req.TableRelations = req.TableRelations[:1]
+	req.Strategies = map[string]Strategy{KindTables: StrategyRename, KindRoles: StrategyOverwrite}
+	r = &restorer{backup: b, req: req}
+	if err := r.plan(); err != nil {
+		t.Fatal(err)
+	}
+	renamed := r.resp.TableIDs["t1"]
+	if !r.resp.Valid || renamed == "" || renamed == "t1" || r.resp.TableIDs["t2"] != "t2" {
+		t.Fatalf("resp %+v", r.resp)
+	}
+	if req.Tables[0].TableID != renamed || req.Tables[0].Schema["items"].(map[string]interface{})["tableID"] != renamed {
+		t.Fatalf("table %+v", req.Tables[0])
+	}
+	if req.TableRelations[0].TableID != renamed || req.Serials[0].TableID != renamed {
+		t.Fatalf("relation %+v, serial %+v", req.TableRelations[0], req.Serials[0])
+	}
+	if len(req.Roles) != 0 || len(r.overwriteRoles) != 1 || req.Permits[0].RoleID != "r0" ||
+		req.Permits[0].Path != "/api/v1/form/dst/home/form/"+renamed+"/search" {
+		t.Fatalf("roles %+v, permit %+v", req.Roles, req.Permits[0])
+	}
+
+	req = newRestoreReq()
+	req.TableRelations = req.TableRelations[:1]
+	req.Strategies = map[string]Strategy{KindTables: StrategySkip, KindRoles: StrategyRename}
+	r = &restorer{backup: b, req: req}
+	if err := r.plan(); err != nil {
+		t.Fatal(err)
+	}
+	if !r.resp.Valid || len(req.Tables) != 1 || len(req.TableRelations) != 0 || len(req.Serials) != 0 ||
+		req.Roles[0].Name != "admin(2)" || req.Permits[0].RoleID != "r1" {
+		t.Fatalf("resp %+v, roles %+v", r.resp, req.Roles)
+	}
+}
+
+func TestRestoreCleared(t *testing.T) {
+	b := &backup{
+		tableRepo:         &fakeRestoreTables{},
+		tableSchemeRepo:   &fakeRestoreSchemas{},
+		tableRelationRepo: &fakeRestoreRelations{},
+		roleRepo:          &fakeRestoreRoles{},
+	}
+	req := newRestoreReq()
+	req.TableRelations = req.TableRelations[:1]
+	req.Strategies = map[string]Strategy{KindTables: StrategyOverwrite, KindRoles: StrategyOverwrite}
+	r := &restorer{backup: b, req: req}
+	if err := r.plan(); err != nil {
+		t.Fatal(err)
+	}
+	if !r.resp.Valid || !reflect.DeepEqual(r.resp.Cleared, []string{"t1", "t1_t3"}) {
+		t.Fatalf("cleared %v", r.resp.Cleared)
+	}
+
+	// the relation of a table which is kept is overwritten alone.
+	req = newRestoreReq()
+	req.Tables, req.TableSchemas = req.Tables[1:], req.TableSchemas[1:]
+	req.TableRelations = req.TableRelations[:1]
+	req.Strategies = map[string]Strategy{KindTableRelations: StrategyOverwrite, KindRoles: StrategyOverwrite}
+	r = &restorer{backup: b, req: req}
+	if err := r.plan(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.resp.Cleared, []string{"t1_t3"}) || r.overwriteRelations[0].SubTableID != "t3" {
+		t.Fatalf("cleared %v", r.resp.Cleared)
+	}
+}
+
+func TestValidStrategy(t *testing.T) {
+	if !validStrategy(KindTables, StrategyRename) || validStrategy(KindTableRelations, StrategyRename) || validStrategy("permits", StrategySkip) {
+		t.Fatal("strategy")
+	}
+}
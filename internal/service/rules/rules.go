@@ -0,0 +1,278 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+)
+
+// ConfigKey the key of rules in the table config.
+const ConfigKey = "rules"
+
+// Operators supported by a condition.
+const (
+	Eq       = "eq"
+	Ne       = "ne"
+	Gt       = "gt"
+	Gte      = "gte"
+	Lt       = "lt"
+	Lte      = "lte"
+	In       = "in"
+	Required = "required"
+	Regex    = "regex"
+)
+
+// Rule a cross-field validation rule, Assert is checked only when When holds.
+type Rule struct {
+	ID      string     `json:"id"`
+	Message string     `json:"message"`
+	When    *Condition `json:"when,omitempty"`
+	Assert  Condition  `json:"assert"`
+}
+
+// Condition compare a field with a value, or with another field when ValueField is set.
+type Condition struct {
+	Field      string      `json:"field"`
+	Op         string      `json:"op"`
+	Value      interface{} `json:"value,omitempty"`
+	ValueField string      `json:"valueField,omitempty"`
+
+	reg *regexp.Regexp
+}
+
+// Violation a rule which the entity does not satisfy.
+type Violation struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+func (v *Violation) String() string {
+	return fmt.Sprintf("[%s] %s", v.ID, v.Message)
+}
+
+// Violations the rules an entity violates, returned as an error,
+// so the caller gets each of them rather than one message.
+type Violations []*Violation
+
+func (v Violations) Error() string {
+	msg := make([]string, 0, len(v))
+	for _, violation := range v {
+		msg = append(msg, violation.String())
+	}
+	return strings.Join(msg, "; ")
+}
+
+// Coded the error with the code of the violations, for the callers which know only the codes.
+func (v Violations) Coded() error2.Error {
+	return error2.New(code.ErrRuleViolation, v.Error())
+}
+
+// Parse read the rules from the table config.
+func Parse(config models.Config) ([]*Rule, error) {
+	value, ok := config[ConfigKey]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]*Rule, 0)
+	if err = json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	ids := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("rule id is blank")
+		}
+		if _, ok := ids[rule.ID]; ok {
+			return nil, fmt.Errorf("rule %s is duplicated", rule.ID)
+		}
+		ids[rule.ID] = struct{}{}
+		if rule.When != nil {
+			if err = rule.When.compile(); err != nil {
+				return nil, fmt.Errorf("rule %s: %s", rule.ID, err.Error())
+			}
+		}
+		if err = rule.Assert.compile(); err != nil {
+			return nil, fmt.Errorf("rule %s: %s", rule.ID, err.Error())
+		}
+	}
+	return rules, nil
+}
+
+func (c *Condition) compile() error {
+	if c.Field == "" {
+		return fmt.Errorf("field is blank")
+	}
+	switch c.Op {
+	case Eq, Ne, Gt, Gte, Lt, Lte, In, Required:
+	case Regex:
+		pattern, ok := c.Value.(string)
+		if !ok {
+			return fmt.Errorf("regex of %s must be a string", c.Field)
+		}
+		reg, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		c.reg = reg
+	default:
+		return fmt.Errorf("unsupported op %s", c.Op)
+	}
+	return nil
+}
+
+// Evaluate return the rules the entity violates.
+func Evaluate(rules []*Rule, entity map[string]interface{}) Violations {
+	return evaluate(rules, entity, false)
+}
+
+// EvaluatePartial the same as Evaluate, for the updated fields of records which are not loaded,
+// a required field absent from the entity is kept as it is stored, so it is not checked.
+func EvaluatePartial(rules []*Rule, entity map[string]interface{}) Violations {
+	return evaluate(rules, entity, true)
+}
+
+func evaluate(rules []*Rule, entity map[string]interface{}, partial bool) Violations {
+	violations := make(Violations, 0)
+	for _, rule := range rules {
+		if partial && rule.Assert.Op == Required && !present(entity, strings.Split(rule.Assert.Field, ".")) {
+			continue
+		}
+		if rule.When != nil && !rule.When.match(entity, false) {
+			continue
+		}
+		if rule.Assert.match(entity, true) {
+			continue
+		}
+		violations = append(violations, &Violation{
+			ID:      rule.ID,
+			Message: rule.Message,
+		})
+	}
+	return violations
+}
+
+// present whether the entity has the key of the path, blank or not.
+func present(entity map[string]interface{}, path []string) bool {
+	for index, key := range path {
+		value, ok := entity[key]
+		if !ok {
+			return false
+		}
+		if index == len(path)-1 {
+			return true
+		}
+		if entity, ok = value.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return false
+}
+
+// match absent is the result when a compared field is blank,
+// an assert leaves blank fields to required while a when condition does not hold.
+func (c *Condition) match(entity map[string]interface{}, absent bool) bool {
+	value := consensus.GetValue(entity, strings.Split(c.Field, "."))
+	if c.Op == Required {
+		return !isBlank(value)
+	}
+	if isBlank(value) {
+		return absent
+	}
+	expect := c.Value
+	if c.ValueField != "" {
+		expect = consensus.GetValue(entity, strings.Split(c.ValueField, "."))
+		if isBlank(expect) {
+			return absent
+		}
+	}
+	switch c.Op {
+	case Eq:
+		return compare(value, expect) == 0
+	case Ne:
+		return compare(value, expect) != 0
+	case Gt:
+		return compare(value, expect) > 0
+	case Gte:
+		return compare(value, expect) >= 0
+	case Lt:
+		return compare(value, expect) < 0
+	case Lte:
+		return compare(value, expect) <= 0
+	case In:
+		list, ok := expect.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, elem := range list {
+			if compare(value, elem) == 0 {
+				return true
+			}
+		}
+		return false
+	case Regex:
+		if c.reg == nil {
+			return true
+		}
+		return c.reg.MatchString(fmt.Sprint(value))
+	}
+	return true
+}
+func isBlank(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	}
+	return false
+}
+
+// compare numbers by value, the others by string, dates in ISO8601 compare well as string.
+func compare(a, b interface{}) int {
+	fa, aok := toFloat(a)
+	fb, bok := toFloat(b)
+	if aok && bok {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,102 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+)
+
+func TestEvaluate(t *testing.T) {
+	config := models.Config{
+		ConfigKey: []interface{}{
+			map[string]interface{}{
+				"id":      "date_range",
+				"message": "end date must not be before start date",
+				"assert": map[string]interface{}{
+					"field":      "end_date",
+					"op":         "gte",
+					"valueField": "start_date",
+				},
+			},
+			map[string]interface{}{
+				"id":      "closed_reason",
+				"message": "reason is required when closed",
+				"when": map[string]interface{}{
+					"field": "status",
+					"op":    "eq",
+					"value": "closed",
+				},
+				"assert": map[string]interface{}{
+					"field": "reason",
+					"op":    "required",
+				},
+			},
+			map[string]interface{}{
+				"id":      "phone",
+				"message": "bad phone",
+				"assert": map[string]interface{}{
+					"field": "phone",
+					"op":    "regex",
+					"value": `^1\d{10}$`,
+				},
+			},
+		},
+	}
+	rules, err := Parse(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		entity map[string]interface{}
+		expect []string
+	}{
+		{
+			entity: map[string]interface{}{
+				"start_date": "2022-01-02T00:00:00Z",
+				"end_date":   "2022-01-03T00:00:00Z",
+				"status":     "open",
+			},
+		},
+		{
+			entity: map[string]interface{}{
+				"start_date": "2022-01-02T00:00:00Z",
+				"end_date":   "2022-01-01T00:00:00Z",
+				"status":     "closed",
+				"phone":      "123",
+			},
+			expect: []string{"date_range", "closed_reason", "phone"},
+		},
+		{
+			entity: map[string]interface{}{
+				"status": "closed",
+				"reason": "done",
+				"phone":  "13800000000",
+			},
+		},
+	}
+	for i, c := range cases {
+		violations := Evaluate(rules, c.entity)
+		if len(violations) != len(c.expect) {
+			t.Fatalf("case %d: expect %v, got %v", i, c.expect, violations)
+		}
+		for j, violation := range violations {
+			if violation.ID != c.expect[j] {
+				t.Fatalf("case %d: expect %v, got %v", i, c.expect, violations)
+			}
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	cases := []models.Config{
+		{ConfigKey: []interface{}{map[string]interface{}{"assert": map[string]interface{}{"field": "a", "op": "eq"}}}},
+		{ConfigKey: []interface{}{map[string]interface{}{"id": "a", "assert": map[string]interface{}{"field": "a", "op": "like"}}}},
+		{ConfigKey: []interface{}{map[string]interface{}{"id": "a", "assert": map[string]interface{}{"field": "a", "op": "regex", "value": "("}}}},
+	}
+	for i, c := range cases {
+		if _, err := Parse(c); err == nil {
+			t.Fatalf("case %d: expect error", i)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/pkg/backup"
+	"github.com/quanxiang-cloud/form/pkg/backup/aide"
+)
+
+func (s *snapshot) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.schedule(ctx, time2.NowUnix())
+		}
+	}
+}
+
+// schedule take the due backups, each policy is claimed first, so the instances do not take the same backup.
+// the backups are exported at once up to the concurrency, it returns after all of them.
+// the backups missed while form is down are not made up, the next is an interval later.
+func (s *snapshot) schedule(ctx context.Context, now int64) {
+	policies, _, err := s.policyRepo.List(s.db, &models.BackupPolicyQuery{
+		DueAt: now,
+	}, 1, 0)
+	if err != nil {
+		logger.Logger.WithName("snapshot").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, policy := range policies {
+		// the policy is claimed when there is a slot, another instance may take it before.
+		if !s.acquire(ctx) {
+			return
+		}
+		ok, err := s.policyRepo.Claim(s.db, policy.ID, policy.NextAt, now+policy.Interval*int64(time.Second/time.Millisecond))
+		if err != nil {
+			s.release()
+			logger.Logger.WithName("snapshot").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			continue
+		}
+		if !ok {
+			s.release()
+			continue
+		}
+		snap, err := s.create(policy.AppID, policy.FullEvery, "", "")
+		if err != nil {
+			s.release()
+			logger.Logger.WithName("snapshot").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.release()
+			s.export(ctx, snap)
+		}()
+	}
+}
+
+// acquire a slot of the exports, false if the ctx is done first.
+func (s *snapshot) acquire(ctx context.Context) bool {
+	select {
+	case s.exports <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *snapshot) release() {
+	<-s.exports
+}
+
+// create a running snapshot of the app, it is full unless there is a full one with fewer than fullEvery
+// snapshots in its chain, then it holds the records changed since the last snapshot started;
+// the deleted records are not held by the incremental ones, they are gone from the next full one.
+// fullEvery below 1 is the default, as the policies saved before it was required.
+func (s *snapshot) create(appID string, fullEvery int, userID, userName string) (*models.Snapshot, error) {
+	if fullEvery < 1 {
+		fullEvery = defaultFullEvery
+	}
+	now := time2.NowUnix()
+	snap := &models.Snapshot{
+		ID:          id2.StringUUID(),
+		AppID:       appID,
+		Type:        models.FullSnapshot,
+		Status:      models.JobRunning,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatorID:   userID,
+		CreatorName: userName,
+	}
+	snap.BaseID = snap.ID
+	snap.Key = fmt.Sprintf("%s/%s.tar.gz", appID, snap.ID)
+
+	if fullEvery != 1 {
+		last, _, err := s.snapshotRepo.List(s.db, &models.SnapshotQuery{
+			AppID:  appID,
+			Status: models.JobSucceed,
+		}, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(last) != 0 {
+			_, count, err := s.snapshotRepo.List(s.db, &models.SnapshotQuery{
+				AppID:  appID,
+				BaseID: last[0].BaseID,
+				Status: models.JobSucceed,
+			}, 1, 1)
+			if err != nil {
+				return nil, err
+			}
+			if count < int64(fullEvery) {
+				snap.Type = models.IncrementalSnapshot
+				snap.BaseID = last[0].BaseID
+				snap.Since = last[0].CreatedAt
+			}
+		}
+	}
+
+	if err := s.snapshotRepo.Create(s.db, snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// export write the archive of the snapshot to the store, then prune the snapshots of the app.
+func (s *snapshot) export(ctx context.Context, snap *models.Snapshot) {
+	pr, pw := io.Pipe()
+	done := make(chan *backup.Manifest, 1)
+	go func() {
+		manifest, err := s.archiver.ExportArchive(ctx, &aide.ExportOption{
+			AppID: snap.AppID,
+			Since: snap.Since,
+		}, pw)
+		pw.CloseWithError(err)
+		done <- manifest
+	}()
+	counter := &countReader{r: pr}
+	err := s.store.Put(ctx, snap.Key, counter)
+	// the export stops at once if the store fails.
+	pr.CloseWithError(err)
+	manifest := <-done
+
+	update := &models.Snapshot{
+		Status:    models.JobSucceed,
+		Size:      counter.n,
+		UpdatedAt: time2.NowUnix(),
+	}
+	if err == nil && manifest == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		logger.Logger.WithName("snapshot").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		update.Status = models.JobFailed
+		update.Message = err.Error()
+	} else {
+		update.Counts = counts(manifest)
+	}
+	if err = s.snapshotRepo.Update(s.db, snap.ID, update); err != nil {
+		logger.Logger.WithName("snapshot").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return
+	}
+	if update.Status == models.JobSucceed {
+		s.prune(ctx, snap.AppID)
+	}
+}
+
+// prune keep the full snapshots the policy retains, with the incremental ones after them,
+// the failed ones before the oldest kept are deleted too. nothing is deleted without a policy.
+func (s *snapshot) prune(ctx context.Context, appID string) {
+	policy, err := s.policyRepo.Get(s.db, appID)
+	if err != nil || policy.ID == "" || policy.Retention < 1 {
+		return
+	}
+	fulls, _, err := s.snapshotRepo.List(s.db, &models.SnapshotQuery{
+		AppID:  appID,
+		Type:   models.FullSnapshot,
+		Status: models.JobSucceed,
+	}, 1, 0)
+	if err != nil {
+		logger.Logger.WithName("snapshot").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return
+	}
+	if len(fulls) <= policy.Retention {
+		return
+	}
+
+	pruned := make(map[string]*models.Snapshot)
+	for _, full := range fulls[policy.Retention:] {
+		chain, _, err := s.snapshotRepo.List(s.db, &models.SnapshotQuery{
+			AppID:  appID,
+			BaseID: full.ID,
+		}, 1, 0)
+		if err != nil {
+			logger.Logger.WithName("snapshot").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			return
+		}
+		for _, snap := range chain {
+			pruned[snap.ID] = snap
+		}
+	}
+	failed, _, err := s.snapshotRepo.List(s.db, &models.SnapshotQuery{
+		AppID:  appID,
+		Status: models.JobFailed,
+	}, 1, 0)
+	if err != nil {
+		logger.Logger.WithName("snapshot").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return
+	}
+	oldest := fulls[policy.Retention-1].CreatedAt
+	for _, snap := range failed {
+		if snap.CreatedAt < oldest {
+			pruned[snap.ID] = snap
+		}
+	}
+
+	ids := make([]string, 0, len(pruned))
+	for _, snap := range pruned {
+		if err := s.store.Delete(ctx, snap.Key); err != nil {
+			logger.Logger.WithName("snapshot").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			continue
+		}
+		ids = append(ids, snap.ID)
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if err = s.snapshotRepo.Delete(s.db, &models.SnapshotQuery{AppID: appID, IDs: ids}); err != nil {
+		logger.Logger.WithName("snapshot").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+	}
+}
+
+// counts the objects of each kind, and the records of all the tables.
+func counts(manifest *backup.Manifest) models.JobReport {
+	result := make(models.JobReport, len(manifest.Counts)+1)
+	for kind, count := range manifest.Counts {
+		result[kind] = count
+	}
+	records := 0
+	for _, count := range manifest.Records {
+		records += count
+	}
+	result[recordsKey] = records
+	return result
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
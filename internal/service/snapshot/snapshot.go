@@ -0,0 +1,423 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/models/mysql"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/pkg/backup"
+	"github.com/quanxiang-cloud/form/pkg/backup/aide"
+	"github.com/quanxiang-cloud/form/pkg/backup/store"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTick        = time.Minute
+	defaultConcurrency = 4
+	// minInterval the backups of an app are an hour apart at least.
+	minInterval = 3600
+	// defaultFullEvery a full backup every week of daily backups, the deleted records are
+	// only seen by the full ones, so the chain of the incremental ones is never unbounded.
+	defaultFullEvery = 7
+	recordsKey       = "records"
+)
+
+// Snapshot the backups of the apps kept in the store, taken by the policies of the apps or on demand.
+type Snapshot interface {
+	PutPolicy(ctx context.Context, req *PutPolicyReq) (*PutPolicyResp, error)
+	GetPolicy(ctx context.Context, req *GetPolicyReq) (*PolicyVo, error)
+	DeletePolicy(ctx context.Context, req *DeletePolicyReq) (*DeletePolicyResp, error)
+	CreateSnapshot(ctx context.Context, req *CreateSnapshotReq) (*CreateSnapshotResp, error)
+	GetSnapshot(ctx context.Context, req *GetSnapshotReq) (*SnapshotVo, error)
+	ListSnapshot(ctx context.Context, req *ListSnapshotReq) (*ListSnapshotResp, error)
+	RestoreSnapshot(ctx context.Context, req *RestoreSnapshotReq) (*RestoreSnapshotResp, error)
+	// Run take the due backups of the policies until the ctx is done.
+	Run(ctx context.Context)
+}
+
+// archiver the archives of the apps, see backup.Backup.
+type archiver interface {
+	ExportArchive(ctx context.Context, opts *aide.ExportOption, w io.Writer) (*backup.Manifest, error)
+	ImportArchives(ctx context.Context, rs []io.Reader, opts *aide.ImportOption) (*backup.Report, error)
+}
+
+type snapshot struct {
+	db           *gorm.DB
+	policyRepo   models.BackupPolicyRepo
+	snapshotRepo models.SnapshotRepo
+	store        store.Store
+	archiver     archiver
+	tick         time.Duration
+	// exports the slots of the exports running, see acquire.
+	exports chan struct{}
+}
+
+// NewSnapshot the archives are made by the internal backup endpoints of form.
+func NewSnapshot(conf *config.Config) (Snapshot, error) {
+	db, err := service.CreateMysqlConn(conf)
+	if err != nil {
+		return nil, err
+	}
+	s, err := store.New(conf.Backup.Store)
+	if err != nil {
+		return nil, err
+	}
+	tick := conf.Backup.Tick
+	if tick <= 0 {
+		tick = defaultTick
+	}
+	concurrency := conf.Backup.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	return &snapshot{
+		db:           db,
+		policyRepo:   mysql.NewBackupPolicyRepo(),
+		snapshotRepo: mysql.NewSnapshotRepo(),
+		store:        s,
+		archiver:     backup.NewBackup(conf.InternalNet),
+		tick:         tick,
+		exports:      make(chan struct{}, concurrency),
+	}, nil
+}
+
+type PutPolicyReq struct {
+	AppID string `json:"appID"`
+	// Interval the seconds between two backups, an hour at least
+	Interval int64 `json:"interval" binding:"required"`
+	// FullEvery a full backup every so many backups, the others are incremental, 7 if it is 0
+	FullEvery int `json:"fullEvery"`
+	// Retention the full backups kept, each with the incremental ones after it
+	Retention int    `json:"retention" binding:"required"`
+	Disabled  bool   `json:"disabled"`
+	UserID    string `json:"-"`
+	UserName  string `json:"-"`
+}
+
+type PutPolicyResp struct {
+	ID string `json:"id"`
+}
+
+// PutPolicy create or update the policy of the app, the next backup is due an interval later.
+func (s *snapshot) PutPolicy(ctx context.Context, req *PutPolicyReq) (*PutPolicyResp, error) {
+	if req.Interval < minInterval {
+		return nil, error2.New(code.ErrInvalidBackupPolicy, fmt.Sprintf("interval must be %d seconds at least", minInterval))
+	}
+	if req.Retention < 1 || req.FullEvery < 0 {
+		return nil, error2.New(code.ErrInvalidBackupPolicy, "retention must be positive, fullEvery must not be negative")
+	}
+	if req.FullEvery == 0 {
+		req.FullEvery = defaultFullEvery
+	}
+	policy, err := s.policyRepo.Get(s.db, req.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time2.NowUnix()
+	policy.Interval = req.Interval
+	policy.FullEvery = req.FullEvery
+	policy.Retention = req.Retention
+	policy.Disabled = req.Disabled
+	policy.NextAt = now + req.Interval*int64(time.Second/time.Millisecond)
+	policy.UpdatedAt = now
+	if policy.ID != "" {
+		err = s.policyRepo.Update(s.db, policy.ID, policy)
+	} else {
+		policy.ID = id2.StringUUID()
+		policy.AppID = req.AppID
+		policy.CreatedAt = now
+		policy.CreatorID = req.UserID
+		policy.CreatorName = req.UserName
+		err = s.policyRepo.Create(s.db, policy)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &PutPolicyResp{
+		ID: policy.ID,
+	}, nil
+}
+
+type GetPolicyReq struct {
+	AppID string `json:"appID"`
+}
+
+type PolicyVo struct {
+	ID          string `json:"id"`
+	Interval    int64  `json:"interval"`
+	FullEvery   int    `json:"fullEvery"`
+	Retention   int    `json:"retention"`
+	Disabled    bool   `json:"disabled"`
+	NextAt      int64  `json:"nextAt"`
+	CreatedAt   int64  `json:"createdAt"`
+	UpdatedAt   int64  `json:"updatedAt"`
+	CreatorID   string `json:"creatorID"`
+	CreatorName string `json:"creatorName"`
+}
+
+// GetPolicy the id is empty if the app has no policy.
+func (s *snapshot) GetPolicy(ctx context.Context, req *GetPolicyReq) (*PolicyVo, error) {
+	policy, err := s.policyRepo.Get(s.db, req.AppID)
+	if err != nil {
+		return nil, err
+	}
+	return &PolicyVo{
+		ID:          policy.ID,
+		Interval:    policy.Interval,
+		FullEvery:   policy.FullEvery,
+		Retention:   policy.Retention,
+		Disabled:    policy.Disabled,
+		NextAt:      policy.NextAt,
+		CreatedAt:   policy.CreatedAt,
+		UpdatedAt:   policy.UpdatedAt,
+		CreatorID:   policy.CreatorID,
+		CreatorName: policy.CreatorName,
+	}, nil
+}
+
+type DeletePolicyReq struct {
+	AppID string `json:"appID"`
+}
+
+type DeletePolicyResp struct{}
+
+// DeletePolicy the snapshots are kept, they are deleted one by one.
+func (s *snapshot) DeletePolicy(ctx context.Context, req *DeletePolicyReq) (*DeletePolicyResp, error) {
+	if err := s.policyRepo.Delete(s.db, req.AppID); err != nil {
+		return nil, err
+	}
+	return &DeletePolicyResp{}, nil
+}
+
+type CreateSnapshotReq struct {
+	AppID string `json:"appID"`
+	// Incremental only the records changed since the last snapshot are held, if there is one
+	Incremental bool   `json:"incremental"`
+	UserID      string `json:"-"`
+	UserName    string `json:"-"`
+}
+
+type CreateSnapshotResp struct {
+	ID string `json:"id"`
+}
+
+// CreateSnapshot take a snapshot in the background, it is running until the file is stored,
+// it waits for a slot with the scheduled ones.
+func (s *snapshot) CreateSnapshot(ctx context.Context, req *CreateSnapshotReq) (*CreateSnapshotResp, error) {
+	fullEvery := 1
+	if req.Incremental {
+		// the chain of the policy, the default one without a policy.
+		policy, err := s.policyRepo.Get(s.db, req.AppID)
+		if err != nil {
+			return nil, err
+		}
+		fullEvery = policy.FullEvery
+	}
+	snap, err := s.create(req.AppID, fullEvery, req.UserID, req.UserName)
+	if err != nil {
+		return nil, err
+	}
+
+	go func(ctx context.Context) {
+		s.acquire(ctx)
+		defer s.release()
+		s.export(ctx, snap)
+	}(service.Detach(ctx))
+	return &CreateSnapshotResp{
+		ID: snap.ID,
+	}, nil
+}
+
+type GetSnapshotReq struct {
+	AppID string `json:"appID"`
+	ID    string `json:"id" binding:"required"`
+}
+
+type SnapshotVo struct {
+	ID          string                 `json:"id"`
+	Type        models.SnapshotType    `json:"type"`
+	BaseID      string                 `json:"baseID"`
+	Since       int64                  `json:"since"`
+	Size        int64                  `json:"size"`
+	Status      models.JobStatus       `json:"status"`
+	Message     string                 `json:"message"`
+	Counts      map[string]interface{} `json:"counts"`
+	CreatedAt   int64                  `json:"createdAt"`
+	UpdatedAt   int64                  `json:"updatedAt"`
+	CreatorID   string                 `json:"creatorID"`
+	CreatorName string                 `json:"creatorName"`
+}
+
+func (s *snapshot) GetSnapshot(ctx context.Context, req *GetSnapshotReq) (*SnapshotVo, error) {
+	snap, err := s.get(req.AppID, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return toVo(snap), nil
+}
+
+type ListSnapshotReq struct {
+	AppID string `json:"appID"`
+	Page  int    `json:"page"`
+	Size  int    `json:"size"`
+}
+
+type ListSnapshotResp struct {
+	List  []*SnapshotVo `json:"list"`
+	Total int64         `json:"total"`
+}
+
+// ListSnapshot the latest come first.
+func (s *snapshot) ListSnapshot(ctx context.Context, req *ListSnapshotReq) (*ListSnapshotResp, error) {
+	snaps, total, err := s.snapshotRepo.List(s.db, &models.SnapshotQuery{
+		AppID: req.AppID,
+	}, req.Page, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*SnapshotVo, 0, len(snaps))
+	for _, snap := range snaps {
+		list = append(list, toVo(snap))
+	}
+	return &ListSnapshotResp{
+		List:  list,
+		Total: total,
+	}, nil
+}
+
+type RestoreSnapshotReq struct {
+	AppID string `json:"appID"`
+	ID    string `json:"id" binding:"required"`
+	// DryRun check the snapshot against the app, nothing is written
+	DryRun bool `json:"dryRun"`
+	// Strategies what to do with the objects which exist in the app, see aide.ImportOption
+	Strategies map[string]string `json:"strategies"`
+	UserID     string            `json:"-"`
+	UserName   string            `json:"-"`
+}
+
+// RestoreSnapshotResp the report of the import.
+type RestoreSnapshotResp struct {
+	*backup.Report
+	// Merged the records of the snapshot are merged into the tables, those created after it are kept,
+	// but in the tables cleared by overwriting them.
+	Merged bool `json:"merged"`
+}
+
+// RestoreSnapshot restore the app to the snapshot, the full one it is taken after is restored first,
+// then the incremental ones up to it. the records are merged, the tables are not cleared before.
+// the report is returned if the snapshot can not be restored.
+func (s *snapshot) RestoreSnapshot(ctx context.Context, req *RestoreSnapshotReq) (*RestoreSnapshotResp, error) {
+	chain, err := s.chain(req.AppID, req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	readers := make([]io.Reader, 0, len(chain))
+	for _, snap := range chain {
+		r, err := s.store.Get(ctx, snap.Key)
+		if err != nil {
+			logger.Logger.WithName("RestoreSnapshot").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			return nil, err
+		}
+		defer r.Close()
+		readers = append(readers, r)
+	}
+
+	report, err := s.archiver.ImportArchives(ctx, readers, &aide.ImportOption{
+		AppID:      req.AppID,
+		UserID:     req.UserID,
+		UserName:   req.UserName,
+		DryRun:     req.DryRun,
+		Strategies: req.Strategies,
+	})
+	switch {
+	case errors.Is(err, backup.ErrInvalid):
+		return &RestoreSnapshotResp{Report: report, Merged: true}, nil
+	case errors.Is(err, backup.ErrCorrupted), errors.Is(err, backup.ErrUnsupported), errors.Is(err, backup.ErrChain):
+		return nil, error2.New(code.ErrInvalidRestore, err.Error())
+	case errors.Is(err, backup.ErrPartial):
+		logger.Logger.WithName("RestoreSnapshot").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return nil, error2.New(code.ErrRestoreIncomplete, err.Error())
+	case err != nil:
+		logger.Logger.WithName("RestoreSnapshot").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return nil, err
+	}
+	return &RestoreSnapshotResp{Report: report, Merged: true}, nil
+}
+
+// chain the full snapshot and the incremental ones after it, up to the snapshot, in order.
+func (s *snapshot) chain(appID, id string) ([]*models.Snapshot, error) {
+	snap, err := s.get(appID, id)
+	if err != nil {
+		return nil, err
+	}
+	if snap.Status != models.JobSucceed {
+		return nil, error2.New(code.ErrSnapshotNotFinished)
+	}
+	if snap.Type == models.FullSnapshot {
+		return []*models.Snapshot{snap}, nil
+	}
+
+	base, err := s.get(appID, snap.BaseID)
+	if err != nil {
+		return nil, err
+	}
+	incrementals, _, err := s.snapshotRepo.List(s.db, &models.SnapshotQuery{
+		AppID:  appID,
+		BaseID: base.ID,
+		Type:   models.IncrementalSnapshot,
+		Status: models.JobSucceed,
+	}, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	chain := []*models.Snapshot{base}
+	for i := len(incrementals) - 1; i >= 0; i-- {
+		if incrementals[i].CreatedAt <= snap.CreatedAt {
+			chain = append(chain, incrementals[i])
+		}
+	}
+	return chain, nil
+}
+
+func (s *snapshot) get(appID, id string) (*models.Snapshot, error) {
+	snap, err := s.snapshotRepo.Get(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if snap.ID == "" || snap.AppID != appID {
+		return nil, error2.New(code.ErrNotExistSnapshot)
+	}
+	return snap, nil
+}
+
+func toVo(snap *models.Snapshot) *SnapshotVo {
+	return &SnapshotVo{
+		ID:          snap.ID,
+		Type:        snap.Type,
+		BaseID:      snap.BaseID,
+		Since:       snap.Since,
+		Size:        snap.Size,
+		Status:      snap.Status,
+		Message:     snap.Message,
+		Counts:      snap.Counts,
+		CreatedAt:   snap.CreatedAt,
+		UpdatedAt:   snap.UpdatedAt,
+		CreatorID:   snap.CreatorID,
+		CreatorName: snap.CreatorName,
+	}
+}
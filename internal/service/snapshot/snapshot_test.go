@@ -0,0 +1,203 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/pkg/backup"
+	"github.com/quanxiang-cloud/form/pkg/backup/aide"
+	"github.com/quanxiang-cloud/form/pkg/backup/store"
+	"gorm.io/gorm"
+)
+
+type fakePolicies struct {
+	models.BackupPolicyRepo
+	policy *models.BackupPolicy
+}
+
+func (f *fakePolicies) Get(db *gorm.DB, appID string) (*models.BackupPolicy, error) {
+	if f.policy == nil || f.policy.AppID != appID {
+		return &models.BackupPolicy{}, nil
+	}
+	return f.policy, nil
+}
+
+func (f *fakePolicies) List(db *gorm.DB, query *models.BackupPolicyQuery, page, size int) ([]*models.BackupPolicy, int64, error) {
+	if f.policy == nil || f.policy.NextAt > query.DueAt {
+		return nil, 0, nil
+	}
+	return []*models.BackupPolicy{f.policy}, 1, nil
+}
+
+func (f *fakePolicies) Claim(db *gorm.DB, id string, nextAt, to int64) (bool, error) {
+	if f.policy.NextAt != nextAt {
+		return false, nil
+	}
+	f.policy.NextAt = to
+	return true, nil
+}
+
+// fakeSnapshots the later created come first.
+type fakeSnapshots struct {
+	models.SnapshotRepo
+	snaps []*models.Snapshot
+}
+
+func (f *fakeSnapshots) Create(db *gorm.DB, snap *models.Snapshot) error {
+	f.snaps = append([]*models.Snapshot{snap}, f.snaps...)
+	return nil
+}
+
+func (f *fakeSnapshots) Update(db *gorm.DB, id string, update *models.Snapshot) error {
+	snap, _ := f.Get(db, id)
+	snap.Status, snap.Size, snap.Counts, snap.Message = update.Status, update.Size, update.Counts, update.Message
+	return nil
+}
+
+func (f *fakeSnapshots) Get(db *gorm.DB, id string) (*models.Snapshot, error) {
+	for _, snap := range f.snaps {
+		if snap.ID == id {
+			return snap, nil
+		}
+	}
+	return &models.Snapshot{}, nil
+}
+
+func (f *fakeSnapshots) match(snap *models.Snapshot, query *models.SnapshotQuery) bool {
+	ids := make(map[string]bool)
+	for _, id := range query.IDs {
+		ids[id] = true
+	}
+	return (query.AppID == "" || snap.AppID == query.AppID) && (query.BaseID == "" || snap.BaseID == query.BaseID) &&
+		(query.Type == "" || snap.Type == query.Type) && (query.Status == "" || snap.Status == query.Status) &&
+		(len(ids) == 0 || ids[snap.ID])
+}
+
+func (f *fakeSnapshots) List(db *gorm.DB, query *models.SnapshotQuery, page, size int) ([]*models.Snapshot, int64, error) {
+	list := make([]*models.Snapshot, 0)
+	for _, snap := range f.snaps {
+		if f.match(snap, query) {
+			list = append(list, snap)
+		}
+	}
+	count := int64(len(list))
+	if size > 0 && len(list) > size {
+		list = list[:size]
+	}
+	return list, count, nil
+}
+
+func (f *fakeSnapshots) Delete(db *gorm.DB, query *models.SnapshotQuery) error {
+	kept := make([]*models.Snapshot, 0)
+	for _, snap := range f.snaps {
+		if !f.match(snap, query) {
+			kept = append(kept, snap)
+		}
+	}
+	f.snaps = kept
+	return nil
+}
+
+// fakeArchiver the archive is the since of the export.
+type fakeArchiver struct {
+	imported []string
+}
+
+func (f *fakeArchiver) ExportArchive(ctx context.Context, opts *aide.ExportOption, w io.Writer) (*backup.Manifest, error) {
+	if _, err := w.Write([]byte(opts.AppID)); err != nil {
+		return nil, err
+	}
+	return &backup.Manifest{AppID: opts.AppID, Counts: map[string]int{"tables": 1}, Records: map[string]int{"t1": 2}}, nil
+}
+
+func (f *fakeArchiver) ImportArchives(ctx context.Context, rs []io.Reader, opts *aide.ImportOption) (*backup.Report, error) {
+	f.imported = nil
+	for _, r := range rs {
+		body, _ := ioutil.ReadAll(r)
+		f.imported = append(f.imported, string(body))
+	}
+	return &backup.Report{Valid: true}, nil
+}
+
+func TestSchedule(t *testing.T) {
+	local, err := store.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies := &fakePolicies{policy: &models.BackupPolicy{ID: "p", AppID: "app", Interval: 3600, FullEvery: 2, Retention: 1, NextAt: 1}}
+	snaps := &fakeSnapshots{}
+	archiver := &fakeArchiver{}
+	s := &snapshot{
+		policyRepo:   policies,
+		snapshotRepo: snaps,
+		store:        local,
+		archiver:     archiver,
+		exports:      make(chan struct{}, 2),
+	}
+	ctx := context.Background()
+
+	// a full snapshot, then an incremental one after it.
+	s.schedule(ctx, 1)
+	s.schedule(ctx, 1)
+	if len(snaps.snaps) != 1 || policies.policy.NextAt != 3600001 {
+		t.Fatalf("snapshots %d, next at %d", len(snaps.snaps), policies.policy.NextAt)
+	}
+	s.schedule(ctx, 3600001)
+	full, incremental := snaps.snaps[1], snaps.snaps[0]
+	if full.Type != models.FullSnapshot || full.Status != models.JobSucceed || full.Size != 3 || full.Counts[recordsKey] != 2 {
+		t.Fatalf("full %+v", full)
+	}
+	if incremental.Type != models.IncrementalSnapshot || incremental.BaseID != full.ID || incremental.Since != full.CreatedAt {
+		t.Fatalf("incremental %+v", incremental)
+	}
+
+	if _, err = s.RestoreSnapshot(ctx, &RestoreSnapshotReq{AppID: "app", ID: incremental.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if len(archiver.imported) != 2 {
+		t.Fatalf("imported %v", archiver.imported)
+	}
+	if _, err = s.RestoreSnapshot(ctx, &RestoreSnapshotReq{AppID: "other", ID: incremental.ID}); err == nil {
+		t.Fatal("the snapshot of another app should not be restored")
+	}
+
+	// the next chain is full, the first is pruned.
+	s.schedule(ctx, 7200001)
+	if len(snaps.snaps) != 1 || snaps.snaps[0].Type != models.FullSnapshot {
+		t.Fatalf("snapshots %+v", snaps.snaps)
+	}
+	if _, err = local.Get(ctx, full.Key); err != store.ErrNotExist {
+		t.Fatalf("pruned: %v", err)
+	}
+	r, err := local.Get(ctx, snaps.snaps[0].Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if body, _ := ioutil.ReadAll(r); !bytes.Equal(body, []byte("app")) {
+		t.Fatalf("body %s", body)
+	}
+}
+
+func TestCreateBoundsTheChain(t *testing.T) {
+	snaps := &fakeSnapshots{}
+	s := &snapshot{snapshotRepo: snaps}
+	types := make([]models.SnapshotType, 0)
+	for i := 0; i <= defaultFullEvery; i++ {
+		// a policy saved with fullEvery 0 before it was required.
+		snap, err := s.create("app", 0, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		snap.Status = models.JobSucceed
+		types = append(types, snap.Type)
+	}
+	if types[0] != models.FullSnapshot || types[defaultFullEvery-1] != models.IncrementalSnapshot ||
+		types[defaultFullEvery] != models.FullSnapshot {
+		t.Fatalf("types %v", types)
+	}
+}
@@ -0,0 +1,281 @@
+package tables
+
+import (
+	"context"
+	"strings"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"gorm.io/gorm"
+)
+
+const (
+	subTableType = "sub_table"
+	appIDKey     = "appID"
+	tableIDKey   = "tableID"
+	maxSize      = 999
+)
+
+type CloneTableReq struct {
+	AppID   string `json:"appID"`
+	TableID string `json:"tableID" binding:"required"`
+	// DstAppID the app to clone into, the same app when blank.
+	DstAppID string `json:"dstAppID"`
+	Title    string `json:"title"`
+	// WithPermit copy the permits of the roles, only within the same app.
+	WithPermit bool   `json:"withPermit"`
+	UserID     string `json:"-"`
+	UserName   string `json:"-"`
+}
+
+type CloneTableResp struct {
+	TableID string `json:"tableID"`
+	// TableIDs old table id to new table id, sub tables included.
+	TableIDs map[string]string `json:"tableIDs"`
+}
+
+// CloneTable copy the table and its sub tables to new table ids.
+func (t *table) CloneTable(ctx context.Context, req *CloneTableReq) (*CloneTableResp, error) {
+	content, err := t.snapshot(req.AppID, req.TableID)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 0 {
+		return nil, error2.New(code.ErrNotExistTable)
+	}
+	if req.Title != "" {
+		content[0].Title = req.Title
+	}
+	dstAppID := req.DstAppID
+	if dstAppID == "" {
+		dstAppID = req.AppID
+	}
+	var (
+		ids      map[string]string
+		deferred Effects
+	)
+	// the tables, schemas, relations and permits are all or nothing,
+	// the serials, the swagger and the indexes are made after the commit.
+	err = t.db.Transaction(func(tx *gorm.DB) error {
+		deferred = nil
+		ids, err = t.restore(ctx, tx, req.AppID, dstAppID, content, req.UserID, req.UserName, &deferred)
+		if err != nil {
+			return err
+		}
+		if req.WithPermit && dstAppID == req.AppID {
+			return t.clonePermit(tx, req.AppID, ids, req.UserID, req.UserName)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = deferred.Run(ctx); err != nil {
+		return nil, err
+	}
+	return &CloneTableResp{
+		TableID:  ids[req.TableID],
+		TableIDs: ids,
+	}, nil
+}
+
+// snapshot the table and its sub tables, the main table comes first.
+func (t *table) snapshot(appID, tableID string) (models.TemplateContent, error) {
+	content := make(models.TemplateContent, 0)
+	visited := make(map[string]bool)
+	queue := []string{tableID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		tables, err := t.tableRepo.Get(t.db, appID, id)
+		if err != nil {
+			return nil, err
+		}
+		if tables.ID == "" {
+			continue
+		}
+		schema, err := t.tableSchemaRepo.Get(t.db, appID, id)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, &models.TemplateTable{
+			TableID: id,
+			Title:   schema.Title,
+			Source:  schema.Source,
+			Schema:  tables.Schema,
+			Config:  tables.Config,
+		})
+
+		relations, err := listRelations(t.db, t.tableRelationRepo, &models.TableRelationQuery{
+			AppID:        appID,
+			TableID:      id,
+			SubTableType: subTableType,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, relation := range relations {
+			queue = append(queue, relation.SubTableID)
+		}
+	}
+	return content, nil
+}
+
+// restore create the tables with new ids through the same guidance as designing a table,
+// so relations, serials and the swagger are prepared as usual, those out of the database are deferred.
+func (t *table) restore(ctx context.Context, tx *gorm.DB, srcAppID, dstAppID string, content models.TemplateContent,
+	userID, userName string, deferred *Effects) (map[string]string, error) {
+	ids := make(map[string]string, len(content))
+	for _, definition := range content {
+		ids[definition.TableID] = id2.String(5)
+	}
+	// sub tables first, the main table is the first one.
+	for i := len(content) - 1; i >= 0; i-- {
+		definition := content[i]
+		schema, _ := remapIDs(map[string]interface{}(definition.Schema), srcAppID, dstAppID, ids).(map[string]interface{})
+		if schema == nil {
+			schema = make(map[string]interface{})
+		}
+		if definition.Title != "" {
+			schema[_title] = definition.Title
+		}
+		bus := &Bus{
+			UserID:   userID,
+			UserName: userName,
+			AppID:    dstAppID,
+			TableID:  ids[definition.TableID],
+			Schema:   schema,
+			Source:   definition.Source,
+			Tx:       tx,
+			Deferred: deferred,
+		}
+		if _, err := t.guidance.Do(ctx, bus); err != nil {
+			return nil, err
+		}
+		if len(definition.Config) == 0 {
+			continue
+		}
+		config, _ := remapIDs(map[string]interface{}(definition.Config), srcAppID, dstAppID, ids).(map[string]interface{})
+		err := t.tableRepo.Update(tx, dstAppID, bus.TableID, &models.Table{
+			Config: config,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// remapIDs replace the table ids of the objects which refer to a copied table,
+// the references to other tables are kept as they are.
+func remapIDs(value interface{}, srcAppID, dstAppID string, ids map[string]string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			m[key] = remapIDs(elem, srcAppID, dstAppID, ids)
+		}
+		tableID, ok := m[tableIDKey].(string)
+		if !ok {
+			return m
+		}
+		if newID, ok := ids[tableID]; ok {
+			m[tableIDKey] = newID
+			if appID, ok := m[appIDKey].(string); ok && appID == srcAppID {
+				m[appIDKey] = dstAppID
+			}
+		}
+		return m
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i, elem := range v {
+			arr[i] = remapIDs(elem, srcAppID, dstAppID, ids)
+		}
+		return arr
+	}
+	return value
+}
+
+func (t *table) clonePermit(tx *gorm.DB, appID string, ids map[string]string, userID, userName string) error {
+	roleIDs := make([]string, 0)
+	for page := 1; ; page++ {
+		roles, _, err := t.roleRepo.List(tx, &models.RoleQuery{
+			AppID: appID,
+		}, page, maxSize)
+		if err != nil {
+			return err
+		}
+		for _, role := range roles {
+			roleIDs = append(roleIDs, role.ID)
+		}
+		if len(roles) < maxSize {
+			break
+		}
+	}
+	if len(roleIDs) == 0 {
+		return nil
+	}
+	// read all pages before writing, the copies would shift the pages.
+	copies := make([]*models.Permit, 0)
+	for page := 1; ; page++ {
+		permits, _, err := t.permitRepo.List(tx, &models.PermitQuery{
+			RoleIDs: roleIDs,
+		}, page, maxSize)
+		if err != nil {
+			return err
+		}
+		for _, value := range permits {
+			path, ok := remapPath(value.Path, ids)
+			if !ok {
+				continue
+			}
+			copies = append(copies, &models.Permit{
+				ID:          id2.StringUUID(),
+				RoleID:      value.RoleID,
+				Path:        path,
+				Params:      value.Params,
+				Response:    value.Response,
+				Condition:   value.Condition,
+				Method:      value.Method,
+				ParamsAll:   value.ParamsAll,
+				ResponseAll: value.ResponseAll,
+				CreatedAt:   time2.NowUnix(),
+				CreatorID:   userID,
+				CreatorName: userName,
+			})
+		}
+		if len(permits) < maxSize {
+			break
+		}
+	}
+	if len(copies) == 0 {
+		return nil
+	}
+	return t.permitRepo.BatchCreate(tx, copies...)
+}
+
+// remapPath replace the table id segments of a form api path,
+// like /api/v1/form/{appID}/home/form/{tableID}/create or .../form/{tableID}/{tableID}_create.r .
+func remapPath(path string, ids map[string]string) (string, bool) {
+	segments := strings.Split(path, "/")
+	matched := false
+	for index, segment := range segments {
+		for oldID, newID := range ids {
+			if segment != oldID && !strings.HasPrefix(segment, oldID+"_") {
+				continue
+			}
+			segments[index] = newID + strings.TrimPrefix(segment, oldID)
+			matched = true
+			break
+		}
+	}
+	return strings.Join(segments, "/"), matched
+}
@@ -0,0 +1,171 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/pkg/misc/client"
+	"gorm.io/gorm"
+)
+
+func TestRemapIDs(t *testing.T) {
+	ids := map[string]string{"abcde": "fghij"}
+	schema := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"sub": map[string]interface{}{
+				"x-component-props": map[string]interface{}{
+					"appID":   "app1",
+					"tableID": "abcde",
+				},
+			},
+			"associated": map[string]interface{}{
+				"x-component-props": map[string]interface{}{
+					"appID":   "app2",
+					"tableID": "other",
+				},
+			},
+		},
+	}
+	expect := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"sub": map[string]interface{}{
+				"x-component-props": map[string]interface{}{
+					"appID":   "app3",
+					"tableID": "fghij",
+				},
+			},
+			"associated": map[string]interface{}{
+				"x-component-props": map[string]interface{}{
+					"appID":   "app2",
+					"tableID": "other",
+				},
+			},
+		},
+	}
+	got := remapIDs(schema, "app1", "app3", ids)
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("expect %v, got %v", expect, got)
+	}
+}
+
+func TestRemapPath(t *testing.T) {
+	ids := map[string]string{"abcde": "fghij"}
+	cases := []struct {
+		path    string
+		expect  string
+		matched bool
+	}{
+		{"/api/v1/form/app1/home/form/abcde/create", "/api/v1/form/app1/home/form/fghij/create", true},
+		{"/system/app/app1/raw/inner/form/abcde/abcde_get.r", "/system/app/app1/raw/inner/form/fghij/fghij_get.r", true},
+		{"/api/v1/form/app1/home/form/other/create", "/api/v1/form/app1/home/form/other/create", false},
+	}
+	for _, c := range cases {
+		path, matched := remapPath(c.path, ids)
+		if path != c.expect || matched != c.matched {
+			t.Fatalf("expect %s %v, got %s %v", c.expect, c.matched, path, matched)
+		}
+	}
+}
+
+type fakeRoleRepo struct {
+	models.RoleRepo
+	roles []*models.Role
+}
+
+func (f *fakeRoleRepo) List(db *gorm.DB, query *models.RoleQuery, page, size int) ([]*models.Role, int64, error) {
+	from, to := bounds(len(f.roles), page, size)
+	return f.roles[from:to], int64(len(f.roles)), nil
+}
+
+type fakePermitRepo struct {
+	models.PermitRepo
+	permits []*models.Permit
+}
+
+func (f *fakePermitRepo) List(db *gorm.DB, query *models.PermitQuery, page, size int) ([]*models.Permit, int64, error) {
+	from, to := bounds(len(f.permits), page, size)
+	return f.permits[from:to], int64(len(f.permits)), nil
+}
+
+func (f *fakePermitRepo) BatchCreate(db *gorm.DB, permits ...*models.Permit) error {
+	f.permits = append(f.permits, permits...)
+	return nil
+}
+
+func bounds(total, page, size int) (int, int) {
+	from, to := (page-1)*size, page*size
+	if from > total {
+		from = total
+	}
+	if to > total {
+		to = total
+	}
+	return from, to
+}
+
+func TestClonePermit(t *testing.T) {
+	permits := &fakePermitRepo{}
+	for i := 0; i < maxSize+1; i++ {
+		permits.permits = append(permits.permits, &models.Permit{
+			RoleID: "r1",
+			Path:   fmt.Sprintf("/api/v1/form/app/home/form/abcde/%d", i),
+		})
+	}
+	permits.permits = append(permits.permits, &models.Permit{RoleID: "r1", Path: "/api/v1/form/app/home/form/other/get"})
+	tables := &table{
+		roleRepo:   &fakeRoleRepo{roles: []*models.Role{{ID: "r1"}}},
+		permitRepo: permits,
+	}
+	if err := tables.clonePermit(nil, "app", map[string]string{"abcde": "fghij"}, "u", "user"); err != nil {
+		t.Fatal(err)
+	}
+	copies := permits.permits[maxSize+2:]
+	if len(copies) != maxSize+1 {
+		t.Fatalf("every page should be copied once, got %d", len(copies))
+	}
+	if copies[maxSize].Path != fmt.Sprintf("/api/v1/form/app/home/form/fghij/%d", maxSize) {
+		t.Fatalf("the path of the last page, got %s", copies[maxSize].Path)
+	}
+}
+
+type fakePolyAPI struct {
+	client.PolyAPI
+	registered []string
+}
+
+func (f *fakePolyAPI) RegSwagger(ctx context.Context, host, swag, appID, tableID, tableName string) (*client.RegSwaggerResp, error) {
+	f.registered = append(f.registered, tableID)
+	return &client.RegSwaggerResp{}, nil
+}
+
+type nopGuidance struct{}
+
+func (nopGuidance) Do(ctx context.Context, bus *Bus) (*DoResponse, error) {
+	return nil, nil
+}
+
+func TestDeferredSwagger(t *testing.T) {
+	poly := &fakePolyAPI{}
+	reg := &registerSwagger{polyAPI: poly, next: nopGuidance{}}
+	var deferred Effects
+	for _, tableID := range []string{"a", "b"} {
+		if _, err := reg.Do(context.Background(), &Bus{AppID: "app", TableID: tableID, Deferred: &deferred}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(poly.registered) != 0 {
+		t.Fatalf("the swagger is registered before the commit, %v", poly.registered)
+	}
+	if err := deferred.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(poly.registered, []string{"a", "b"}) {
+		t.Fatalf("registered %v", poly.registered)
+	}
+	if _, err := reg.Do(context.Background(), &Bus{AppID: "app", TableID: "c"}); err != nil || len(poly.registered) != 3 {
+		t.Fatalf("the swagger is registered at once without a transaction, %v %v", poly.registered, err)
+	}
+}
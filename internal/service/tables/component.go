@@ -38,23 +38,31 @@ func (c *component) Do(ctx context.Context, bus *Bus) (*DoResponse, error) {
 	if err != nil {
 		return nil, err
 	}
-	c.subDo(ctx, asMap, &base{
+	err = c.subDo(ctx, asMap, &base{
+		db:      bus.tx(c.db),
 		appID:   bus.AppID,
 		tableID: bus.TableID,
+		owner:   bus,
 	})
+	if err != nil {
+		return nil, err
+	}
 	return c.next.Do(ctx, bus)
 }
 
 type base struct {
+	db        *gorm.DB
 	appID     string
 	tableID   string
 	fieldName string
 
 	fieldValue types.M
 	components string
+	// owner the bus of the table, the serials are kept in redis, out of its transaction.
+	owner *Bus
 }
 
-func (c *component) subDo(ctx context.Context, properties types.M, bus *base) {
+func (c *component) subDo(ctx context.Context, properties types.M, bus *base) error {
 	// 判断是否是 数据组件
 	for fieldName, fieldValue := range properties {
 		isLayout := util.IsLayoutComponent(fieldValue)
@@ -69,7 +77,9 @@ func (c *component) subDo(ctx context.Context, properties types.M, bus *base) {
 			if err != nil {
 				continue
 			}
-			c.subDo(ctx, toMap, bus)
+			if err = c.subDo(ctx, toMap, bus); err != nil {
+				return err
+			}
 		}
 		asMap, err := util.GetAsMap(fieldValue)
 		if err != nil {
@@ -80,12 +90,18 @@ func (c *component) subDo(ctx context.Context, properties types.M, bus *base) {
 		bus.fieldName = fieldName
 		bus.fieldValue = asMap
 		if components == "Serial" {
-			c.doSerial(ctx, bus)
+			serial := *bus
+			bus.owner.effect(ctx, func(ctx context.Context) error {
+				return c.doSerial(ctx, &serial)
+			})
 		}
-		if components == "SubTable" || components == "AssociatedRecords" {
-			c.doRelation(ctx, bus)
+		if components == "SubTable" || components == "AssociatedRecords" || components == "AggregationRecords" {
+			if err = c.doRelation(ctx, bus); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
 }
 
 func (c *component) doRelation(ctx context.Context, bus *base) error {
@@ -107,10 +123,13 @@ func (c *component) doRelation(ctx context.Context, bus *base) error {
 		Filter:     cp.Columns,
 	}
 	tables.SubTableType = cp.Subordination
-	if bus.components == "AssociatedRecords" {
-		tables.SubTableType = "associated_records"
+	switch bus.components {
+	case "AssociatedRecords":
+		tables.SubTableType = associatedRecordsType
+	case "AggregationRecords":
+		tables.SubTableType = aggregationType
 	}
-	err = c.addRepo(tables)
+	err = c.addRepo(bus.db, tables)
 	if err != nil {
 		return err
 	}
@@ -125,22 +144,26 @@ func (c *component) doRelation(ctx context.Context, bus *base) error {
 		return nil
 	}
 	bases := &base{
+		db:      bus.db,
 		appID:   cp.AppID,
 		tableID: cp.TableID,
+		owner:   bus.owner,
 	}
-	c.subDo(ctx, mapToMap, bases)
-	return nil
+	return c.subDo(ctx, mapToMap, bases)
 }
 
-func (c *component) addRepo(table *models.TableRelation) error {
-	relation, err := c.tableRelationRepo.Get(c.db, table.TableID, table.FieldName)
+func (c *component) addRepo(db *gorm.DB, table *models.TableRelation) error {
+	if err := checkCycle(db, c.tableRelationRepo, table); err != nil {
+		return err
+	}
+	relation, err := c.tableRelationRepo.Get(db, table.TableID, table.FieldName)
 	if err != nil {
 		return err
 	}
 	if relation.ID == "" { // create
-		return c.tableRelationRepo.BatchCreate(c.db, table)
+		return c.tableRelationRepo.BatchCreate(db, table)
 	}
-	return c.tableRelationRepo.Update(c.db, table.TableID, table.FieldName, table)
+	return c.tableRelationRepo.Update(db, table.TableID, table.FieldName, table)
 }
 
 func (c *component) doSerial(ctx context.Context, bus *base) error {
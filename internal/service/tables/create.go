@@ -27,7 +27,7 @@ type webTable struct {
 }
 
 func (w *webTable) Do(ctx context.Context, bus *Bus) (*DoResponse, error) {
-	one, err := w.tableRepo.Get(w.db, bus.AppID, bus.TableID)
+	one, err := w.tableRepo.Get(bus.tx(w.db), bus.AppID, bus.TableID)
 	if err != nil {
 		return nil, err
 	}
@@ -42,12 +42,12 @@ func (w *webTable) Do(ctx context.Context, bus *Bus) (*DoResponse, error) {
 		tables.TableID = bus.TableID
 		tables.AppID = bus.AppID
 		tables.CreatedAt = time2.NowUnix()
-		err = w.tableRepo.BatchCreate(w.db, tables)
+		err = w.tableRepo.BatchCreate(bus.tx(w.db), tables)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		err = w.tableRepo.Update(w.db, bus.AppID, bus.TableID, tables)
+		err = w.tableRepo.Update(bus.tx(w.db), bus.AppID, bus.TableID, tables)
 		if err != nil {
 			return nil, err
 		}
@@ -112,7 +112,7 @@ func (t *tableSchema) Do(ctx context.Context, bus *Bus) (*DoResponse, error) {
 		tables.CreatedAt = time2.NowUnix()
 		tables.CreatorName = bus.UserName
 		tables.CreatorID = bus.UserID
-		err = t.tableSchemaRepo.BatchCreate(t.db, tables)
+		err = t.tableSchemaRepo.BatchCreate(bus.tx(t.db), tables)
 		if err != nil {
 			return nil, err
 		}
@@ -122,7 +122,7 @@ func (t *tableSchema) Do(ctx context.Context, bus *Bus) (*DoResponse, error) {
 		tables.UpdatedAt = time2.NowUnix()
 		tables.EditorID = bus.UserID
 		tables.EditorName = bus.UserName
-		err = t.tableSchemaRepo.Update(t.db, bus.AppID, bus.TableID, tables)
+		err = t.tableSchemaRepo.Update(bus.tx(t.db), bus.AppID, bus.TableID, tables)
 		if err != nil {
 			return nil, err
 		}
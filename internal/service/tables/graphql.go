@@ -0,0 +1,133 @@
+package tables
+
+import (
+	"context"
+	"sort"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/pkg/graphql"
+)
+
+const idKey = "_id"
+
+type GetGraphQLSchemaReq struct {
+	AppID string `json:"appID"`
+}
+
+type GetGraphQLSchemaResp struct {
+	*graphql.Schema
+	SDL string `json:"sdl"`
+}
+
+// GetGraphQLSchema the graphql types of the tables of the app, the fields of the sub tables
+// and the associated records are the lists of the related types.
+func (t *table) GetGraphQLSchema(ctx context.Context, req *GetGraphQLSchemaReq) (*GetGraphQLSchemaResp, error) {
+	schemas, _, err := t.tableSchemaRepo.List(t.db, &models.TableSchemaQuery{
+		AppID: req.AppID,
+	}, 1, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	relations, _, err := t.tableRelationRepo.List(t.db, &models.TableRelationQuery{
+		AppID: req.AppID,
+	}, 1, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	schema := graphQLSchema(schemas, relations)
+	return &GetGraphQLSchemaResp{
+		Schema: schema,
+		SDL:    schema.SDL(),
+	}, nil
+}
+
+func graphQLSchema(schemas []*models.TableSchema, relations []*models.TableRelation) *graphql.Schema {
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].TableID < schemas[j].TableID
+	})
+	names := make(map[string]string, len(schemas))
+	for _, schema := range schemas {
+		names[schema.TableID] = graphql.TypeName(schema.TableID)
+	}
+	related := make(map[string][]*models.TableRelation)
+	for _, relation := range relations {
+		// aggregations are stored as the values of the records.
+		if relation.SubTableType == aggregationType || names[relation.SubTableID] == "" {
+			continue
+		}
+		related[relation.TableID] = append(related[relation.TableID], relation)
+	}
+
+	result := &graphql.Schema{
+		Types: make([]*graphql.Type, 0, len(schemas)),
+	}
+	for _, schema := range schemas {
+		typ := &graphql.Type{
+			Name:        names[schema.TableID],
+			TableID:     schema.TableID,
+			Description: schema.Title,
+			Fields:      make([]*graphql.Field, 0, len(schema.Schema)),
+		}
+		relationFields := make(map[string]*models.TableRelation, len(related[schema.TableID]))
+		for _, relation := range related[schema.TableID] {
+			relationFields[relation.FieldName] = relation
+		}
+		keys := make([]string, 0, len(schema.Schema)+len(relationFields))
+		for key := range schema.Schema {
+			if _, ok := relationFields[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		for key := range relationFields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if _, ok := schema.Schema[idKey]; !ok {
+			keys = append([]string{idKey}, keys...)
+		}
+
+		for _, key := range keys {
+			field := &graphql.Field{
+				Name: graphql.TypeName(key),
+				Key:  key,
+			}
+			if relation, ok := relationFields[key]; ok {
+				field.Type = names[relation.SubTableID]
+				field.List = true
+				field.Relation = true
+			} else {
+				props := schema.Schema[key]
+				field.Description = props.Title
+				field.Type, field.List = scalarType(key, props)
+			}
+			if typ.Field(field.Name) != nil {
+				// the names of the keys clash after replacing the invalid characters.
+				continue
+			}
+			typ.Fields = append(typ.Fields, field)
+		}
+		result.Types = append(result.Types, typ)
+	}
+	return result
+}
+
+func scalarType(key string, props models.SchemaProps) (string, bool) {
+	if key == idKey {
+		return graphql.ID, false
+	}
+	switch props.Type {
+	case "string", "datetime":
+		return graphql.String, false
+	case "number":
+		return graphql.Float, false
+	case "boolean":
+		return graphql.Boolean, false
+	case "array":
+		if props.Items != nil {
+			if item, _ := scalarType("", *props.Items); item != graphql.JSON {
+				return item, true
+			}
+		}
+	}
+	return graphql.JSON, false
+}
@@ -0,0 +1,49 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/pkg/graphql"
+)
+
+func TestGraphQLSchema(t *testing.T) {
+	schemas := []*models.TableSchema{
+		{TableID: "order", Title: "订单", Schema: models.SchemaProperties{
+			"_id":    {Type: "string"},
+			"amount": {Type: "number", Title: "金额"},
+			"tags":   {Type: "array", Items: &models.SchemaProps{Type: "string"}},
+			"addr":   {Type: "object"},
+			"items":  {Type: "array"},
+		}},
+		{TableID: "item-1", Schema: models.SchemaProperties{"name": {Type: "string"}}},
+	}
+	relations := []*models.TableRelation{
+		{TableID: "order", FieldName: "items", SubTableID: "item-1", SubTableType: subTableType},
+		{TableID: "order", FieldName: "sum", SubTableID: "item-1", SubTableType: aggregationType},
+		{TableID: "order", FieldName: "other", SubTableID: "deleted", SubTableType: associatedRecordsType},
+	}
+	schema := graphQLSchema(schemas, relations)
+	if len(schema.Types) != 2 || schema.Types[0].Name != "item_1" || schema.Types[1].Description != "订单" {
+		t.Fatalf("types %+v", schema.Types)
+	}
+	if f := schema.Types[0].Field("_id"); f == nil || f.Type != graphql.ID {
+		t.Fatal("_id should be added to every type")
+	}
+
+	order := schema.Types[1]
+	for name, expect := range map[string]graphql.Field{
+		"amount": {Type: graphql.Float},
+		"tags":   {Type: graphql.String, List: true},
+		"addr":   {Type: graphql.JSON},
+		"items":  {Type: "item_1", List: true, Relation: true},
+	} {
+		f := order.Field(name)
+		if f == nil || f.Type != expect.Type || f.List != expect.List || f.Relation != expect.Relation {
+			t.Fatalf("field %s: %+v", name, f)
+		}
+	}
+	if order.Field("sum") != nil || order.Field("other") != nil {
+		t.Fatal("aggregations and the relations of unknown tables are not fields")
+	}
+}
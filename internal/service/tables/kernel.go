@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/quanxiang-cloud/form/internal/models"
+	"gorm.io/gorm"
 )
 
 type Bus struct {
@@ -16,9 +17,43 @@ type Bus struct {
 
 	Source models.SourceType `json:"source"` // source 1 是表单驱动，2是模型驱动
 	Update bool              `json:"update"`
+	// Tx the transaction the table is written in, the db of each step if nil.
+	Tx *gorm.DB `json:"-"`
+	// Deferred keep the steps out of the database, like the serials, the swagger and the index,
+	// instead of running them, the owner of Tx runs them after the commit.
+	Deferred *Effects `json:"-"`
 	ConvertSchemas
 }
 
+func (b *Bus) tx(db *gorm.DB) *gorm.DB {
+	if b.Tx != nil {
+		return b.Tx
+	}
+	return db
+}
+
+// effect run the step out of the database, or keep it for the commit.
+func (b *Bus) effect(ctx context.Context, fn func(ctx context.Context) error) error {
+	if b.Deferred != nil {
+		*b.Deferred = append(*b.Deferred, fn)
+		return nil
+	}
+	return fn(ctx)
+}
+
+// Effects the steps out of the database which are deferred to the commit.
+type Effects []func(ctx context.Context) error
+
+// Run the steps in order, it stops at the first error.
+func (e Effects) Run(ctx context.Context) error {
+	for _, fn := range e {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type ConvertSchemas struct {
 	ConvertSchema models.SchemaProperties `json:"convertSchema"`
 	Title         string
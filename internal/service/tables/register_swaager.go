@@ -24,11 +24,18 @@ func (reg *registerSwagger) Do(ctx context.Context, bus *Bus) (*DoResponse, erro
 	if err != nil {
 		return nil, err
 	}
-	regSwagger, err := reg.polyAPI.RegSwagger(ctx, "form", base64.StdEncoding.EncodeToString([]byte(swagger)), bus.AppID, bus.TableID, bus.Title)
+	appID, tableID, title := bus.AppID, bus.TableID, bus.Title
+	err = bus.effect(ctx, func(ctx context.Context) error {
+		regSwagger, err := reg.polyAPI.RegSwagger(ctx, "form", base64.StdEncoding.EncodeToString([]byte(swagger)), appID, tableID, title)
+		if err != nil {
+			return err
+		}
+		logger.Logger.Errorw("msg", "request-id", regSwagger)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	logger.Logger.Errorw("msg", "request-id", regSwagger)
 
 	return reg.next.Do(ctx, bus)
 }
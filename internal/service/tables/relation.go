@@ -0,0 +1,257 @@
+package tables
+
+import (
+	"context"
+	"strings"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"gorm.io/gorm"
+)
+
+const (
+	foreignTableType      = "foreign_table"
+	associatedRecordsType = "associated_records"
+	aggregationType       = "aggregation"
+)
+
+type RelationGraphReq struct {
+	AppID string `json:"appID"`
+}
+
+type RelationGraphResp struct {
+	Nodes []*graphNode `json:"nodes"`
+	Edges []*graphEdge `json:"edges"`
+}
+
+type graphNode struct {
+	TableID string `json:"tableID"`
+	Title   string `json:"title"`
+	// External the table belongs to another app, or is deleted.
+	External bool `json:"external"`
+}
+
+type graphEdge struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	FieldName string `json:"fieldName"`
+	Type      string `json:"type"`
+}
+
+// RelationGraph the tables of the app and the relations between them.
+func (t *table) RelationGraph(ctx context.Context, req *RelationGraphReq) (*RelationGraphResp, error) {
+	schemas := make([]*models.TableSchema, 0)
+	for page := 1; ; page++ {
+		list, _, err := t.tableSchemaRepo.List(t.db, &models.TableSchemaQuery{
+			AppID: req.AppID,
+		}, page, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, list...)
+		if len(list) < maxSize {
+			break
+		}
+	}
+	relations, err := listRelations(t.db, t.tableRelationRepo, &models.TableRelationQuery{
+		AppID: req.AppID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &RelationGraphResp{
+		Nodes: make([]*graphNode, 0, len(schemas)),
+		Edges: make([]*graphEdge, 0, len(relations)),
+	}
+	nodes := make(map[string]bool, len(schemas))
+	for _, schema := range schemas {
+		nodes[schema.TableID] = true
+		resp.Nodes = append(resp.Nodes, &graphNode{
+			TableID: schema.TableID,
+			Title:   schema.Title,
+		})
+	}
+	for _, relation := range relations {
+		resp.Edges = append(resp.Edges, &graphEdge{
+			From:      relation.TableID,
+			To:        relation.SubTableID,
+			FieldName: relation.FieldName,
+			Type:      relation.SubTableType,
+		})
+		for _, id := range []string{relation.TableID, relation.SubTableID} {
+			if nodes[id] {
+				continue
+			}
+			nodes[id] = true
+			resp.Nodes = append(resp.Nodes, &graphNode{
+				TableID:  id,
+				External: true,
+			})
+		}
+	}
+	return resp, nil
+}
+
+// isNested sub tables are expanded recursively, so they must not form a cycle.
+func isNested(relationType string) bool {
+	return relationType == subTableType || relationType == foreignTableType
+}
+
+// checkCycle check whether saving the relation makes the nested tables a cycle.
+func checkCycle(db *gorm.DB, repo models.TableRelationRepo, relation *models.TableRelation) error {
+	if !isNested(relation.SubTableType) {
+		return nil
+	}
+	relations, err := listRelations(db, repo, &models.TableRelationQuery{
+		AppID: relation.AppID,
+	})
+	if err != nil {
+		return err
+	}
+	edges := make(map[string][]string)
+	for _, value := range relations {
+		if !isNested(value.SubTableType) {
+			continue
+		}
+		// the relation of the field is replaced.
+		if value.TableID == relation.TableID && value.FieldName == relation.FieldName {
+			continue
+		}
+		edges[value.TableID] = append(edges[value.TableID], value.SubTableID)
+	}
+	if path := findCycle(edges, relation.TableID, relation.SubTableID); path != nil {
+		return error2.New(code.ErrRelationCycle, strings.Join(path, " -> "))
+	}
+	return nil
+}
+
+// findCycle return the cycle made by adding the edge from -> to, nil if there is none.
+func findCycle(edges map[string][]string, from, to string) []string {
+	visited := make(map[string]bool)
+	var walk func(node string, path []string) []string
+	walk = func(node string, path []string) []string {
+		path = append(path, node)
+		if node == from {
+			return path
+		}
+		if visited[node] {
+			return nil
+		}
+		visited[node] = true
+		for _, next := range edges[node] {
+			if cycle := walk(next, path); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return walk(to, []string{from})
+}
+
+// listRelations all of the relations of the query, page by page.
+func listRelations(db *gorm.DB, repo models.TableRelationRepo, query *models.TableRelationQuery) ([]*models.TableRelation, error) {
+	result := make([]*models.TableRelation, 0)
+	for page := 1; ; page++ {
+		relations, _, err := repo.List(db, query, page, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, relations...)
+		if len(relations) < maxSize {
+			return result, nil
+		}
+	}
+}
+
+// referrers the relations of other tables which refer to the table,
+// those of the other apps included, like the foreign tables across the apps.
+func (t *table) referrers(db *gorm.DB, tableID string) ([]*models.TableRelation, error) {
+	relations, err := listRelations(db, t.tableRelationRepo, &models.TableRelationQuery{
+		SubTableID: tableID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*models.TableRelation, 0, len(relations))
+	for _, relation := range relations {
+		if relation.TableID == tableID {
+			continue
+		}
+		result = append(result, relation)
+	}
+	return result, nil
+}
+
+// detach strip the components of the referrers which refer to the table,
+// and design the referrers again in the transaction, so their schemas and swagger no longer point at it.
+func (t *table) detach(ctx context.Context, tx *gorm.DB, tableID string, referrers []*models.TableRelation) error {
+	type referrer struct {
+		appID   string
+		tableID string
+	}
+	fields := make(map[referrer][]string)
+	order := make([]referrer, 0)
+	for _, relation := range referrers {
+		key := referrer{appID: relation.AppID, tableID: relation.TableID}
+		if _, ok := fields[key]; !ok {
+			order = append(order, key)
+		}
+		fields[key] = append(fields[key], relation.FieldName)
+	}
+	for _, referrer := range order {
+		tables, err := t.tableRepo.Get(tx, referrer.appID, referrer.tableID)
+		if err != nil {
+			return err
+		}
+		if tables.ID == "" {
+			continue
+		}
+		schema := map[string]interface{}(tables.Schema)
+		properties, ok := schema[_properties].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		stripped := false
+		for _, fieldName := range fields[referrer] {
+			stripped = stripComponent(properties, fieldName, tableID) || stripped
+		}
+		if !stripped {
+			continue
+		}
+		_, err = t.guidance.Do(ctx, &Bus{
+			AppID:   referrer.appID,
+			TableID: referrer.tableID,
+			Schema:  schema,
+			Tx:      tx,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripComponent delete the field whose component refers to the table,
+// the fields within layout components included.
+func stripComponent(properties map[string]interface{}, fieldName, tableID string) bool {
+	stripped := false
+	for key, value := range properties {
+		field, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if key == fieldName {
+			if props, ok := field[xComponentProps].(map[string]interface{}); ok && props[tableIDKey] == tableID {
+				delete(properties, key)
+				stripped = true
+				continue
+			}
+		}
+		if nested, ok := field[_properties].(map[string]interface{}); ok {
+			stripped = stripComponent(nested, fieldName, tableID) || stripped
+		}
+	}
+	return stripped
+}
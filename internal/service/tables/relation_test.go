@@ -0,0 +1,103 @@
+package tables
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestFindCycle(t *testing.T) {
+	edges := map[string][]string{
+		"a": {"b"},
+		"b": {"c", "d"},
+	}
+	cases := []struct {
+		from   string
+		to     string
+		expect []string
+	}{
+		{"c", "a", []string{"c", "a", "b", "c"}},
+		{"d", "e", nil},
+		{"e", "e", []string{"e", "e"}},
+		{"a", "d", nil},
+	}
+	for _, c := range cases {
+		path := findCycle(edges, c.from, c.to)
+		if !reflect.DeepEqual(path, c.expect) {
+			t.Fatalf("%s -> %s: expect %v, got %v", c.from, c.to, c.expect, path)
+		}
+	}
+}
+
+func TestStripComponent(t *testing.T) {
+	properties := map[string]interface{}{
+		"name": map[string]interface{}{"type": "string"},
+		"items": map[string]interface{}{
+			"x-component-props": map[string]interface{}{"tableID": "item"},
+		},
+		"layout": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"vendor": map[string]interface{}{
+					"x-component-props": map[string]interface{}{"tableID": "vendor"},
+				},
+			},
+		},
+	}
+	if stripComponent(properties, "items", "other") {
+		t.Fatal("items refers to item, not other")
+	}
+	if !stripComponent(properties, "items", "item") || !stripComponent(properties, "vendor", "vendor") {
+		t.Fatal("the components should be stripped")
+	}
+	expect := map[string]interface{}{
+		"name":   map[string]interface{}{"type": "string"},
+		"layout": map[string]interface{}{"properties": map[string]interface{}{}},
+	}
+	if !reflect.DeepEqual(properties, expect) {
+		t.Fatalf("expect %v, got %v", expect, properties)
+	}
+}
+
+// fakeRelationRepo filter the relations by the sub table, the app is not filtered when it is blank.
+type fakeRelationRepo struct {
+	models.TableRelationRepo
+	relations []*models.TableRelation
+}
+
+func (f *fakeRelationRepo) List(db *gorm.DB, query *models.TableRelationQuery, page, size int) ([]*models.TableRelation, int64, error) {
+	matched := make([]*models.TableRelation, 0)
+	for _, relation := range f.relations {
+		if (query.AppID == "" || relation.AppID == query.AppID) &&
+			(query.SubTableID == "" || relation.SubTableID == query.SubTableID) {
+			matched = append(matched, relation)
+		}
+	}
+	from, to := bounds(len(matched), page, size)
+	return matched[from:to], int64(len(matched)), nil
+}
+
+func TestReferrers(t *testing.T) {
+	repo := &fakeRelationRepo{}
+	for i := 0; i < maxSize; i++ {
+		repo.relations = append(repo.relations, &models.TableRelation{AppID: "app", TableID: fmt.Sprintf("t%d", i), SubTableID: "target"})
+	}
+	repo.relations = append(repo.relations,
+		&models.TableRelation{AppID: "app", TableID: "target", SubTableID: "target"},
+		&models.TableRelation{AppID: "other", TableID: "foreign", SubTableID: "target"},
+		&models.TableRelation{AppID: "app", TableID: "t0", SubTableID: "unrelated"},
+	)
+	tables := &table{tableRelationRepo: repo}
+	referrers, err := tables.referrers(nil, "target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(referrers) != maxSize+1 {
+		t.Fatalf("all pages and the other apps, got %d", len(referrers))
+	}
+	if last := referrers[maxSize]; last.AppID != "other" || last.TableID != "foreign" {
+		t.Fatalf("the referrer of the other app, got %+v", last)
+	}
+}
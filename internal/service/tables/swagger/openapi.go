@@ -0,0 +1,438 @@
+package swagger
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/tables/util"
+)
+
+const (
+	openAPIVersion = "3.0.3"
+	mediaJSON      = "application/json"
+	url3Template   = "/api/v2/form/%s/home/form/%s/{id}"
+
+	entityRef      = "#/components/schemas/Entity"
+	entityInputRef = "#/components/schemas/EntityInput"
+	entityPatchRef = "#/components/schemas/EntityPatch"
+	queryRef       = "#/components/schemas/Query"
+	errorRef       = "#/components/schemas/Error"
+	errorRespRef   = "#/components/responses/Error"
+)
+
+// OpenAPI an OpenAPI 3.0 document, only the parts used by the form service.
+type OpenAPI struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Tags       []Tag                `json:"tags,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas   map[string]*Schema   `json:"schemas,omitempty"`
+	Responses map[string]*Response `json:"responses,omitempty"`
+}
+
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	MaxLength   int                `json:"maxLength,omitempty"`
+	ReadOnly    bool               `json:"readOnly,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+}
+
+// DoOpenAPI generate the OpenAPI 3.0 document of the table.
+func DoOpenAPI(appID, tableID, tableName string, properties models.SchemaProperties) *OpenAPI {
+	input := make(models.SchemaProperties, len(properties))
+	for key, value := range properties {
+		if !util.IsSystemField(key) {
+			input[key] = value
+		}
+	}
+	entity := objectSchema(properties, false)
+	entity.Title = tableName
+	entityInput := objectSchema(input, true)
+	entityInput.Title = tableName
+	// the required fields are not required on update.
+	entityPatch := objectSchema(input, false)
+	entityPatch.Title = tableName
+	entityUpdate := &Schema{Ref: entityPatchRef}
+
+	doc := &OpenAPI{
+		OpenAPI: openAPIVersion,
+		Info: Info{
+			Title:       tableName,
+			Description: "表单引擎",
+			Version:     "last",
+		},
+		Tags: []Tag{{Name: "table"}},
+		Paths: map[string]*PathItem{
+			fmt.Sprintf(url2, appID, tableID): {
+				Get: newOperation("v2_search", util.GetSummary(tableName, "查询多条"),
+					entitiesSchema()).withParameters(&Parameter{
+					Name:        "query",
+					In:          "query",
+					Description: "query dsl",
+					Schema:      &Schema{Type: "string"},
+				}, &Parameter{
+					Name:        "cursor",
+					In:          "query",
+					Description: "nextCursor of the last page",
+					Schema:      &Schema{Type: "string"},
+				}, fieldsParameter(), expandParameter(), &Parameter{
+					Name:        "q",
+					In:          "query",
+					Description: "the text matched by any string field",
+					Schema:      &Schema{Type: "string"},
+				}, &Parameter{
+					Name:        "searchFields",
+					In:          "query",
+					Description: "the fields q matches joined by commas, all string fields if empty",
+					Schema:      &Schema{Type: "string"},
+				}),
+				Post: newOperation("v2_create", util.GetSummary(tableName, "创建"),
+					countAndEntitySchema()).withParameters(idempotencyParameter()).withBody(&Schema{Ref: entityInputRef}, true),
+			},
+			fmt.Sprintf(url3Template, appID, tableID): {
+				Get: newOperation("v2_get", util.GetSummary(tableName, "查询单条"),
+					entitySchema()).withParameters(idPathParameter(), fieldsParameter(), expandParameter()),
+				Put: newOperation("v2_update", util.GetSummary(tableName, "更新"),
+					countAndEntitySchema()).withParameters(idPathParameter()).withBody(entityUpdate, true),
+				Delete: newOperation("v2_delete", util.GetSummary(tableName, "删除"),
+					countSchema()).withParameters(idPathParameter()),
+			},
+			fmt.Sprintf(url1, appID, tableID, get): {
+				Post: newOperation(fmt.Sprintf("%s_%s", tableID, get), util.GetSummary(tableName, "查询单条v1"),
+					entitySchema()).withBody(bodySchema([]string{"query"},
+					"query", idQuerySchema(),
+					"fields", fieldsSchema(),
+					"expand", expandSchema(),
+				), true),
+			},
+			fmt.Sprintf(url1, appID, tableID, search): {
+				Post: newOperation(fmt.Sprintf("%s_%s", tableID, search), util.GetSummary(tableName, "查询多条v1"),
+					entitiesSchema()).withBody(bodySchema(nil,
+					"query", &Schema{Ref: queryRef},
+					"page", &Schema{Type: "integer"},
+					"size", &Schema{Type: "integer"},
+					"sort", &Schema{Type: "array", Items: &Schema{Type: "string"}},
+					"cursor", &Schema{Type: "string"},
+					"fields", fieldsSchema(),
+					"expand", expandSchema(),
+					"q", &Schema{Type: "string", Description: "the text matched by any string field"},
+					"searchFields", &Schema{Type: "array", Items: &Schema{Type: "string"}},
+				), true),
+			},
+			fmt.Sprintf(url1, appID, tableID, update): {
+				Post: newOperation(fmt.Sprintf("%s_%s", tableID, update), util.GetSummary(tableName, "更新v1"),
+					countAndEntitySchema()).withBody(bodySchema([]string{"query", "entity"},
+					"query", idQuerySchema(),
+					"entity", entityUpdate,
+				), true),
+			},
+			fmt.Sprintf(url1, appID, tableID, delete): {
+				Post: newOperation(fmt.Sprintf("%s_%s", tableID, delete), util.GetSummary(tableName, "删除v1"),
+					countSchema()).withBody(bodySchema([]string{"query"}, "query", idQuerySchema()), true),
+			},
+			fmt.Sprintf(url1, appID, tableID, create): {
+				Post: newOperation(fmt.Sprintf("%s_%s", tableID, create), util.GetSummary(tableName, "创建v1"),
+					countAndEntitySchema()).withParameters(idempotencyParameter()).withBody(bodySchema([]string{"entity"},
+					"entity", &Schema{Ref: entityInputRef},
+				), true),
+			},
+			fmt.Sprintf(url1, appID, tableID, upsert): {
+				Post: newOperation(fmt.Sprintf("%s_%s", tableID, upsert), util.GetSummary(tableName, "更新或创建v1"),
+					countAndEntitySchema()).withBody(bodySchema([]string{"entity"},
+					"entity", entityUpdate,
+				), true),
+			},
+		},
+		Components: Components{
+			Schemas: map[string]*Schema{
+				"Entity":      entity,
+				"EntityInput": entityInput,
+				"EntityPatch": entityPatch,
+				"Query": {
+					Type:        "object",
+					Description: "query dsl, like {\"term\":{\"_id\":\"id\"}}",
+				},
+				"Error": {
+					Type: "object",
+					Properties: map[string]*Schema{
+						"code": {Type: "integer", Title: "状态码"},
+						"msg":  {Type: "string", Title: "描述信息"},
+					},
+					Required: []string{"code", "msg"},
+				},
+			},
+			Responses: map[string]*Response{
+				"Error": {
+					Description: "error",
+					Content: map[string]*MediaType{
+						mediaJSON: {Schema: &Schema{Ref: errorRef}},
+					},
+				},
+			},
+		},
+	}
+	return doc
+}
+
+// objectSchema the schema of the properties, withRequired is false for output and update.
+func objectSchema(properties models.SchemaProperties, withRequired bool) *Schema {
+	schema := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema, len(properties)),
+	}
+	required := make([]string, 0)
+	for key, value := range properties {
+		prop := propSchema(value, withRequired)
+		if util.IsSystemField(key) {
+			prop.ReadOnly = true
+		}
+		schema.Properties[key] = prop
+		if value.Required {
+			required = append(required, key)
+		}
+	}
+	sort.Strings(required)
+	if withRequired && len(required) != 0 {
+		schema.Required = required
+	}
+	return schema
+}
+
+func propSchema(value models.SchemaProps, withRequired bool) *Schema {
+	schema := &Schema{
+		Type:  value.Type,
+		Title: value.Title,
+		Enum:  value.Enum,
+	}
+	switch value.Type {
+	case "string":
+		schema.MaxLength = value.Length
+	case "object":
+		sub := objectSchema(value.Properties, withRequired)
+		schema.Properties, schema.Required = sub.Properties, sub.Required
+	case "array":
+		schema.Items = &Schema{Type: "string"}
+		if value.Items != nil {
+			schema.Items = propSchema(*value.Items, withRequired)
+			if value.Items.Type == "" && len(value.Items.Properties) != 0 {
+				schema.Items.Type = "object"
+				sub := objectSchema(value.Items.Properties, withRequired)
+				schema.Items.Properties, schema.Items.Required = sub.Properties, sub.Required
+			}
+		}
+		// the enum of a multi-select lists the options of each element, not of the array.
+		if len(schema.Enum) != 0 {
+			if len(schema.Items.Enum) == 0 {
+				schema.Items.Enum = schema.Enum
+			}
+			schema.Enum = nil
+		}
+	}
+	return schema
+}
+
+func newOperation(id, summary string, data *Schema) *Operation {
+	return &Operation{
+		OperationID: id,
+		Summary:     summary,
+		Tags:        []string{"table"},
+		Responses: map[string]*Response{
+			"200": {
+				Description: "200 is ok",
+				Content: map[string]*MediaType{
+					mediaJSON: {Schema: &Schema{
+						Type: "object",
+						Properties: map[string]*Schema{
+							"code": {Type: "integer", Title: "状态码"},
+							"msg":  {Type: "string", Title: "描述信息"},
+							"data": data,
+						},
+					}},
+				},
+			},
+			"400": {Ref: errorRespRef},
+			"401": {Ref: errorRespRef},
+			"500": {Ref: errorRespRef},
+		},
+	}
+}
+
+func (o *Operation) withParameters(parameters ...*Parameter) *Operation {
+	o.Parameters = append(o.Parameters, parameters...)
+	return o
+}
+
+func (o *Operation) withBody(schema *Schema, required bool) *Operation {
+	o.RequestBody = &RequestBody{
+		Required: required,
+		Content: map[string]*MediaType{
+			mediaJSON: {Schema: schema},
+		},
+	}
+	return o
+}
+
+// bodySchema an object of the key and schema pairs.
+func bodySchema(required []string, pairs ...interface{}) *Schema {
+	schema := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema, len(pairs)/2),
+		Required:   required,
+	}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		schema.Properties[pairs[i].(string)] = pairs[i+1].(*Schema)
+	}
+	return schema
+}
+
+func idPathParameter() *Parameter {
+	return &Parameter{
+		Name:     "id",
+		In:       "path",
+		Required: true,
+		Schema:   &Schema{Type: "string"},
+	}
+}
+
+func idempotencyParameter() *Parameter {
+	return &Parameter{
+		Name:        "Idempotency-Key",
+		In:          "header",
+		Description: "a retried request with the same key returns the result of the first one",
+		Schema:      &Schema{Type: "string"},
+	}
+}
+
+func fieldsParameter() *Parameter {
+	return &Parameter{
+		Name:        "fields",
+		In:          "query",
+		Description: "the fields to return joined by commas, like a,b.c",
+		Schema:      &Schema{Type: "string"},
+	}
+}
+
+func fieldsSchema() *Schema {
+	return &Schema{
+		Type:        "array",
+		Description: "the fields to return, nested ones like b.c",
+		Items:       &Schema{Type: "string"},
+	}
+}
+
+func expandParameter() *Parameter {
+	return &Parameter{
+		Name:        "expand",
+		In:          "query",
+		Description: "the relation fields to fill with the related records joined by commas, like a,a.b",
+		Schema:      &Schema{Type: "string"},
+	}
+}
+
+func expandSchema() *Schema {
+	return &Schema{
+		Type:        "array",
+		Description: "the relation fields to fill with the related records, nested ones like a.b",
+		Items:       &Schema{Type: "string"},
+	}
+}
+
+func idQuerySchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"term": {
+				Type: "object",
+				Properties: map[string]*Schema{
+					"_id": {Type: "string"},
+				},
+				Required: []string{"_id"},
+			},
+		},
+		Required: []string{"term"},
+	}
+}
+
+func entitySchema() *Schema {
+	return bodySchema(nil, "entity", &Schema{Ref: entityRef})
+}
+
+func entitiesSchema() *Schema {
+	return bodySchema(nil,
+		"entities", &Schema{Type: "array", Items: &Schema{Ref: entityRef}},
+		"total", &Schema{Type: "integer", Title: "总数"},
+		"nextCursor", &Schema{Type: "string", Description: "cursor of the next page"},
+		"highlights", &Schema{Type: "object", Description: "the fragments of the fields matching q by _id"},
+	)
+}
+
+func countSchema() *Schema {
+	return bodySchema(nil, "total", &Schema{Type: "integer", Title: "处理的条数"})
+}
+
+func countAndEntitySchema() *Schema {
+	return bodySchema(nil,
+		"entity", &Schema{Ref: entityRef},
+		"total", &Schema{Type: "integer", Title: "处理的条数"},
+	)
+}
@@ -0,0 +1,55 @@
+package swagger
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+)
+
+func TestDoOpenAPI(t *testing.T) {
+	properties := models.SchemaProperties{
+		"_id":   {Type: "string"},
+		"title": {Type: "string", Title: "标题", Required: true, Length: 20},
+		"level": {Type: "string", Enum: []interface{}{"low", "high"}},
+		"tags":  {Type: "array", Enum: []interface{}{"a", "b"}},
+		"items": {Type: "array", Items: &models.SchemaProps{
+			Type: "object",
+			Properties: models.SchemaProperties{
+				"name": {Type: "string", Required: true},
+			},
+		}},
+	}
+	doc := DoOpenAPI("app", "table", "订单", properties)
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	input := doc.Components.Schemas["EntityInput"]
+	if _, ok := input.Properties["_id"]; ok {
+		t.Fatal("system field in input")
+	}
+	if !reflect.DeepEqual(input.Required, []string{"title"}) {
+		t.Fatalf("required is %v", input.Required)
+	}
+	if !reflect.DeepEqual(input.Properties["level"].Enum, []interface{}{"low", "high"}) {
+		t.Fatalf("enum is %v", input.Properties["level"].Enum)
+	}
+	if tags := input.Properties["tags"]; tags.Enum != nil || !reflect.DeepEqual(tags.Items.Enum, []interface{}{"a", "b"}) {
+		t.Fatalf("the enum of a multi-select is %v, of the items %v", tags.Enum, tags.Items.Enum)
+	}
+	item := input.Properties["items"].Items
+	if item == nil || item.Type != "object" || !reflect.DeepEqual(item.Required, []string{"name"}) {
+		t.Fatalf("items is %+v", item)
+	}
+	if len(doc.Components.Schemas["EntityPatch"].Required) != 0 {
+		t.Fatal("patch should not require fields")
+	}
+	if !doc.Components.Schemas["Entity"].Properties["_id"].ReadOnly {
+		t.Fatal("_id should be read only")
+	}
+	if _, ok := doc.Paths["/api/v2/form/app/home/form/table/{id}"]; !ok {
+		t.Fatal("path with id is missing")
+	}
+}
@@ -63,6 +63,18 @@ func entitiesResp(schemas spec.SchemaProperties) *spec.Responses {
 				Title:       "总数",
 			},
 		},
+		"nextCursor": spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "cursor of the next page",
+				Type:        []string{"string"},
+			},
+		},
+		"highlights": spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "the fragments of the fields matching q by _id",
+				Type:        []string{"object"},
+			},
+		},
 	}
 	return response(respSchemas)
 }
@@ -292,6 +304,27 @@ func v1Search() spec.Parameter {
 						"sort": {
 							SchemaProps: getItem("string"),
 						},
+						"cursor": {
+							SchemaProps: spec.SchemaProps{
+								Type:        []string{"string"},
+								Description: "nextCursor of the last page",
+							},
+						},
+						"fields": {
+							SchemaProps: getItem("string"),
+						},
+						"expand": {
+							SchemaProps: getItem("string"),
+						},
+						"q": {
+							SchemaProps: spec.SchemaProps{
+								Type:        []string{"string"},
+								Description: "the text matched by any string field",
+							},
+						},
+						"searchFields": {
+							SchemaProps: getItem("string"),
+						},
 					},
 					Required: []string{"query", "size", "page", "sort"},
 				},
@@ -17,6 +17,7 @@ const (
 	update = "update"
 	delete = "delete"
 	search = "search"
+	upsert = "upsert"
 )
 
 func GetMethod(schemasBus *schemasBus) spec.OperationProps {
@@ -2,11 +2,19 @@ package tables
 
 import (
 	"context"
+	"strings"
 
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	redis2 "github.com/quanxiang-cloud/cabin/tailormade/db/redis"
 	"github.com/quanxiang-cloud/form/internal/models"
 	"github.com/quanxiang-cloud/form/internal/models/mysql"
+	"github.com/quanxiang-cloud/form/internal/models/redis"
 	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/internal/service/rules"
+	"github.com/quanxiang-cloud/form/internal/service/tables/swagger"
+	"github.com/quanxiang-cloud/form/internal/service/tables/util"
 	"github.com/quanxiang-cloud/form/pkg/misc/client"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
 	config2 "github.com/quanxiang-cloud/form/pkg/misc/config"
 	"gorm.io/gorm"
 )
@@ -46,13 +54,28 @@ type Table interface {
 	FindTable(ctx context.Context, req *FindTableReq) (*FindTableResp, error)
 	UpdateConfig(ctx context.Context, req *UpdateConfigReq) (*UpdateConfigResp, error)
 	GetTableInfo(ctx context.Context, req *GetTableInfoReq) (*GetTableInfoResp, error)
+	CloneTable(ctx context.Context, req *CloneTableReq) (*CloneTableResp, error)
+	SaveTemplate(ctx context.Context, req *SaveTemplateReq) (*SaveTemplateResp, error)
+	ListTemplate(ctx context.Context, req *ListTemplateReq) (*ListTemplateResp, error)
+	DeleteTemplate(ctx context.Context, req *DeleteTemplateReq) (*DeleteTemplateResp, error)
+	InstantiateTemplate(ctx context.Context, req *InstantiateTemplateReq) (*InstantiateTemplateResp, error)
+	RelationGraph(ctx context.Context, req *RelationGraphReq) (*RelationGraphResp, error)
+	GetOpenAPI(ctx context.Context, req *GetOpenAPIReq) (*swagger.OpenAPI, error)
+	GetGraphQLSchema(ctx context.Context, req *GetGraphQLSchemaReq) (*GetGraphQLSchemaResp, error)
 }
 
 type table struct {
-	db              *gorm.DB
-	tableRepo       models.TableRepo
-	tableSchemaRepo models.TableSchemeRepo
-	polyAPI         client.PolyAPI
+	db                *gorm.DB
+	tableRepo         models.TableRepo
+	tableSchemaRepo   models.TableSchemeRepo
+	tableRelationRepo models.TableRelationRepo
+	tableTemplateRepo models.TableTemplateRepo
+	tableViewRepo     models.TableViewRepo
+	roleRepo          models.RoleRepo
+	permitRepo        models.PermitRepo
+	serialRepo        models.SerialRepo
+	polyAPI           client.PolyAPI
+	guidance          Guidance
 }
 
 func NewTable(conf *config2.Config) (Table, error) {
@@ -60,11 +83,26 @@ func NewTable(conf *config2.Config) (Table, error) {
 	if err != nil {
 		return nil, err
 	}
+	guidance, err := NewWebTable(conf)
+	if err != nil {
+		return nil, err
+	}
+	redisClient, err := redis2.NewClient(conf.Redis)
+	if err != nil {
+		return nil, err
+	}
 	return &table{
-		db:              db,
-		tableRepo:       mysql.NewTableRepo(),
-		tableSchemaRepo: mysql.NewTableSchema(),
-		polyAPI:         client.NewPolyAPI(conf),
+		db:                db,
+		tableRepo:         mysql.NewTableRepo(),
+		tableSchemaRepo:   mysql.NewTableSchema(),
+		tableRelationRepo: mysql.NewTableRelationRepo(),
+		tableTemplateRepo: mysql.NewTableTemplateRepo(),
+		tableViewRepo:     mysql.NewTableViewRepo(),
+		roleRepo:          mysql.NewRoleRepo(),
+		permitRepo:        mysql.NewPermitRepo(),
+		serialRepo:        redis.NewSerialRepo(redisClient),
+		polyAPI:           client.NewPolyAPI(conf),
+		guidance:          guidance,
 	}, nil
 }
 
@@ -112,32 +150,85 @@ func (t *table) GetTable(ctx context.Context, req *GetTableReq) (*GetTableResp,
 type DeleteTableReq struct {
 	AppID   string `json:"appID"`
 	TableID string `json:"tableID"`
+	// Cascade strip the components of the tables which refer to the table as well,
+	// the table is not deleted while it is referred to otherwise.
+	Cascade bool `json:"cascade"`
 }
 
 type DeleteTableResp struct{}
 
-// DeleteTable 不开启事务.
+// DeleteTable the referrers are detached and the table is deleted in one transaction,
+// the namespace and the serials are deleted after the commit.
 func (t *table) DeleteTable(ctx context.Context, req *DeleteTableReq) (*DeleteTableResp, error) {
-	err := t.tableRepo.Delete(t.db, &models.TableQuery{
-		TableID: req.TableID,
-		AppID:   req.AppID,
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		referrers, err := t.referrers(tx, req.TableID)
+		if err != nil {
+			return err
+		}
+		if len(referrers) != 0 && !req.Cascade {
+			tableIDs := make([]string, 0, len(referrers))
+			for _, relation := range referrers {
+				tableIDs = append(tableIDs, relation.TableID)
+			}
+			return error2.New(code.ErrTableReferenced, strings.Join(tableIDs, ","))
+		}
+		if err = t.detach(ctx, tx, req.TableID, referrers); err != nil {
+			return err
+		}
+		return t.deleteTable(tx, req.AppID, req.TableID, len(referrers) != 0)
 	})
 	if err != nil {
 		return nil, err
 	}
-	err = t.tableSchemaRepo.Delete(t.db, &models.TableSchemaQuery{
-		TableID: req.TableID,
-	})
+	_, err = t.polyAPI.DeleteNamespace(ctx, req.AppID, req.TableID)
 	if err != nil {
 		return nil, err
 	}
-	_, err = t.polyAPI.DeleteNamespace(ctx, req.AppID, req.TableID)
+	err = t.serialRepo.Delete(ctx, req.AppID, req.TableID)
 	if err != nil {
 		return nil, err
 	}
 	return &DeleteTableResp{}, nil
 }
 
+// deleteTable delete the metadata of the table, and the relations referring to it when it is referred to.
+func (t *table) deleteTable(tx *gorm.DB, appID, tableID string, referred bool) error {
+	err := t.tableRepo.Delete(tx, &models.TableQuery{
+		TableID: tableID,
+		AppID:   appID,
+	})
+	if err != nil {
+		return err
+	}
+	err = t.tableSchemaRepo.Delete(tx, &models.TableSchemaQuery{
+		AppID:   appID,
+		TableID: tableID,
+	})
+	if err != nil {
+		return err
+	}
+	err = t.tableRelationRepo.Delete(tx, &models.TableRelationQuery{
+		AppID:   appID,
+		TableID: tableID,
+	})
+	if err != nil {
+		return err
+	}
+	if referred {
+		// the referrers may be in the other apps.
+		err = t.tableRelationRepo.Delete(tx, &models.TableRelationQuery{
+			SubTableID: tableID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return t.tableViewRepo.Delete(tx, &models.TableViewQuery{
+		AppID:   appID,
+		TableID: tableID,
+	})
+}
+
 type FindTableReq struct {
 	Title  string            `json:"title"`
 	AppID  string            `json:"appID"`
@@ -205,6 +296,18 @@ type UpdateConfigReq struct {
 type UpdateConfigResp struct{}
 
 func (t *table) UpdateConfig(ctx context.Context, req *UpdateConfigReq) (*UpdateConfigResp, error) {
+	if _, err := rules.Parse(req.Config); err != nil {
+		return nil, error2.New(code.ErrInvalidRule, err.Error())
+	}
+	if _, ok := req.Config[util.UpsertKeysConfig]; ok {
+		schema, err := t.tableSchemaRepo.Get(t.db, req.AppID, req.TableID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = util.UpsertKeys(req.Config, schema.Schema); err != nil {
+			return nil, error2.New(code.ErrInvalidUpsert, err.Error())
+		}
+	}
 	tables := &models.Table{
 		TableID: req.TableID,
 		Config:  req.Config,
@@ -217,3 +320,20 @@ func (t *table) UpdateConfig(ctx context.Context, req *UpdateConfigReq) (*Update
 	}
 	return &UpdateConfigResp{}, nil
 }
+
+type GetOpenAPIReq struct {
+	AppID   string `json:"appID"`
+	TableID string `json:"tableID"`
+}
+
+// GetOpenAPI the OpenAPI 3.0 document of the table, built from the saved schema.
+func (t *table) GetOpenAPI(ctx context.Context, req *GetOpenAPIReq) (*swagger.OpenAPI, error) {
+	schema, err := t.tableSchemaRepo.Get(t.db, req.AppID, req.TableID)
+	if err != nil {
+		return nil, err
+	}
+	if schema.ID == "" {
+		return nil, error2.New(code.ErrNotExistTable)
+	}
+	return swagger.DoOpenAPI(req.AppID, req.TableID, schema.Title, schema.Schema), nil
+}
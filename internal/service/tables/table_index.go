@@ -14,7 +14,11 @@ type tableIndex struct {
 }
 
 func (t *tableIndex) Do(ctx context.Context, bus *Bus) (*DoResponse, error) {
-	_, err := t.FormDDLAPI.Index(ctx, consensus.GetTableID(bus.AppID, bus.TableID), "created_at", "created_at")
+	tableName := consensus.GetTableID(bus.AppID, bus.TableID)
+	err := bus.effect(ctx, func(ctx context.Context) error {
+		_, err := t.FormDDLAPI.Index(ctx, tableName, "created_at", "created_at")
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
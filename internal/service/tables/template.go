@@ -0,0 +1,170 @@
+package tables
+
+import (
+	"context"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"gorm.io/gorm"
+)
+
+type SaveTemplateReq struct {
+	AppID       string `json:"appID"`
+	TableID     string `json:"tableID" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	UserID      string `json:"-"`
+	UserName    string `json:"-"`
+}
+
+type SaveTemplateResp struct {
+	ID string `json:"id"`
+}
+
+// SaveTemplate save the definition of the table and its sub tables as a template.
+func (t *table) SaveTemplate(ctx context.Context, req *SaveTemplateReq) (*SaveTemplateResp, error) {
+	content, err := t.snapshot(req.AppID, req.TableID)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 0 {
+		return nil, error2.New(code.ErrNotExistTable)
+	}
+	template := &models.TableTemplate{
+		ID:          id2.StringUUID(),
+		AppID:       req.AppID,
+		Name:        req.Name,
+		Description: req.Description,
+		Content:     content,
+		CreatedAt:   time2.NowUnix(),
+		CreatorID:   req.UserID,
+		CreatorName: req.UserName,
+	}
+	err = t.tableTemplateRepo.BatchCreate(t.db, template)
+	if err != nil {
+		return nil, err
+	}
+	return &SaveTemplateResp{
+		ID: template.ID,
+	}, nil
+}
+
+type ListTemplateReq struct {
+	Name string `json:"name"`
+	Page int    `json:"page"`
+	Size int    `json:"size"`
+}
+
+type ListTemplateResp struct {
+	List  []*templateVo `json:"list"`
+	Total int64         `json:"total"`
+}
+
+type templateVo struct {
+	ID          string `json:"id"`
+	AppID       string `json:"appID"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Title       string `json:"title"`
+	TableLen    int    `json:"tableLen"`
+	CreatedAt   int64  `json:"createdAt"`
+	CreatorName string `json:"creatorName"`
+}
+
+// ListTemplate the templates are shared by all apps.
+func (t *table) ListTemplate(ctx context.Context, req *ListTemplateReq) (*ListTemplateResp, error) {
+	templates, total, err := t.tableTemplateRepo.List(t.db, &models.TableTemplateQuery{
+		Name: req.Name,
+	}, req.Page, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	resp := &ListTemplateResp{
+		List:  make([]*templateVo, len(templates)),
+		Total: total,
+	}
+	for index, value := range templates {
+		vo := &templateVo{
+			ID:          value.ID,
+			AppID:       value.AppID,
+			Name:        value.Name,
+			Description: value.Description,
+			TableLen:    len(value.Content),
+			CreatedAt:   value.CreatedAt,
+			CreatorName: value.CreatorName,
+		}
+		if len(value.Content) != 0 {
+			vo.Title = value.Content[0].Title
+		}
+		resp.List[index] = vo
+	}
+	return resp, nil
+}
+
+type DeleteTemplateReq struct {
+	AppID string `json:"appID"`
+	ID    string `json:"id" binding:"required"`
+}
+
+type DeleteTemplateResp struct{}
+
+// DeleteTemplate only the app which saved the template can delete it.
+func (t *table) DeleteTemplate(ctx context.Context, req *DeleteTemplateReq) (*DeleteTemplateResp, error) {
+	err := t.tableTemplateRepo.Delete(t.db, &models.TableTemplateQuery{
+		ID:    req.ID,
+		AppID: req.AppID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &DeleteTemplateResp{}, nil
+}
+
+type InstantiateTemplateReq struct {
+	AppID    string `json:"appID"`
+	ID       string `json:"id" binding:"required"`
+	Title    string `json:"title"`
+	UserID   string `json:"-"`
+	UserName string `json:"-"`
+}
+
+type InstantiateTemplateResp struct {
+	TableID  string            `json:"tableID"`
+	TableIDs map[string]string `json:"tableIDs"`
+}
+
+// InstantiateTemplate create the tables of the template in the app.
+func (t *table) InstantiateTemplate(ctx context.Context, req *InstantiateTemplateReq) (*InstantiateTemplateResp, error) {
+	template, err := t.tableTemplateRepo.Get(t.db, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if template.ID == "" || len(template.Content) == 0 {
+		return nil, error2.New(code.ErrNotExistTemplate)
+	}
+	if req.Title != "" {
+		template.Content[0].Title = req.Title
+	}
+	var (
+		ids      map[string]string
+		deferred Effects
+	)
+	err = t.db.Transaction(func(tx *gorm.DB) error {
+		deferred = nil
+		ids, err = t.restore(ctx, tx, template.AppID, req.AppID, template.Content, req.UserID, req.UserName, &deferred)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = deferred.Run(ctx); err != nil {
+		return nil, err
+	}
+	return &InstantiateTemplateResp{
+		TableID:  ids[template.Content[0].TableID],
+		TableIDs: ids,
+	}, nil
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 
@@ -20,6 +21,8 @@ const (
 	_updatedAt    = "updated_at"
 	_modifierID   = "modifier_id"
 	_modifierName = "modifier_name"
+	_default      = "default"
+	xDefault      = "x-default"
 )
 
 func GetMapToMap(schema map[string]interface{}, key string) (map[string]interface{}, error) {
@@ -127,6 +130,8 @@ func Convert1(schema map[string]interface{}) (s models.SchemaProperties, total i
 			case "required":
 				t, _ := v1.(bool)
 				schemaProps.Required = t
+			case "enum":
+				schemaProps.Enum = getEnum(v1)
 			case "properties":
 				if p, ok := v1.(map[string]interface{}); ok {
 					s2, _, _ := Convert1(p)
@@ -152,12 +157,50 @@ func Convert1(schema map[string]interface{}) (s models.SchemaProperties, total i
 				continue
 			}
 		}
+		schemaProps.Default = getDefaultValue(v)
 		s[key] = schemaProps
 	}
 
 	return s, total, nil
 }
 
+// getEnum the options are either values or label-value objects.
+func getEnum(value interface{}) []interface{} {
+	options, ok := value.([]interface{})
+	if !ok || len(options) == 0 {
+		return nil
+	}
+	enum := make([]interface{}, 0, len(options))
+	for _, option := range options {
+		if m, ok := option.(map[string]interface{}); ok {
+			if v, ok := m["value"]; ok {
+				enum = append(enum, v)
+			}
+			continue
+		}
+		enum = append(enum, option)
+	}
+	return enum
+}
+
+// getDefaultValue x-default takes precedence over the static default.
+func getDefaultValue(field map[string]interface{}) *models.DefaultValue {
+	if x, ok := field[xDefault].(map[string]interface{}); ok {
+		dv := &models.DefaultValue{}
+		data, err := json.Marshal(x)
+		if err == nil && json.Unmarshal(data, dv) == nil && dv.Type != "" {
+			return dv
+		}
+	}
+	if value, ok := field[_default]; ok && value != nil {
+		return &models.DefaultValue{
+			Type:  models.StaticDefault,
+			Value: value,
+		}
+	}
+	return nil
+}
+
 func GetSpecSchema(properties models.SchemaProperties) (spec.SchemaProperties, []string) {
 	if properties == nil {
 		return nil, nil
@@ -221,14 +264,21 @@ func FilterSystem(sourceStruct spec.SchemaProperties, dst spec.SchemaProperties)
 		return
 	}
 	for key, value := range sourceStruct {
-		switch key {
-		case _id, _createdAt, _creatorID, _creatorName, _updatedAt, _modifierID, _modifierName:
-		default:
+		if !IsSystemField(key) {
 			dst[key] = value
 		}
 	}
 }
 
+// IsSystemField the fields maintained by the form service.
+func IsSystemField(key string) bool {
+	switch key {
+	case _id, _createdAt, _creatorID, _creatorName, _updatedAt, _modifierID, _modifierName:
+		return true
+	}
+	return false
+}
+
 func GetSummary(tableName, operate string) string {
 	return fmt.Sprintf("%s(%s)", tableName, operate)
 }
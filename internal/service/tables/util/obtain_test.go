@@ -0,0 +1,74 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+)
+
+func TestConvertDefault(t *testing.T) {
+	properties := map[string]interface{}{
+		"status": map[string]interface{}{
+			"type":    "string",
+			"default": "open",
+		},
+		"owner": map[string]interface{}{
+			"type":    "array",
+			"default": []interface{}{},
+			"x-default": map[string]interface{}{
+				"type": "currentUser",
+			},
+		},
+		"remark": map[string]interface{}{
+			"type": "string",
+		},
+	}
+	schema, total, err := Convert1(properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 {
+		t.Fatalf("total is %d", total)
+	}
+	if d := schema["status"].Default; d == nil || d.Type != models.StaticDefault || d.Value != "open" {
+		t.Fatalf("status default is %+v", d)
+	}
+	if d := schema["owner"].Default; d == nil || d.Type != models.CurrentUserDefault {
+		t.Fatalf("owner default is %+v", d)
+	}
+	if d := schema["remark"].Default; d != nil {
+		t.Fatalf("remark default is %+v", d)
+	}
+}
+
+func TestConvertRelationDefault(t *testing.T) {
+	properties := map[string]interface{}{
+		"phone": map[string]interface{}{
+			"type":    "string",
+			"default": "none",
+			"x-default": map[string]interface{}{
+				"type":      "relation",
+				"tableID":   "customers",
+				"refField":  "customer",
+				"fieldName": "phone",
+			},
+		},
+		// an x-default without a type falls back to the static default.
+		"level": map[string]interface{}{
+			"type":      "number",
+			"default":   float64(1),
+			"x-default": map[string]interface{}{},
+		},
+	}
+	schema, _, err := Convert1(properties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := schema["phone"].Default
+	if d == nil || d.Type != models.RelationDefault || d.TableID != "customers" || d.RefField != "customer" || d.FieldName != "phone" {
+		t.Fatalf("phone default is %+v", d)
+	}
+	if d := schema["level"].Default; d == nil || d.Type != models.StaticDefault || d.Value != float64(1) {
+		t.Fatalf("level default is %+v", d)
+	}
+}
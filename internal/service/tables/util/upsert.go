@@ -0,0 +1,41 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+)
+
+// UpsertKeysConfig the key of the fields which identify a record on upsert in the table config.
+const UpsertKeysConfig = "upsertKeys"
+
+// UpsertKeys read the upsert keys from the table config, the keys are checked against the schema if any.
+func UpsertKeys(config models.Config, schema models.SchemaProperties) ([]string, error) {
+	value, ok := config[UpsertKeysConfig]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	elems, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of fields", UpsertKeysConfig)
+	}
+	keys := make([]string, 0, len(elems))
+	seen := make(map[string]bool, len(elems))
+	for _, elem := range elems {
+		key, ok := elem.(string)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s must be an array of fields", UpsertKeysConfig)
+		}
+		if key == _id || seen[key] {
+			return nil, fmt.Errorf("%s can not be an upsert key", key)
+		}
+		if schema != nil {
+			if _, ok := schema[key]; !ok {
+				return nil, fmt.Errorf("%s is not a field of the table", key)
+			}
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+)
+
+func TestUpsertKeys(t *testing.T) {
+	schema := models.SchemaProperties{"code": {}, "region": {}}
+	keys, err := UpsertKeys(models.Config{UpsertKeysConfig: []interface{}{"code", "region"}}, schema)
+	if err != nil || !reflect.DeepEqual(keys, []string{"code", "region"}) {
+		t.Fatalf("keys %v, err %v", keys, err)
+	}
+	if keys, err = UpsertKeys(models.Config{}, schema); err != nil || keys != nil {
+		t.Fatalf("no keys declared, got %v, err %v", keys, err)
+	}
+	for _, value := range []interface{}{
+		"code",
+		[]interface{}{""},
+		[]interface{}{"_id"},
+		[]interface{}{"code", "code"},
+		[]interface{}{"name"},
+	} {
+		if _, err = UpsertKeys(models.Config{UpsertKeysConfig: value}, schema); err == nil {
+			t.Fatalf("%v should fail", value)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package transfer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/tables/util"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+)
+
+const (
+	// SyncLimit the tables with more records are exported by a job.
+	SyncLimit = 5000
+	pageSize  = 500
+	// utf8BOM lets excel read the csv as utf-8.
+	utf8BOM   = "\xEF\xBB\xBF"
+	labelKey  = "label"
+	separator = ","
+
+	dataKey     = "data"
+	entitiesKey = "entities"
+	objectType  = "object"
+	arrayType   = "array"
+)
+
+type ExportReq struct {
+	AppID   string      `json:"appID"`
+	TableID string      `json:"tableID"`
+	Query   types.Query `json:"query"`
+	Sort    []string    `json:"sort"`
+	// Async export by a job no matter how many records there are.
+	Async bool `json:"async"`
+	// Permit the response permit of search passed by the permit gateway, nil for all fields.
+	Permit   models.FiledPermit `json:"-"`
+	UserID   string             `json:"-"`
+	UserName string             `json:"-"`
+	DepID    string             `json:"-"`
+}
+
+type ExportMeta struct {
+	Total    int64  `json:"total"`
+	FileName string `json:"fileName"`
+}
+
+type ExportResp struct {
+	JobID string `json:"jobID"`
+}
+
+// Prepare count the records and name the file.
+func (t *transfer) Prepare(ctx context.Context, req *ExportReq) (*ExportMeta, error) {
+	schema, err := t.tableSchemaRepo.Get(t.db, req.AppID, req.TableID)
+	if err != nil {
+		return nil, err
+	}
+	if schema.ID == "" {
+		return nil, error2.New(code.ErrNotExistTable)
+	}
+	resp, err := t.guidance.Do(ctx, t.searchBus(req, 1, ""))
+	if err != nil {
+		return nil, err
+	}
+	title := schema.Title
+	if title == "" {
+		title = req.TableID
+	}
+	return &ExportMeta{
+		Total:    resp.Total,
+		FileName: fmt.Sprintf("%s_%s.csv", title, time.Now().Format("20060102150405")),
+	}, nil
+}
+
+// WriteCSV write the records to w page by page.
+func (t *transfer) WriteCSV(ctx context.Context, req *ExportReq, w io.Writer) error {
+	return t.writeCSV(ctx, req, w, nil)
+}
+
+// Export start a job which keeps the file for download.
+func (t *transfer) Export(ctx context.Context, req *ExportReq) (*ExportResp, error) {
+	meta, err := t.Prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	now := time2.NowUnix()
+	job := &models.FormJob{
+		ID:          id2.StringUUID(),
+		AppID:       req.AppID,
+		TableID:     req.TableID,
+		Type:        models.ExportJob,
+		Status:      models.JobRunning,
+		Total:       meta.Total,
+		FileName:    meta.FileName,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatorID:   req.UserID,
+		CreatorName: req.UserName,
+	}
+	if err = t.formJobRepo.Create(t.db, job); err != nil {
+		return nil, err
+	}
+
+	go t.runExport(service.Detach(ctx), req, job.ID)
+	return &ExportResp{
+		JobID: job.ID,
+	}, nil
+}
+
+func (t *transfer) runExport(ctx context.Context, req *ExportReq, jobID string) {
+	err := t.saveCSV(ctx, req, t.filePath(req.AppID, jobID), func(processed int64) {
+		err := t.formJobRepo.Update(t.db, jobID, &models.FormJob{
+			Processed: processed,
+			UpdatedAt: time2.NowUnix(),
+		})
+		if err != nil {
+			logger.Logger.WithName("export").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		}
+	})
+	result := &models.FormJob{
+		Status:    models.JobSucceed,
+		UpdatedAt: time2.NowUnix(),
+	}
+	if err != nil {
+		logger.Logger.WithName("export").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		result.Status = models.JobFailed
+		result.Message = err.Error()
+	}
+	if err = t.formJobRepo.Update(t.db, jobID, result); err != nil {
+		logger.Logger.WithName("export").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+	}
+}
+
+// filePath the result file of an export job.
+func (t *transfer) filePath(appID, jobID string) string {
+	return filepath.Join(t.dir, appID, jobID+".csv")
+}
+
+// saveCSV write the records to a temporary file beside the path, then rename it,
+// so the file is never held in memory, and a half written one is never downloaded.
+func (t *transfer) saveCSV(ctx context.Context, req *ExportReq, path string, progress func(processed int64)) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(filepath.Dir(path), "export-*.csv")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if err = t.writeCSV(ctx, req, file, progress); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (t *transfer) writeCSV(ctx context.Context, req *ExportReq, w io.Writer, progress func(processed int64)) error {
+	schema, err := t.tableSchemaRepo.Get(t.db, req.AppID, req.TableID)
+	if err != nil {
+		return err
+	}
+	if schema.ID == "" {
+		return error2.New(code.ErrNotExistTable)
+	}
+	cols := getColumns(schema.Schema, req.Permit.Sub(dataKey, entitiesKey))
+
+	if _, err = io.WriteString(w, utf8BOM); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	titles := make([]string, len(cols))
+	for index, col := range cols {
+		titles[index] = col.title
+	}
+	if err = writer.Write(titles); err != nil {
+		return err
+	}
+
+	var (
+		processed int64
+		cursor    string
+	)
+	for {
+		// the cursor keeps the pages stable while the records change.
+		resp, err := t.guidance.Do(ctx, t.searchBus(req, pageSize, cursor))
+		if err != nil {
+			return err
+		}
+		for _, entity := range resp.Entities {
+			record := make([]string, len(cols))
+			for index, col := range cols {
+				record[index] = format(consensus.GetValue(entity, col.path))
+			}
+			if err = writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		if err = writer.Error(); err != nil {
+			return err
+		}
+		processed += int64(len(resp.Entities))
+		if progress != nil {
+			progress(processed)
+		}
+		if resp.NextCursor == "" {
+			return nil
+		}
+		cursor = resp.NextCursor
+	}
+}
+
+func (t *transfer) searchBus(req *ExportReq, size int64, cursor string) *consensus.Bus {
+	bus := new(consensus.Bus)
+	bus.Universal = consensus.Universal{
+		UserID:   req.UserID,
+		UserName: req.UserName,
+		DepID:    req.DepID,
+	}
+	bus.Foundation = consensus.Foundation{
+		AppID:   req.AppID,
+		TableID: req.TableID,
+		Method:  "search",
+	}
+	bus.Get.Query = req.Query
+	sorts := req.Sort
+	if len(sorts) == 0 {
+		sorts = []string{"created_at"}
+	}
+	bus.List = consensus.List{
+		Page:   1,
+		Size:   size,
+		Sort:   sorts,
+		Cursor: cursor,
+	}
+	return bus
+}
+
+type column struct {
+	path  []string
+	title string
+	props models.SchemaProps
+}
+
+// getColumns flatten the nested objects, the fields out of permit are left out.
+// the system fields come last, the others are ordered by key.
+func getColumns(properties models.SchemaProperties, permit models.FiledPermit) []*column {
+	return walkColumns(properties, permit, nil, "")
+}
+
+func walkColumns(properties models.SchemaProperties, permit models.FiledPermit, parent []string, parentTitle string) []*column {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		si, sj := util.IsSystemField(keys[i]), util.IsSystemField(keys[j])
+		if si != sj {
+			return sj
+		}
+		return keys[i] < keys[j]
+	})
+
+	cols := make([]*column, 0, len(keys))
+	for _, key := range keys {
+		var sub models.FiledPermit
+		if permit != nil {
+			p, ok := permit[key]
+			if !ok {
+				continue
+			}
+			// the same as the gateway, only objects and arrays are filtered in depth.
+			if p.Type == objectType || p.Type == arrayType {
+				sub = p.Properties
+			}
+		}
+		props := properties[key]
+		path := append(append([]string{}, parent...), key)
+		title := props.Title
+		if title == "" {
+			title = key
+		}
+		if parentTitle != "" {
+			title = parentTitle + "." + title
+		}
+		if props.Type == "object" && len(props.Properties) != 0 {
+			cols = append(cols, walkColumns(props.Properties, sub, path, title)...)
+			continue
+		}
+		cols = append(cols, &column{
+			path:  path,
+			title: title,
+			props: props,
+		})
+	}
+	return cols
+}
+
+// format label-value fields are written as labels, arrays are joined.
+func format(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case map[string]interface{}:
+		if label, ok := v[labelKey]; ok {
+			return format(label)
+		}
+	case []interface{}:
+		elems := make([]string, 0, len(v))
+		for _, elem := range v {
+			elems = append(elems, format(elem))
+		}
+		return strings.Join(elems, separator)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
@@ -0,0 +1,115 @@
+package transfer
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"gorm.io/gorm"
+)
+
+func TestGetColumns(t *testing.T) {
+	properties := models.SchemaProperties{
+		"_id":  {Type: "string"},
+		"name": {Type: "string", Title: "名称"},
+		"addr": {Type: "object", Title: "地址", Properties: models.SchemaProperties{
+			"city": {Type: "string", Title: "城市"},
+			"zip":  {Type: "string"},
+		}},
+	}
+	cols := getColumns(properties, nil)
+	titles := make([]string, len(cols))
+	for i, col := range cols {
+		titles[i] = col.title
+	}
+	expect := []string{"地址.城市", "地址.zip", "名称", "_id"}
+	if !reflect.DeepEqual(titles, expect) {
+		t.Fatalf("expect %v, got %v", expect, titles)
+	}
+
+	permit := models.FiledPermit{
+		"name": {Type: "string"},
+		"addr": {Type: "object", Properties: models.FiledPermit{
+			"city": {Type: "string"},
+		}},
+	}
+	response := models.FiledPermit{
+		"data": {Type: "object", Properties: models.FiledPermit{
+			"entities": {Type: "array", Properties: permit},
+		}},
+	}
+	cols = getColumns(properties, response.Sub(dataKey, entitiesKey))
+	if len(cols) != 2 || cols[0].title != "地址.城市" || cols[1].title != "名称" {
+		t.Fatalf("columns out of permit: %+v", cols)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		value  interface{}
+		expect string
+	}{
+		{nil, ""},
+		{float64(12.5), "12.5"},
+		{float64(3), "3"},
+		{true, "true"},
+		{map[string]interface{}{"label": "男", "value": "1"}, "男"},
+		{[]interface{}{
+			map[string]interface{}{"label": "a", "value": "1"},
+			map[string]interface{}{"label": "b", "value": "2"},
+		}, "a,b"},
+		{map[string]interface{}{"x": float64(1)}, `{"x":1}`},
+	}
+	for _, c := range cases {
+		if got := format(c.value); got != c.expect {
+			t.Fatalf("expect %s, got %s", c.expect, got)
+		}
+	}
+}
+
+type fakeSchemaRepo struct {
+	models.TableSchemeRepo
+	schema models.SchemaProperties
+}
+
+func (f *fakeSchemaRepo) Get(db *gorm.DB, appID, tableID string) (*models.TableSchema, error) {
+	return &models.TableSchema{ID: "1", AppID: appID, TableID: tableID, Schema: f.schema}, nil
+}
+
+// fakeSearch two pages of one record, chained by the cursor.
+type fakeSearch struct{}
+
+func (fakeSearch) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	if bus.List.Cursor == "" {
+		return &consensus.Response{Entities: types.Entities{{"name": "a"}}, NextCursor: "next"}, nil
+	}
+	return &consensus.Response{Entities: types.Entities{{"name": "b"}}}, nil
+}
+
+func TestSaveCSV(t *testing.T) {
+	tr := &transfer{
+		guidance:        fakeSearch{},
+		tableSchemaRepo: &fakeSchemaRepo{schema: models.SchemaProperties{"name": {Type: "string"}}},
+		dir:             t.TempDir(),
+	}
+	path := tr.filePath("app", "job")
+	if err := tr.saveCSV(context.Background(), &ExportReq{AppID: "app", TableID: "t"}, path, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != utf8BOM+"name\na\nb\n" {
+		t.Fatalf("unexpected file %q", data)
+	}
+	files, _ := ioutil.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Fatalf("the temporary file is left, %d files", len(files))
+	}
+}
@@ -0,0 +1,725 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/rules"
+	"github.com/quanxiang-cloud/form/internal/service/tables/util"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+)
+
+const (
+	csvExt  = ".csv"
+	xlsxExt = ".xlsx"
+	// maxErrors the errors kept in the report, the failed rows are still counted.
+	maxErrors = 1000
+	// firstRow the rows are numbered as in the sheet, the titles take the first one.
+	firstRow = 2
+
+	entityKey  = "entity"
+	typeKey    = "type"
+	appIDKey   = "appID"
+	tableIDKey = "tableID"
+	newKey     = "new"
+
+	propertiesKey     = "properties"
+	titleKey          = "title"
+	componentKey      = "x-component"
+	componentPropsKey = "x-component-props"
+	serialComponent   = "Serial"
+	subTableComponent = "SubTable"
+	serialType        = "serial"
+	subTableType      = "sub_table"
+	subordinationKey  = "subordination"
+	valueKey          = "value"
+	enumKey           = "enum"
+	itemsKey          = "items"
+	labelValueType    = "label-value"
+	numberType        = "number"
+	booleanType       = "boolean"
+	requiredMessage   = "必填"
+	numberMessage     = "不是数字"
+	booleanMessage    = "不是布尔值"
+	jsonMessage       = "格式错误"
+	subTableMessage   = "子表数据应为对象数组"
+	emptyFileMessage  = "文件没有数据"
+	fileTypeMessage   = "仅支持csv和xlsx文件"
+	noColumnMessage   = "没有可导入的列"
+	unknownMessage    = "%s：字段不存在或没有权限"
+	missingMessage    = "%s：列不存在"
+	duplicateMessage  = "%s：字段重复映射"
+	relationMessage   = "%s：子表未关联"
+	optionMessage     = "%s：选项不存在"
+	labelValueMessage = "没有选项，应为{label,value}格式"
+)
+
+type ImportReq struct {
+	AppID    string `json:"appID"`
+	TableID  string `json:"tableID"`
+	FileName string `json:"-"`
+	File     []byte `json:"-"`
+	// Mapping the column titles to the field keys, the nested fields are joined by dots, like addr.city.
+	// the columns are matched by the titles or the keys of the fields if there is no mapping.
+	Mapping map[string]string `json:"mapping"`
+	// DryRun check the rows without creating the records, the default values are not filled.
+	DryRun bool `json:"dryRun"`
+	// Async import by a job no matter how many rows there are.
+	Async bool `json:"async"`
+	// Permit the params permit of create passed by the permit gateway, nil for all fields.
+	Permit   models.FiledPermit `json:"-"`
+	UserID   string             `json:"-"`
+	UserName string             `json:"-"`
+	DepID    string             `json:"-"`
+}
+
+type ImportResp struct {
+	JobID  string        `json:"jobID,omitempty"`
+	Report *ImportReport `json:"report,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun  bool        `json:"dryRun"`
+	Total   int64       `json:"total"`
+	Succeed int64       `json:"succeed"`
+	Failed  int64       `json:"failed"`
+	Errors  []*RowError `json:"errors"`
+}
+
+type RowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (r *ImportReport) fail(row int, errs []*RowError) {
+	r.Failed++
+	for _, err := range errs {
+		if len(r.Errors) >= maxErrors {
+			return
+		}
+		err.Row = row
+		r.Errors = append(r.Errors, err)
+	}
+}
+
+func (r *ImportReport) jobReport() (models.JobReport, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	report := models.JobReport{}
+	if err = json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Import create the records of the rows like a normal create, by a job when there are too many.
+func (t *transfer) Import(ctx context.Context, req *ImportReq) (*ImportResp, error) {
+	rows, err := readRows(req.FileName, req.File)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, error2.New(code.ErrImportFile, emptyFileMessage)
+	}
+	im, err := t.newImporter(ctx, req, rows[0])
+	if err != nil {
+		return nil, err
+	}
+	records := dataRows(rows)
+
+	if !req.Async && len(records) <= SyncLimit {
+		return &ImportResp{
+			Report: t.runRows(ctx, req, im, records, nil),
+		}, nil
+	}
+
+	now := time2.NowUnix()
+	job := &models.FormJob{
+		ID:          id2.StringUUID(),
+		AppID:       req.AppID,
+		TableID:     req.TableID,
+		Type:        models.ImportJob,
+		Status:      models.JobRunning,
+		Total:       int64(len(records)),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatorID:   req.UserID,
+		CreatorName: req.UserName,
+	}
+	if err = t.formJobRepo.Create(t.db, job); err != nil {
+		return nil, err
+	}
+
+	go t.runImport(service.Detach(ctx), req, im, records, job.ID)
+	return &ImportResp{
+		JobID: job.ID,
+	}, nil
+}
+
+func (t *transfer) runImport(ctx context.Context, req *ImportReq, im *importer, records []*row, jobID string) {
+	report := t.runRows(ctx, req, im, records, func(processed int64) {
+		err := t.formJobRepo.Update(t.db, jobID, &models.FormJob{
+			Processed: processed,
+			UpdatedAt: time2.NowUnix(),
+		})
+		if err != nil {
+			logger.Logger.WithName("import").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		}
+	})
+	result := &models.FormJob{
+		Status:    models.JobSucceed,
+		Processed: report.Total,
+		UpdatedAt: time2.NowUnix(),
+	}
+	jobReport, err := report.jobReport()
+	if err != nil {
+		logger.Logger.WithName("import").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		result.Status = models.JobFailed
+		result.Message = err.Error()
+	}
+	result.Report = jobReport
+	if err = t.formJobRepo.Update(t.db, jobID, result); err != nil {
+		logger.Logger.WithName("import").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+	}
+}
+
+func (t *transfer) runRows(ctx context.Context, req *ImportReq, im *importer, records []*row, progress func(processed int64)) *ImportReport {
+	report := &ImportReport{
+		DryRun: req.DryRun,
+		Errors: make([]*RowError, 0),
+	}
+	for _, record := range records {
+		report.Total++
+		entity, ref, errs := im.convert(record.cells)
+		errs = append(errs, im.check(entity, req.DryRun)...)
+		if len(errs) == 0 && !req.DryRun {
+			_, err := t.guidance.Do(ctx, createBus(req, entity, ref))
+			if violations, ok := err.(rules.Violations); ok {
+				errs = append(errs, violationErrors(violations)...)
+			} else if err != nil {
+				errs = append(errs, &RowError{
+					Message: err.Error(),
+				})
+			}
+		}
+		if len(errs) != 0 {
+			report.fail(record.num, errs)
+		} else {
+			report.Succeed++
+		}
+		if progress != nil && report.Total%pageSize == 0 {
+			progress(report.Total)
+		}
+	}
+	return report
+}
+
+func createBus(req *ImportReq, entity map[string]interface{}, ref types.Ref) *consensus.Bus {
+	bus := new(consensus.Bus)
+	bus.Universal = consensus.Universal{
+		UserID:   req.UserID,
+		UserName: req.UserName,
+		DepID:    req.DepID,
+	}
+	bus.Foundation = consensus.Foundation{
+		AppID:   req.AppID,
+		TableID: req.TableID,
+		Method:  "create",
+	}
+	bus.CreatedOrUpdate.Entity = entity
+	bus.Ref.Ref = ref
+	return bus
+}
+
+// readRows the rows of csv, or of the first sheet of xlsx.
+func readRows(fileName string, file []byte) ([][]string, error) {
+	var (
+		rows [][]string
+		err  error
+	)
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case csvExt:
+		reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(file, []byte(utf8BOM))))
+		reader.FieldsPerRecord = -1
+		rows, err = reader.ReadAll()
+	case xlsxExt:
+		rows, err = readXLSX(file)
+	default:
+		return nil, error2.New(code.ErrImportFile, fileTypeMessage)
+	}
+	if err != nil {
+		return nil, error2.New(code.ErrImportFile, err.Error())
+	}
+	return rows, nil
+}
+
+type row struct {
+	num   int
+	cells []string
+}
+
+// dataRows the rows under the titles, the blank ones are skipped.
+func dataRows(rows [][]string) []*row {
+	records := make([]*row, 0, len(rows))
+	for index := 1; index < len(rows); index++ {
+		blank := true
+		for _, cell := range rows[index] {
+			if strings.TrimSpace(cell) != "" {
+				blank = false
+				break
+			}
+		}
+		if blank {
+			continue
+		}
+		records = append(records, &row{
+			num:   index - 1 + firstRow,
+			cells: rows[index],
+		})
+	}
+	return records
+}
+
+// binding a column of the file bound to a field.
+type binding struct {
+	index int
+	*column
+	// subTable the cell is a json array of the records of the sub table.
+	subTable *subTable
+	// labelValue the cell holds the labels, as they are exported.
+	labelValue *labelValue
+}
+
+type subTable struct {
+	appID   string
+	tableID string
+}
+
+// labelValue a field saving {label,value} objects, or an array of them when multiple,
+// the labels are mapped back to the options of the component.
+type labelValue struct {
+	multiple bool
+	options  []map[string]interface{}
+}
+
+type importer struct {
+	bindings []*binding
+	serials  []string
+	required []*column
+	rules    []*rules.Rule
+}
+
+func (t *transfer) newImporter(ctx context.Context, req *ImportReq, titles []string) (*importer, error) {
+	schema, err := t.tableSchemaRepo.Get(t.db, req.AppID, req.TableID)
+	if err != nil {
+		return nil, err
+	}
+	if schema.ID == "" {
+		return nil, error2.New(code.ErrNotExistTable)
+	}
+	table, err := t.tableRepo.Get(t.db, req.AppID, req.TableID)
+	if err != nil {
+		return nil, err
+	}
+	im := &importer{}
+	// the params permit of the sub tables is checked here, they are left out of the columns.
+	permit := req.Permit.Sub(entityKey)
+	subTables := make(map[string]*binding)
+	labelValues := make(map[string]*labelValue)
+	walkComponents(table.Schema, func(key string, field map[string]interface{}) {
+		if lv := getLabelValue(field); lv != nil {
+			labelValues[key] = lv
+		}
+		switch util.GetMapToString(field, componentKey) {
+		case serialComponent:
+			im.serials = append(im.serials, key)
+		case subTableComponent:
+			props, _ := field[componentPropsKey].(map[string]interface{})
+			if util.GetMapToString(props, subordinationKey) != subTableType {
+				return
+			}
+			if _, ok := permit[key]; permit != nil && !ok {
+				return
+			}
+			title := util.GetMapToString(field, titleKey)
+			if title == "" {
+				title = key
+			}
+			subTables[key] = &binding{
+				column: &column{
+					path:  []string{key},
+					title: title,
+				},
+				subTable: &subTable{
+					appID:   util.GetMapToString(props, appIDKey),
+					tableID: util.GetMapToString(props, tableIDKey),
+				},
+			}
+		}
+	})
+	cols := getColumns(schema.Schema, permit)
+	if err = im.bind(titles, cols, subTables, labelValues, req.Mapping); err != nil {
+		return nil, err
+	}
+
+	for _, col := range getColumns(schema.Schema, nil) {
+		if col.props.Required && col.props.Default == nil && !im.generated(col.path[0]) {
+			im.required = append(im.required, col)
+		}
+	}
+	im.rules, err = rules.Parse(table.Config)
+	if err != nil {
+		// the same as the validation, a broken rule should not block the data.
+		logger.Logger.WithName("import").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+	}
+	return im, nil
+}
+
+// generated the fields filled by the form service.
+func (im *importer) generated(key string) bool {
+	if util.IsSystemField(key) {
+		return true
+	}
+	for _, serial := range im.serials {
+		if serial == key {
+			return true
+		}
+	}
+	return false
+}
+
+// bind the columns to the writable fields, by the mapping or by the titles and keys.
+func (im *importer) bind(titles []string, cols []*column, subTables map[string]*binding,
+	labelValues map[string]*labelValue, mapping map[string]string) error {
+	fields := make(map[string]*binding, len(cols)+len(subTables))
+	byTitle := make(map[string]*binding, len(cols)+len(subTables))
+	for _, col := range cols {
+		if im.generated(col.path[0]) {
+			continue
+		}
+		b := &binding{
+			column: col,
+		}
+		if len(col.path) == 1 {
+			b.labelValue = labelValues[col.path[0]]
+		}
+		fields[strings.Join(col.path, ".")] = b
+		byTitle[col.title] = b
+	}
+	for key, b := range subTables {
+		fields[key] = b
+		byTitle[b.title] = b
+	}
+
+	found := make(map[string]bool, len(titles))
+	used := make(map[string]bool, len(titles))
+	for index, title := range titles {
+		title = strings.TrimSpace(title)
+		found[title] = true
+		var b *binding
+		if mapping != nil {
+			key, ok := mapping[title]
+			if !ok || key == "" {
+				continue
+			}
+			if b, ok = fields[key]; !ok {
+				return error2.New(code.ErrImportMapping, fmt.Sprintf(unknownMessage, key))
+			}
+		} else {
+			var ok bool
+			if b, ok = byTitle[title]; !ok {
+				if b, ok = fields[title]; !ok {
+					continue
+				}
+			}
+		}
+		key := strings.Join(b.path, ".")
+		if used[key] {
+			return error2.New(code.ErrImportMapping, fmt.Sprintf(duplicateMessage, key))
+		}
+		used[key] = true
+		if b.subTable != nil && b.subTable.tableID == "" {
+			return error2.New(code.ErrImportMapping, fmt.Sprintf(relationMessage, key))
+		}
+		im.bindings = append(im.bindings, &binding{
+			index:      index,
+			column:     b.column,
+			subTable:   b.subTable,
+			labelValue: b.labelValue,
+		})
+	}
+	for title := range mapping {
+		if !found[title] {
+			return error2.New(code.ErrImportMapping, fmt.Sprintf(missingMessage, title))
+		}
+	}
+	if len(im.bindings) == 0 {
+		return error2.New(code.ErrImportMapping, noColumnMessage)
+	}
+	return nil
+}
+
+// convert the cells to an entity and the refs of the serials and sub tables, the empty cells are left out.
+func (im *importer) convert(cells []string) (map[string]interface{}, types.Ref, []*RowError) {
+	entity := make(map[string]interface{})
+	ref := make(types.Ref)
+	var errs []*RowError
+	for _, b := range im.bindings {
+		if b.index >= len(cells) {
+			continue
+		}
+		cell := strings.TrimSpace(cells[b.index])
+		if cell == "" {
+			continue
+		}
+		if b.subTable != nil {
+			records, err := subRecords(cell)
+			if err != nil {
+				errs = append(errs, &RowError{Column: b.title, Message: err.Error()})
+				continue
+			}
+			ref[b.path[0]] = map[string]interface{}{
+				typeKey:    subTableType,
+				appIDKey:   b.subTable.appID,
+				tableIDKey: b.subTable.tableID,
+				newKey:     records,
+			}
+			continue
+		}
+		var (
+			value interface{}
+			err   error
+		)
+		if b.labelValue != nil {
+			value, err = b.labelValue.coerce(cell)
+		} else {
+			value, err = coerce(cell, &b.props)
+		}
+		if err != nil {
+			errs = append(errs, &RowError{Column: b.title, Message: err.Error()})
+			continue
+		}
+		setPath(entity, b.path, value)
+	}
+	for _, key := range im.serials {
+		ref[key] = map[string]interface{}{
+			typeKey: serialType,
+		}
+	}
+	return entity, ref, errs
+}
+
+// check the required fields, and the rules of the table on dry run,
+// which are checked by the validation otherwise.
+func (im *importer) check(entity map[string]interface{}, dryRun bool) []*RowError {
+	var errs []*RowError
+	for _, col := range im.required {
+		if consensus.GetValue(entity, col.path) == nil {
+			errs = append(errs, &RowError{Column: col.title, Message: requiredMessage})
+		}
+	}
+	if !dryRun {
+		return errs
+	}
+	return append(errs, violationErrors(rules.Evaluate(im.rules, entity))...)
+}
+
+func violationErrors(violations rules.Violations) []*RowError {
+	errs := make([]*RowError, 0, len(violations))
+	for _, violation := range violations {
+		errs = append(errs, &RowError{Message: violation.String()})
+	}
+	return errs
+}
+
+// coerce the text of a cell to the type of the field.
+func coerce(cell string, props *models.SchemaProps) (interface{}, error) {
+	switch props.Type {
+	case numberType:
+		f, err := strconv.ParseFloat(cell, 64)
+		if err != nil {
+			return nil, errors.New(numberMessage)
+		}
+		return f, nil
+	case booleanType:
+		switch strings.ToLower(cell) {
+		case "true", "1", "是":
+			return true, nil
+		case "false", "0", "否":
+			return false, nil
+		}
+		return nil, errors.New(booleanMessage)
+	case objectType:
+		value := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(cell), &value); err != nil {
+			return nil, errors.New(jsonMessage)
+		}
+		return value, nil
+	case arrayType:
+		if strings.HasPrefix(cell, "[") {
+			value := make([]interface{}, 0)
+			if err := json.Unmarshal([]byte(cell), &value); err != nil {
+				return nil, errors.New(jsonMessage)
+			}
+			return value, nil
+		}
+		item := &models.SchemaProps{}
+		if props.Items != nil {
+			item = props.Items
+		}
+		elems := strings.Split(cell, separator)
+		values := make([]interface{}, 0, len(elems))
+		for _, elem := range elems {
+			elem = strings.TrimSpace(elem)
+			if elem == "" {
+				continue
+			}
+			value, err := coerce(elem, item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	return cell, nil
+}
+
+// getLabelValue the label-value field of the table design, like the selects, nil for the others.
+func getLabelValue(field map[string]interface{}) *labelValue {
+	lv := &labelValue{}
+	enum := field[enumKey]
+	switch util.GetMapToString(field, typeKey) {
+	case labelValueType:
+	case arrayType:
+		items, _ := field[itemsKey].(map[string]interface{})
+		if util.GetMapToString(items, typeKey) != labelValueType {
+			return nil
+		}
+		lv.multiple = true
+		if enum == nil {
+			enum = items[enumKey]
+		}
+	default:
+		return nil
+	}
+	options, _ := enum.([]interface{})
+	for _, option := range options {
+		switch v := option.(type) {
+		case map[string]interface{}:
+			if _, ok := v[labelKey]; ok {
+				lv.options = append(lv.options, map[string]interface{}{labelKey: v[labelKey], valueKey: v[valueKey]})
+			}
+		case string:
+			lv.options = append(lv.options, map[string]interface{}{labelKey: v, valueKey: v})
+		}
+	}
+	return lv
+}
+
+// coerce the labels of a cell to the options, a json cell is taken as it is,
+// which is the only way for the fields without options, like the pickers of users.
+func (lv *labelValue) coerce(cell string) (interface{}, error) {
+	if strings.HasPrefix(cell, "{") || strings.HasPrefix(cell, "[") {
+		var value interface{}
+		if err := json.Unmarshal([]byte(cell), &value); err != nil {
+			return nil, errors.New(jsonMessage)
+		}
+		return value, nil
+	}
+	if len(lv.options) == 0 {
+		return nil, errors.New(labelValueMessage)
+	}
+	labels := []string{cell}
+	if lv.multiple {
+		labels = strings.Split(cell, separator)
+	}
+	values := make([]interface{}, 0, len(labels))
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			continue
+		}
+		option := lv.find(label)
+		if option == nil {
+			return nil, fmt.Errorf(optionMessage, label)
+		}
+		values = append(values, option)
+	}
+	if lv.multiple {
+		return values, nil
+	}
+	return values[0], nil
+}
+
+func (lv *labelValue) find(label string) map[string]interface{} {
+	for _, option := range lv.options {
+		if format(option[labelKey]) == label {
+			return map[string]interface{}{labelKey: option[labelKey], valueKey: option[valueKey]}
+		}
+	}
+	return nil
+}
+
+// subRecords the records of a sub table are written as a json array of objects.
+func subRecords(cell string) ([]interface{}, error) {
+	var entities []map[string]interface{}
+	if err := json.Unmarshal([]byte(cell), &entities); err != nil {
+		return nil, errors.New(subTableMessage)
+	}
+	records := make([]interface{}, 0, len(entities))
+	for _, entity := range entities {
+		records = append(records, map[string]interface{}{
+			entityKey: entity,
+		})
+	}
+	return records, nil
+}
+
+func setPath(entity map[string]interface{}, path []string, value interface{}) {
+	current := entity
+	for _, key := range path[:len(path)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[key] = next
+		}
+		current = next
+	}
+	current[path[len(path)-1]] = value
+}
+
+// walkComponents visit the fields of the table design, the layouts are flattened.
+func walkComponents(schema map[string]interface{}, fn func(key string, field map[string]interface{})) {
+	properties, _ := schema[propertiesKey].(map[string]interface{})
+	for key, value := range properties {
+		field, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if util.IsLayoutComponent(field) {
+			walkComponents(field, fn)
+			continue
+		}
+		fn(key, field)
+	}
+}
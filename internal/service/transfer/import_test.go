@@ -0,0 +1,182 @@
+package transfer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestCoerce(t *testing.T) {
+	cases := []struct {
+		cell   string
+		props  models.SchemaProps
+		expect interface{}
+		fail   bool
+	}{
+		{"abc", models.SchemaProps{Type: "string"}, "abc", false},
+		{"12.5", models.SchemaProps{Type: "number"}, float64(12.5), false},
+		{"twelve", models.SchemaProps{Type: "number"}, nil, true},
+		{"是", models.SchemaProps{Type: "boolean"}, true, false},
+		{"0", models.SchemaProps{Type: "boolean"}, false, false},
+		{"a, b", models.SchemaProps{Type: "array"}, []interface{}{"a", "b"}, false},
+		{"1,2", models.SchemaProps{Type: "array", Items: &models.SchemaProps{Type: "number"}},
+			[]interface{}{float64(1), float64(2)}, false},
+		{`{"x":1}`, models.SchemaProps{Type: "object"}, map[string]interface{}{"x": float64(1)}, false},
+		{"{", models.SchemaProps{Type: "object"}, nil, true},
+	}
+	for _, c := range cases {
+		value, err := coerce(c.cell, &c.props)
+		if (err != nil) != c.fail {
+			t.Fatalf("%s: unexpected error %v", c.cell, err)
+		}
+		if !c.fail && !reflect.DeepEqual(value, c.expect) {
+			t.Fatalf("%s: expect %v, got %v", c.cell, c.expect, value)
+		}
+	}
+}
+
+func TestReadRows(t *testing.T) {
+	rows, err := readRows("a.CSV", []byte(utf8BOM+"名称,数量\n苹果,3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rows, [][]string{{"名称", "数量"}, {"苹果", "3"}}) {
+		t.Fatalf("csv rows %v", rows)
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	files := map[string]string{
+		xlsxSharedStrings: `<sst><si><t>名称</t></si><si><r><t>苹</t></r><r><t>果</t></r></si></sst>`,
+		xlsxFirstSheet: `<worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="inlineStr"><is><t>数量</t></is></c></row>` +
+			`<row r="3"><c r="A3" t="s"><v>1</v></c><c r="B3" t="b"><v>1</v></c><c r="C3"><v>3</v></c></row>` +
+			`</sheetData></worksheet>`,
+	}
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	rows, err = readRows("a.xlsx", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := [][]string{{"名称", "", "数量"}, nil, {"苹果", "true", "3"}}
+	if !reflect.DeepEqual(rows, expect) {
+		t.Fatalf("xlsx rows %q", rows)
+	}
+	records := dataRows(rows)
+	if len(records) != 1 || records[0].num != 3 {
+		t.Fatalf("data rows %+v", records)
+	}
+
+	if _, err = readRows("a.xls", nil); err == nil {
+		t.Fatal("xls should be refused")
+	}
+}
+
+type fakeTableRepo struct {
+	models.TableRepo
+	schema models.WebSchema
+}
+
+func (f *fakeTableRepo) Get(db *gorm.DB, appID, tableID string) (*models.Table, error) {
+	return &models.Table{ID: "1", AppID: appID, TableID: tableID, Schema: f.schema}, nil
+}
+
+func TestImportLabelValue(t *testing.T) {
+	tr := &transfer{
+		tableSchemaRepo: &fakeSchemaRepo{schema: models.SchemaProperties{
+			"level": {Type: "string"},
+			"tags":  {Type: "array", Items: &models.SchemaProps{Type: "label-value"}},
+			"owner": {Type: "string"},
+		}},
+		tableRepo: &fakeTableRepo{schema: models.WebSchema{
+			"properties": map[string]interface{}{
+				"level": map[string]interface{}{
+					"type": "label-value",
+					"enum": []interface{}{
+						map[string]interface{}{"label": "高", "value": "high"},
+						map[string]interface{}{"label": "低", "value": "low"},
+					},
+				},
+				"tags": map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"type": "label-value"},
+					"enum":  []interface{}{"a", "b"},
+				},
+				"owner": map[string]interface{}{"type": "label-value"},
+			},
+		}},
+	}
+	im, err := tr.newImporter(context.Background(), &ImportReq{}, []string{"level", "tags", "owner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported := map[string]interface{}{
+		"level": map[string]interface{}{"label": "高", "value": "high"},
+		"tags": []interface{}{
+			map[string]interface{}{"label": "a", "value": "a"},
+			map[string]interface{}{"label": "b", "value": "b"},
+		},
+		"owner": map[string]interface{}{"label": "alice", "value": "u1"},
+	}
+	owner, _ := json.Marshal(exported["owner"])
+	entity, _, errs := im.convert([]string{format(exported["level"]), format(exported["tags"]), string(owner)})
+	if len(errs) != 0 {
+		t.Fatalf("errors %+v", errs[0])
+	}
+	if !reflect.DeepEqual(entity, exported) {
+		t.Fatalf("round trip %v", entity)
+	}
+
+	_, _, errs = im.convert([]string{"中", "", "alice"})
+	if len(errs) != 2 {
+		t.Fatalf("unknown option and label without options, got %d errors", len(errs))
+	}
+}
+
+func TestImportSubTablePermit(t *testing.T) {
+	tr := &transfer{
+		tableSchemaRepo: &fakeSchemaRepo{schema: models.SchemaProperties{"name": {Type: "string"}}},
+		tableRepo: &fakeTableRepo{schema: models.WebSchema{
+			"properties": map[string]interface{}{
+				"items": map[string]interface{}{
+					"type":        "array",
+					"x-component": "SubTable",
+					"x-component-props": map[string]interface{}{
+						"subordination": "sub_table",
+						"tableID":       "items",
+					},
+				},
+			},
+		}},
+	}
+	titles := []string{"name", "items"}
+	im, err := tr.newImporter(context.Background(), &ImportReq{}, titles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(im.bindings) != 2 {
+		t.Fatalf("all of the columns are bound without a permit, got %d", len(im.bindings))
+	}
+	permit := models.FiledPermit{"entity": {Type: "object", Properties: models.FiledPermit{"name": {Type: "string"}}}}
+	im, err = tr.newImporter(context.Background(), &ImportReq{Permit: permit}, titles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(im.bindings) != 1 || im.bindings[0].subTable != nil {
+		t.Fatalf("the sub table out of the permit is bound")
+	}
+}
@@ -0,0 +1,132 @@
+package transfer
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/models/mysql"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	"gorm.io/gorm"
+)
+
+// Transfer export and import the records of a table.
+type Transfer interface {
+	Prepare(ctx context.Context, req *ExportReq) (*ExportMeta, error)
+	WriteCSV(ctx context.Context, req *ExportReq, w io.Writer) error
+	Export(ctx context.Context, req *ExportReq) (*ExportResp, error)
+	GetJob(ctx context.Context, req *GetJobReq) (*GetJobResp, error)
+	Download(ctx context.Context, req *GetJobReq) (*DownloadResp, error)
+	Import(ctx context.Context, req *ImportReq) (*ImportResp, error)
+}
+
+type transfer struct {
+	db              *gorm.DB
+	guidance        consensus.Guidance
+	tableSchemaRepo models.TableSchemeRepo
+	tableRepo       models.TableRepo
+	formJobRepo     models.FormJobRepo
+	// dir keep the result files of export, by the apps and the jobs.
+	dir string
+}
+
+func NewTransfer(conf *config.Config, guidance consensus.Guidance) (Transfer, error) {
+	db, err := service.CreateMysqlConn(conf)
+	if err != nil {
+		return nil, err
+	}
+	dir := conf.Export.Dir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "form", "exports")
+	}
+	return &transfer{
+		db:              db,
+		guidance:        guidance,
+		tableSchemaRepo: mysql.NewTableSchema(),
+		tableRepo:       mysql.NewTableRepo(),
+		formJobRepo:     mysql.NewFormJobRepo(),
+		dir:             dir,
+	}, nil
+}
+
+type GetJobReq struct {
+	AppID   string `json:"appID"`
+	TableID string `json:"tableID"`
+	ID      string `json:"id"`
+	UserID  string `json:"-"`
+}
+
+type GetJobResp struct {
+	ID        string           `json:"id"`
+	Type      models.JobType   `json:"type"`
+	Status    models.JobStatus `json:"status"`
+	Total     int64            `json:"total"`
+	Processed int64            `json:"processed"`
+	FileName  string           `json:"fileName,omitempty"`
+	Report    models.JobReport `json:"report,omitempty"`
+	Message   string           `json:"message,omitempty"`
+	CreatedAt int64            `json:"createdAt"`
+	UpdatedAt int64            `json:"updatedAt"`
+}
+
+// GetJob only the creator can see the job.
+func (t *transfer) GetJob(ctx context.Context, req *GetJobReq) (*GetJobResp, error) {
+	job, err := t.getJob(req)
+	if err != nil {
+		return nil, err
+	}
+	return &GetJobResp{
+		ID:        job.ID,
+		Type:      job.Type,
+		Status:    job.Status,
+		Total:     job.Total,
+		Processed: job.Processed,
+		FileName:  job.FileName,
+		Report:    job.Report,
+		Message:   job.Message,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}, nil
+}
+
+type DownloadResp struct {
+	FileName string
+	// File the caller closes it.
+	File io.ReadCloser
+}
+
+// Download the result file of an export job.
+func (t *transfer) Download(ctx context.Context, req *GetJobReq) (*DownloadResp, error) {
+	job, err := t.getJob(req)
+	if err != nil {
+		return nil, err
+	}
+	if job.Type != models.ExportJob || job.Status != models.JobSucceed {
+		return nil, error2.New(code.ErrJobNotFinished)
+	}
+	file, err := os.Open(t.filePath(job.AppID, job.ID))
+	if err != nil {
+		return nil, err
+	}
+	return &DownloadResp{
+		FileName: job.FileName,
+		File:     file,
+	}, nil
+}
+
+func (t *transfer) getJob(req *GetJobReq) (*models.FormJob, error) {
+	job, err := t.formJobRepo.Get(t.db, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if job.ID == "" || job.AppID != req.AppID || job.TableID != req.TableID || job.CreatorID != req.UserID {
+		return nil, error2.New(code.ErrNotExistJob)
+	}
+	return job, nil
+}
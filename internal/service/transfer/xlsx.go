@@ -0,0 +1,191 @@
+package transfer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	xlsxWorkbook      = "xl/workbook.xml"
+	xlsxWorkbookRels  = "xl/_rels/workbook.xml.rels"
+	xlsxSharedStrings = "xl/sharedStrings.xml"
+	xlsxFirstSheet    = "xl/worksheets/sheet1.xml"
+	xlsxDir           = "xl/"
+
+	cellShared  = "s"
+	cellInline  = "inlineStr"
+	cellBoolean = "b"
+)
+
+type xlsxBook struct {
+	Sheets []struct {
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRels struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSST struct {
+	Items []xlsxText `xml:"si"`
+}
+
+// xlsxText plain text or rich text made of runs.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (x *xlsxText) text() string {
+	if len(x.Runs) == 0 {
+		return x.T
+	}
+	var b strings.Builder
+	for _, run := range x.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int        `xml:"r,attr"`
+		Cells []xlsxCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+type xlsxCell struct {
+	R      string    `xml:"r,attr"`
+	T      string    `xml:"t,attr"`
+	V      string    `xml:"v"`
+	Inline *xlsxText `xml:"is"`
+}
+
+func (c *xlsxCell) value(shared []string) string {
+	switch c.T {
+	case cellShared:
+		index, err := strconv.Atoi(c.V)
+		if err != nil || index < 0 || index >= len(shared) {
+			return ""
+		}
+		return shared[index]
+	case cellInline:
+		if c.Inline == nil {
+			return ""
+		}
+		return c.Inline.text()
+	case cellBoolean:
+		return strconv.FormatBool(c.V == "1")
+	}
+	return c.V
+}
+
+// readXLSX read the cells of the first sheet as text, the styles like dates are not applied.
+func readXLSX(file []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var shared []string
+	if f, ok := files[xlsxSharedStrings]; ok {
+		sst := &xlsxSST{}
+		if err = decodeXML(f, sst); err != nil {
+			return nil, err
+		}
+		shared = make([]string, len(sst.Items))
+		for index := range sst.Items {
+			shared[index] = sst.Items[index].text()
+		}
+	}
+
+	f, ok := files[firstSheet(files)]
+	if !ok {
+		return nil, errors.New("no sheet in the workbook")
+	}
+	sheet := &xlsxSheet{}
+	if err = decodeXML(f, sheet); err != nil {
+		return nil, err
+	}
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		// the empty rows are left out of the sheet.
+		for row.R > len(rows)+1 {
+			rows = append(rows, nil)
+		}
+		record := make([]string, 0, len(row.Cells))
+		for index, cell := range row.Cells {
+			if i := columnIndex(cell.R); i >= 0 {
+				index = i
+			}
+			if index < len(record) {
+				record[index] = cell.value(shared)
+				continue
+			}
+			for len(record) < index {
+				record = append(record, "")
+			}
+			record = append(record, cell.value(shared))
+		}
+		rows = append(rows, record)
+	}
+	return rows, nil
+}
+
+// firstSheet the path of the first sheet in the workbook, sheet1.xml if it can not be resolved.
+func firstSheet(files map[string]*zip.File) string {
+	book, rels := &xlsxBook{}, &xlsxRels{}
+	bf, ok := files[xlsxWorkbook]
+	if !ok || decodeXML(bf, book) != nil || len(book.Sheets) == 0 {
+		return xlsxFirstSheet
+	}
+	rf, ok := files[xlsxWorkbookRels]
+	if !ok || decodeXML(rf, rels) != nil {
+		return xlsxFirstSheet
+	}
+	for _, rel := range rels.Items {
+		if rel.ID != book.Sheets[0].RID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/")
+		}
+		return xlsxDir + rel.Target
+	}
+	return xlsxFirstSheet
+}
+
+func decodeXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// columnIndex the zero based column of a cell reference, like 1 for B3.
+func columnIndex(ref string) int {
+	index := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A') + 1
+	}
+	return index - 1
+}
@@ -1,7 +1,9 @@
 package service
 
 import (
+	"context"
 	"regexp"
+	"time"
 
 	"github.com/quanxiang-cloud/cabin/logger"
 	mysql2 "github.com/quanxiang-cloud/cabin/tailormade/db/mysql"
@@ -29,3 +31,19 @@ func CreateMysqlConn(conf *config.Config) (*gorm.DB, error) {
 func IsFormAPI(path string) bool {
 	return regexpForm.MatchString(path)
 }
+
+// Detach keep the values of the request, like the request id, but not its deadline
+// or cancellation, for the jobs which outlive the request.
+func Detach(ctx context.Context) context.Context {
+	return detached{ctx}
+}
+
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detached) Done() <-chan struct{} { return nil }
+
+func (detached) Err() error { return nil }
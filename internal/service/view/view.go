@@ -0,0 +1,427 @@
+package view
+
+import (
+	"context"
+	"strings"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/models/mysql"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxPageSize the page size of a view at most.
+	MaxPageSize = 1000
+	defaultSize = 10
+	descPrefix  = "-"
+	objectType  = "object"
+)
+
+// View the saved searches of a table.
+type View interface {
+	CreateView(ctx context.Context, req *CreateViewReq) (*CreateViewResp, error)
+	UpdateView(ctx context.Context, req *UpdateViewReq) (*UpdateViewResp, error)
+	DeleteView(ctx context.Context, req *DeleteViewReq) (*DeleteViewResp, error)
+	GetView(ctx context.Context, req *GetViewReq) (*ViewVo, error)
+	ListView(ctx context.Context, req *ListViewReq) (*ListViewResp, error)
+	Search(ctx context.Context, req *SearchReq) (*consensus.Response, error)
+}
+
+type view struct {
+	db              *gorm.DB
+	guidance        consensus.Guidance
+	tableViewRepo   models.TableViewRepo
+	tableSchemaRepo models.TableSchemeRepo
+	roleRepo        models.RoleRepo
+	userRoleRepo    models.UserRoleRepo
+}
+
+func NewView(conf *config.Config, guidance consensus.Guidance) (View, error) {
+	db, err := service.CreateMysqlConn(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &view{
+		db:              db,
+		guidance:        guidance,
+		tableViewRepo:   mysql.NewTableViewRepo(),
+		tableSchemaRepo: mysql.NewTableSchema(),
+		roleRepo:        mysql.NewRoleRepo(),
+		userRoleRepo:    mysql.NewUserRoleRepo(),
+	}, nil
+}
+
+// ViewBase the saved search.
+type ViewBase struct {
+	Name     string      `json:"name" binding:"required"`
+	Query    types.Query `json:"query"`
+	Sort     []string    `json:"sort"`
+	Columns  []string    `json:"columns"`
+	PageSize int64       `json:"pageSize"`
+	// RoleID share the view to the users of the role, only the creator can see it if empty.
+	RoleID string `json:"roleID"`
+}
+
+type CreateViewReq struct {
+	AppID   string `json:"appID"`
+	TableID string `json:"tableID"`
+	ViewBase
+	UserID   string `json:"-"`
+	UserName string `json:"-"`
+}
+
+type CreateViewResp struct {
+	ID string `json:"id"`
+}
+
+// CreateView the fields of the view must be in the schema of the table.
+func (v *view) CreateView(ctx context.Context, req *CreateViewReq) (*CreateViewResp, error) {
+	if err := v.check(req.AppID, req.TableID, &req.ViewBase); err != nil {
+		return nil, err
+	}
+	now := time2.NowUnix()
+	tv := &models.TableView{
+		ID:          id2.StringUUID(),
+		AppID:       req.AppID,
+		TableID:     req.TableID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatorID:   req.UserID,
+		CreatorName: req.UserName,
+	}
+	setBase(tv, &req.ViewBase)
+	if err := v.tableViewRepo.BatchCreate(v.db, tv); err != nil {
+		return nil, err
+	}
+	return &CreateViewResp{
+		ID: tv.ID,
+	}, nil
+}
+
+type UpdateViewReq struct {
+	AppID   string `json:"appID"`
+	TableID string `json:"tableID"`
+	ID      string `json:"id"`
+	ViewBase
+	UserID string `json:"-"`
+}
+
+type UpdateViewResp struct{}
+
+// UpdateView only the creator can update the view.
+func (v *view) UpdateView(ctx context.Context, req *UpdateViewReq) (*UpdateViewResp, error) {
+	tv, err := v.getOwned(req.AppID, req.TableID, req.ID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err = v.check(req.AppID, req.TableID, &req.ViewBase); err != nil {
+		return nil, err
+	}
+	setBase(tv, &req.ViewBase)
+	tv.UpdatedAt = time2.NowUnix()
+	if err = v.tableViewRepo.Update(v.db, tv.ID, tv); err != nil {
+		return nil, err
+	}
+	return &UpdateViewResp{}, nil
+}
+
+type DeleteViewReq struct {
+	AppID   string `json:"appID"`
+	TableID string `json:"tableID"`
+	ID      string `json:"id"`
+	UserID  string `json:"-"`
+}
+
+type DeleteViewResp struct{}
+
+// DeleteView only the creator can delete the view.
+func (v *view) DeleteView(ctx context.Context, req *DeleteViewReq) (*DeleteViewResp, error) {
+	tv, err := v.getOwned(req.AppID, req.TableID, req.ID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	err = v.tableViewRepo.Delete(v.db, &models.TableViewQuery{
+		ID: tv.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &DeleteViewResp{}, nil
+}
+
+type GetViewReq struct {
+	AppID   string `json:"appID"`
+	TableID string `json:"tableID"`
+	ID      string `json:"id"`
+	UserID  string `json:"-"`
+}
+
+type ViewVo struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Query       types.Query `json:"query"`
+	Sort        []string    `json:"sort"`
+	Columns     []string    `json:"columns"`
+	PageSize    int64       `json:"pageSize"`
+	RoleID      string      `json:"roleID"`
+	CreatedAt   int64       `json:"createdAt"`
+	UpdatedAt   int64       `json:"updatedAt"`
+	CreatorID   string      `json:"creatorID"`
+	CreatorName string      `json:"creatorName"`
+}
+
+// GetView the view must be created by the user or shared to the role of the user.
+func (v *view) GetView(ctx context.Context, req *GetViewReq) (*ViewVo, error) {
+	tv, err := v.getVisible(req.AppID, req.TableID, req.ID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return toVo(tv), nil
+}
+
+type ListViewReq struct {
+	AppID   string `json:"appID"`
+	TableID string `json:"tableID"`
+	Page    int    `json:"page" form:"page"`
+	Size    int    `json:"size" form:"size"`
+	UserID  string `json:"-"`
+}
+
+type ListViewResp struct {
+	List  []*ViewVo `json:"list"`
+	Total int64     `json:"total"`
+}
+
+// ListView the views created by the user and shared to the role of the user.
+func (v *view) ListView(ctx context.Context, req *ListViewReq) (*ListViewResp, error) {
+	roleID, err := v.roleOf(req.AppID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	views, total, err := v.tableViewRepo.List(v.db, &models.TableViewQuery{
+		AppID:   req.AppID,
+		TableID: req.TableID,
+		OwnerID: req.UserID,
+		RoleID:  roleID,
+	}, req.Page, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*ViewVo, 0, len(views))
+	for _, tv := range views {
+		list = append(list, toVo(tv))
+	}
+	return &ListViewResp{
+		List:  list,
+		Total: total,
+	}, nil
+}
+
+type SearchReq struct {
+	AppID   string `json:"appID"`
+	TableID string `json:"tableID"`
+	ID      string `json:"id"`
+	// Query the conditions added to the view, the permit gateway ANDs the row condition of the permit in.
+	Query    types.Query `json:"query"`
+	Page     int64       `json:"page"`
+	Size     int64       `json:"size"`
+	Cursor   string      `json:"cursor"`
+	UserID   string      `json:"-"`
+	UserName string      `json:"-"`
+	DepID    string      `json:"-"`
+}
+
+// Search the records of the view, the query of the request is ANDed with the query of the view.
+func (v *view) Search(ctx context.Context, req *SearchReq) (*consensus.Response, error) {
+	tv, err := v.getVisible(req.AppID, req.TableID, req.ID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	bus := new(consensus.Bus)
+	bus.Universal = consensus.Universal{
+		UserID:   req.UserID,
+		UserName: req.UserName,
+		DepID:    req.DepID,
+	}
+	bus.Foundation = consensus.Foundation{
+		AppID:   req.AppID,
+		TableID: req.TableID,
+		Method:  "search",
+	}
+	bus.Get.Query = andQuery(types.Query(tv.Query), req.Query)
+	bus.Get.Fields = tv.Columns
+	size := req.Size
+	if size <= 0 {
+		size = tv.PageSize
+	}
+	if size <= 0 {
+		size = defaultSize
+	}
+	page := req.Page
+	if page <= 0 {
+		page = 1
+	}
+	bus.List = consensus.List{
+		Page:   page,
+		Size:   size,
+		Sort:   tv.Sort,
+		Cursor: req.Cursor,
+	}
+	return v.guidance.Do(ctx, bus)
+}
+
+// check the sort keys and columns must be in the schema, the role must be of the app.
+func (v *view) check(appID, tableID string, base *ViewBase) error {
+	if strings.TrimSpace(base.Name) == "" {
+		return error2.New(code.ErrInvalidView, "name")
+	}
+	if base.PageSize < 0 || base.PageSize > MaxPageSize {
+		return error2.New(code.ErrInvalidView, "pageSize")
+	}
+	schema, err := v.tableSchemaRepo.Get(v.db, appID, tableID)
+	if err != nil {
+		return err
+	}
+	if schema.ID == "" {
+		return error2.New(code.ErrNotExistTable)
+	}
+	for _, key := range base.Sort {
+		if !hasField(schema.Schema, strings.TrimPrefix(key, descPrefix)) {
+			return error2.New(code.ErrInvalidView, key)
+		}
+	}
+	base.Columns = consensus.SplitFields(base.Columns)
+	for _, column := range base.Columns {
+		if !hasField(schema.Schema, column) {
+			return error2.New(code.ErrInvalidView, column)
+		}
+	}
+	if base.RoleID != "" {
+		role, err := v.roleRepo.Get(v.db, base.RoleID)
+		if err != nil {
+			return err
+		}
+		if role.ID == "" || role.AppID != appID {
+			return error2.New(code.ErrInvalidView, base.RoleID)
+		}
+	}
+	return nil
+}
+
+func (v *view) getOwned(appID, tableID, id, userID string) (*models.TableView, error) {
+	tv, err := v.getVisible(appID, tableID, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tv.CreatorID != userID {
+		return nil, error2.New(code.ErrNotViewOwner)
+	}
+	return tv, nil
+}
+
+func (v *view) getVisible(appID, tableID, id, userID string) (*models.TableView, error) {
+	tv, err := v.get(appID, tableID, id)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := v.visible(tv, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// the views of the others are not found.
+		return nil, error2.New(code.ErrNotExistView)
+	}
+	return tv, nil
+}
+
+func (v *view) get(appID, tableID, id string) (*models.TableView, error) {
+	tv, err := v.tableViewRepo.Get(v.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if tv.ID == "" || tv.AppID != appID || tv.TableID != tableID {
+		return nil, error2.New(code.ErrNotExistView)
+	}
+	return tv, nil
+}
+
+func (v *view) visible(tv *models.TableView, userID string) (bool, error) {
+	if tv.CreatorID == userID {
+		return true, nil
+	}
+	if tv.RoleID == "" {
+		return false, nil
+	}
+	roleID, err := v.roleOf(tv.AppID, userID)
+	if err != nil {
+		return false, err
+	}
+	return roleID == tv.RoleID, nil
+}
+
+// roleOf the role of the user in the app, empty if none.
+func (v *view) roleOf(appID, userID string) (string, error) {
+	userRole, err := v.userRoleRepo.Get(v.db, appID, userID)
+	if err != nil {
+		return "", err
+	}
+	return userRole.RoleID, nil
+}
+
+func setBase(tv *models.TableView, base *ViewBase) {
+	tv.Name = strings.TrimSpace(base.Name)
+	tv.Query = models.Condition(base.Query)
+	tv.Sort = base.Sort
+	tv.Columns = base.Columns
+	tv.PageSize = base.PageSize
+	tv.RoleID = base.RoleID
+}
+
+func toVo(tv *models.TableView) *ViewVo {
+	return &ViewVo{
+		ID:          tv.ID,
+		Name:        tv.Name,
+		Query:       types.Query(tv.Query),
+		Sort:        tv.Sort,
+		Columns:     tv.Columns,
+		PageSize:    tv.PageSize,
+		RoleID:      tv.RoleID,
+		CreatedAt:   tv.CreatedAt,
+		UpdatedAt:   tv.UpdatedAt,
+		CreatorID:   tv.CreatorID,
+		CreatorName: tv.CreatorName,
+	}
+}
+
+// andQuery both of the queries must be met, either may be empty.
+func andQuery(a, b types.Query) types.Query {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	return consensus.GetBool(consensus.Must, map[string]interface{}(a), map[string]interface{}(b))
+}
+
+// hasField the nested fields are joined by dots.
+func hasField(properties models.SchemaProperties, field string) bool {
+	keys := strings.Split(field, ".")
+	for index, key := range keys {
+		props, ok := properties[key]
+		if !ok || index != len(keys)-1 && props.Type != objectType {
+			return false
+		}
+		properties = props.Properties
+	}
+	return true
+}
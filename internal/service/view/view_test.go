@@ -0,0 +1,114 @@
+package view
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"gorm.io/gorm"
+)
+
+type fakeViewRepo struct {
+	models.TableViewRepo
+	views map[string]*models.TableView
+}
+
+func (f *fakeViewRepo) Get(db *gorm.DB, id string) (*models.TableView, error) {
+	if tv, ok := f.views[id]; ok {
+		return tv, nil
+	}
+	return &models.TableView{}, nil
+}
+
+type fakeUserRoleRepo struct {
+	models.UserRoleRepo
+	roles map[string]string
+}
+
+func (f *fakeUserRoleRepo) Get(db *gorm.DB, appID, userID string) (*models.UserRole, error) {
+	return &models.UserRole{AppID: appID, UserID: userID, RoleID: f.roles[userID]}, nil
+}
+
+type fakeGuidance struct {
+	bus *consensus.Bus
+}
+
+func (f *fakeGuidance) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	f.bus = bus
+	return &consensus.Response{}, nil
+}
+
+func errCode(err error) int64 {
+	if e, ok := err.(error2.Error); ok {
+		return e.Code
+	}
+	return 0
+}
+
+func TestSearch(t *testing.T) {
+	guidance := &fakeGuidance{}
+	v := &view{
+		guidance: guidance,
+		tableViewRepo: &fakeViewRepo{views: map[string]*models.TableView{
+			"v1": {
+				ID: "v1", AppID: "app", TableID: "order", CreatorID: "alice", RoleID: "sales",
+				Query:    models.Condition{"term": map[string]interface{}{"status": "open"}},
+				Sort:     models.Filters{"-created_at"},
+				Columns:  models.Filters{"name"},
+				PageSize: 20,
+			},
+		}},
+		userRoleRepo: &fakeUserRoleRepo{roles: map[string]string{"bob": "sales", "carol": "hr"}},
+	}
+
+	condition := types.Query{"term": map[string]interface{}{"creator_id": "bob"}}
+	_, err := v.Search(context.Background(), &SearchReq{
+		AppID: "app", TableID: "order", ID: "v1", UserID: "bob", Query: condition,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := guidance.bus
+	expect := types.Query(consensus.GetBool(consensus.Must,
+		map[string]interface{}{"term": map[string]interface{}{"status": "open"}},
+		map[string]interface{}(condition)))
+	if !reflect.DeepEqual(bus.Get.Query, expect) {
+		t.Fatalf("query %v", bus.Get.Query)
+	}
+	if bus.List.Size != 20 || bus.List.Page != 1 || !reflect.DeepEqual(bus.List.Sort, []string{"-created_at"}) ||
+		!reflect.DeepEqual(bus.Get.Fields, []string{"name"}) {
+		t.Fatalf("list %+v, fields %v", bus.List, bus.Get.Fields)
+	}
+
+	// the view is not shared to carol, and is of another table.
+	if _, err = v.Search(context.Background(), &SearchReq{AppID: "app", TableID: "order", ID: "v1", UserID: "carol"}); errCode(err) != code.ErrNotExistView {
+		t.Fatalf("carol should not see the view, got %v", err)
+	}
+	if _, err = v.Search(context.Background(), &SearchReq{AppID: "app", TableID: "item", ID: "v1", UserID: "alice"}); errCode(err) != code.ErrNotExistView {
+		t.Fatalf("the view is of another table, got %v", err)
+	}
+	// bob can see the view but only alice can change it.
+	if _, err = v.getOwned("app", "order", "v1", "bob"); errCode(err) != code.ErrNotViewOwner {
+		t.Fatalf("bob should not own the view, got %v", err)
+	}
+	if _, err = v.getOwned("app", "order", "v1", "alice"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHasField(t *testing.T) {
+	properties := models.SchemaProperties{
+		"name": {Type: "string"},
+		"addr": {Type: "object", Properties: models.SchemaProperties{"city": {Type: "string"}}},
+	}
+	for field, expect := range map[string]bool{"name": true, "addr.city": true, "addr.zip": false, "name.x": false, "": false} {
+		if hasField(properties, field) != expect {
+			t.Fatalf("%s should be %v", field, expect)
+		}
+	}
+}
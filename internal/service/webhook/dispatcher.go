@@ -0,0 +1,386 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/logger"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/models/mysql"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/internal/service/form/inform"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	"gorm.io/gorm"
+)
+
+const (
+	// SignatureHeader sha256=hex(hmac-sha256(secret, timestamp + "." + body)).
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader the unix seconds the request is signed at.
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+
+	signaturePrefix = "sha256="
+
+	// maxAttempts a delivery is failed after, it can be redelivered by hand.
+	maxAttempts = 5
+	// backoff the wait before the second attempt, doubled after each.
+	backoff     = time.Second
+	timeout     = 10 * time.Second
+	maxResponse = 1024
+	// lease how long a pending delivery is left to its sender after the next attempt is due,
+	// then the retry loop takes it over.
+	lease = time.Minute
+	// retryTick the retry loop looks for the deliveries left over every tick.
+	retryTick  = time.Minute
+	retryBatch = 100
+)
+
+const (
+	EventCreate = "create"
+	EventUpdate = "update"
+	EventDelete = "delete"
+)
+
+// Payload the body posted to the webhooks.
+type Payload struct {
+	WebhookID string `json:"webhookID"`
+	AppID     string `json:"appID"`
+	TableID   string `json:"tableID"`
+	Event     string `json:"event"`
+	// Timestamp the unix milliseconds the change is made at
+	Timestamp int64 `json:"timestamp"`
+	// Data the inform.FormData of the change
+	Data json.RawMessage `json:"data"`
+}
+
+// Sign the signature of the body, the receivers check it with the secret of the webhook.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher send the changes of the records to the webhooks of the table.
+type Dispatcher interface {
+	// Dispatch returns at once, the deliveries are made in the background.
+	Dispatch(ctx context.Context, appID string, data *inform.FormData)
+}
+
+type dispatcher struct {
+	db          *gorm.DB
+	webhookRepo models.WebhookRepo
+	guidance    consensus.Guidance
+	sender      *sender
+}
+
+// NewDispatcher the records are matched to the filters of the webhooks by the guidance.
+func NewDispatcher(conf *config.Config, guidance consensus.Guidance) (Dispatcher, error) {
+	db, err := service.CreateMysqlConn(conf)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := newSender(db, conf.Webhook)
+	if err != nil {
+		return nil, err
+	}
+	return &dispatcher{
+		db:          db,
+		webhookRepo: mysql.NewWebhookRepo(),
+		guidance:    guidance,
+		sender:      sender,
+	}, nil
+}
+
+func (d *dispatcher) Dispatch(ctx context.Context, appID string, data *inform.FormData) {
+	event := data.Event()
+	if event == "" {
+		return
+	}
+	// the data is taken now, the entity may be changed after the return.
+	raw, err := json.Marshal(data)
+	if err != nil {
+		logger.Logger.WithName("webhook").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return
+	}
+	var id interface{}
+	if entity, ok := data.Entity.(map[string]interface{}); ok {
+		id = entity[consensus.IDKey]
+	}
+	go d.dispatch(service.Detach(ctx), appID, data.TableID, event, id, raw)
+}
+
+func (d *dispatcher) dispatch(ctx context.Context, appID, tableID, event string, id interface{}, raw json.RawMessage) {
+	webhooks, _, err := d.webhookRepo.List(d.db, &models.WebhookQuery{
+		AppID:   appID,
+		TableID: tableID,
+		Enabled: true,
+	}, 1, 0)
+	if err != nil {
+		logger.Logger.WithName("webhook").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return
+	}
+	now := time2.NowUnix()
+	for _, hook := range webhooks {
+		if !hasEvent(hook.Events, event) {
+			continue
+		}
+		ok, err := d.match(ctx, hook, event, id)
+		if err != nil {
+			logger.Logger.WithName("webhook").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		body, err := json.Marshal(&Payload{
+			WebhookID: hook.ID,
+			AppID:     appID,
+			TableID:   tableID,
+			Event:     event,
+			Timestamp: now,
+			Data:      raw,
+		})
+		if err != nil {
+			logger.Logger.WithName("webhook").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			continue
+		}
+		delivery, err := d.sender.create(hook, event, string(body))
+		if err != nil {
+			logger.Logger.WithName("webhook").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			continue
+		}
+		go d.sender.send(ctx, hook, delivery)
+	}
+}
+
+// match the record must match the filter of the webhook,
+// the deleted records are gone, so the filter is not applied to delete.
+func (d *dispatcher) match(ctx context.Context, hook *models.Webhook, event string, id interface{}) (bool, error) {
+	if len(hook.Filter) == 0 || event == EventDelete {
+		return true, nil
+	}
+	if id == nil {
+		return false, nil
+	}
+	bus := new(consensus.Bus)
+	bus.Foundation = consensus.Foundation{
+		AppID:   hook.AppID,
+		TableID: hook.TableID,
+		Method:  "search",
+	}
+	bus.Get.Query = types.Query(consensus.GetBool(consensus.Must,
+		consensus.GetSimple(consensus.TermKey, consensus.IDKey, id),
+		map[string]interface{}(hook.Filter),
+	))
+	bus.Get.Fields = []string{consensus.IDKey}
+	bus.List = consensus.List{
+		Page: 1,
+		Size: 1,
+	}
+	resp, err := d.guidance.Do(ctx, bus)
+	if err != nil {
+		return false, err
+	}
+	return len(resp.Entities) != 0, nil
+}
+
+func hasEvent(events []string, event string) bool {
+	for _, elem := range events {
+		if elem == event {
+			return true
+		}
+	}
+	return false
+}
+
+// sender post the deliveries, with retries.
+type sender struct {
+	db           *gorm.DB
+	webhookRepo  models.WebhookRepo
+	deliveryRepo models.WebhookDeliveryRepo
+	egress       *egress
+	client       *http.Client
+	backoff      time.Duration
+}
+
+func newSender(db *gorm.DB, conf config.Webhook) (*sender, error) {
+	egress, err := newEgress(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &sender{
+		db:           db,
+		webhookRepo:  mysql.NewWebhookRepo(),
+		deliveryRepo: mysql.NewWebhookDeliveryRepo(),
+		egress:       egress,
+		client: &http.Client{
+			Timeout: timeout,
+			// no proxy, the guard checks the address dialed, which would be the proxy's.
+			Transport: &http.Transport{
+				Proxy:       nil,
+				DialContext: egress.dialContext(timeout),
+			},
+			// the redirects are not followed, they could lead to the denied hosts.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		backoff: backoff,
+	}, nil
+}
+
+func (s *sender) create(hook *models.Webhook, event, payload string) (*models.WebhookDelivery, error) {
+	now := time2.NowUnix()
+	delivery := &models.WebhookDelivery{
+		ID:        id2.StringUUID(),
+		WebhookID: hook.ID,
+		AppID:     hook.AppID,
+		TableID:   hook.TableID,
+		Event:     event,
+		Payload:   payload,
+		Status:    models.DeliveryPending,
+		NextAt:    now + s.leaseOf(0),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.deliveryRepo.Create(s.db, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// send retry with backoff until a 2xx response, each attempt is logged to the delivery.
+func (s *sender) send(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) {
+	wait := s.backoff
+	for delivery.Attempts < maxAttempts {
+		if delivery.Attempts != 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+		delivery.Attempts++
+		statusCode, response, err := s.post(ctx, hook, delivery)
+		delivery.StatusCode, delivery.Response = statusCode, truncate(response, maxResponse)
+		if err != nil {
+			delivery.Response = truncate(err.Error(), maxResponse)
+		}
+		switch {
+		case err == nil && statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices:
+			delivery.Status = models.DeliverySucceed
+		case delivery.Attempts >= maxAttempts:
+			delivery.Status = models.DeliveryFailed
+		}
+		delivery.UpdatedAt = time2.NowUnix()
+		delivery.NextAt = delivery.UpdatedAt + s.leaseOf(wait)
+		if err := s.deliveryRepo.Update(s.db, delivery.ID, delivery); err != nil {
+			logger.Logger.WithName("webhook").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		}
+		if delivery.Status != models.DeliveryPending {
+			return
+		}
+	}
+}
+
+func (s *sender) post(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, timestamp, body))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	if err != nil {
+		return resp.StatusCode, "", fmt.Errorf("read response: %w", err)
+	}
+	return resp.StatusCode, string(data), nil
+}
+
+// leaseOf the milliseconds a pending delivery is kept by its sender, which waits before the next attempt.
+func (s *sender) leaseOf(wait time.Duration) int64 {
+	return int64((wait + timeout + lease) / time.Millisecond)
+}
+
+// retry take over the pending deliveries whose senders are gone, like the ones of a stopped service.
+func (s *sender) retry(ctx context.Context) {
+	now := time2.NowUnix()
+	deliveries, _, err := s.deliveryRepo.List(s.db, &models.WebhookDeliveryQuery{
+		Status: models.DeliveryPending,
+		DueAt:  now,
+	}, 1, retryBatch)
+	if err != nil {
+		logger.Logger.WithName("webhook").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+		return
+	}
+	for _, delivery := range deliveries {
+		// another instance may take it at the same time, only one claims it.
+		claimed, err := s.deliveryRepo.Claim(s.db, delivery.ID, delivery.NextAt, now+s.leaseOf(0))
+		if err != nil {
+			logger.Logger.WithName("webhook").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		hook, err := s.webhookRepo.Get(s.db, delivery.WebhookID)
+		if err != nil {
+			logger.Logger.WithName("webhook").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+			continue
+		}
+		switch {
+		case hook.ID == "" || hook.Disabled:
+			s.fail(ctx, delivery, "the webhook is deleted or disabled")
+		case delivery.Attempts >= maxAttempts:
+			s.fail(ctx, delivery, delivery.Response)
+		default:
+			go s.send(ctx, hook, delivery)
+		}
+	}
+}
+
+func (s *sender) fail(ctx context.Context, delivery *models.WebhookDelivery, response string) {
+	delivery.Status = models.DeliveryFailed
+	delivery.Response = response
+	delivery.UpdatedAt = time2.NowUnix()
+	if err := s.deliveryRepo.Update(s.db, delivery.ID, delivery); err != nil {
+		logger.Logger.WithName("webhook").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
+	}
+}
+
+// truncate s to n bytes at most, without breaking a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,257 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/service/consensus"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	"gorm.io/gorm"
+)
+
+type fakeWebhookRepo struct {
+	models.WebhookRepo
+	hooks []*models.Webhook
+}
+
+func (f *fakeWebhookRepo) Get(db *gorm.DB, id string) (*models.Webhook, error) {
+	for _, hook := range f.hooks {
+		if hook.ID == id {
+			return hook, nil
+		}
+	}
+	return &models.Webhook{}, nil
+}
+
+func (f *fakeWebhookRepo) List(db *gorm.DB, query *models.WebhookQuery, page, size int) ([]*models.Webhook, int64, error) {
+	return f.hooks, int64(len(f.hooks)), nil
+}
+
+type fakeDeliveryRepo struct {
+	models.WebhookDeliveryRepo
+	mu         sync.Mutex
+	deliveries map[string]models.WebhookDelivery
+}
+
+func (f *fakeDeliveryRepo) Create(db *gorm.DB, delivery *models.WebhookDelivery) error {
+	return f.Update(db, delivery.ID, delivery)
+}
+
+func (f *fakeDeliveryRepo) Update(db *gorm.DB, id string, delivery *models.WebhookDelivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deliveries[id] = *delivery
+	return nil
+}
+
+func (f *fakeDeliveryRepo) List(db *gorm.DB, query *models.WebhookDeliveryQuery, page, size int) ([]*models.WebhookDelivery, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	list := make([]*models.WebhookDelivery, 0)
+	for _, delivery := range f.deliveries {
+		if delivery.Status == query.Status && delivery.NextAt <= query.DueAt {
+			copied := delivery
+			list = append(list, &copied)
+		}
+	}
+	return list, int64(len(list)), nil
+}
+
+func (f *fakeDeliveryRepo) Claim(db *gorm.DB, id string, nextAt, until int64) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delivery := f.deliveries[id]
+	if delivery.Status != models.DeliveryPending || delivery.NextAt != nextAt {
+		return false, nil
+	}
+	delivery.NextAt = until
+	f.deliveries[id] = delivery
+	return true, nil
+}
+
+func (f *fakeDeliveryRepo) get(id string) models.WebhookDelivery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.deliveries[id]
+}
+
+func (f *fakeDeliveryRepo) list() []models.WebhookDelivery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	list := make([]models.WebhookDelivery, 0, len(f.deliveries))
+	for _, delivery := range f.deliveries {
+		list = append(list, delivery)
+	}
+	return list
+}
+
+// fakeGuidance no record matches the filters.
+type fakeGuidance struct{}
+
+func (fakeGuidance) Do(ctx context.Context, bus *consensus.Bus) (*consensus.Response, error) {
+	return &consensus.Response{}, nil
+}
+
+func TestDispatch(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []*http.Request
+		bodies   [][]byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		if len(requests) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	deliveries := &fakeDeliveryRepo{deliveries: map[string]models.WebhookDelivery{}}
+	d := &dispatcher{
+		webhookRepo: &fakeWebhookRepo{hooks: []*models.Webhook{
+			{ID: "h1", AppID: "app", TableID: "t", URL: srv.URL, Secret: "s", Events: []string{EventCreate}},
+			{ID: "h2", AppID: "app", TableID: "t", URL: srv.URL, Events: []string{EventUpdate}},
+			{ID: "h3", AppID: "app", TableID: "t", URL: srv.URL, Events: []string{EventCreate},
+				Filter: models.Condition{"term": map[string]interface{}{"status": "open"}}},
+		}},
+		guidance: fakeGuidance{},
+		sender: &sender{
+			deliveryRepo: deliveries,
+			client:       srv.Client(),
+			backoff:      time.Millisecond,
+		},
+	}
+	d.dispatch(context.Background(), "app", "t", EventCreate, "r1", json.RawMessage(`{"tableID":"t"}`))
+
+	var list []models.WebhookDelivery
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if list = deliveries.list(); len(list) == 1 && list[0].Status != models.DeliveryPending {
+			break
+		}
+	}
+	if len(list) != 1 || list[0].WebhookID != "h1" || list[0].Status != models.DeliverySucceed ||
+		list[0].Attempts != 2 || list[0].StatusCode != http.StatusOK || list[0].Response != "ok" {
+		t.Fatalf("deliveries %+v", list)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	r, body := requests[1], bodies[1]
+	timestamp, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+	if r.Header.Get(SignatureHeader) != Sign("s", timestamp, body) || r.Header.Get(EventHeader) != EventCreate ||
+		r.Header.Get(DeliveryHeader) != list[0].ID {
+		t.Fatalf("headers %v", r.Header)
+	}
+	payload := new(Payload)
+	if err := json.Unmarshal(body, payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.WebhookID != "h1" || payload.Event != EventCreate || string(payload.Data) != `{"tableID":"t"}` {
+		t.Fatalf("payload %s", body)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	deliveries := &fakeDeliveryRepo{deliveries: map[string]models.WebhookDelivery{
+		// left over by a stopped sender.
+		"d1": {ID: "d1", WebhookID: "h1", Status: models.DeliveryPending, Attempts: 2, NextAt: 1},
+		// still kept by its sender.
+		"d2": {ID: "d2", WebhookID: "h1", Status: models.DeliveryPending, Attempts: 1, NextAt: 1 << 62},
+		"d3": {ID: "d3", WebhookID: "h2", Status: models.DeliveryPending, Attempts: 1, NextAt: 1},
+		"d4": {ID: "d4", WebhookID: "h1", Status: models.DeliveryPending, Attempts: maxAttempts, NextAt: 1},
+	}}
+	s := &sender{
+		webhookRepo: &fakeWebhookRepo{hooks: []*models.Webhook{
+			{ID: "h1", URL: srv.URL},
+			{ID: "h2", URL: srv.URL, Disabled: true},
+		}},
+		deliveryRepo: deliveries,
+		client:       srv.Client(),
+		backoff:      time.Millisecond,
+	}
+	s.retry(context.Background())
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if deliveries.get("d1").Status != models.DeliveryPending {
+			break
+		}
+	}
+	if d := deliveries.get("d1"); d.Status != models.DeliverySucceed || d.Attempts != 3 {
+		t.Fatalf("d1 should be taken over, got %+v", d)
+	}
+	if d := deliveries.get("d2"); d.Status != models.DeliveryPending || d.Attempts != 1 {
+		t.Fatalf("d2 is not due, got %+v", d)
+	}
+	if d := deliveries.get("d3"); d.Status != models.DeliveryFailed {
+		t.Fatalf("the webhook of d3 is disabled, got %+v", d)
+	}
+	if d := deliveries.get("d4"); d.Status != models.DeliveryFailed || d.Attempts != maxAttempts {
+		t.Fatalf("d4 has no attempt left, got %+v", d)
+	}
+}
+
+func TestEgress(t *testing.T) {
+	e, err := newEgress(config.Webhook{
+		Allow: []string{"hooks.internal", "10.1.0.0/16"},
+		Deny:  []string{"203.0.113.7"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for host, allowed := range map[string]bool{
+		"127.0.0.1":       false,
+		"169.254.169.254": false,
+		"192.168.1.1":     false,
+		"::1":             false,
+		"10.2.0.1":        false,
+		"10.1.0.1":        true,
+		"203.0.113.7":     false,
+		"203.0.113.8":     true,
+		"hooks.internal":  true,
+		"localhost":       false,
+	} {
+		if err := e.check(ctx, host); (err == nil) != allowed {
+			t.Fatalf("%s allowed %v, got %v", host, allowed, err)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	client := &http.Client{Transport: &http.Transport{DialContext: e.dialContext(time.Second)}}
+	if _, err = client.Get(srv.URL); err == nil {
+		t.Fatal("the loopback should be denied on dial")
+	}
+
+	// a proxy would be dialed instead of the host, the sender never takes one from the environment.
+	s, err := newSender(nil, config.Webhook{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transport := s.client.Transport.(*http.Transport); transport.Proxy != nil {
+		t.Fatal("the sender should not use a proxy")
+	}
+
+	if s := truncate("ab中", 4); s != "ab" {
+		t.Fatalf("truncate %q", s)
+	}
+}
@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+)
+
+// reserved the addresses of the cluster and the host, like the metadata of the cloud,
+// which are denied unless allowed.
+var reserved = mustCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+)
+
+// egress the hosts the webhooks may post to, it is checked on save, and again on dial,
+// since the names may be resolved to another address later.
+type egress struct {
+	allowHosts map[string]bool
+	allowNets  []*net.IPNet
+	denyHosts  map[string]bool
+	denyNets   []*net.IPNet
+	resolver   *net.Resolver
+}
+
+func newEgress(conf config.Webhook) (*egress, error) {
+	e := &egress{
+		allowHosts: make(map[string]bool),
+		denyHosts:  make(map[string]bool),
+		resolver:   net.DefaultResolver,
+	}
+	var err error
+	if e.allowNets, err = parseHosts(conf.Allow, e.allowHosts); err != nil {
+		return nil, err
+	}
+	if e.denyNets, err = parseHosts(conf.Deny, e.denyHosts); err != nil {
+		return nil, err
+	}
+	e.denyNets = append(e.denyNets, reserved...)
+	return e, nil
+}
+
+// check the host and all the addresses of it.
+func (e *egress) check(ctx context.Context, host string) error {
+	host = strings.ToLower(host)
+	if e.denyHosts[host] {
+		return fmt.Errorf("host %s is denied", host)
+	}
+	if e.allowHosts[host] {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return e.checkIP(ip)
+	}
+	addrs, err := e.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if err = e.checkIP(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *egress) checkIP(ip net.IP) error {
+	for _, n := range e.allowNets {
+		if n.Contains(ip) {
+			return nil
+		}
+	}
+	if ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("address %s is denied", ip)
+	}
+	for _, n := range e.denyNets {
+		if n.Contains(ip) {
+			return fmt.Errorf("address %s is denied", ip)
+		}
+	}
+	return nil
+}
+
+// dialContext the addresses are checked when connected, unless the host is allowed by name.
+func (e *egress) dialContext(timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	plain := &net.Dialer{Timeout: timeout}
+	guarded := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("address %s is not an ip", host)
+			}
+			return e.checkIP(ip)
+		},
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		host = strings.ToLower(host)
+		if e.denyHosts[host] {
+			return nil, fmt.Errorf("host %s is denied", host)
+		}
+		if e.allowHosts[host] {
+			return plain.DialContext(ctx, network, addr)
+		}
+		return guarded.DialContext(ctx, network, addr)
+	}
+}
+
+// parseHosts the CIDRs and the IPs are returned, the names are put into hosts.
+func parseHosts(entries []string, hosts map[string]bool) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			_, n, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, err
+			}
+			nets = append(nets, n)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		hosts[entry] = true
+	}
+	return nets, nil
+}
+
+func mustCIDRs(cidrs ...string) []*net.IPNet {
+	nets, err := parseHosts(cidrs, nil)
+	if err != nil {
+		panic(err)
+	}
+	return nets
+}
@@ -0,0 +1,406 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+
+	error2 "github.com/quanxiang-cloud/cabin/error"
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/internal/models/mysql"
+	"github.com/quanxiang-cloud/form/internal/service"
+	"github.com/quanxiang-cloud/form/internal/service/types"
+	"github.com/quanxiang-cloud/form/pkg/misc/code"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+	"gorm.io/gorm"
+)
+
+// Webhook the webhooks of the tables of an app, and their deliveries.
+type Webhook interface {
+	CreateWebhook(ctx context.Context, req *CreateWebhookReq) (*CreateWebhookResp, error)
+	UpdateWebhook(ctx context.Context, req *UpdateWebhookReq) (*UpdateWebhookResp, error)
+	DeleteWebhook(ctx context.Context, req *DeleteWebhookReq) (*DeleteWebhookResp, error)
+	GetWebhook(ctx context.Context, req *GetWebhookReq) (*WebhookVo, error)
+	ListWebhook(ctx context.Context, req *ListWebhookReq) (*ListWebhookResp, error)
+	ListDelivery(ctx context.Context, req *ListDeliveryReq) (*ListDeliveryResp, error)
+	Redeliver(ctx context.Context, req *RedeliverReq) (*RedeliverResp, error)
+	// Run retry the pending deliveries left over by the stopped senders until the ctx is done.
+	Run(ctx context.Context)
+}
+
+type webhook struct {
+	db              *gorm.DB
+	webhookRepo     models.WebhookRepo
+	deliveryRepo    models.WebhookDeliveryRepo
+	tableSchemaRepo models.TableSchemeRepo
+	sender          *sender
+}
+
+func NewWebhook(conf *config.Config) (Webhook, error) {
+	db, err := service.CreateMysqlConn(conf)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := newSender(db, conf.Webhook)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook{
+		db:              db,
+		webhookRepo:     mysql.NewWebhookRepo(),
+		deliveryRepo:    mysql.NewWebhookDeliveryRepo(),
+		tableSchemaRepo: mysql.NewTableSchema(),
+		sender:          sender,
+	}, nil
+}
+
+func (w *webhook) Run(ctx context.Context) {
+	ticker := time.NewTicker(retryTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.sender.retry(ctx)
+		}
+	}
+}
+
+// WebhookBase the subscription.
+type WebhookBase struct {
+	TableID string `json:"tableID" binding:"required"`
+	Name    string `json:"name" binding:"required"`
+	URL     string `json:"url" binding:"required"`
+	// Events create, update or delete
+	Events []string `json:"events" binding:"required"`
+	// Filter only the created or updated records matching the query are sent
+	Filter   types.Query `json:"filter"`
+	Disabled bool        `json:"disabled"`
+}
+
+type CreateWebhookReq struct {
+	AppID string `json:"appID"`
+	WebhookBase
+	UserID   string `json:"-"`
+	UserName string `json:"-"`
+}
+
+type CreateWebhookResp struct {
+	ID string `json:"id"`
+	// Secret the signing key, only returned on create
+	Secret string `json:"secret"`
+}
+
+// CreateWebhook a secret is generated for the webhook.
+func (w *webhook) CreateWebhook(ctx context.Context, req *CreateWebhookReq) (*CreateWebhookResp, error) {
+	if err := w.check(ctx, req.AppID, &req.WebhookBase); err != nil {
+		return nil, err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	now := time2.NowUnix()
+	hook := &models.Webhook{
+		ID:          id2.StringUUID(),
+		AppID:       req.AppID,
+		Secret:      secret,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatorID:   req.UserID,
+		CreatorName: req.UserName,
+	}
+	setBase(hook, &req.WebhookBase)
+	if err = w.webhookRepo.Create(w.db, hook); err != nil {
+		return nil, err
+	}
+	return &CreateWebhookResp{
+		ID:     hook.ID,
+		Secret: secret,
+	}, nil
+}
+
+type UpdateWebhookReq struct {
+	AppID string `json:"appID"`
+	ID    string `json:"id" binding:"required"`
+	WebhookBase
+}
+
+type UpdateWebhookResp struct{}
+
+// UpdateWebhook the secret is kept.
+func (w *webhook) UpdateWebhook(ctx context.Context, req *UpdateWebhookReq) (*UpdateWebhookResp, error) {
+	hook, err := w.get(req.AppID, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err = w.check(ctx, req.AppID, &req.WebhookBase); err != nil {
+		return nil, err
+	}
+	setBase(hook, &req.WebhookBase)
+	hook.UpdatedAt = time2.NowUnix()
+	if err = w.webhookRepo.Update(w.db, hook.ID, hook); err != nil {
+		return nil, err
+	}
+	return &UpdateWebhookResp{}, nil
+}
+
+type DeleteWebhookReq struct {
+	AppID string `json:"appID"`
+	ID    string `json:"id" binding:"required"`
+}
+
+type DeleteWebhookResp struct{}
+
+// DeleteWebhook the deliveries are deleted too.
+func (w *webhook) DeleteWebhook(ctx context.Context, req *DeleteWebhookReq) (*DeleteWebhookResp, error) {
+	hook, err := w.get(req.AppID, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = w.db.Transaction(func(tx *gorm.DB) error {
+		if err := w.webhookRepo.Delete(tx, &models.WebhookQuery{ID: hook.ID}); err != nil {
+			return err
+		}
+		return w.deliveryRepo.Delete(tx, &models.WebhookDeliveryQuery{WebhookID: hook.ID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &DeleteWebhookResp{}, nil
+}
+
+type GetWebhookReq struct {
+	AppID string `json:"appID"`
+	ID    string `json:"id" binding:"required"`
+}
+
+// WebhookVo the webhook without the secret.
+type WebhookVo struct {
+	ID          string      `json:"id"`
+	TableID     string      `json:"tableID"`
+	Name        string      `json:"name"`
+	URL         string      `json:"url"`
+	Events      []string    `json:"events"`
+	Filter      types.Query `json:"filter"`
+	Disabled    bool        `json:"disabled"`
+	CreatedAt   int64       `json:"createdAt"`
+	UpdatedAt   int64       `json:"updatedAt"`
+	CreatorID   string      `json:"creatorID"`
+	CreatorName string      `json:"creatorName"`
+}
+
+func (w *webhook) GetWebhook(ctx context.Context, req *GetWebhookReq) (*WebhookVo, error) {
+	hook, err := w.get(req.AppID, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return toVo(hook), nil
+}
+
+type ListWebhookReq struct {
+	AppID   string `json:"appID"`
+	TableID string `json:"tableID"`
+	Page    int    `json:"page"`
+	Size    int    `json:"size"`
+}
+
+type ListWebhookResp struct {
+	List  []*WebhookVo `json:"list"`
+	Total int64        `json:"total"`
+}
+
+// ListWebhook the webhooks of the app, or of the table if any.
+func (w *webhook) ListWebhook(ctx context.Context, req *ListWebhookReq) (*ListWebhookResp, error) {
+	hooks, total, err := w.webhookRepo.List(w.db, &models.WebhookQuery{
+		AppID:   req.AppID,
+		TableID: req.TableID,
+	}, req.Page, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*WebhookVo, 0, len(hooks))
+	for _, hook := range hooks {
+		list = append(list, toVo(hook))
+	}
+	return &ListWebhookResp{
+		List:  list,
+		Total: total,
+	}, nil
+}
+
+type ListDeliveryReq struct {
+	AppID     string `json:"appID"`
+	WebhookID string `json:"webhookID" binding:"required"`
+	// Status pending, succeed or failed, all if empty
+	Status models.DeliveryStatus `json:"status"`
+	Page   int                   `json:"page"`
+	Size   int                   `json:"size"`
+}
+
+type DeliveryVo struct {
+	ID         string                `json:"id"`
+	WebhookID  string                `json:"webhookID"`
+	TableID    string                `json:"tableID"`
+	Event      string                `json:"event"`
+	Payload    string                `json:"payload"`
+	Status     models.DeliveryStatus `json:"status"`
+	Attempts   int                   `json:"attempts"`
+	StatusCode int                   `json:"statusCode"`
+	Response   string                `json:"response"`
+	CreatedAt  int64                 `json:"createdAt"`
+	UpdatedAt  int64                 `json:"updatedAt"`
+}
+
+type ListDeliveryResp struct {
+	List  []*DeliveryVo `json:"list"`
+	Total int64         `json:"total"`
+}
+
+// ListDelivery the deliveries of a webhook, the latest first.
+func (w *webhook) ListDelivery(ctx context.Context, req *ListDeliveryReq) (*ListDeliveryResp, error) {
+	hook, err := w.get(req.AppID, req.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, total, err := w.deliveryRepo.List(w.db, &models.WebhookDeliveryQuery{
+		WebhookID: hook.ID,
+		Status:    req.Status,
+	}, req.Page, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*DeliveryVo, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		list = append(list, &DeliveryVo{
+			ID:         delivery.ID,
+			WebhookID:  delivery.WebhookID,
+			TableID:    delivery.TableID,
+			Event:      delivery.Event,
+			Payload:    delivery.Payload,
+			Status:     delivery.Status,
+			Attempts:   delivery.Attempts,
+			StatusCode: delivery.StatusCode,
+			Response:   delivery.Response,
+			CreatedAt:  delivery.CreatedAt,
+			UpdatedAt:  delivery.UpdatedAt,
+		})
+	}
+	return &ListDeliveryResp{
+		List:  list,
+		Total: total,
+	}, nil
+}
+
+type RedeliverReq struct {
+	AppID string `json:"appID"`
+	ID    string `json:"id" binding:"required"`
+}
+
+type RedeliverResp struct {
+	// ID the new delivery
+	ID string `json:"id"`
+}
+
+// Redeliver post the payload of a delivery again, as a new delivery, to the current url of the webhook.
+func (w *webhook) Redeliver(ctx context.Context, req *RedeliverReq) (*RedeliverResp, error) {
+	delivery, err := w.deliveryRepo.Get(w.db, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.ID == "" || delivery.AppID != req.AppID {
+		return nil, error2.New(code.ErrNotExistDelivery)
+	}
+	hook, err := w.get(req.AppID, delivery.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+	redelivery, err := w.sender.create(hook, delivery.Event, delivery.Payload)
+	if err != nil {
+		return nil, err
+	}
+	go w.sender.send(service.Detach(ctx), hook, redelivery)
+	return &RedeliverResp{
+		ID: redelivery.ID,
+	}, nil
+}
+
+// check the table must exist, the url must be http or https, to a host allowed by the egress.
+func (w *webhook) check(ctx context.Context, appID string, base *WebhookBase) error {
+	if strings.TrimSpace(base.Name) == "" {
+		return error2.New(code.ErrInvalidWebhook, "name")
+	}
+	u, err := url.Parse(base.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return error2.New(code.ErrInvalidWebhook, "url")
+	}
+	if err = w.sender.egress.check(ctx, u.Hostname()); err != nil {
+		return error2.New(code.ErrInvalidWebhook, err.Error())
+	}
+	if len(base.Events) == 0 {
+		return error2.New(code.ErrInvalidWebhook, "events")
+	}
+	for _, event := range base.Events {
+		if event != EventCreate && event != EventUpdate && event != EventDelete {
+			return error2.New(code.ErrInvalidWebhook, event)
+		}
+	}
+	schema, err := w.tableSchemaRepo.Get(w.db, appID, base.TableID)
+	if err != nil {
+		return err
+	}
+	if schema.ID == "" {
+		return error2.New(code.ErrNotExistTable)
+	}
+	return nil
+}
+
+func (w *webhook) get(appID, id string) (*models.Webhook, error) {
+	hook, err := w.webhookRepo.Get(w.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if hook.ID == "" || hook.AppID != appID {
+		return nil, error2.New(code.ErrNotExistWebhook)
+	}
+	return hook, nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func setBase(hook *models.Webhook, base *WebhookBase) {
+	hook.TableID = base.TableID
+	hook.Name = strings.TrimSpace(base.Name)
+	hook.URL = base.URL
+	hook.Events = base.Events
+	hook.Filter = models.Condition(base.Filter)
+	hook.Disabled = base.Disabled
+}
+
+func toVo(hook *models.Webhook) *WebhookVo {
+	return &WebhookVo{
+		ID:          hook.ID,
+		TableID:     hook.TableID,
+		Name:        hook.Name,
+		URL:         hook.URL,
+		Events:      hook.Events,
+		Filter:      types.Query(hook.Filter),
+		Disabled:    hook.Disabled,
+		CreatedAt:   hook.CreatedAt,
+		UpdatedAt:   hook.UpdatedAt,
+		CreatorID:   hook.CreatorID,
+		CreatorName: hook.CreatorName,
+	}
+}
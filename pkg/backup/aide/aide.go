@@ -22,7 +22,9 @@ var defaultReq = ExportReq{
 // Aide is the interface of aide.
 type Aide interface {
 	Export(ctx context.Context, opts *ExportOption) (map[string]Object, error)
-	Import(ctx context.Context, objs map[string]Object, opts *ImportOption) (map[string]string, error)
+	// Remap give the objects new ids in the app of opts, the old ids to the new ones are returned.
+	// the objects of all the aides are imported at once, see Backup.Import.
+	Remap(ctx context.Context, objs map[string]Object, opts *ImportOption) (map[string]Object, map[string]string, error)
 }
 
 // Object is a slice of interface{}.
@@ -31,6 +33,9 @@ type Object []interface{}
 // ExportOption is the option of export.
 type ExportOption struct {
 	AppID string `required:"true"`
+	// Since only the records created or updated since, in unix milliseconds, all if zero;
+	// the other objects are always exported in full.
+	Since int64
 
 	// these parameters do not need to be passed
 	Host   string
@@ -39,8 +44,9 @@ type ExportOption struct {
 
 // ExportReq is the request of export.
 type ExportReq struct {
-	Page int `json:"page"`
-	Size int `json:"size"`
+	Page  int   `json:"page"`
+	Size  int   `json:"size"`
+	Since int64 `json:"since,omitempty"`
 }
 
 // ExportResp is the response of export.
@@ -56,6 +62,12 @@ type ImportOption struct {
 	UserID   string `required:"true"`
 	UserName string `required:"true"`
 
+	// DryRun check the objects against the app, nothing is written.
+	DryRun bool
+	// Strategies what to do with the objects which exist in the app, by the kinds:
+	// tables, tableRelations and roles, to skip, overwrite or rename; the conflicts fail the import without.
+	Strategies map[string]string
+
 	// these parameters do not need to be passed
 	Host   string
 	Client http.Client
@@ -63,11 +75,25 @@ type ImportOption struct {
 
 // ExportObject export object.
 func ExportObject(ctx context.Context, url string, opts *ExportOption) (Object, error) {
+	result := make(Object, 0)
+	err := ExportPages(ctx, url, opts, func(page Object) error {
+		result = append(result, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ExportPages export object page by page, each page is passed to fn.
+func ExportPages(ctx context.Context, url string, opts *ExportOption, fn func(Object) error) error {
 	var (
 		totalPage = 0
 		req       = defaultReq
-		result    = make(Object, 0)
 	)
+	req.Since = opts.Since
 
 	for {
 		resp := &ExportResp{}
@@ -75,24 +101,26 @@ func ExportObject(ctx context.Context, url string, opts *ExportOption) (Object,
 		if err != nil {
 			logger.Logger.WithName("export request").Errorf("send http request failed: %v", err)
 
-			return nil, err
+			return err
 		}
 
-		result = append(result, resp.Data...)
-
-		if resp.Count <= req.Size {
-			break
+		if err = fn(resp.Data); err != nil {
+			return err
 		}
 
-		// count is greater than size
+		// count is the total of all pages
 		if totalPage == 0 {
 			totalPage = callTotalPage(resp.Count, req.Size)
 		}
 
+		if req.Page >= totalPage || len(resp.Data) == 0 {
+			break
+		}
+
 		req.Page++
 	}
 
-	return result, nil
+	return nil
 }
 
 // ImportReq is the request of import.
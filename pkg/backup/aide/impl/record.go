@@ -0,0 +1,35 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quanxiang-cloud/form/pkg/backup/aide"
+)
+
+var (
+	exportRecordURL = "%s/api/v1/form/%s/internal/backup/export/record/%s"
+	importRecordURL = "%s/api/v1/form/%s/internal/backup/import/record/%s"
+)
+
+// Record the records of the tables, they are too many to be held as an aide, so they are passed page by page.
+// INFO: the records keep their ids, the tables keep theirs in the new app.
+type Record struct{}
+
+// Export export the records of the table, or of the relation of a table to its sub table.
+func (r *Record) Export(ctx context.Context, tableID string, opts *aide.ExportOption, fn func(aide.Object) error) error {
+	url := fmt.Sprintf(exportRecordURL, opts.Host, opts.AppID, tableID)
+
+	return aide.ExportPages(ctx, url, opts, fn)
+}
+
+// Import import.
+func (r *Record) Import(ctx context.Context, tableID string, obj aide.Object, opts *aide.ImportOption) error {
+	if len(obj) == 0 {
+		return nil
+	}
+
+	url := fmt.Sprintf(importRecordURL, opts.Host, opts.AppID, tableID)
+
+	return aide.ImportObject(ctx, url, obj, opts)
+}
@@ -13,10 +13,8 @@ import (
 
 var (
 	exportRoleURL = "%s/api/v1/form/%s/internal/backup/export/role"
-	importRoleURL = "%s/api/v1/form/%s/internal/backup/import/role"
 
 	exportPermitURL = "%s/api/v1/form/%s/internal/backup/export/permit"
-	importPermitURL = "%s/api/v1/form/%s/internal/backup/import/permit"
 )
 
 // Role role.
@@ -73,40 +71,41 @@ func (r *Role) exportPermit(ctx context.Context, opts *aide.ExportOption) (aide.
 	return obj, nil
 }
 
-// Import import.
-func (r *Role) Import(ctx context.Context, objs map[string]aide.Object, opts *aide.ImportOption) (map[string]string, error) {
+// Remap remap.
+func (r *Role) Remap(ctx context.Context, objs map[string]aide.Object, opts *aide.ImportOption) (map[string]aide.Object, map[string]string, error) {
 	ids := make(map[string]string)
+	// the app of the last backup.
+	r.appID = ""
 
-	roleObj := objs[r.roleTag()]
-
-	roleIDs, err := r.importRole(ctx, roleObj, opts)
+	roleObj, roleIDs, err := r.remapRole(objs[r.roleTag()], opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	for k, v := range roleIDs {
 		ids[k] = v
 	}
 
-	permitObj := objs[r.permitTag()]
-
-	permitIDs, err := r.importPermit(ctx, permitObj, roleIDs, opts)
+	permitObj, permitIDs, err := r.remapPermit(objs[r.permitTag()], roleIDs, opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	for k, v := range permitIDs {
 		ids[k] = v
 	}
 
-	return ids, nil
+	return map[string]aide.Object{
+		r.roleTag():   roleObj,
+		r.permitTag(): permitObj,
+	}, ids, nil
 }
 
-func (r *Role) importRole(ctx context.Context, obj aide.Object, opts *aide.ImportOption) (map[string]string, error) {
+func (r *Role) remapRole(obj aide.Object, opts *aide.ImportOption) (aide.Object, map[string]string, error) {
 	var roles []*models.Role
 	err := aide.Serialize(obj, &roles)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	ids := r.replaceRoleParam(roles, opts)
@@ -116,14 +115,7 @@ func (r *Role) importRole(ctx context.Context, obj aide.Object, opts *aide.Impor
 		data[i] = roles[i]
 	}
 
-	url := fmt.Sprintf(importRoleURL, opts.Host, opts.AppID)
-
-	err = aide.ImportObject(ctx, url, data, opts)
-	if err != nil {
-		return nil, err
-	}
-
-	return ids, nil
+	return data, ids, nil
 }
 
 func (r *Role) replaceRoleParam(roles []*models.Role, opts *aide.ImportOption) map[string]string {
@@ -147,11 +139,11 @@ func (r *Role) replaceRoleParam(roles []*models.Role, opts *aide.ImportOption) m
 	return ids
 }
 
-func (r *Role) importPermit(ctx context.Context, obj aide.Object, roleIDs map[string]string, opts *aide.ImportOption) (map[string]string, error) {
+func (r *Role) remapPermit(obj aide.Object, roleIDs map[string]string, opts *aide.ImportOption) (aide.Object, map[string]string, error) {
 	var permits []*models.Permit
 	err := aide.Serialize(obj, &permits)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	ids := r.replacePermitParam(permits, roleIDs, opts)
@@ -161,14 +153,7 @@ func (r *Role) importPermit(ctx context.Context, obj aide.Object, roleIDs map[st
 		data[i] = permits[i]
 	}
 
-	url := fmt.Sprintf(importPermitURL, opts.Host, opts.AppID)
-
-	err = aide.ImportObject(ctx, url, data, opts)
-	if err != nil {
-		return nil, err
-	}
-
-	return ids, nil
+	return data, ids, nil
 }
 
 func (r *Role) replacePermitParam(permits []*models.Permit, roleIDs map[string]string, opts *aide.ImportOption) map[string]string {
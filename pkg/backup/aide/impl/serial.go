@@ -0,0 +1,41 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/quanxiang-cloud/form/pkg/backup/aide"
+)
+
+var (
+	exportSerialURL = "%s/api/v1/form/%s/internal/backup/export/serial"
+)
+
+// Serial the states of the serial fields, so the numbers go on after restore.
+type Serial struct{}
+
+func (s *Serial) tag() string {
+	return "serials"
+}
+
+// Export export.
+func (s *Serial) Export(ctx context.Context, opts *aide.ExportOption) (map[string]aide.Object, error) {
+	url := fmt.Sprintf(exportSerialURL, opts.Host, opts.AppID)
+
+	obj, err := aide.ExportObject(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]aide.Object{
+		s.tag(): obj,
+	}, nil
+}
+
+// Remap remap.
+// INFO: the serials are keyed by the app, table and field ids, the app id is given by the restore.
+func (s *Serial) Remap(ctx context.Context, objs map[string]aide.Object, opts *aide.ImportOption) (map[string]aide.Object, map[string]string, error) {
+	return map[string]aide.Object{
+		s.tag(): objs[s.tag()],
+	}, nil, nil
+}
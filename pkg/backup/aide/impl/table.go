@@ -14,7 +14,6 @@ import (
 
 var (
 	exportTableURL = "%s/api/v1/form/%s/internal/backup/export/table"
-	importTableURL = "%s/api/v1/form/%s/internal/backup/import/table"
 )
 
 // Table table.
@@ -38,19 +37,19 @@ func (t *Table) Export(ctx context.Context, opts *aide.ExportOption) (map[string
 	}, nil
 }
 
-// Import import.
-func (t *Table) Import(ctx context.Context, objs map[string]aide.Object, opts *aide.ImportOption) (map[string]string, error) {
+// Remap remap.
+func (t *Table) Remap(ctx context.Context, objs map[string]aide.Object, opts *aide.ImportOption) (map[string]aide.Object, map[string]string, error) {
 	obj := objs[t.tag()]
 
 	var tables []*models.Table
 	err := aide.Serialize(obj, &tables)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	ids, err := t.replaceParam(tables, opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	data := make(aide.Object, len(obj))
@@ -58,14 +57,9 @@ func (t *Table) Import(ctx context.Context, objs map[string]aide.Object, opts *a
 		data[i] = tables[i]
 	}
 
-	url := fmt.Sprintf(importTableURL, opts.Host, opts.AppID)
-
-	err = aide.ImportObject(ctx, url, data, opts)
-	if err != nil {
-		return nil, err
-	}
-
-	return ids, nil
+	return map[string]aide.Object{
+		t.tag(): data,
+	}, ids, nil
 }
 
 func (t *Table) replaceParam(tables []*models.Table, opts *aide.ImportOption) (map[string]string, error) {
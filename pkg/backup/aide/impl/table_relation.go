@@ -12,7 +12,6 @@ import (
 
 var (
 	exportTableRelationURL = "%s/api/v1/form/%s/internal/backup/export/tableRelation"
-	importTableRelationURL = "%s/api/v1/form/%s/internal/backup/import/tableRelation"
 )
 
 // TableRelation tableRelation.
@@ -36,15 +35,15 @@ func (tr *TableRelation) Export(ctx context.Context, opts *aide.ExportOption) (m
 	}, nil
 }
 
-// Import import.
+// Remap remap.
 // nolint: dupl
-func (tr *TableRelation) Import(ctx context.Context, objs map[string]aide.Object, opts *aide.ImportOption) (map[string]string, error) {
+func (tr *TableRelation) Remap(ctx context.Context, objs map[string]aide.Object, opts *aide.ImportOption) (map[string]aide.Object, map[string]string, error) {
 	obj := objs[tr.tag()]
 
 	var tables []*models.TableRelation
 	err := aide.Serialize(obj, &tables)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	ids := tr.replaceParam(tables, opts)
@@ -54,14 +53,9 @@ func (tr *TableRelation) Import(ctx context.Context, objs map[string]aide.Object
 		data[i] = tables[i]
 	}
 
-	url := fmt.Sprintf(importTableRelationURL, opts.Host, opts.AppID)
-
-	err = aide.ImportObject(ctx, url, data, opts)
-	if err != nil {
-		return nil, err
-	}
-
-	return ids, nil
+	return map[string]aide.Object{
+		tr.tag(): data,
+	}, ids, nil
 }
 
 func (tr *TableRelation) replaceParam(tableRelations []*models.TableRelation, opts *aide.ImportOption) map[string]string {
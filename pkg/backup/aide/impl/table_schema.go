@@ -12,7 +12,6 @@ import (
 
 var (
 	exportTableSchemaURL = "%s/api/v1/form/%s/internal/backup/export/tableSchema"
-	importTableSchemaURL = "%s/api/v1/form/%s/internal/backup/import/tableSchema"
 )
 
 // TableSchema tableSchema.
@@ -36,15 +35,15 @@ func (ts *TableSchema) Export(ctx context.Context, opts *aide.ExportOption) (map
 	}, nil
 }
 
-// Import import.
+// Remap remap.
 // nolint: dupl
-func (ts *TableSchema) Import(ctx context.Context, objs map[string]aide.Object, opts *aide.ImportOption) (map[string]string, error) {
+func (ts *TableSchema) Remap(ctx context.Context, objs map[string]aide.Object, opts *aide.ImportOption) (map[string]aide.Object, map[string]string, error) {
 	obj := objs[ts.tag()]
 
 	var tables []*models.TableSchema
 	err := aide.Serialize(obj, &tables)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	ids := ts.replaceParam(tables, opts)
@@ -54,14 +53,9 @@ func (ts *TableSchema) Import(ctx context.Context, objs map[string]aide.Object,
 		data[i] = tables[i]
 	}
 
-	url := fmt.Sprintf(importTableSchemaURL, opts.Host, opts.AppID)
-
-	err = aide.ImportObject(ctx, url, data, opts)
-	if err != nil {
-		return nil, err
-	}
-
-	return ids, nil
+	return map[string]aide.Object{
+		ts.tag(): data,
+	}, ids, nil
 }
 
 func (ts *TableSchema) replaceParam(tableSchemas []*models.TableSchema, opts *aide.ImportOption) map[string]string {
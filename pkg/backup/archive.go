@@ -0,0 +1,479 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/form/pkg/backup/aide"
+	"github.com/quanxiang-cloud/form/pkg/backup/aide/impl"
+)
+
+// the entries of an archive, the result comes first, then the records of
+// each table page by page, as records/<tableID>/<page>.json, the manifest last.
+const (
+	manifestName = "manifest.json"
+	resultName   = "result.json"
+	recordsDir   = "records"
+)
+
+// the versions of the format of the backups.
+const (
+	// versionJSON a Result in plain JSON, written by the releases before the archives.
+	versionJSON = 0
+	// versionArchive the first archives, the manifest has neither a version nor checksums.
+	versionArchive = 1
+	// FormatVersion the version of the archives written by ExportArchive.
+	FormatVersion = 2
+)
+
+var (
+	// ErrUnsupported the archive is written by a newer release.
+	ErrUnsupported = errors.New("the version of the archive is not supported")
+	// ErrCorrupted the archive does not match its manifest.
+	ErrCorrupted = errors.New("the archive is corrupted")
+	// ErrChain the archives are not a full backup of an app followed by its incremental ones.
+	ErrChain = errors.New("the archives are not a full backup followed by its incremental ones")
+	// ErrPartial the objects are imported, but not all of the records, the report tells the ones imported.
+	// importing again with the tables overwritten clears them and retries.
+	ErrPartial = errors.New("the records are imported partially")
+)
+
+var records = &impl.Record{}
+
+// Manifest what an archive holds.
+type Manifest struct {
+	Version int `json:"version"`
+	// AppID the app which is backed up.
+	AppID     string `json:"appID"`
+	CreatedAt int64  `json:"createdAt"`
+	// Since the records created or updated since are held, in unix milliseconds, all if zero.
+	Since int64 `json:"since,omitempty"`
+	// Counts the objects of each kind, like tables or roles.
+	Counts map[string]int `json:"counts"`
+	// Records the records of each table, the relations of the tables to their sub tables included.
+	Records map[string]int `json:"records"`
+	// Sections the SHA-256 of each section in hex, the result, and the records of each table
+	// as records/<tableID>, over their entries in order.
+	Sections map[string]string `json:"sections"`
+}
+
+// ExportArchive write the app with its records and serials to w, as a tar.gz.
+func (b *Backup) ExportArchive(ctx context.Context, opts *aide.ExportOption, w io.Writer) (*Manifest, error) {
+	result, err := b.Export(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Version:   FormatVersion,
+		AppID:     opts.AppID,
+		CreatedAt: time2.NowUnix(),
+		Since:     opts.Since,
+		Records:   make(map[string]int),
+	}
+	if manifest.Counts, err = countObjects(result); err != nil {
+		return nil, err
+	}
+
+	sections := make(map[string]hash.Hash)
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	write := func(name string, data interface{}) error {
+		body, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		section := sectionOf(name)
+		if _, ok := sections[section]; !ok {
+			sections[section] = sha256.New()
+		}
+		sections[section].Write(body)
+		return writeEntry(tw, name, body)
+	}
+
+	if err = write(resultName, result); err != nil {
+		return nil, err
+	}
+	for _, tableID := range recordTables(result) {
+		page := 0
+		err = records.Export(ctx, tableID, opts, func(obj aide.Object) error {
+			if len(obj) == 0 {
+				return nil
+			}
+			page++
+			manifest.Records[tableID] += len(obj)
+			return write(path.Join(recordsDir, tableID, fmt.Sprintf("%d.json", page)), obj)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	manifest.Sections = sums(sections)
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if err = writeEntry(tw, manifestName, body); err != nil {
+		return nil, err
+	}
+	if err = tw.Close(); err != nil {
+		return nil, err
+	}
+	if err = gw.Close(); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// Verify check a backup against its manifest, nothing is written. the backups of the older versions
+// are migrated, the manifest of a plain JSON is made of its result, with no records.
+func Verify(r io.Reader) (*Manifest, error) {
+	manifest, _, err := verify(r)
+	return manifest, err
+}
+
+// ImportArchive restore a backup of ExportArchive, or of the older versions, to the app of opts.
+// the whole archive is verified at first, then the objects are imported as Import does, then the
+// records of the tables which are not skipped; in a dry run, the objects are checked only.
+func (b *Backup) ImportArchive(ctx context.Context, r io.Reader, opts *aide.ImportOption) (*Report, error) {
+	return b.ImportArchives(ctx, []io.Reader{r}, opts)
+}
+
+// ImportArchives restore a full backup and the incremental ones taken after it, in order, as ImportArchive does.
+// all of them are verified at first, the objects are taken from the last one, then the records of each are
+// imported in order, the later ones replace the earlier of the same ids. the records are imported after the
+// objects are committed, ErrPartial is returned with the report if any of them fails.
+func (b *Backup) ImportArchives(ctx context.Context, rs []io.Reader, opts *aide.ImportOption) (*Report, error) {
+	if len(rs) == 0 {
+		return nil, ErrChain
+	}
+
+	// the archives are read twice, to verify them before any write.
+	files := make([]*os.File, 0, len(rs))
+	defer func() {
+		for _, f := range files {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	var (
+		manifests = make([]*Manifest, 0, len(rs))
+		result    *Result
+	)
+	for i, r := range rs {
+		f, err := os.CreateTemp("", "form-backup-*")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		if _, err = io.Copy(f, r); err != nil {
+			return nil, err
+		}
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		manifest, res, err := verify(f)
+		if err != nil {
+			return nil, err
+		}
+		if (i == 0) != (manifest.Since == 0) {
+			return nil, ErrChain
+		}
+		if i > 0 && (manifest.AppID != manifests[0].AppID || manifest.CreatedAt < manifests[i-1].CreatedAt) {
+			return nil, ErrChain
+		}
+		manifests = append(manifests, manifest)
+		result = res
+	}
+
+	report, err := b.Import(ctx, result, opts)
+	if err != nil {
+		return report, err
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	report.Records = make(map[string]int)
+	for i, f := range files {
+		if manifests[i].Version == versionJSON {
+			continue
+		}
+		if _, err = f.Seek(0, io.SeekStart); err != nil {
+			return report, fmt.Errorf("%w: %v", ErrPartial, err)
+		}
+		err = walkArchive(f, func(name string, body []byte) error {
+			if !strings.HasPrefix(name, recordsDir+"/") {
+				return nil
+			}
+			to := recordTable(report.TableIDs, path.Base(path.Dir(name)))
+			if to == "" {
+				return nil
+			}
+			var obj aide.Object
+			if err := json.Unmarshal(body, &obj); err != nil {
+				return err
+			}
+			if err := records.Import(ctx, to, obj, opts); err != nil {
+				return fmt.Errorf("the records of %s: %v", to, err)
+			}
+			report.Records[to] += len(obj)
+			return nil
+		})
+		if err != nil {
+			return report, fmt.Errorf("%w: %v", ErrPartial, err)
+		}
+	}
+
+	return report, nil
+}
+
+// verify read the whole backup, the manifest, migrated to the current version, and the result are returned
+// if they match.
+func verify(r io.Reader) (*Manifest, *Result, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrCorrupted, err)
+	}
+	if magic[0] != 0x1f || magic[1] != 0x8b {
+		result := &Result{}
+		if err = json.NewDecoder(br).Decode(result); err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", ErrCorrupted, err)
+		}
+		manifest := &Manifest{Version: versionJSON}
+		if err = migrate(manifest, result); err != nil {
+			return nil, nil, err
+		}
+		return manifest, result, nil
+	}
+
+	var (
+		manifest *Manifest
+		result   *Result
+		recorded = make(map[string]int)
+		sections = make(map[string]hash.Hash)
+	)
+	err = walkArchive(br, func(name string, body []byte) error {
+		if name == manifestName {
+			manifest = &Manifest{}
+			return json.Unmarshal(body, manifest)
+		}
+
+		section := sectionOf(name)
+		if _, ok := sections[section]; !ok {
+			sections[section] = sha256.New()
+		}
+		sections[section].Write(body)
+
+		switch {
+		case name == resultName:
+			result = &Result{}
+			return json.Unmarshal(body, result)
+		case strings.HasPrefix(name, recordsDir+"/"):
+			var obj aide.Object
+			if err := json.Unmarshal(body, &obj); err != nil {
+				return err
+			}
+			recorded[path.Base(path.Dir(name))] += len(obj)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrCorrupted, err)
+	}
+	if manifest == nil || result == nil {
+		return nil, nil, fmt.Errorf("%w: no manifest or result", ErrCorrupted)
+	}
+	if manifest.Version == versionJSON {
+		manifest.Version = versionArchive
+	}
+	if err = migrate(manifest, result); err != nil {
+		return nil, nil, err
+	}
+
+	// the first archives have no checksums, their counts are checked only.
+	if manifest.Version >= FormatVersion {
+		got := sums(sections)
+		if len(got) != len(manifest.Sections) {
+			return nil, nil, fmt.Errorf("%w: %d of %d sections", ErrCorrupted, len(got), len(manifest.Sections))
+		}
+		for section, sum := range manifest.Sections {
+			if got[section] != sum {
+				return nil, nil, fmt.Errorf("%w: the checksum of %s mismatches", ErrCorrupted, section)
+			}
+		}
+	}
+	counts, err := countObjects(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	for kind, count := range manifest.Counts {
+		if counts[kind] != count {
+			return nil, nil, fmt.Errorf("%w: %d of %d %s", ErrCorrupted, counts[kind], count, kind)
+		}
+	}
+	if len(recorded) != len(manifest.Records) {
+		return nil, nil, fmt.Errorf("%w: records of %d of %d tables", ErrCorrupted, len(recorded), len(manifest.Records))
+	}
+	for tableID, count := range manifest.Records {
+		if recorded[tableID] != count {
+			return nil, nil, fmt.Errorf("%w: %d of %d records of %s", ErrCorrupted, recorded[tableID], count, tableID)
+		}
+	}
+
+	return manifest, result, nil
+}
+
+// migrate fill in what the manifest of an older version lacks, the version is kept.
+func migrate(manifest *Manifest, result *Result) error {
+	switch manifest.Version {
+	case versionJSON:
+		counts, err := countObjects(result)
+		if err != nil {
+			return err
+		}
+		manifest.Counts = counts
+		manifest.Records = make(map[string]int)
+		if len(result.Tables) > 0 {
+			manifest.AppID = result.Tables[0].AppID
+		}
+		fallthrough
+	case versionArchive:
+		manifest.Sections = nil
+	case FormatVersion:
+	default:
+		return fmt.Errorf("%w: %d, %d at most", ErrUnsupported, manifest.Version, FormatVersion)
+	}
+
+	if manifest.Records == nil {
+		manifest.Records = make(map[string]int)
+	}
+	return nil
+}
+
+// walkArchive call fn with each entry of a tar.gz.
+func walkArchive(r io.Reader, fn func(name string, body []byte) error) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		if err = fn(hdr.Name, body); err != nil {
+			return err
+		}
+	}
+}
+
+// sectionOf the section of an entry, records/<tableID> for the pages of the records of a table.
+func sectionOf(name string) string {
+	if strings.HasPrefix(name, recordsDir+"/") {
+		return path.Dir(name)
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+func sums(sections map[string]hash.Hash) map[string]string {
+	result := make(map[string]string, len(sections))
+	for section, h := range sections {
+		result[section] = hex.EncodeToString(h.Sum(nil))
+	}
+	return result
+}
+
+// countObjects the objects of each kind in the result.
+func countObjects(result *Result) (map[string]int, error) {
+	var objs map[string]aide.Object
+	if err := aide.Serialize(result, &objs); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(objs))
+	for kind, obj := range objs {
+		counts[kind] = len(obj)
+	}
+	return counts, nil
+}
+
+// recordTable where the records of the table go, empty if the table, or a table of the relation, is skipped,
+// or the table is not restored, like a table deleted before the last of the incremental backups.
+func recordTable(tableIDs map[string]string, tableID string) string {
+	ids := strings.SplitN(tableID, "_", 2)
+	if _, ok := tableIDs[ids[0]]; !ok {
+		return ""
+	}
+	for i, id := range ids {
+		if to, ok := tableIDs[id]; ok {
+			ids[i] = to
+		}
+		if ids[i] == "" {
+			return ""
+		}
+	}
+	return strings.Join(ids, "_")
+}
+
+// recordTables the tables and the relations of the tables to their sub tables, which hold records.
+func recordTables(result *Result) []string {
+	seen := make(map[string]bool)
+	for _, table := range result.Tables {
+		seen[table.TableID] = true
+	}
+	for _, relation := range result.TableRelations {
+		seen[fmt.Sprintf("%s_%s", relation.TableID, relation.SubTableID)] = true
+	}
+
+	tableIDs := make([]string, 0, len(seen))
+	for tableID := range seen {
+		tableIDs = append(tableIDs, tableID)
+	}
+	sort.Strings(tableIDs)
+
+	return tableIDs
+}
+
+func writeEntry(tw *tar.Writer, name string, body []byte) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(body)),
+		ModTime: time2.Time(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(body)
+	return err
+}
@@ -0,0 +1,302 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/internal/models"
+	"github.com/quanxiang-cloud/form/pkg/backup/aide"
+)
+
+// fakeForm the backup endpoints of form, the source app is exported, the target app is imported.
+type fakeForm struct {
+	mu       sync.Mutex
+	exports  map[string][]interface{}
+	restored map[string]interface{}
+	report   *Report
+	imported map[string][]interface{}
+	// failing the kind whose import fails.
+	failing string
+}
+
+func (f *fakeForm) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// /api/v1/form/:appID/internal/backup/:action[/:kind[/:tableName]]
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/api/v1/form/"), "/", 6)
+	action, kind := parts[3], ""
+	if len(parts) > 4 {
+		kind = strings.Join(parts[4:], "/")
+	}
+
+	var data interface{} = struct{}{}
+	switch {
+	case action == "import" && kind == "":
+		json.NewDecoder(r.Body).Decode(&f.restored)
+		data = f.report
+	case action == "export":
+		req := &aide.ExportReq{}
+		json.NewDecoder(r.Body).Decode(req)
+		objs := f.exports[kind]
+		if req.Since != 0 && strings.HasPrefix(kind, "record/") {
+			changed := make([]interface{}, 0, len(objs))
+			for _, obj := range objs {
+				if int64(obj.(map[string]interface{})["updated"].(float64)) >= req.Since {
+					changed = append(changed, obj)
+				}
+			}
+			objs = changed
+		}
+		start, end := (req.Page-1)*req.Size, req.Page*req.Size
+		if start > len(objs) {
+			start = len(objs)
+		}
+		if end > len(objs) {
+			end = len(objs)
+		}
+		data = map[string]interface{}{"data": objs[start:end], "count": len(objs)}
+	case action == "import" && kind == f.failing:
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": -1, "msg": "unavailable"})
+		return
+	case action == "import":
+		req := &aide.ImportReq{}
+		json.NewDecoder(r.Body).Decode(req)
+		f.mu.Lock()
+		f.imported[kind] = append(f.imported[kind], req.Data...)
+		f.mu.Unlock()
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "data": data})
+}
+
+func TestArchive(t *testing.T) {
+	records := make([]interface{}, 0, 1500)
+	for i := 0; i < 1500; i++ {
+		records = append(records, map[string]interface{}{"_id": fmt.Sprintf("r%d", i), "amount": i})
+	}
+	form := &fakeForm{
+		exports: map[string][]interface{}{
+			"table": {
+				map[string]interface{}{"ID": "1", "AppID": "src", "TableID": "t1", "Schema": map[string]interface{}{"appID": "src"}},
+			},
+			"tableRelation": {
+				map[string]interface{}{"ID": "2", "AppID": "src", "TableID": "t1", "SubTableID": "t2", "SubTableType": "sub_table"},
+			},
+			"serial": {
+				map[string]interface{}{"tableID": "t1", "fieldID": "no", "values": map[string]string{"serials": "{}"}},
+			},
+			"record/t1":    records,
+			"record/t1_t2": {map[string]interface{}{"_id": "l1", "primitiveID": "r1", "subID": "s1"}},
+		},
+		imported: make(map[string][]interface{}),
+	}
+	srv := httptest.NewServer(form)
+	defer srv.Close()
+	b := &Backup{formHost: srv.URL, client: *srv.Client()}
+	ctx := context.Background()
+
+	buf := new(bytes.Buffer)
+	manifest, err := b.ExportArchive(ctx, &aide.ExportOption{AppID: "src"}, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Records["t1"] != 1500 || manifest.Records["t1_t2"] != 1 || manifest.Counts["tables"] != 1 || manifest.Counts["serials"] != 1 {
+		t.Fatalf("manifest %+v", manifest)
+	}
+
+	archive := buf.Bytes()
+	form.report = &Report{Valid: true, TableIDs: map[string]string{"t1": "t1"}}
+	report, err := b.ImportArchive(ctx, bytes.NewReader(archive), &aide.ImportOption{AppID: "dst", UserID: "u", UserName: "u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.IDs["1"] == "" || len(form.imported["record/t1"]) != 1500 || len(form.imported["record/t1_t2"]) != 1 ||
+		report.Records["t1"] != 1500 || report.Records["t1_t2"] != 1 {
+		t.Fatalf("ids %v, imported %d, records %v", report.IDs, len(form.imported["record/t1"]), report.Records)
+	}
+	table := form.restored["tables"].([]interface{})[0].(map[string]interface{})
+	if table["AppID"] != "dst" || table["Schema"].(map[string]interface{})["appID"] != "dst" || len(form.restored["serials"].([]interface{})) != 1 {
+		t.Fatalf("restored %+v", form.restored)
+	}
+
+	// the records follow the renamed table.
+	form.report = &Report{Valid: true, TableIDs: map[string]string{"t1": "x1"}}
+	form.imported = make(map[string][]interface{})
+	strategies := map[string]string{"tables": "rename"}
+	if _, err = b.ImportArchive(ctx, bytes.NewReader(archive), &aide.ImportOption{AppID: "dst", Strategies: strategies}); err != nil {
+		t.Fatal(err)
+	}
+	if len(form.imported["record/x1"]) != 1500 || len(form.imported["record/x1_t2"]) != 1 || form.restored["strategies"].(map[string]interface{})["tables"] != "rename" {
+		t.Fatalf("imported %d", len(form.imported["record/x1"]))
+	}
+
+	// the objects are committed before the records, the ones imported are reported.
+	form.report = &Report{Valid: true, TableIDs: map[string]string{"t1": "t1"}}
+	form.failing = "record/t1_t2"
+	report, err = b.ImportArchive(ctx, bytes.NewReader(archive), &aide.ImportOption{AppID: "dst"})
+	if !errors.Is(err, ErrPartial) || !strings.Contains(err.Error(), "t1_t2") || report.Records["t1"] != 1500 || report.Records["t1_t2"] != 0 {
+		t.Fatalf("partial: err %v, report %+v", err, report)
+	}
+	form.failing = ""
+
+	// nothing is imported in a dry run, or if the backup conflicts with the app.
+	form.imported = make(map[string][]interface{})
+	if _, err = b.ImportArchive(ctx, bytes.NewReader(archive), &aide.ImportOption{AppID: "dst", DryRun: true}); err != nil || len(form.imported) != 0 {
+		t.Fatalf("dry run: err %v, imported %d", err, len(form.imported))
+	}
+	form.report = &Report{Conflicts: []*Conflict{{Kind: "tables", ID: "t1"}}}
+	if report, err = b.ImportArchive(ctx, bytes.NewReader(archive), &aide.ImportOption{AppID: "dst"}); err != ErrInvalid || len(report.Conflicts) != 1 || len(form.imported) != 0 {
+		t.Fatalf("conflict: err %v, imported %d", err, len(form.imported))
+	}
+
+	// a truncated archive is not restored, nothing is written.
+	form.report = &Report{Valid: true}
+	form.restored = nil
+	if _, err = b.ImportArchive(ctx, bytes.NewReader(archive[:len(archive)/2]), &aide.ImportOption{AppID: "dst"}); !errors.Is(err, ErrCorrupted) || form.restored != nil {
+		t.Fatalf("the truncated archive should fail: %v", err)
+	}
+}
+
+// rewrite an archive, fn returns the new body of an entry, nil to drop it.
+func rewrite(t *testing.T, archive []byte, fn func(name string, body []byte) []byte) []byte {
+	buf := new(bytes.Buffer)
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	err := walkArchive(bytes.NewReader(archive), func(name string, body []byte) error {
+		if body = fn(name, body); body == nil {
+			return nil
+		}
+		return writeEntry(tw, name, body)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gw.Close()
+	return buf.Bytes()
+}
+
+func TestVerify(t *testing.T) {
+	form := &fakeForm{
+		exports: map[string][]interface{}{
+			"table":     {map[string]interface{}{"ID": "1", "AppID": "src", "TableID": "t1"}},
+			"record/t1": {map[string]interface{}{"_id": "r1"}, map[string]interface{}{"_id": "r2"}},
+		},
+		imported: make(map[string][]interface{}),
+		report:   &Report{Valid: true, TableIDs: map[string]string{"t1": "t1"}},
+	}
+	srv := httptest.NewServer(form)
+	defer srv.Close()
+	b := &Backup{formHost: srv.URL, client: *srv.Client()}
+	ctx := context.Background()
+
+	buf := new(bytes.Buffer)
+	if _, err := b.ExportArchive(ctx, &aide.ExportOption{AppID: "src"}, buf); err != nil {
+		t.Fatal(err)
+	}
+	archive := buf.Bytes()
+	manifest, err := Verify(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Version != FormatVersion || manifest.AppID != "src" || manifest.Sections["result"] == "" || manifest.Sections["records/t1"] == "" {
+		t.Fatalf("manifest %+v", manifest)
+	}
+
+	tampered := rewrite(t, archive, func(name string, body []byte) []byte {
+		if name == "records/t1/1.json" {
+			return bytes.Replace(body, []byte("r2"), []byte("r3"), 1)
+		}
+		return body
+	})
+	if _, err = b.ImportArchive(ctx, bytes.NewReader(tampered), &aide.ImportOption{AppID: "dst"}); !errors.Is(err, ErrCorrupted) || form.restored != nil {
+		t.Fatalf("tampered: err %v", err)
+	}
+
+	newer := rewrite(t, archive, func(name string, body []byte) []byte {
+		if name == manifestName {
+			return bytes.Replace(body, []byte(`"version":2`), []byte(`"version":3`), 1)
+		}
+		return body
+	})
+	if _, err = Verify(bytes.NewReader(newer)); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("newer: err %v", err)
+	}
+
+	// the first archives have neither a version nor checksums.
+	first := rewrite(t, archive, func(name string, body []byte) []byte {
+		if name == manifestName {
+			body, _ = json.Marshal(map[string]interface{}{"appID": "src", "counts": manifest.Counts, "records": manifest.Records})
+		}
+		return body
+	})
+	if manifest, err = Verify(bytes.NewReader(first)); err != nil || manifest.Version != versionArchive {
+		t.Fatalf("first: err %v, manifest %+v", err, manifest)
+	}
+	if _, err = b.ImportArchive(ctx, bytes.NewReader(first), &aide.ImportOption{AppID: "dst"}); err != nil || len(form.imported["record/t1"]) != 2 {
+		t.Fatalf("first: err %v, imported %d", err, len(form.imported["record/t1"]))
+	}
+
+	// a Result in plain JSON, without records.
+	plain, _ := json.Marshal(&Result{Tables: []*models.Table{{ID: "1", AppID: "src", TableID: "t1"}}})
+	if manifest, err = Verify(bytes.NewReader(plain)); err != nil || manifest.Version != versionJSON || manifest.AppID != "src" || manifest.Counts["tables"] != 1 {
+		t.Fatalf("plain: err %v, manifest %+v", err, manifest)
+	}
+	form.restored = nil
+	if _, err = b.ImportArchive(ctx, bytes.NewReader(plain), &aide.ImportOption{AppID: "dst"}); err != nil || len(form.restored["tables"].([]interface{})) != 1 {
+		t.Fatalf("plain: err %v, restored %+v", err, form.restored)
+	}
+}
+
+func TestImportArchives(t *testing.T) {
+	form := &fakeForm{
+		exports: map[string][]interface{}{
+			"table": {map[string]interface{}{"ID": "1", "AppID": "src", "TableID": "t1"}},
+			"record/t1": {
+				map[string]interface{}{"_id": "r1", "updated": float64(1)},
+				map[string]interface{}{"_id": "r2", "updated": float64(5)},
+			},
+		},
+		imported: make(map[string][]interface{}),
+		report:   &Report{Valid: true, TableIDs: map[string]string{"t1": "t1"}},
+	}
+	srv := httptest.NewServer(form)
+	defer srv.Close()
+	b := &Backup{formHost: srv.URL, client: *srv.Client()}
+	ctx := context.Background()
+
+	full, incremental := new(bytes.Buffer), new(bytes.Buffer)
+	if _, err := b.ExportArchive(ctx, &aide.ExportOption{AppID: "src"}, full); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := b.ExportArchive(ctx, &aide.ExportOption{AppID: "src", Since: 3}, incremental)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Since != 3 || manifest.Records["t1"] != 1 || manifest.Counts["tables"] != 1 {
+		t.Fatalf("manifest %+v", manifest)
+	}
+
+	// the records of the incremental backup come last, to replace the earlier ones.
+	_, err = b.ImportArchives(ctx, []io.Reader{bytes.NewReader(full.Bytes()), bytes.NewReader(incremental.Bytes())}, &aide.ImportOption{AppID: "dst"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := form.imported["record/t1"]; len(got) != 3 || got[2].(map[string]interface{})["_id"] != "r2" {
+		t.Fatalf("imported %v", got)
+	}
+
+	form.imported = make(map[string][]interface{})
+	_, err = b.ImportArchives(ctx, []io.Reader{bytes.NewReader(incremental.Bytes()), bytes.NewReader(full.Bytes())}, &aide.ImportOption{AppID: "dst"})
+	if err != ErrChain || len(form.imported) != 0 {
+		t.Fatalf("err %v, imported %d", err, len(form.imported))
+	}
+}
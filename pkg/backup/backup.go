@@ -2,6 +2,8 @@ package backup
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 
@@ -40,6 +42,7 @@ var aides = []aide.Aide{
 	&impl.TableRelation{},
 	&impl.TableSchema{},
 	&impl.Role{},
+	&impl.Serial{},
 }
 
 // Result is the result of export.
@@ -49,6 +52,7 @@ type Result struct {
 	Roles          []*models.Role          `json:"roles"`
 	Tables         []*models.Table         `json:"tables"`
 	TableRelations []*models.TableRelation `json:"tableRelations"`
+	Serials        []*models.Serial        `json:"serials"`
 }
 
 // Export export.
@@ -78,8 +82,47 @@ func (b *Backup) Export(ctx context.Context, opts *aide.ExportOption) (*Result,
 	return result, nil
 }
 
-// Import import.
-func (b *Backup) Import(ctx context.Context, result *Result, opts *aide.ImportOption) (map[string]string, error) {
+var restoreURL = "%s/api/v1/form/%s/internal/backup/import"
+
+// ErrInvalid the objects conflict with the app without a strategy, or refer to the missing ones.
+var ErrInvalid = errors.New("the backup can not be imported to the app, see the report")
+
+// Conflict an object which exists in the app already.
+type Conflict struct {
+	Kind     string `json:"kind"`
+	ID       string `json:"id"`
+	Strategy string `json:"strategy,omitempty"`
+	To       string `json:"to,omitempty"`
+}
+
+// Report the result of Import, or what it would be in a dry run.
+type Report struct {
+	DryRun    bool        `json:"dryRun"`
+	Valid     bool        `json:"valid"`
+	Conflicts []*Conflict `json:"conflicts"`
+	Problems  []string    `json:"problems"`
+	// TableIDs where the records of the tables go, a new id if renamed, empty if skipped.
+	TableIDs map[string]string `json:"tableIDs"`
+	Counts   map[string]int    `json:"counts"`
+	// Cleared the tables whose records are replaced with the ones of the backup.
+	Cleared []string `json:"cleared"`
+	// IDs the old ids of the objects to the new ones.
+	IDs map[string]string `json:"ids"`
+	// Records the records imported to each table, by ImportArchives after the objects.
+	Records map[string]int `json:"records,omitempty"`
+}
+
+type restoreReq struct {
+	UserID     string            `json:"userID"`
+	UserName   string            `json:"userName"`
+	DryRun     bool              `json:"dryRun"`
+	Strategies map[string]string `json:"strategies"`
+}
+
+// Import import all the objects in one transaction of form, nothing is written if it fails.
+// the report tells the conflicts with the app and how they are solved, ErrInvalid is returned with it
+// if they are not solved.
+func (b *Backup) Import(ctx context.Context, result *Result, opts *aide.ImportOption) (*Report, error) {
 	ids := make(map[string]string)
 
 	var objs map[string]aide.Object
@@ -91,16 +134,41 @@ func (b *Backup) Import(ctx context.Context, result *Result, opts *aide.ImportOp
 	opts.Client = b.client
 	opts.Host = b.formHost
 
+	req := map[string]interface{}{}
+	err = aide.Serialize(&restoreReq{
+		UserID:     opts.UserID,
+		UserName:   opts.UserName,
+		DryRun:     opts.DryRun,
+		Strategies: opts.Strategies,
+	}, &req)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, a := range aides {
-		idMap, err := a.Import(ctx, objs, opts)
+		remapped, idMap, err := a.Remap(ctx, objs, opts)
 		if err != nil {
 			return nil, err
 		}
 
+		for key, val := range remapped {
+			req[key] = val
+		}
 		for key, val := range idMap {
 			ids[key] = val
 		}
 	}
 
-	return ids, nil
+	report := &Report{}
+	err = client.POST(ctx, &opts.Client, fmt.Sprintf(restoreURL, opts.Host, opts.AppID), req, report)
+	if err != nil {
+		return nil, err
+	}
+	report.IDs = ids
+
+	if !report.Valid {
+		return report, ErrInvalid
+	}
+
+	return report, nil
 }
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// TypeLocal the files are kept in a directory.
+	TypeLocal = "local"
+	// TypeS3 the files are kept in a bucket of an S3 compatible storage, like minio.
+	TypeS3 = "s3"
+)
+
+// ErrNotExist the file is not in the store.
+var ErrNotExist = errors.New("the file does not exist in the store")
+
+// Config where the backups are kept.
+type Config struct {
+	// Type local or s3, local if empty.
+	Type string `yaml:"type"`
+	// Path the directory of local, or the prefix of the keys in the bucket of s3.
+	Path string `yaml:"path"`
+
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"accessKey"`
+	SecretKey string `yaml:"secretKey"`
+}
+
+// Store keep the files by keys, like <appID>/<snapshotID>.tar.gz.
+type Store interface {
+	// Put write the file of the key, the one which exists is replaced.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get ErrNotExist if the key is not in the store.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete the key which is not in the store is ignored.
+	Delete(ctx context.Context, key string) error
+}
+
+// New the store of the config.
+func New(conf Config) (Store, error) {
+	switch conf.Type {
+	case "", TypeLocal:
+		return NewLocal(conf.Path)
+	case TypeS3:
+		return NewS3(conf)
+	default:
+		return nil, fmt.Errorf("unknown store type %s", conf.Type)
+	}
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 keep the objects by path, the requests must be signed.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=ak/") || r.Header.Get("X-Amz-Date") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		f.objects[r.URL.Path] = string(body)
+	case http.MethodGet:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+	if err := s.Put(ctx, "app/1.tar.gz", strings.NewReader("backup")); err != nil {
+		t.Fatal(err)
+	}
+	r, err := s.Get(ctx, "app/1.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(r)
+	r.Close()
+	if string(body) != "backup" {
+		t.Fatalf("body %s", body)
+	}
+	if err = s.Delete(ctx, "app/1.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Get(ctx, "app/1.tar.gz"); err != ErrNotExist {
+		t.Fatalf("deleted: %v", err)
+	}
+	if err = s.Delete(ctx, "app/1.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	s, err := New(Config{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+	if err = s.Put(context.Background(), "../escape", strings.NewReader("")); err == nil {
+		t.Fatal("the key should stay under the root")
+	}
+}
+
+func TestS3(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string]string)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	s, err := New(Config{Type: TypeS3, Endpoint: srv.URL, Bucket: "form", Path: "backups", AccessKey: "ak", SecretKey: "sk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Put(context.Background(), "app/1.tar.gz", strings.NewReader("backup")); err != nil {
+		t.Fatal(err)
+	}
+	if fake.objects["/form/backups/app/1.tar.gz"] != "backup" {
+		t.Fatalf("objects %v", fake.objects)
+	}
+	testStore(t, s)
+}
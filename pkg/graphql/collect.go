@@ -0,0 +1,134 @@
+package graphql
+
+import "strings"
+
+// Coerce the values of the variables of the operation, the defaults are filled and the required ones are checked.
+func (op *Operation) Coerce(values map[string]interface{}) (map[string]interface{}, error) {
+	variables := make(map[string]interface{}, len(op.Variables))
+	for _, definition := range op.Variables {
+		value, ok := values[definition.Name]
+		if !ok && definition.Default != nil {
+			var err error
+			if value, err = definition.Default.Resolve(nil); err != nil {
+				return nil, err
+			}
+		}
+		if value == nil && strings.HasSuffix(definition.Type, "!") {
+			return nil, Errorf("variable $%s of type %s is required", definition.Name, definition.Type)
+		}
+		variables[definition.Name] = value
+	}
+	return variables, nil
+}
+
+// Collect the fields of the selections on the type, the fragments are spread, the fields
+// left out by @skip and @include are dropped and the fields of the same key are merged.
+func (d *Document) Collect(selections []*Selection, typeName string, variables map[string]interface{}) ([]*Selection, error) {
+	c := &collector{
+		doc:       d,
+		typeName:  typeName,
+		variables: variables,
+		keys:      make(map[string]*Selection),
+		visited:   make(map[string]bool),
+	}
+	if err := c.collect(selections); err != nil {
+		return nil, err
+	}
+	return c.fields, nil
+}
+
+type collector struct {
+	doc       *Document
+	typeName  string
+	variables map[string]interface{}
+	fields    []*Selection
+	keys      map[string]*Selection
+	visited   map[string]bool
+}
+
+func (c *collector) collect(selections []*Selection) error {
+	for _, selection := range selections {
+		included, err := c.included(selection.Directives)
+		if err != nil {
+			return err
+		}
+		if !included {
+			continue
+		}
+		switch {
+		case selection.Fragment != "":
+			if c.visited[selection.Fragment] {
+				continue
+			}
+			c.visited[selection.Fragment] = true
+			fragment, ok := c.doc.Fragments[selection.Fragment]
+			if !ok {
+				return Errorf("unknown fragment %q", selection.Fragment)
+			}
+			if fragment.TypeCondition != c.typeName {
+				continue
+			}
+			if err = c.collect(fragment.Selections); err != nil {
+				return err
+			}
+		case selection.Inline:
+			if selection.TypeCondition != "" && selection.TypeCondition != c.typeName {
+				continue
+			}
+			if err = c.collect(selection.Selections); err != nil {
+				return err
+			}
+		default:
+			c.add(selection)
+		}
+	}
+	return nil
+}
+
+func (c *collector) add(selection *Selection) {
+	key := selection.Key()
+	field, ok := c.keys[key]
+	if !ok {
+		field = &Selection{
+			Alias:      selection.Alias,
+			Name:       selection.Name,
+			Arguments:  selection.Arguments,
+			Selections: selection.Selections,
+		}
+		c.keys[key] = field
+		c.fields = append(c.fields, field)
+		return
+	}
+	// the sub selections of the same field are merged, the fields of different names are left
+	// to the executor, which returns the first one.
+	if field.Name == selection.Name {
+		field.Selections = append(append([]*Selection{}, field.Selections...), selection.Selections...)
+	}
+}
+
+func (c *collector) included(directives []*Directive) (bool, error) {
+	for _, directive := range directives {
+		if directive.Name != "skip" && directive.Name != "include" {
+			continue
+		}
+		var condition interface{}
+		for _, argument := range directive.Arguments {
+			if argument.Name != "if" {
+				continue
+			}
+			value, err := argument.Value.Resolve(c.variables)
+			if err != nil {
+				return false, err
+			}
+			condition = value
+		}
+		b, ok := condition.(bool)
+		if !ok {
+			return false, Errorf("@%s requires a boolean if", directive.Name)
+		}
+		if b == (directive.Name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
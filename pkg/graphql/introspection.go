@@ -0,0 +1,254 @@
+package graphql
+
+import "strings"
+
+// Meta fields of the introspection of the query root.
+const (
+	SchemaField = "__schema"
+	TypeField   = "__type"
+)
+
+// Kinds of the introspected types.
+const (
+	scalarKind  = "SCALAR"
+	objectKind  = "OBJECT"
+	listKind    = "LIST"
+	nonNullKind = "NON_NULL"
+)
+
+// meta an object of the introspection, like __Type, the values are scalars, metas or lists of metas.
+type meta struct {
+	typeName string
+	fields   map[string]interface{}
+}
+
+// Introspect resolves the field of __schema or __type on the schema by its selections.
+func (d *Document) Introspect(s *Schema, field *Selection, variables map[string]interface{}) (interface{}, error) {
+	types := s.introspect()
+	switch field.Name {
+	case SchemaField:
+		if len(field.Arguments) != 0 {
+			return nil, Errorf("field %q takes no argument", field.Name)
+		}
+		return d.shapeMeta(types[""], field.Selections, variables)
+	case TypeField:
+		var name interface{}
+		for _, arg := range field.Arguments {
+			if arg.Name != "name" {
+				return nil, Errorf("unknown argument %q of field %q", arg.Name, field.Name)
+			}
+			value, err := arg.Value.Resolve(variables)
+			if err != nil {
+				return nil, err
+			}
+			name = value
+		}
+		typeName, ok := name.(string)
+		if !ok {
+			return nil, Errorf("argument \"name\" of field %q must be a string", field.Name)
+		}
+		t, ok := types[typeName]
+		if !ok || typeName == "" {
+			return nil, nil
+		}
+		return d.shapeMeta(t, field.Selections, variables)
+	}
+	return nil, Errorf("unknown field %q", field.Name)
+}
+
+// introspect the __Type of the named types, and the __Schema of the blank name.
+func (s *Schema) introspect() map[string]*meta {
+	types := make(map[string]*meta)
+	list := make([]interface{}, 0, len(s.Types)*2+8)
+	named := func(kind, name, description string) *meta {
+		t := &meta{typeName: "__Type", fields: map[string]interface{}{
+			"kind":           kind,
+			"name":           name,
+			"description":    nilIfBlank(description),
+			"fields":         nil,
+			"interfaces":     nil,
+			"possibleTypes":  nil,
+			"enumValues":     nil,
+			"inputFields":    nil,
+			"ofType":         nil,
+			"specifiedByURL": nil,
+		}}
+		if kind == objectKind {
+			t.fields["interfaces"] = []interface{}{}
+		}
+		types[name] = t
+		list = append(list, t)
+		return t
+	}
+
+	for _, name := range []string{ID, String, Float, Int, Boolean, JSON} {
+		named(scalarKind, name, "")
+	}
+	objects := make(map[string][]interface{})
+	for _, t := range s.Types {
+		named(objectKind, t.Name, t.Description)
+		named(objectKind, t.Name+PageSuffix, "")
+	}
+	queries, mutations := s.rootFields()
+	named(objectKind, QueryRoot, "")
+	named(objectKind, MutationRoot, "")
+
+	// the fields are built after all the named types, they refer to each other.
+	for _, t := range s.Types {
+		fields := make([]interface{}, 0, len(t.Fields))
+		for _, f := range t.Fields {
+			typ := f.Type
+			if f.List {
+				typ = "[" + typ + "]"
+			}
+			fields = append(fields, metaField(types, f.Name, f.Description, typ, nil))
+		}
+		objects[t.Name] = fields
+		objects[t.Name+PageSuffix] = []interface{}{
+			metaField(types, EntitiesField, "", "["+t.Name+"]", nil),
+			metaField(types, TotalField, "", Int, nil),
+			metaField(types, NextCursorField, "", String, nil),
+		}
+	}
+	for root, rootFields := range map[string][]*rootField{QueryRoot: queries, MutationRoot: mutations} {
+		fields := make([]interface{}, 0, len(rootFields))
+		for _, f := range rootFields {
+			fields = append(fields, metaField(types, f.name, "", f.typ, f.args))
+		}
+		objects[root] = fields
+	}
+	for name, fields := range objects {
+		types[name].fields["fields"] = fields
+	}
+
+	types[""] = &meta{typeName: "__Schema", fields: map[string]interface{}{
+		"description":      nil,
+		"types":            list,
+		"queryType":        types[QueryRoot],
+		"mutationType":     types[MutationRoot],
+		"subscriptionType": nil,
+		"directives": []interface{}{
+			metaDirective(types, "skip"),
+			metaDirective(types, "include"),
+		},
+	}}
+	return types
+}
+
+func metaField(types map[string]*meta, name, description, typ string, args []rootArgument) *meta {
+	metaArgs := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		metaArgs = append(metaArgs, metaInputValue(types, arg.name, arg.typ))
+	}
+	return &meta{typeName: "__Field", fields: map[string]interface{}{
+		"name":              name,
+		"description":       nilIfBlank(description),
+		"args":              metaArgs,
+		"type":              typeRef(types, typ),
+		"isDeprecated":      false,
+		"deprecationReason": nil,
+	}}
+}
+
+func metaInputValue(types map[string]*meta, name, typ string) *meta {
+	return &meta{typeName: "__InputValue", fields: map[string]interface{}{
+		"name":              name,
+		"description":       nil,
+		"type":              typeRef(types, typ),
+		"defaultValue":      nil,
+		"isDeprecated":      false,
+		"deprecationReason": nil,
+	}}
+}
+
+func metaDirective(types map[string]*meta, name string) *meta {
+	return &meta{typeName: "__Directive", fields: map[string]interface{}{
+		"name":         name,
+		"description":  nil,
+		"locations":    []interface{}{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		"args":         []interface{}{metaInputValue(types, "if", Boolean+"!")},
+		"isRepeatable": false,
+	}}
+}
+
+// typeRef the __Type of a type reference like [String]!, the wrappers are unnamed.
+func typeRef(types map[string]*meta, typ string) *meta {
+	wrap := func(kind string, of *meta) *meta {
+		return &meta{typeName: "__Type", fields: map[string]interface{}{
+			"kind":   kind,
+			"name":   nil,
+			"ofType": of,
+		}}
+	}
+	switch {
+	case strings.HasSuffix(typ, "!"):
+		return wrap(nonNullKind, typeRef(types, strings.TrimSuffix(typ, "!")))
+	case strings.HasPrefix(typ, "[") && strings.HasSuffix(typ, "]"):
+		return wrap(listKind, typeRef(types, typ[1:len(typ)-1]))
+	}
+	return types[typ]
+}
+
+// shapeMeta the result of the meta by the selections, the metas may refer to each other,
+// so they are resolved only as deep as the selections.
+func (d *Document) shapeMeta(value interface{}, selections []*Selection, variables map[string]interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch v := value.(type) {
+	case *meta:
+		if v == nil {
+			return nil, nil
+		}
+		if len(selections) == 0 {
+			return nil, Errorf("field of type %s must have a selection of subfields", v.typeName)
+		}
+		fields, err := d.Collect(selections, v.typeName, variables)
+		if err != nil {
+			return nil, err
+		}
+		object := NewObject()
+		for _, field := range fields {
+			if field.Name == TypeNameField {
+				object.Set(field.Key(), v.typeName)
+				continue
+			}
+			elem, ok := v.fields[field.Name]
+			if !ok {
+				// the fields of the wrappers are left out, they are null.
+				if v.fields["kind"] == listKind || v.fields["kind"] == nonNullKind {
+					object.Set(field.Key(), nil)
+					continue
+				}
+				return nil, Errorf("unknown field %q on %s", field.Name, v.typeName)
+			}
+			shaped, err := d.shapeMeta(elem, field.Selections, variables)
+			if err != nil {
+				return nil, err
+			}
+			object.Set(field.Key(), shaped)
+		}
+		return object, nil
+	case []interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, elem := range v {
+			shaped, err := d.shapeMeta(elem, selections, variables)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, shaped)
+		}
+		return list, nil
+	}
+	if len(selections) != 0 {
+		return nil, Errorf("a scalar field must not have a selection")
+	}
+	return value, nil
+}
+
+func nilIfBlank(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,578 @@
+package graphql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Operation types.
+const (
+	Query    = "query"
+	Mutation = "mutation"
+)
+
+// Document a parsed request of operations and fragments.
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation a query or a mutation.
+type Operation struct {
+	Type       string
+	Name       string
+	Variables  []*VariableDefinition
+	Selections []*Selection
+}
+
+// VariableDefinition a variable of an operation, Type is like [String]!.
+type VariableDefinition struct {
+	Name    string
+	Type    string
+	Default *Value
+}
+
+// Fragment a named fragment.
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Selections    []*Selection
+}
+
+// Selection a field, a fragment spread if Fragment is set, or an inline fragment if Inline.
+type Selection struct {
+	Alias      string
+	Name       string
+	Arguments  []*Argument
+	Directives []*Directive
+	Selections []*Selection
+
+	Fragment      string
+	Inline        bool
+	TypeCondition string
+}
+
+// Key the key of the field in the response.
+func (s *Selection) Key() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.Name
+}
+
+// Argument an argument of a field or a directive.
+type Argument struct {
+	Name  string
+	Value *Value
+}
+
+// Directive like @skip and @include.
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+}
+
+// ValueKind the kind of a literal.
+type ValueKind int
+
+// Value kinds.
+const (
+	VariableValue ValueKind = iota
+	IntValue
+	FloatValue
+	StringValue
+	BooleanValue
+	NullValue
+	EnumValue
+	ListValue
+	ObjectValue
+)
+
+// Value a literal, Raw is the name of a variable or an enum, or the text of a scalar.
+type Value struct {
+	Kind   ValueKind
+	Raw    string
+	List   []*Value
+	Fields []*ObjectField
+}
+
+// ObjectField a field of an object literal.
+type ObjectField struct {
+	Name  string
+	Value *Value
+}
+
+// Resolve the go value of the literal, the variables are replaced by their values.
+func (v *Value) Resolve(variables map[string]interface{}) (interface{}, error) {
+	switch v.Kind {
+	case VariableValue:
+		return variables[v.Raw], nil
+	case IntValue:
+		return strconv.ParseInt(v.Raw, 10, 64)
+	case FloatValue:
+		return strconv.ParseFloat(v.Raw, 64)
+	case StringValue, EnumValue:
+		return v.Raw, nil
+	case BooleanValue:
+		return v.Raw == "true", nil
+	case ListValue:
+		list := make([]interface{}, 0, len(v.List))
+		for _, item := range v.List {
+			value, err := item.Resolve(variables)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case ObjectValue:
+		object := make(map[string]interface{}, len(v.Fields))
+		for _, field := range v.Fields {
+			value, err := field.Value.Resolve(variables)
+			if err != nil {
+				return nil, err
+			}
+			object[field.Name] = value
+		}
+		return object, nil
+	}
+	return nil, nil
+}
+
+// Operation the operation to execute, the name can be empty if there is only one.
+func (d *Document) Operation(name string) (*Operation, error) {
+	if name == "" {
+		if len(d.Operations) != 1 {
+			return nil, Errorf("operationName is required for %d operations", len(d.Operations))
+		}
+		return d.Operations[0], nil
+	}
+	for _, op := range d.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, Errorf("unknown operation %q", name)
+}
+
+// Parse parses an executable document, the type system definitions are not supported.
+func Parse(src string) (*Document, error) {
+	p := &parser{lexer: &lexer{src: src}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &Document{
+		Fragments: make(map[string]*Fragment),
+	}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Type: Query, Selections: selections})
+		case p.peekName(Query), p.peekName(Mutation):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case p.peekName("fragment"):
+			fragment, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.Fragments[fragment.Name]; ok {
+				return nil, Errorf("duplicate fragment %q", fragment.Name)
+			}
+			doc.Fragments[fragment.Name] = fragment
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, Errorf("no operation")
+	}
+	names := make(map[string]bool, len(doc.Operations))
+	for _, op := range doc.Operations {
+		if op.Name == "" && len(doc.Operations) > 1 {
+			return nil, Errorf("anonymous operation must be the only one")
+		}
+		if names[op.Name] {
+			return nil, Errorf("duplicate operation %q", op.Name)
+		}
+		names[op.Name] = true
+	}
+	return doc, nil
+}
+
+type parser struct {
+	lexer *lexer
+	tok   token
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokenPunct && p.tok.value == punct
+}
+
+func (p *parser) peekName(name string) bool {
+	return p.tok.kind == tokenName && p.tok.value == name
+}
+
+func (p *parser) unexpected() error {
+	return p.lexer.errorf(p.tok.pos, "unexpected %s", p.tok)
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		return p.lexer.errorf(p.tok.pos, "expected %q, found %s", punct, p.tok)
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.lexer.errorf(p.tok.pos, "expected name, found %s", p.tok)
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *parser) operation() (*Operation, error) {
+	op := &Operation{Type: p.tok.value}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if p.tok.kind == tokenName {
+		if op.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		if op.Variables, err = p.variableDefinitions(); err != nil {
+			return nil, err
+		}
+	}
+	// the directives of operations mean nothing here.
+	if _, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if op.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) variableDefinitions() ([]*VariableDefinition, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	definitions := make([]*VariableDefinition, 0)
+	for !p.peek(")") {
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		typ, err := p.typeRef()
+		if err != nil {
+			return nil, err
+		}
+		definition := &VariableDefinition{Name: name, Type: typ}
+		if p.peek("=") {
+			if err = p.advance(); err != nil {
+				return nil, err
+			}
+			if definition.Default, err = p.value(true); err != nil {
+				return nil, err
+			}
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, p.advance()
+}
+
+func (p *parser) typeRef() (string, error) {
+	var typ string
+	if p.peek("[") {
+		if err := p.advance(); err != nil {
+			return "", err
+		}
+		item, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		if err = p.expect("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + item + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		typ = name
+	}
+	if p.peek("!") {
+		return typ + "!", p.advance()
+	}
+	return typ, nil
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, p.lexer.errorf(p.tok.pos, "fragment can not be named on")
+	}
+	if !p.peekName("on") {
+		return nil, p.lexer.errorf(p.tok.pos, "expected \"on\", found %s", p.tok)
+	}
+	if err = p.advance(); err != nil {
+		return nil, err
+	}
+	fragment := &Fragment{Name: name}
+	if fragment.TypeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if _, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if fragment.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return fragment, nil
+}
+
+func (p *parser) selectionSet() ([]*Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	selections := make([]*Selection, 0)
+	for !p.peek("}") {
+		if p.tok.kind == tokenEOF {
+			return nil, p.unexpected()
+		}
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	if len(selections) == 0 {
+		return nil, p.lexer.errorf(p.tok.pos, "empty selection set")
+	}
+	return selections, p.advance()
+}
+
+func (p *parser) selection() (*Selection, error) {
+	var err error
+	selection := &Selection{}
+	if p.peek("...") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokenName && !p.peekName("on") {
+			if selection.Fragment, err = p.name(); err != nil {
+				return nil, err
+			}
+			selection.Directives, err = p.directives()
+			return selection, err
+		}
+		selection.Inline = true
+		if p.peekName("on") {
+			if err = p.advance(); err != nil {
+				return nil, err
+			}
+			if selection.TypeCondition, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if selection.Directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		selection.Selections, err = p.selectionSet()
+		return selection, err
+	}
+
+	if selection.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.peek(":") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		selection.Alias = selection.Name
+		if selection.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		if selection.Arguments, err = p.arguments(); err != nil {
+			return nil, err
+		}
+	}
+	if selection.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if selection.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return selection, nil
+}
+
+func (p *parser) arguments() ([]*Argument, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	arguments := make([]*Argument, 0)
+	for !p.peek(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.value(false)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, &Argument{Name: name, Value: value})
+	}
+	return arguments, p.advance()
+}
+
+func (p *parser) directives() ([]*Directive, error) {
+	var directives []*Directive
+	for p.peek("@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		directive := &Directive{Name: name}
+		if p.peek("(") {
+			if directive.Arguments, err = p.arguments(); err != nil {
+				return nil, err
+			}
+		}
+		directives = append(directives, directive)
+	}
+	return directives, nil
+}
+
+// value parses a literal, the variables are not allowed in the constants like the default values.
+func (p *parser) value(constant bool) (*Value, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				return nil, p.lexer.errorf(tok.pos, "variable is not allowed here")
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			return &Value{Kind: VariableValue, Raw: name}, nil
+		case "[":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			value := &Value{Kind: ListValue, List: make([]*Value, 0)}
+			for !p.peek("]") {
+				item, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				value.List = append(value.List, item)
+			}
+			return value, p.advance()
+		case "{":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			value := &Value{Kind: ObjectValue, Fields: make([]*ObjectField, 0)}
+			for !p.peek("}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err = p.expect(":"); err != nil {
+					return nil, err
+				}
+				field, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				value.Fields = append(value.Fields, &ObjectField{Name: name, Value: field})
+			}
+			return value, p.advance()
+		}
+	case tokenInt:
+		return &Value{Kind: IntValue, Raw: tok.value}, p.advance()
+	case tokenFloat:
+		return &Value{Kind: FloatValue, Raw: tok.value}, p.advance()
+	case tokenString:
+		return &Value{Kind: StringValue, Raw: tok.value}, p.advance()
+	case tokenName:
+		value := &Value{Kind: EnumValue, Raw: tok.value}
+		switch tok.value {
+		case "true", "false":
+			value.Kind = BooleanValue
+		case "null":
+			value.Kind = NullValue
+		}
+		return value, p.advance()
+	}
+	return nil, p.unexpected()
+}
+
+// TypeName a valid name of graphql from an id, the invalid characters are replaced by underscores.
+func TypeName(id string) string {
+	var b strings.Builder
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !isNameContinue(c) {
+			c = '_'
+		}
+		if i == 0 && isDigit(c) {
+			b.WriteByte('_')
+		}
+		b.WriteByte(c)
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package graphql
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(`
+	# the orders of a customer
+	query Orders($size: Int = 10, $withItems: Boolean!) {
+		list: search_order(query: {term: {customer: "c\"1中"}}, sort: ["-created_at"], size: $size) {
+			total
+			entities {
+				...order
+				items @include(if: $withItems) { name }
+				items @include(if: $withItems) { count }
+				hidden @skip(if: true)
+			}
+		}
+	}
+	fragment order on order {
+		_id, amount
+		... on order { __typename }
+	}
+	mutation Create { create_order(entity: {amount: -1.5e2, tags: [A, null]}) { _id } }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Operations) != 2 || len(doc.Fragments) != 1 {
+		t.Fatalf("operations %d, fragments %d", len(doc.Operations), len(doc.Fragments))
+	}
+	if _, err = doc.Operation(""); err == nil {
+		t.Fatal("operation name should be required")
+	}
+	op, err := doc.Operation("Orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	variables, err := op.Coerce(map[string]interface{}{"withItems": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variables["size"] != int64(10) {
+		t.Fatalf("default size %v", variables["size"])
+	}
+	if _, err = op.Coerce(nil); err == nil {
+		t.Fatal("withItems should be required")
+	}
+
+	list := op.Selections[0]
+	if list.Key() != "list" || list.Name != "search_order" {
+		t.Fatalf("alias %s, name %s", list.Key(), list.Name)
+	}
+	args := make(map[string]interface{})
+	for _, arg := range list.Arguments {
+		if args[arg.Name], err = arg.Value.Resolve(variables); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := map[string]interface{}{
+		"query": map[string]interface{}{"term": map[string]interface{}{"customer": "c\"1中"}},
+		"sort":  []interface{}{"-created_at"},
+		"size":  int64(10),
+	}
+	if !reflect.DeepEqual(args, expect) {
+		t.Fatalf("arguments %v", args)
+	}
+
+	page, err := doc.Collect(list.Selections, "order_page", variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := doc.Collect(page[1].Selections, "order", variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 0, len(fields))
+	for _, field := range fields {
+		keys = append(keys, field.Key())
+	}
+	if strings.Join(keys, ",") != "_id,amount,__typename,items" {
+		t.Fatalf("fields %v", keys)
+	}
+	if len(fields[3].Selections) != 2 {
+		t.Fatalf("items should be merged, got %d", len(fields[3].Selections))
+	}
+
+	create, _ := doc.Operation("Create")
+	entity, err := create.Selections[0].Arguments[0].Value.Resolve(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entity, map[string]interface{}{"amount": -150.0, "tags": []interface{}{"A", nil}}) {
+		t.Fatalf("entity %v", entity)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, src := range []string{
+		``,
+		`{`,
+		`{ a }}`,
+		`{ a(b: $c) { } }`,
+		`query ($a: Int = $b) { a }`,
+		`{ a(b: "x) }`,
+		`{ a(b: 1.) }`,
+		`{ a } { b }`,
+		`subscription { a }`,
+	} {
+		if _, err := Parse(src); err == nil {
+			t.Fatalf("%q should fail", src)
+		}
+	}
+	_, err := Parse("{\n  a(b: %) }")
+	if err == nil || !strings.Contains(err.Error(), "2:8") {
+		t.Fatalf("error should be located, got %v", err)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema := &Schema{Types: []*Type{{
+		Name: "order", TableID: "order", Description: "订单",
+		Fields: []*Field{
+			{Name: "_id", Key: "_id", Type: ID},
+			{Name: "items", Key: "items", Type: "item", List: true, Relation: true},
+		},
+	}}}
+	sdl := schema.SDL()
+	for _, s := range []string{
+		`"""订单"""`,
+		"type order {\n  _id: ID\n  items: [item]\n}",
+		"type order_page {\n  entities: [order]\n  total: Int\n  nextCursor: String\n}",
+		"get_order(id: ID!): order",
+		"update_order(id: ID!, entity: JSON!): Int",
+	} {
+		if !strings.Contains(sdl, s) {
+			t.Fatalf("%q is not in\n%s", s, sdl)
+		}
+	}
+	if _, err := Parse(`{ get_order(id: "1") { _id } }`); err != nil {
+		t.Fatal(err)
+	}
+	action, typeName, ok := RootField("search_order_item")
+	if !ok || action != SearchAction || typeName != "order_item" {
+		t.Fatalf("root field %s %s", action, typeName)
+	}
+	if TypeName("9a-b") != "_9a_b" {
+		t.Fatalf("type name %s", TypeName("9a-b"))
+	}
+
+	object := NewObject()
+	object.Set("b", 1)
+	object.Set("a", []interface{}{NewObject()})
+	data, _ := json.Marshal(&Response{Data: object})
+	if string(data) != `{"data":{"b":1,"a":[{}]}}` {
+		t.Fatalf("response %s", data)
+	}
+}
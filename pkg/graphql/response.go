@@ -0,0 +1,65 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Response the result of a request, Data is null if the request fails before executing.
+type Response struct {
+	Data   *Object  `json:"data"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Object the fields of a result in the order of the selections.
+type Object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewObject returns a new object.
+func NewObject() *Object {
+	return &Object{
+		values: make(map[string]interface{}),
+	}
+}
+
+// Set set the value of the key, the order of the keys is kept.
+func (o *Object) Set(key string, value interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// Get the value of the key.
+func (o *Object) Get(key string) interface{} {
+	return o.values[key]
+}
+
+// MarshalJSON json.Marshaler.
+func (o *Object) MarshalJSON() ([]byte, error) {
+	if o == nil {
+		return []byte("null"), nil
+	}
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,190 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scalars.
+const (
+	ID      = "ID"
+	String  = "String"
+	Float   = "Float"
+	Int     = "Int"
+	Boolean = "Boolean"
+	JSON    = "JSON"
+)
+
+// TypeNameField the meta field of the name of the type.
+const TypeNameField = "__typename"
+
+// Actions of the root fields, a root field is named like search_<type>.
+const (
+	GetAction    = "get"
+	SearchAction = "search"
+	CreateAction = "create"
+	UpdateAction = "update"
+	DeleteAction = "delete"
+)
+
+// Fields of the page of search.
+const (
+	EntitiesField   = "entities"
+	TotalField      = "total"
+	NextCursorField = "nextCursor"
+)
+
+// PageSuffix the suffix of the name of the page type of search.
+const PageSuffix = "_page"
+
+// Schema the object types of the tables of an app.
+type Schema struct {
+	Types []*Type `json:"types"`
+}
+
+// Type an object type of a table.
+type Type struct {
+	Name        string   `json:"name"`
+	TableID     string   `json:"tableID"`
+	Description string   `json:"description,omitempty"`
+	Fields      []*Field `json:"fields"`
+}
+
+// Field a field of a type, Key is the field of the record.
+type Field struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	// Type a scalar, or the type of the related table for a relation.
+	Type string `json:"type"`
+	List bool   `json:"list,omitempty"`
+	// Relation the field is expanded to the related records.
+	Relation    bool   `json:"relation,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Type the type of the name, nil if not found.
+func (s *Schema) Type(name string) *Type {
+	for _, t := range s.Types {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Field the field of the name, nil if not found.
+func (t *Type) Field(name string) *Field {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// RootField the action and the type of a root field.
+func RootField(name string) (action, typeName string, ok bool) {
+	for _, action := range []string{GetAction, SearchAction, CreateAction, UpdateAction, DeleteAction} {
+		if strings.HasPrefix(name, action+"_") && len(name) > len(action)+1 {
+			return action, name[len(action)+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// SDL the schema definition language of the schema, with the root types of the actions.
+func (s *Schema) SDL() string {
+	var b strings.Builder
+	b.WriteString("scalar JSON\n")
+	for _, t := range s.Types {
+		b.WriteString("\n")
+		writeDescription(&b, "", t.Description)
+		fmt.Fprintf(&b, "type %s {\n", t.Name)
+		for _, f := range t.Fields {
+			writeDescription(&b, "  ", f.Description)
+			typ := f.Type
+			if f.List {
+				typ = "[" + typ + "]"
+			}
+			fmt.Fprintf(&b, "  %s: %s\n", f.Name, typ)
+		}
+		b.WriteString("}\n")
+		fmt.Fprintf(&b, "\ntype %s%s {\n  %s: [%s]\n  %s: %s\n  %s: %s\n}\n",
+			t.Name, PageSuffix, EntitiesField, t.Name, TotalField, Int, NextCursorField, String)
+	}
+	if len(s.Types) == 0 {
+		return b.String()
+	}
+
+	queries, mutations := s.rootFields()
+	writeRoot(&b, QueryRoot, queries)
+	writeRoot(&b, MutationRoot, mutations)
+	return b.String()
+}
+
+// Names of the root types.
+const (
+	QueryRoot    = "Query"
+	MutationRoot = "Mutation"
+)
+
+// rootField a field of the root types, the types are in the type references of the SDL.
+type rootField struct {
+	name string
+	args []rootArgument
+	typ  string
+}
+
+type rootArgument struct {
+	name string
+	typ  string
+}
+
+var (
+	idArgument     = rootArgument{name: "id", typ: ID + "!"}
+	entityArgument = rootArgument{name: "entity", typ: JSON + "!"}
+	searchArgs     = []rootArgument{
+		{name: "query", typ: JSON},
+		{name: "sort", typ: "[" + String + "]"},
+		{name: "page", typ: Int},
+		{name: "size", typ: Int},
+		{name: "cursor", typ: String},
+		{name: "q", typ: String},
+		{name: "searchFields", typ: "[" + String + "]"},
+	}
+)
+
+// rootFields the fields of Query and Mutation, by the actions of the types.
+func (s *Schema) rootFields() (queries, mutations []*rootField) {
+	for _, t := range s.Types {
+		queries = append(queries,
+			&rootField{name: GetAction + "_" + t.Name, args: []rootArgument{idArgument}, typ: t.Name},
+			&rootField{name: SearchAction + "_" + t.Name, args: searchArgs, typ: t.Name + PageSuffix},
+		)
+		mutations = append(mutations,
+			&rootField{name: CreateAction + "_" + t.Name, args: []rootArgument{entityArgument}, typ: t.Name},
+			&rootField{name: UpdateAction + "_" + t.Name, args: []rootArgument{idArgument, entityArgument}, typ: Int},
+			&rootField{name: DeleteAction + "_" + t.Name, args: []rootArgument{idArgument}, typ: Int},
+		)
+	}
+	return queries, mutations
+}
+
+func writeRoot(b *strings.Builder, name string, fields []*rootField) {
+	fmt.Fprintf(b, "\ntype %s {\n", name)
+	for _, f := range fields {
+		args := make([]string, 0, len(f.args))
+		for _, arg := range f.args {
+			args = append(args, arg.name+": "+arg.typ)
+		}
+		fmt.Fprintf(b, "  %s(%s): %s\n", f.name, strings.Join(args, ", "), f.typ)
+	}
+	b.WriteString("}\n")
+}
+
+func writeDescription(b *strings.Builder, indent, description string) {
+	if description == "" {
+		return
+	}
+	fmt.Fprintf(b, "%s\"\"\"%s\"\"\"\n", indent, strings.ReplaceAll(description, `"""`, `\"""`))
+}
@@ -34,6 +34,8 @@ func Transport(conf *config.Config) *http.Transport {
 type Proxys struct {
 	Url       *url.URL
 	Transport http.RoundTripper
+	// KeepBody forward the body as it is, like the multipart files, which can not be bound as json.
+	KeepBody bool
 }
 
 func DoPoxy(ctx context.Context, req *permit.Request, p *Proxys, modify ModifyResponse) error {
@@ -49,7 +51,7 @@ func DoPoxy(ctx context.Context, req *permit.Request, p *Proxys, modify ModifyRe
 	}
 	r := req.Echo.Request()
 	r.Host = p.Url.Host
-	if !IsQueryMethod(req.Echo.Request().Method) {
+	if !p.KeepBody && !IsQueryMethod(req.Echo.Request().Method) {
 		data, err := json.Marshal(req.Data)
 		if err != nil {
 			logger.Logger.WithName("form proxy").Errorw(err.Error(), header.GetRequestIDKV(ctx).Fuzzy()...)
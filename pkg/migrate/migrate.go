@@ -0,0 +1,235 @@
+package migrate
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	time2 "github.com/quanxiang-cloud/cabin/time"
+)
+
+// Status the state of a step.
+type Status string
+
+const (
+	// StatusSucceed the step is applied and verified, it is not applied again.
+	StatusSucceed Status = "succeed"
+	// StatusFailed the step failed or its counts did not match, it is applied again by the next run.
+	StatusFailed Status = "failed"
+	// StatusRunning the step was interrupted if it is left running, it is applied again by the next run.
+	StatusRunning Status = "running"
+	// StatusSkipped the step can not be applied in the environment, it is looked at again by the next run.
+	StatusSkipped Status = "skipped"
+	// StatusPending the step is to be applied, only reported in a dry run.
+	StatusPending Status = "pending"
+)
+
+// Counts the rows or the objects of each kind.
+type Counts map[string]int64
+
+// Value 实现方法.
+func (c Counts) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan 实现方法.
+func (c *Counts) Scan(data interface{}) error {
+	return json.Unmarshal(data.([]byte), &c)
+}
+
+// Step a change of the database of a version, the steps are applied in order, each once.
+type Step struct {
+	// Version like v0.0.2, the steps of a version follow the ones of the versions before.
+	Version string
+	Name    string
+	// Skip the reason the step can not be applied in the environment, if any.
+	Skip func() string
+	// Expect the counts the step should reach, nothing is changed.
+	Expect func(ctx context.Context) (Counts, error)
+	// Apply must be idempotent, it is applied again after a failure.
+	Apply func(ctx context.Context) error
+	// Verify the counts after the step is applied, they must equal the expected ones.
+	Verify func(ctx context.Context) (Counts, error)
+}
+
+// ID the step in the state, like v0.0.2/permit.
+func (s *Step) ID() string {
+	return s.Version + "/" + s.Name
+}
+
+// Record the state of a step.
+type Record struct {
+	ID         string
+	Version    string
+	Name       string
+	Status     Status
+	Expected   Counts
+	Verified   Counts
+	Message    string
+	StartedAt  int64
+	FinishedAt int64
+}
+
+// State where the records of the steps are kept.
+type State interface {
+	// Init create the place of the records if it does not exist.
+	Init(ctx context.Context) error
+	// List the records by the ids of the steps, none if the place does not exist.
+	List(ctx context.Context) (map[string]*Record, error)
+	Save(ctx context.Context, record *Record) error
+}
+
+// Migrator apply the steps which are not succeed, in order, it stops at the first failure,
+// so the run after resumes from it.
+type Migrator struct {
+	steps []*Step
+	state State
+}
+
+// New the steps must be in the order of the versions, with unique ids.
+func New(state State, steps ...*Step) (*Migrator, error) {
+	seen := make(map[string]bool, len(steps))
+	for i, step := range steps {
+		if seen[step.ID()] {
+			return nil, fmt.Errorf("duplicated step %s", step.ID())
+		}
+		seen[step.ID()] = true
+		if i > 0 && compareVersion(steps[i-1].Version, step.Version) > 0 {
+			return nil, fmt.Errorf("step %s comes after %s", step.ID(), steps[i-1].ID())
+		}
+	}
+	return &Migrator{
+		steps: steps,
+		state: state,
+	}, nil
+}
+
+// Run apply the steps, in a dry run the expected counts of the pending steps are reported only.
+// the records of all the steps are returned, with the error of the failed one.
+func (m *Migrator) Run(ctx context.Context, dryRun bool) ([]*Record, error) {
+	if !dryRun {
+		if err := m.state.Init(ctx); err != nil {
+			return nil, err
+		}
+	}
+	records, err := m.state.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Record, 0, len(m.steps))
+	for _, step := range m.steps {
+		record, ok := records[step.ID()]
+		if ok && record.Status == StatusSucceed {
+			result = append(result, record)
+			continue
+		}
+
+		record = &Record{
+			ID:        step.ID(),
+			Version:   step.Version,
+			Name:      step.Name,
+			Status:    StatusPending,
+			StartedAt: time2.NowUnix(),
+		}
+		result = append(result, record)
+		if step.Skip != nil {
+			if reason := step.Skip(); reason != "" {
+				record.Status, record.Message = StatusSkipped, reason
+				if !dryRun {
+					if err = m.state.Save(ctx, record); err != nil {
+						return result, err
+					}
+				}
+				continue
+			}
+		}
+
+		record.Expected, err = step.Expect(ctx)
+		if err != nil {
+			return result, m.fail(ctx, record, dryRun, err)
+		}
+		if dryRun {
+			continue
+		}
+
+		record.Status = StatusRunning
+		if err = m.state.Save(ctx, record); err != nil {
+			return result, err
+		}
+		if err = step.Apply(ctx); err != nil {
+			return result, m.fail(ctx, record, dryRun, err)
+		}
+		record.Verified, err = step.Verify(ctx)
+		if err != nil {
+			return result, m.fail(ctx, record, dryRun, err)
+		}
+		if diff := mismatch(record.Expected, record.Verified); diff != "" {
+			return result, m.fail(ctx, record, dryRun, fmt.Errorf("the counts do not match: %s", diff))
+		}
+
+		record.Status = StatusSucceed
+		record.FinishedAt = time2.NowUnix()
+		if err = m.state.Save(ctx, record); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func (m *Migrator) fail(ctx context.Context, record *Record, dryRun bool, err error) error {
+	record.Status = StatusFailed
+	record.Message = err.Error()
+	record.FinishedAt = time2.NowUnix()
+	if dryRun {
+		return err
+	}
+	if err := m.state.Save(ctx, record); err != nil {
+		return err
+	}
+	return fmt.Errorf("step %s: %w", record.ID, err)
+}
+
+// mismatch the kinds whose counts differ, empty if none.
+func mismatch(expected, verified Counts) string {
+	kinds := make([]string, 0)
+	for kind, count := range expected {
+		if verified[kind] != count {
+			kinds = append(kinds, fmt.Sprintf("%s %d of %d", kind, verified[kind], count))
+		}
+	}
+	for kind, count := range verified {
+		if _, ok := expected[kind]; !ok {
+			kinds = append(kinds, fmt.Sprintf("%s %d of 0", kind, count))
+		}
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, ", ")
+}
+
+// compareVersion compare the versions like v0.0.1 by their numbers.
+func compareVersion(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
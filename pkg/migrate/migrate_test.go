@@ -0,0 +1,155 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/quanxiang-cloud/form/schema"
+)
+
+type fakeState struct {
+	records map[string]*Record
+	saves   int
+}
+
+func (f *fakeState) Init(ctx context.Context) error {
+	if f.records == nil {
+		f.records = make(map[string]*Record)
+	}
+	return nil
+}
+
+func (f *fakeState) List(ctx context.Context) (map[string]*Record, error) {
+	records := make(map[string]*Record, len(f.records))
+	for id, record := range f.records {
+		copied := *record
+		records[id] = &copied
+	}
+	return records, nil
+}
+
+func (f *fakeState) Save(ctx context.Context, record *Record) error {
+	copied := *record
+	f.records[record.ID] = &copied
+	f.saves++
+	return nil
+}
+
+// fakeStep copy n rows to the table, it fails while fail is set.
+func fakeStep(version, name string, n int64, rows *int64, fail *bool) *Step {
+	return &Step{
+		Version: version,
+		Name:    name,
+		Expect: func(ctx context.Context) (Counts, error) {
+			return Counts{name: n}, nil
+		},
+		Apply: func(ctx context.Context) error {
+			if *fail {
+				return errors.New("broken")
+			}
+			*rows = n
+			return nil
+		},
+		Verify: func(ctx context.Context) (Counts, error) {
+			return Counts{name: *rows}, nil
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	var a, b int64
+	fail := false
+	broken := true
+	state := &fakeState{}
+	m, err := New(state,
+		fakeStep("v0.0.1", "a", 2, &a, &fail),
+		fakeStep("v0.0.2", "b", 3, &b, &broken),
+		&Step{Version: "v0.0.10", Name: "c", Skip: func() string { return "no mongo" }},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := m.Run(context.Background(), true)
+	if err != nil || state.saves != 0 || a != 0 || len(records) != 3 ||
+		records[1].Status != StatusPending || records[1].Expected["b"] != 3 || records[2].Status != StatusSkipped {
+		t.Fatalf("dry run %v, %+v", err, records)
+	}
+
+	// b fails, c is not reached.
+	records, err = m.Run(context.Background(), false)
+	if err == nil || len(records) != 2 || records[0].Status != StatusSucceed || records[1].Status != StatusFailed ||
+		state.records["v0.0.2/b"].Message != "broken" || state.records["v0.0.10/c"] != nil {
+		t.Fatalf("run %v, %+v", err, records)
+	}
+
+	// a is not applied again.
+	a = 0
+	broken = false
+	records, err = m.Run(context.Background(), false)
+	if err != nil || a != 0 || b != 3 || records[0].Status != StatusSucceed || records[1].Status != StatusSucceed ||
+		records[1].Verified["b"] != 3 || records[2].Status != StatusSkipped {
+		t.Fatalf("resume %v, %+v", err, records)
+	}
+}
+
+func TestRunMismatch(t *testing.T) {
+	var rows int64
+	fail := false
+	step := fakeStep("v0.0.1", "a", 2, &rows, &fail)
+	step.Apply = func(ctx context.Context) error {
+		rows = 1
+		return nil
+	}
+	state := &fakeState{}
+	m, err := New(state, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Run(context.Background(), false)
+	if err == nil || state.records["v0.0.1/a"].Status != StatusFailed || !strings.Contains(err.Error(), "a 1 of 2") {
+		t.Fatalf("run %v, %+v", err, state.records)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(&fakeState{}, &Step{Version: "v0.0.10", Name: "a"}, &Step{Version: "v0.0.9", Name: "b"}); err == nil {
+		t.Fatal("out of order")
+	}
+	if _, err := New(&fakeState{}, &Step{Version: "v0.0.1", Name: "a"}, &Step{Version: "v0.0.1", Name: "a"}); err == nil {
+		t.Fatal("duplicated")
+	}
+}
+
+func TestParse(t *testing.T) {
+	files, err := fs.Glob(schema.FS, "*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatal(files, err)
+	}
+	for _, file := range files {
+		text, err := fs.ReadFile(schema.FS, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		statements, err := parse(string(text))
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		for _, statement := range statements {
+			if statement.column == "" && !strings.HasPrefix(statement.sql, "CREATE TABLE IF NOT EXISTS `"+statement.table+"`") {
+				t.Fatalf("%s: %s", file, statement.sql)
+			}
+		}
+	}
+
+	statements, err := parse("DROP TABLE IF EXISTS `permit`;\nALTER TABLE `permit` ADD `params_all`   bool;")
+	if err != nil || len(statements) != 1 || statements[0].table != "permit" || statements[0].column != "params_all" {
+		t.Fatalf("%v, %+v", err, statements)
+	}
+	if _, err = parse("UPDATE `permit` SET `params_all` = 1;"); err == nil {
+		t.Fatal("update")
+	}
+}
@@ -0,0 +1,241 @@
+package migrate
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+
+	id2 "github.com/quanxiang-cloud/cabin/id"
+	time2 "github.com/quanxiang-cloud/cabin/time"
+	"github.com/quanxiang-cloud/form/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// structor the database where the tables were kept before v0.0.1.
+const structor = "structor"
+
+// move copy the documents of a collection to a table, a row is skipped if one with
+// the same key exists, so the move can be applied again.
+type move struct {
+	collection string
+	table      string
+	// convert the document to the row, with the columns of its key.
+	convert func(cursor *mongo.Cursor) (interface{}, map[string]interface{}, error)
+}
+
+// MongoSteps move the tables, their relations and their schemas out of mongo.
+func MongoSteps(db *gorm.DB, client *mongo.Client, version string) []*Step {
+	moves := []*move{
+		{collection: "table_schema", table: "table", convert: convertTable},
+		{collection: "sub_table_relation", table: "table_relation", convert: convertRelation},
+		{collection: "database_schema", table: "table_schema", convert: convertSchema},
+	}
+	steps := make([]*Step, 0, len(moves))
+	for _, m := range moves {
+		steps = append(steps, m.step(db, client, version))
+	}
+	return steps
+}
+
+func (m *move) step(db *gorm.DB, client *mongo.Client, version string) *Step {
+	return &Step{
+		Version: version,
+		Name:    m.table,
+		Skip: func() string {
+			if client == nil {
+				return "mongo is not configured"
+			}
+			return ""
+		},
+		Expect: func(ctx context.Context) (Counts, error) {
+			count, err := client.Database(structor).Collection(m.collection).CountDocuments(ctx, bson.M{})
+			if err != nil {
+				return nil, err
+			}
+			return Counts{m.table: count}, nil
+		},
+		Apply: func(ctx context.Context) error {
+			return m.each(ctx, db, client, func(tx *gorm.DB, row interface{}, exists bool) error {
+				if exists {
+					return nil
+				}
+				return tx.Table(m.table).Create(row).Error
+			})
+		},
+		Verify: func(ctx context.Context) (Counts, error) {
+			var count int64
+			err := m.each(ctx, db, client, func(tx *gorm.DB, row interface{}, exists bool) error {
+				if exists {
+					count++
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			return Counts{m.table: count}, nil
+		},
+	}
+}
+
+func (m *move) each(ctx context.Context, db *gorm.DB, client *mongo.Client, fn func(tx *gorm.DB, row interface{}, exists bool) error) error {
+	cursor, err := client.Database(structor).Collection(m.collection).Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	tx := db.WithContext(ctx)
+	for cursor.Next(ctx) {
+		row, key, err := m.convert(cursor)
+		if err != nil {
+			return err
+		}
+		var count int64
+		if err = tx.Table(m.table).Where(key).Count(&count).Error; err != nil {
+			return err
+		}
+		if err = fn(tx, row, count != 0); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// table the document of structor.table_schema.
+type table struct {
+	ID      string                 `bson:"_id"`
+	AppID   string                 `bson:"app_id"`
+	TableID string                 `bson:"table_id"`
+	Schema  map[string]interface{} `bson:"schema"`
+	Config  map[string]interface{} `bson:"config"`
+}
+
+func convertTable(cursor *mongo.Cursor) (interface{}, map[string]interface{}, error) {
+	value := &table{}
+	if err := cursor.Decode(value); err != nil {
+		return nil, nil, err
+	}
+	return &models.Table{
+		ID:        id2.StringUUID(),
+		AppID:     value.AppID,
+		TableID:   value.TableID,
+		Schema:    value.Schema,
+		Config:    value.Config,
+		CreatedAt: time2.NowUnix(),
+	}, map[string]interface{}{
+		"app_id":   value.AppID,
+		"table_id": value.TableID,
+	}, nil
+}
+
+// subTable the document of structor.sub_table_relation.
+type subTable struct {
+	ID           string   `bson:"_id"`
+	AppID        string   `bson:"app_id"`
+	TableID      string   `bson:"table_id"`
+	FieldName    string   `bson:"field_name"`
+	SubTableID   string   `bson:"sub_table_id"`
+	SubTableType string   `bson:"sub_table_type"`
+	Filter       []string `bson:"filter"`
+}
+
+func convertRelation(cursor *mongo.Cursor) (interface{}, map[string]interface{}, error) {
+	value := &subTable{}
+	if err := cursor.Decode(value); err != nil {
+		return nil, nil, err
+	}
+	relation := &models.TableRelation{
+		ID:         id2.StringUUID(),
+		AppID:      value.AppID,
+		TableID:    value.TableID,
+		FieldName:  value.FieldName,
+		SubTableID: value.SubTableID,
+		Filter:     value.Filter,
+		CreatedAt:  time2.NowUnix(),
+	}
+	if value.SubTableType == "AssociatedRecords" {
+		relation.SubTableType = "associated_records"
+	}
+	return relation, map[string]interface{}{
+		"app_id":     value.AppID,
+		"table_id":   value.TableID,
+		"field_name": value.FieldName,
+	}, nil
+}
+
+// dataBaseSchema the document of structor.database_schema, its times are in seconds.
+type dataBaseSchema struct {
+	ID          string                 `bson:"_id"`
+	Title       string                 `bson:"title"`
+	AppID       string                 `bson:"app_id"`
+	TableID     string                 `bson:"table_id"`
+	FieldLen    int64                  `bson:"field_len"`
+	Description string                 `bson:"description"`
+	Source      int                    `bson:"source"`
+	CreatedAt   int64                  `bson:"created_at"`
+	UpdatedAt   int64                  `bson:"updated_at"`
+	CreatorID   string                 `bson:"creator_id"`
+	CreatorName string                 `bson:"creator_name"`
+	EditorID    string                 `bson:"editor_id"`
+	EditorName  string                 `bson:"editor_name"`
+	Schema      map[string]interface{} `bson:"schema"`
+}
+
+// tableSchema the row of table_schema, the schema is kept as it is.
+type tableSchema struct {
+	ID          string
+	AppID       string
+	TableID     string
+	FieldLen    int64
+	Title       string
+	Description string
+	Source      int
+	CreatedAt   int64
+	UpdatedAt   int64
+	CreatorID   string
+	CreatorName string
+	EditorID    string
+	EditorName  string
+	Schema      schemaProperties
+}
+
+type schemaProperties map[string]interface{}
+
+// Value 实现方法.
+func (p schemaProperties) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan 实现方法.
+func (p *schemaProperties) Scan(data interface{}) error {
+	return json.Unmarshal(data.([]byte), &p)
+}
+
+func convertSchema(cursor *mongo.Cursor) (interface{}, map[string]interface{}, error) {
+	value := &dataBaseSchema{}
+	if err := cursor.Decode(value); err != nil {
+		return nil, nil, err
+	}
+	return &tableSchema{
+		ID:          id2.StringUUID(),
+		AppID:       value.AppID,
+		TableID:     value.TableID,
+		FieldLen:    value.FieldLen,
+		Title:       value.Title,
+		Description: value.Description,
+		Source:      value.Source,
+		CreatedAt:   value.CreatedAt * 1000,
+		UpdatedAt:   value.UpdatedAt * 1000,
+		CreatorID:   value.CreatorID,
+		CreatorName: value.CreatorName,
+		EditorID:    value.EditorID,
+		EditorName:  value.EditorName,
+		Schema:      value.Schema,
+	}, map[string]interface{}{
+		"app_id":   value.AppID,
+		"table_id": value.TableID,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	tablesKey  = "tables"
+	columnsKey = "columns"
+)
+
+var (
+	dropTable   = regexp.MustCompile("(?is)^DROP\\s+TABLE\\s+")
+	createTable = regexp.MustCompile("(?is)^CREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?`?(\\w+)`?(.*)$")
+	addColumn   = regexp.MustCompile("(?is)^ALTER\\s+TABLE\\s+`?(\\w+)`?\\s+ADD\\s+(?:COLUMN\\s+)?`?(\\w+)`?(.*)$")
+)
+
+// statement a statement of a schema file, made idempotent.
+type statement struct {
+	table  string
+	column string
+	sql    string
+}
+
+// parse the statements of a schema file. the tables are created if they do not exist, instead of
+// being dropped first, and the columns are added if they do not exist; other statements can not be
+// applied again, so they are refused.
+func parse(text string) ([]*statement, error) {
+	statements := make([]*statement, 0)
+	for _, sql := range strings.Split(text, ";") {
+		sql = strings.TrimSpace(sql)
+		switch {
+		case sql == "", dropTable.MatchString(sql):
+			continue
+		case createTable.MatchString(sql):
+			match := createTable.FindStringSubmatch(sql)
+			statements = append(statements, &statement{
+				table: match[1],
+				sql:   fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s`%s", match[1], match[2]),
+			})
+		case addColumn.MatchString(sql):
+			match := addColumn.FindStringSubmatch(sql)
+			statements = append(statements, &statement{
+				table:  match[1],
+				column: match[2],
+				sql:    fmt.Sprintf("ALTER TABLE `%s` ADD `%s`%s", match[1], match[2], match[3]),
+			})
+		default:
+			return nil, fmt.Errorf("unsupported statement: %.64s", sql)
+		}
+	}
+	return statements, nil
+}
+
+// SQLStep apply the schema file of the version, named like v0.0.2.sql.
+func SQLStep(db *gorm.DB, files fs.FS, version string) (*Step, error) {
+	text, err := fs.ReadFile(files, version+".sql")
+	if err != nil {
+		return nil, err
+	}
+	statements, err := parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("%s.sql: %w", version, err)
+	}
+
+	return &Step{
+		Version: version,
+		Name:    "schema",
+		Expect: func(ctx context.Context) (Counts, error) {
+			counts := Counts{tablesKey: 0, columnsKey: 0}
+			for _, statement := range statements {
+				counts[statement.kind()]++
+			}
+			return counts, nil
+		},
+		Apply: func(ctx context.Context) error {
+			db := db.WithContext(ctx)
+			for _, statement := range statements {
+				if statement.column != "" && db.Migrator().HasColumn(statement.table, statement.column) {
+					continue
+				}
+				if err := db.Exec(statement.sql).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Verify: func(ctx context.Context) (Counts, error) {
+			db := db.WithContext(ctx)
+			counts := Counts{tablesKey: 0, columnsKey: 0}
+			for _, statement := range statements {
+				if statement.column != "" && db.Migrator().HasColumn(statement.table, statement.column) ||
+					statement.column == "" && db.Migrator().HasTable(statement.table) {
+					counts[statement.kind()]++
+				}
+			}
+			return counts, nil
+		},
+	}, nil
+}
+
+func (s *statement) kind() string {
+	if s.column != "" {
+		return columnsKey
+	}
+	return tablesKey
+}
@@ -0,0 +1,55 @@
+package migrate
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+const stateTable = "schema_migration"
+
+type mysqlState struct {
+	db *gorm.DB
+}
+
+// NewMysqlState keep the records in the table schema_migration of the database.
+func NewMysqlState(db *gorm.DB) State {
+	return &mysqlState{
+		db: db,
+	}
+}
+
+func (s *mysqlState) Init(ctx context.Context) error {
+	return s.db.WithContext(ctx).Exec("CREATE TABLE IF NOT EXISTS `" + stateTable + "` (" +
+		"`id` VARCHAR(128) COMMENT 'version/name'," +
+		"`version` VARCHAR(32) COMMENT 'version'," +
+		"`name` VARCHAR(64) COMMENT 'step name'," +
+		"`status` VARCHAR(16) COMMENT 'running, succeed, failed or skipped'," +
+		"`expected` TEXT COMMENT 'expected counts'," +
+		"`verified` TEXT COMMENT 'verified counts'," +
+		"`message` TEXT COMMENT 'error or skip reason'," +
+		"`started_at` BIGINT(20) COMMENT 'start time'," +
+		"`finished_at` BIGINT(20) COMMENT 'finish time'," +
+		"PRIMARY KEY (`id`)" +
+		")ENGINE=InnoDB DEFAULT CHARSET=utf8").Error
+}
+
+func (s *mysqlState) List(ctx context.Context) (map[string]*Record, error) {
+	db := s.db.WithContext(ctx)
+	records := make(map[string]*Record)
+	if !db.Migrator().HasTable(stateTable) {
+		return records, nil
+	}
+	list := make([]*Record, 0)
+	if err := db.Table(stateTable).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for _, record := range list {
+		records[record.ID] = record
+	}
+	return records, nil
+}
+
+func (s *mysqlState) Save(ctx context.Context, record *Record) error {
+	return s.db.WithContext(ctx).Table(stateTable).Save(record).Error
+}
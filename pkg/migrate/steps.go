@@ -0,0 +1,26 @@
+package migrate
+
+import (
+	"io/fs"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// Steps the steps of form, a new version appends its own. the client may be nil if there is no mongo,
+// the data of structor are moved once it is configured.
+func Steps(db *gorm.DB, client *mongo.Client, files fs.FS) ([]*Step, error) {
+	schemas := make(map[string]*Step)
+	for _, version := range []string{"v0.0.1", "v0.0.2", "v0.0.3"} {
+		step, err := SQLStep(db, files, version)
+		if err != nil {
+			return nil, err
+		}
+		schemas[version] = step
+	}
+
+	steps := []*Step{schemas["v0.0.1"]}
+	steps = append(steps, MongoSteps(db, client, "v0.0.1")...)
+	steps = append(steps, schemas["v0.0.2"], schemas["v0.0.3"])
+	return steps, nil
+}
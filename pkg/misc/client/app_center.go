@@ -102,6 +102,11 @@ func GetRole(c *gin.Context) []string {
 	return strings.Split(roleStr, ",")
 }
 
+// IsSuper whether the request is from a super admin.
+func IsSuper(c *gin.Context) bool {
+	return isSuper(GetRole(c))
+}
+
 func isSuper(roles []string) bool {
 	for _, role := range roles {
 		if role == "super" {
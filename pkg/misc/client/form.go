@@ -169,6 +169,32 @@ func (f *FormAPI) Insert(ctx context.Context, formReq *FormReq) (*InsertResp, er
 	}, err
 }
 
+// BatchInsert insert the entities as they are, in one request.
+func (f *FormAPI) BatchInsert(ctx context.Context, tableID string, entities []interface{}) (*InsertResp, error) {
+	anyArr := make([]*anypb.Any, 0, len(entities))
+	for _, entity := range entities {
+		marshal, err := json.Marshal(entity)
+		if err != nil {
+			return nil, err
+		}
+		any, err := rawToAny(marshal)
+		if err != nil {
+			return nil, err
+		}
+		anyArr = append(anyArr, any)
+	}
+	insert, err := f.client.Insert(ctx, &pb.InsertReq{
+		TableName: tableID,
+		Entities:  anyArr,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &InsertResp{
+		SuccessCount: insert.Count,
+	}, nil
+}
+
 type GetResp struct {
 	Entity map[string]interface{} `json:"entity"`
 }
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/quanxiang-cloud/cabin/tailormade/client"
+	"github.com/quanxiang-cloud/form/pkg/misc/config"
+)
+
+const (
+	depByIDs  = "/api/v1/org/o/dep/ids"
+	userByIDs = "/api/v1/org/o/user/ids"
+)
+
+// Dep a department.
+type Dep struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// User a user, with the departments the user is in.
+type User struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Deps []Dep  `json:"deps"`
+}
+
+// OrgAPI the users and the departments of the org service.
+type OrgAPI interface {
+	GetDepByIDs(ctx context.Context, ids ...string) ([]*Dep, error)
+	GetUserByIDs(ctx context.Context, ids ...string) ([]*User, error)
+}
+
+type orgAPI struct {
+	conf   *config.Config
+	client http.Client
+}
+
+// NewOrgAPI NewOrgAPI
+func NewOrgAPI(conf *config.Config) OrgAPI {
+	return &orgAPI{
+		conf:   conf,
+		client: client.New(conf.InternalNet),
+	}
+}
+
+func (o *orgAPI) GetDepByIDs(ctx context.Context, ids ...string) ([]*Dep, error) {
+	params := struct {
+		IDs []string `json:"ids"`
+	}{
+		IDs: ids,
+	}
+	resp := &struct {
+		Deps []*Dep `json:"deps"`
+	}{}
+	err := client.POST(ctx, &o.client, fmt.Sprintf("%s%s", o.conf.Endpoint.Org, depByIDs), params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Deps, nil
+}
+
+func (o *orgAPI) GetUserByIDs(ctx context.Context, ids ...string) ([]*User, error) {
+	params := struct {
+		IDs []string `json:"ids"`
+	}{
+		IDs: ids,
+	}
+	resp := &struct {
+		Users []*User `json:"users"`
+	}{}
+	err := client.POST(ctx, &o.client, fmt.Sprintf("%s%s", o.conf.Endpoint.Org, userByIDs), params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Users, nil
+}
@@ -17,13 +17,112 @@ const (
 	ErrNotPermit = 90074000002
 	// ErrParameter ErrParameter
 	ErrParameter = 90074000003
+	// ErrRuleViolation ErrRuleViolation
+	ErrRuleViolation = 90074000004
+	// ErrInvalidRule ErrInvalidRule
+	ErrInvalidRule = 90074000005
+	// ErrNotExistTable ErrNotExistTable
+	ErrNotExistTable = 90074000006
+	// ErrNotExistTemplate ErrNotExistTemplate
+	ErrNotExistTemplate = 90074000007
+	// ErrTableReferenced ErrTableReferenced
+	ErrTableReferenced = 90074000008
+	// ErrRelationCycle ErrRelationCycle
+	ErrRelationCycle = 90074000009
+	// ErrNotExistJob ErrNotExistJob
+	ErrNotExistJob = 90074000010
+	// ErrJobNotFinished ErrJobNotFinished
+	ErrJobNotFinished = 90074000011
+	// ErrImportFile ErrImportFile
+	ErrImportFile = 90074000012
+	// ErrImportMapping ErrImportMapping
+	ErrImportMapping = 90074000013
+	// ErrInvalidCursor ErrInvalidCursor
+	ErrInvalidCursor = 90074000014
+	// ErrInvalidExpand ErrInvalidExpand
+	ErrInvalidExpand = 90074000015
+	// ErrInvalidAggregate ErrInvalidAggregate
+	ErrInvalidAggregate = 90074000016
+	// ErrAggregateLimit ErrAggregateLimit
+	ErrAggregateLimit = 90074000017
+	// ErrNotExistView ErrNotExistView
+	ErrNotExistView = 90074000018
+	// ErrNotViewOwner ErrNotViewOwner
+	ErrNotViewOwner = 90074000019
+	// ErrInvalidView ErrInvalidView
+	ErrInvalidView = 90074000020
+	// ErrInvalidUpsert ErrInvalidUpsert
+	ErrInvalidUpsert = 90074000021
+	// ErrUpsertConflict ErrUpsertConflict
+	ErrUpsertConflict = 90074000022
+	// ErrUpsertForbidden ErrUpsertForbidden
+	ErrUpsertForbidden = 90074000023
+	// ErrIdempotencyInProgress ErrIdempotencyInProgress
+	ErrIdempotencyInProgress = 90074000024
+	// ErrIdempotencyMismatch ErrIdempotencyMismatch
+	ErrIdempotencyMismatch = 90074000025
+	// ErrUpsertBusy ErrUpsertBusy
+	ErrUpsertBusy = 90074000026
+	// ErrNotExistWebhook ErrNotExistWebhook
+	ErrNotExistWebhook = 90074000027
+	// ErrInvalidWebhook ErrInvalidWebhook
+	ErrInvalidWebhook = 90074000028
+	// ErrNotExistDelivery ErrNotExistDelivery
+	ErrNotExistDelivery = 90074000029
+	// ErrInvalidEventID ErrInvalidEventID
+	ErrInvalidEventID = 90074000030
+	// ErrInvalidCommand ErrInvalidCommand
+	ErrInvalidCommand = 90074000031
+	// ErrInvalidRestore ErrInvalidRestore
+	ErrInvalidRestore = 90074000032
+	// ErrNotExistSnapshot ErrNotExistSnapshot
+	ErrNotExistSnapshot = 90074000033
+	// ErrInvalidBackupPolicy ErrInvalidBackupPolicy
+	ErrInvalidBackupPolicy = 90074000034
+	// ErrSnapshotNotFinished ErrSnapshotNotFinished
+	ErrSnapshotNotFinished = 90074000035
+	// ErrRestoreIncomplete ErrRestoreIncomplete
+	ErrRestoreIncomplete = 90074000036
 )
 
 // CodeTable 码表
 var CodeTable = map[int64]string{
-	ErrExistRoleNameState: "角色名称不能重复，请重新输入！",
-	ErrExistPermitState:   "权限设置已设置 ,不能重复设置",
-	ErrItemConvert:        "参数Items错误",
-	ErrNotPermit:          "没有权限 ，权限为空",
-	ErrParameter:          "类型转换错误",
+	ErrExistRoleNameState:    "角色名称不能重复，请重新输入！",
+	ErrExistPermitState:      "权限设置已设置 ,不能重复设置",
+	ErrItemConvert:           "参数Items错误",
+	ErrNotPermit:             "没有权限 ，权限为空",
+	ErrParameter:             "类型转换错误",
+	ErrRuleViolation:         "数据校验未通过：%s",
+	ErrInvalidRule:           "校验规则错误：%s",
+	ErrNotExistTable:         "表单不存在",
+	ErrNotExistTemplate:      "模板不存在",
+	ErrTableReferenced:       "表单被其他表单引用，无法删除：%s",
+	ErrRelationCycle:         "表单关联存在循环：%s",
+	ErrNotExistJob:           "任务不存在",
+	ErrJobNotFinished:        "任务未完成",
+	ErrImportFile:            "导入文件错误：%s",
+	ErrImportMapping:         "导入字段映射错误：%s",
+	ErrInvalidCursor:         "分页游标无效",
+	ErrInvalidExpand:         "无法展开字段：%s",
+	ErrInvalidAggregate:      "聚合参数错误：%s",
+	ErrAggregateLimit:        "聚合的记录数超过上限：%d",
+	ErrNotExistView:          "视图不存在",
+	ErrNotViewOwner:          "只有创建者可以修改视图",
+	ErrInvalidView:           "视图参数错误：%s",
+	ErrInvalidUpsert:         "更新插入参数错误：%s",
+	ErrUpsertConflict:        "唯一键匹配到多条记录：%s",
+	ErrUpsertForbidden:       "唯一键匹配的记录没有修改权限",
+	ErrIdempotencyInProgress: "相同幂等键的请求正在处理中",
+	ErrIdempotencyMismatch:   "幂等键已被其他请求使用",
+	ErrUpsertBusy:            "相同唯一键的记录正在更新插入，请稍后重试",
+	ErrNotExistWebhook:       "回调不存在",
+	ErrInvalidWebhook:        "回调参数错误：%s",
+	ErrNotExistDelivery:      "回调记录不存在",
+	ErrInvalidEventID:        "事件ID无效",
+	ErrInvalidCommand:        "命令参数错误：%s",
+	ErrInvalidRestore:        "恢复参数错误：%s",
+	ErrNotExistSnapshot:      "备份不存在",
+	ErrInvalidBackupPolicy:   "备份策略参数错误：%s",
+	ErrSnapshotNotFinished:   "备份未完成",
+	ErrRestoreIncomplete:     "表结构已恢复，数据未恢复完成，请以覆盖策略重试：%s",
 }
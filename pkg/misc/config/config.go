@@ -9,6 +9,7 @@ import (
 	"github.com/quanxiang-cloud/cabin/tailormade/client"
 	mysql2 "github.com/quanxiang-cloud/cabin/tailormade/db/mysql"
 	redis2 "github.com/quanxiang-cloud/cabin/tailormade/db/redis"
+	"github.com/quanxiang-cloud/form/pkg/backup/store"
 	"gopkg.in/yaml.v2"
 )
 
@@ -31,11 +32,48 @@ type Config struct {
 	Endpoint    Endpoint      `yaml:"endpoint"`
 	Transport   Transport     `yaml:"transport"`
 	Dapr        Dapr          `yaml:"dapr"`
+	Idempotency Idempotency   `yaml:"idempotency"`
+	Backup      Backup        `yaml:"backup"`
+	Export      Export        `yaml:"export"`
+	Webhook     Webhook       `yaml:"webhook"`
+}
+
+// Webhook the hosts the webhooks may post to, the entries are host names, IPs or CIDRs.
+// the loopback, private and link local addresses are denied unless allowed.
+type Webhook struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// Export the result files of the export jobs are kept in the directory, it is shared by the instances,
+// a directory in the temp one if not set.
+type Export struct {
+	Dir string `yaml:"dir"`
+}
+
+// Backup the snapshots of the apps are kept in the store, the due policies are looked for every tick,
+// a minute if not set.
+type Backup struct {
+	Store store.Config  `yaml:"store"`
+	Tick  time.Duration `yaml:"tick"`
+	// Concurrency the snapshots exported at once, the others wait.
+	Concurrency int `yaml:"concurrency"`
+}
+
+// Idempotency the results of the requests with an Idempotency-Key are kept for TTL, one day if not set.
+type Idempotency struct {
+	TTL time.Duration `yaml:"ttl"`
 }
 
 type Dapr struct {
 	PubSubName string `yaml:"pubSubName"`
 	TopicFlow  string `yaml:"topicFlow"`
+	// TopicEvents the changes are published to as CloudEvents, none if empty.
+	TopicEvents string `yaml:"topicEvents"`
+	// DisableLegacy stop publishing the FormData to TopicFlow, once the consumers read the CloudEvents.
+	DisableLegacy bool `yaml:"disableLegacy"`
+	// TopicReply the results of the record commands are published to, unless the command names one.
+	TopicReply string `yaml:"topicReply"`
 }
 
 type Endpoint struct {
@@ -0,0 +1,9 @@
+// Package schema the sql of each version of form, applied in order by cmd/migrate.
+package schema
+
+import "embed"
+
+// FS the files, as v0.0.1.sql.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,129 @@
+DROP TABLE IF EXISTS `table_template`;
+CREATE TABLE `table_template` (
+    `id` 		 VARCHAR(64) 	COMMENT 'unique id',
+    `app_id` 	 VARCHAR(64) 	COMMENT 'app id the template is saved from',
+    `name`          VARCHAR(64)     NOT NULL COMMENT 'template name',
+    `description`   VARCHAR(255) COMMENT 'description',
+    `content`      MEDIUMTEXT   COMMENT 'table definitions',
+    `created_at`     BIGINT(20) 	    COMMENT 'create time',
+    `creator_id`    VARCHAR(36) COMMENT 'creator id',
+    `creator_name`   VARCHAR(16) COMMENT 'creator name',
+    PRIMARY KEY (`id`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+DROP TABLE IF EXISTS `form_job`;
+CREATE TABLE `form_job` (
+    `id` 		 VARCHAR(64) 	COMMENT 'unique id',
+    `app_id` 	 VARCHAR(64) 	COMMENT 'app id',
+    `table_id`      VARCHAR(64)     COMMENT 'table id',
+    `type`          VARCHAR(16)     COMMENT 'export or import',
+    `status`        VARCHAR(16)     COMMENT 'running, succeed or failed',
+    `total`         BIGINT(20)      COMMENT 'records or rows to handle',
+    `processed`     BIGINT(20)      COMMENT 'records or rows handled',
+    `file_name`     VARCHAR(255)    COMMENT 'result file name of export',
+    `file`          LONGBLOB        COMMENT 'result file of export',
+    `report`        MEDIUMTEXT      COMMENT 'result of import',
+    `message`       VARCHAR(1024)   COMMENT 'error message',
+    `created_at`     BIGINT(20) 	    COMMENT 'create time',
+    `updated_at`     BIGINT(20) 	    COMMENT 'update time',
+    `creator_id`    VARCHAR(36) COMMENT 'creator id',
+    `creator_name`   VARCHAR(16) COMMENT 'creator name',
+    PRIMARY KEY (`id`),
+    KEY `idx_app_table` (`app_id`, `table_id`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+DROP TABLE IF EXISTS `table_view`;
+CREATE TABLE `table_view` (
+    `id` 		 VARCHAR(64) 	COMMENT 'unique id',
+    `app_id` 	 VARCHAR(64) 	COMMENT 'app id',
+    `table_id`      VARCHAR(64)     COMMENT 'table id',
+    `name`          VARCHAR(64)     NOT NULL COMMENT 'view name',
+    `query`         TEXT            COMMENT 'query dsl',
+    `sort`          VARCHAR(1024)   COMMENT 'sort keys',
+    `columns`       TEXT            COMMENT 'visible fields',
+    `page_size`     BIGINT(20)      COMMENT 'page size',
+    `role_id`       VARCHAR(64)     COMMENT 'role the view is shared to',
+    `created_at`     BIGINT(20) 	    COMMENT 'create time',
+    `updated_at`     BIGINT(20) 	    COMMENT 'update time',
+    `creator_id`    VARCHAR(36) COMMENT 'creator id',
+    `creator_name`   VARCHAR(16) COMMENT 'creator name',
+    PRIMARY KEY (`id`),
+    KEY `idx_app_table` (`app_id`, `table_id`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+DROP TABLE IF EXISTS `webhook`;
+CREATE TABLE `webhook` (
+    `id` 		 VARCHAR(64) 	COMMENT 'unique id',
+    `app_id` 	 VARCHAR(64) 	COMMENT 'app id',
+    `table_id`      VARCHAR(64)     COMMENT 'table id',
+    `name`          VARCHAR(64)     NOT NULL COMMENT 'webhook name',
+    `url`           VARCHAR(1024)   NOT NULL COMMENT 'callback url',
+    `secret`        VARCHAR(128)    COMMENT 'signing key of the payloads',
+    `events`        VARCHAR(255)    COMMENT 'create, update or delete',
+    `filter`        TEXT            COMMENT 'query dsl the records must match',
+    `disabled`      TINYINT(1)      DEFAULT 0 COMMENT 'disabled or not',
+    `created_at`     BIGINT(20) 	    COMMENT 'create time',
+    `updated_at`     BIGINT(20) 	    COMMENT 'update time',
+    `creator_id`    VARCHAR(36) COMMENT 'creator id',
+    `creator_name`   VARCHAR(16) COMMENT 'creator name',
+    PRIMARY KEY (`id`),
+    KEY `idx_app_table` (`app_id`, `table_id`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+DROP TABLE IF EXISTS `webhook_delivery`;
+CREATE TABLE `webhook_delivery` (
+    `id` 		 VARCHAR(64) 	COMMENT 'unique id',
+    `webhook_id`    VARCHAR(64)     COMMENT 'webhook id',
+    `app_id` 	 VARCHAR(64) 	COMMENT 'app id',
+    `table_id`      VARCHAR(64)     COMMENT 'table id',
+    `event`         VARCHAR(16)     COMMENT 'create, update or delete',
+    `payload`       MEDIUMTEXT      COMMENT 'posted body',
+    `status`        VARCHAR(16)     COMMENT 'pending, succeed or failed',
+    `attempts`      INT             COMMENT 'attempts made',
+    `status_code`   INT             COMMENT 'http status of the last attempt',
+    `response`      VARCHAR(1024)   COMMENT 'response body or error of the last attempt',
+    `next_at`       BIGINT(20)      COMMENT 'a pending delivery is retried by the retry loop after',
+    `created_at`     BIGINT(20) 	    COMMENT 'create time',
+    `updated_at`     BIGINT(20) 	    COMMENT 'update time',
+    PRIMARY KEY (`id`),
+    KEY `idx_webhook` (`webhook_id`),
+    KEY `idx_status_next` (`status`, `next_at`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+DROP TABLE IF EXISTS `backup_policy`;
+CREATE TABLE `backup_policy` (
+    `id` 		 VARCHAR(64) 	COMMENT 'unique id',
+    `app_id` 	 VARCHAR(64) 	COMMENT 'app id',
+    `interval`      BIGINT(20)      COMMENT 'seconds between two backups',
+    `full_every`    INT             COMMENT 'a full backup every so many backups',
+    `retention`     INT             COMMENT 'full backups kept with their incremental ones',
+    `disabled`      TINYINT(1)      DEFAULT 0 COMMENT 'disabled or not',
+    `next_at`       BIGINT(20)      COMMENT 'time the next backup is due at',
+    `created_at`     BIGINT(20) 	    COMMENT 'create time',
+    `updated_at`     BIGINT(20) 	    COMMENT 'update time',
+    `creator_id`    VARCHAR(36) COMMENT 'creator id',
+    `creator_name`   VARCHAR(16) COMMENT 'creator name',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_app` (`app_id`),
+    KEY `idx_next_at` (`next_at`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+DROP TABLE IF EXISTS `snapshot`;
+CREATE TABLE `snapshot` (
+    `id` 		 VARCHAR(64) 	COMMENT 'unique id',
+    `app_id` 	 VARCHAR(64) 	COMMENT 'app id',
+    `type`          VARCHAR(16)     COMMENT 'full or incremental',
+    `base_id`       VARCHAR(64)     COMMENT 'full snapshot the incremental one is taken after',
+    `since`         BIGINT(20)      COMMENT 'records created or updated since are held',
+    `key`           VARCHAR(255)    COMMENT 'file in the store',
+    `size`          BIGINT(20)      COMMENT 'file size',
+    `status`        VARCHAR(16)     COMMENT 'running, succeed or failed',
+    `message`       VARCHAR(1024)   COMMENT 'error message',
+    `counts`        TEXT            COMMENT 'objects and records held',
+    `created_at`     BIGINT(20) 	    COMMENT 'create time',
+    `updated_at`     BIGINT(20) 	    COMMENT 'update time',
+    `creator_id`    VARCHAR(36) COMMENT 'creator id',
+    `creator_name`   VARCHAR(16) COMMENT 'creator name',
+    PRIMARY KEY (`id`),
+    KEY `idx_app_base` (`app_id`, `base_id`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;